@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	aliasGroup  = "aliases"     // Groups the aliases in the help of the root command
+	aliasTarget = "aliasTarget" // Annotation holding the command line of an alias
+)
+
+// AddAliases adds a top-level command for each alias, running the command line it
+// maps to with the arguments given to the alias, e.g. "start" to "service start"
+func AddAliases(root *cobra.Command, aliases map[string]string) error {
+	names := make([]string, 0, len(aliases))
+	for name := range aliases {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		target := strings.Fields(aliases[name])
+		if len(target) == 0 {
+			return fmt.Errorf("alias %q has no target", name)
+		}
+		if c, _, err := root.Find([]string{name}); err == nil && c != root {
+			return fmt.Errorf("alias %q conflicts with command %q", name, c.CommandPath())
+		}
+		if c, _, err := root.Find(target); err != nil || c == root {
+			return fmt.Errorf("alias %q targets unknown command %q", name, aliases[name])
+		}
+
+		if !root.ContainsGroup(aliasGroup) {
+			root.AddGroup(&cobra.Group{ID: aliasGroup, Title: "Aliases:"})
+		}
+		root.AddCommand(newAliasCmd(root, name, target))
+	}
+	return nil
+}
+
+// newAliasCmd creates the command listing the alias name of target in the help.
+// ExpandAlias replaces it with target before the command line is executed.
+func newAliasCmd(root *cobra.Command, name string, target []string) *cobra.Command {
+	return &cobra.Command{
+		Use:                name,
+		Short:              fmt.Sprintf("Alias for %s %s", root.Name(), strings.Join(target, " ")),
+		GroupID:            aliasGroup,
+		DisableFlagParsing: true,
+		Annotations:        map[string]string{aliasTarget: strings.Join(target, " ")},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return fmt.Errorf("alias %q was not expanded", name)
+		},
+	}
+}
+
+// ExpandAlias returns args with an alias added by AddAliases replaced by the command
+// line it maps to, for root to execute once. The alias must come first, after the
+// flags of root only.
+func ExpandAlias(root *cobra.Command, args []string) []string {
+	i := 0
+	for i < len(args) && strings.HasPrefix(args[i], "-") {
+		i++
+	}
+	if i == len(args) {
+		return args
+	}
+	c, _, err := root.Find([]string{args[i]})
+	if err != nil || c.Annotations[aliasTarget] == "" {
+		return args
+	}
+
+	expanded := append([]string(nil), args[:i]...)
+	expanded = append(expanded, strings.Fields(c.Annotations[aliasTarget])...)
+	return append(expanded, args[i+1:]...)
+}
@@ -0,0 +1,196 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/lucasdecamargo/go-appservice-example/pkg/atomicfile"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/bundle"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/config"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/svcctl"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/systemd"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/ui"
+	"github.com/lucasdecamargo/kardianos"
+	"github.com/spf13/cobra"
+)
+
+// newServiceExportCmd creates a command writing the effective service definition to a bundle
+func newServiceExportCmd(cfg *kardianos.Config) *cobra.Command {
+	var output string
+
+	c := &cobra.Command{
+		Use:   "export",
+		Short: "Export the effective service definition as a portable bundle",
+		Long: `Write the config file, the effective service options and the rendered unit file
+to a single JSON bundle, to move the service to another machine with "service import"
+or restore it after a disaster.
+
+The files, secrets and host variables the managed environment reads are listed by
+reference, never by value. Inline env values are exported as written in the config
+file, so keep the bundle private.`,
+		Example: `  svcapp service export -o svcapp-service.json
+  svcapp service export -o - | ssh host svcapp service import -`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			b, err := exportBundle(cfg)
+			if err != nil {
+				return err
+			}
+
+			var buf bytes.Buffer
+			if err := b.Write(&buf); err != nil {
+				return err
+			}
+			if output == "-" {
+				_, err := os.Stdout.Write(buf.Bytes())
+				return err
+			}
+			if output == "" {
+				output = cfg.Name + "-service.json"
+			}
+			if err := atomicfile.WriteFile(output, buf.Bytes(), 0o600, false); err != nil {
+				return fmt.Errorf("failed to write bundle: %w", err)
+			}
+			ui.Success("Exported %s to %s.", cfg.Name, output)
+			if len(b.Refs) > 0 {
+				ui.Warn("The environment reads %d files, secrets or host variables that aren't in the bundle, make them available on the target machine.", len(b.Refs))
+			}
+			return nil
+		},
+	}
+
+	c.Flags().StringVarP(&output, "output", "o", "", `File to write, "-" for stdout, <name>-service.json by default`)
+
+	return c
+}
+
+// newServiceImportCmd creates a command restoring the service definition from a bundle
+func newServiceImportCmd(i kardianos.Interface, cfg *kardianos.Config) *cobra.Command {
+	var (
+		install bool
+		force   bool
+	)
+
+	c := &cobra.Command{
+		Use:   "import <bundle>",
+		Short: "Import a service definition exported by service export",
+		Long: `Replace the config file with the one of a bundle written by "service export",
+keeping the previous file as a .bak backup, and check that the files, secrets and
+host variables the environment reads are available on this machine.
+
+The bundle must be of the same service. With --install the service is installed
+with the imported definition.`,
+		Example: `  svcapp service import svcapp-service.json --install
+  svcapp service import - < svcapp-service.json`,
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true, // Missing privileges aren't usage errors
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if install {
+				if err := checkPrivileges(cfg.Name, "install"); err != nil {
+					return err
+				}
+			}
+			b, err := readBundle(args[0])
+			if err != nil {
+				return err
+			}
+			if b.Name != cfg.Name {
+				return fmt.Errorf("the bundle is of service %q, not %q", b.Name, cfg.Name)
+			}
+			if platform := kardianos.Platform(); b.Platform != platform && !force {
+				return fmt.Errorf("the bundle was exported on %s, not %s, use --force to import it anyway", b.Platform, platform)
+			}
+
+			path := config.DefaultPath()
+			if err := config.Replace(path, b.Config); err != nil {
+				return fmt.Errorf("failed to import config: %w", err)
+			}
+			ui.Success("Imported the definition exported on %s to %s.", b.ExportedAt.Local().Format(time.DateTime), path)
+
+			for _, r := range b.Refs {
+				if err := r.Check(); err != nil {
+					ui.Warn("%s reads %s %s, which isn't available: %v", r.Env, r.Kind, r.Source, err)
+				}
+			}
+
+			if !install {
+				return nil
+			}
+			if err := ApplyServiceOverrides(cfg); err != nil {
+				return err
+			}
+			return handleServiceCommand(cmd.Context(), i, cfg, "install", svcctl.DefaultRetryConfig())
+		},
+	}
+
+	c.Flags().BoolVar(&install, "install", false, "Install the service after the import")
+	c.Flags().BoolVar(&force, "force", false, "Import a bundle exported on another platform")
+
+	return c
+}
+
+// exportBundle builds the bundle of the config file and the effective settings of cfg
+func exportBundle(cfg *kardianos.Config) (*bundle.Bundle, error) {
+	path := config.DefaultPath()
+	c, err := config.Load(path)
+	if err != nil {
+		return nil, err
+	}
+	settings, err := serviceSettings(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	// Keep the file as written, with the effective settings as its service section
+	doc := map[string]any{}
+	data, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	default:
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		if err := dec.Decode(&doc); err != nil {
+			return nil, fmt.Errorf("failed to parse config %s: %w", path, err)
+		}
+	}
+	doc["service"] = settings
+	if data, err = json.MarshalIndent(doc, "", "    "); err != nil {
+		return nil, err
+	}
+
+	b := &bundle.Bundle{
+		Name:       cfg.Name,
+		Platform:   kardianos.Platform(),
+		ExportedAt: time.Now().UTC(),
+		Config:     data,
+		Refs:       bundle.Refs(c),
+	}
+	if strings.HasSuffix(b.Platform, "systemd") {
+		if b.Unit, err = systemd.Render(cfg); err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+// readBundle reads a bundle from a file, or from stdin with "-"
+func readBundle(name string) (*bundle.Bundle, error) {
+	var r io.Reader = os.Stdin
+	if name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+	return bundle.Read(r)
+}
@@ -0,0 +1,179 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/lucasdecamargo/go-appservice-example/pkg/atomicfile"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/dirs"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/ui"
+	"github.com/spf13/cobra"
+)
+
+// Shells supported by completion install
+const (
+	shellBash       = "bash"
+	shellZsh        = "zsh"
+	shellFish       = "fish"
+	shellPowerShell = "powershell"
+)
+
+// AddCompletionInstall adds an install subcommand to the completion command of root,
+// creating the default completion command first
+func AddCompletionInstall(root *cobra.Command) {
+	root.InitDefaultCompletionCmd()
+	for _, c := range root.Commands() {
+		if c.Name() == "completion" {
+			c.AddCommand(newCompletionInstallCmd(root))
+			return
+		}
+	}
+}
+
+// newCompletionInstallCmd creates a command writing the completion script where the shell loads it
+func newCompletionInstallCmd(root *cobra.Command) *cobra.Command {
+	var (
+		shell   string
+		userDir bool
+	)
+
+	c := &cobra.Command{
+		Use:   "install",
+		Short: "Install the autocompletion script for the current shell",
+		Long: `Detect the shell and write its autocompletion script where it is loaded from.
+
+As root, the script is installed system-wide. Otherwise, or with --user, it is installed
+for the current user. Under sudo, --user installs it for the user who invoked sudo.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if shell == "" {
+				shell = detectShell()
+			}
+
+			var script bytes.Buffer
+			if err := genCompletion(root, shell, &script); err != nil {
+				return err
+			}
+
+			username := ""
+			system := !userDir && isRoot()
+			if !system && isRoot() {
+				username = os.Getenv("SUDO_USER")
+			}
+
+			path, err := completionPath(root.Name(), shell, system, username)
+			if err != nil {
+				return err
+			}
+			if err := dirs.Ensure(username, filepath.Dir(path)); err != nil {
+				return err
+			}
+			if err := atomicfile.WriteFile(path, script.Bytes(), 0o644, false); err != nil {
+				return fmt.Errorf("failed to write completion script: %w", err)
+			}
+			if username != "" {
+				if err := dirs.Chown(path, username); err != nil {
+					return err
+				}
+			}
+
+			ui.Success("Installed %s completion to %s.", shell, path)
+			fmt.Println(completionHint(shell, path))
+			return nil
+		},
+	}
+
+	c.Flags().StringVar(&shell, "shell", "", "Shell to install for: bash, zsh, fish or powershell (default detected)")
+	c.Flags().BoolVar(&userDir, "user", false, "Install for the current user even when running as root")
+
+	return c
+}
+
+// detectShell returns the login shell, or PowerShell on Windows
+func detectShell() string {
+	if runtime.GOOS == "windows" {
+		return shellPowerShell
+	}
+	return filepath.Base(os.Getenv("SHELL"))
+}
+
+// genCompletion writes the completion script of root for shell to buf
+func genCompletion(root *cobra.Command, shell string, buf *bytes.Buffer) error {
+	switch shell {
+	case shellBash:
+		return root.GenBashCompletionV2(buf, true)
+	case shellZsh:
+		return root.GenZshCompletion(buf)
+	case shellFish:
+		return root.GenFishCompletion(buf, true)
+	case shellPowerShell:
+		return root.GenPowerShellCompletionWithDesc(buf)
+	default:
+		return fmt.Errorf("unsupported shell %q: use --shell with bash, zsh, fish or powershell", shell)
+	}
+}
+
+// completionPath returns where shell loads the completion script of name from,
+// system-wide or from the home directory of username (the current user when empty)
+func completionPath(name, shell string, system bool, username string) (string, error) {
+	if system {
+		switch shell {
+		case shellBash:
+			return "/usr/share/bash-completion/completions/" + name, nil
+		case shellZsh:
+			return "/usr/local/share/zsh/site-functions/_" + name, nil
+		case shellFish:
+			return "/usr/share/fish/vendor_completions.d/" + name + ".fish", nil
+		}
+		if runtime.GOOS != "windows" {
+			return "", fmt.Errorf("%s has no system-wide completion directory, use --user", shell)
+		}
+	}
+
+	home, err := os.UserHomeDir()
+	if username != "" {
+		home, err = dirs.ExpandHome("~", username)
+	}
+	if err != nil {
+		return "", err
+	}
+	// Only the current user's XDG variables apply, sudo keeps the caller's environment
+	xdg := func(env, def string) string {
+		if v := os.Getenv(env); v != "" && username == "" {
+			return v
+		}
+		return filepath.Join(home, def)
+	}
+
+	switch shell {
+	case shellBash:
+		return filepath.Join(xdg("XDG_DATA_HOME", ".local/share"), "bash-completion", "completions", name), nil
+	case shellZsh:
+		return filepath.Join(home, ".zfunc", "_"+name), nil
+	case shellFish:
+		return filepath.Join(xdg("XDG_CONFIG_HOME", ".config"), "fish", "completions", name+".fish"), nil
+	default:
+		return filepath.Join(home, "Documents", "PowerShell", "Scripts", name+"-completion.ps1"), nil
+	}
+}
+
+// completionHint returns what the user still has to do for shell to load path
+func completionHint(shell, path string) string {
+	switch {
+	case shell == shellZsh && strings.HasSuffix(filepath.Dir(path), ".zfunc"):
+		return "Add 'fpath=(~/.zfunc $fpath)' before 'compinit' in ~/.zshrc."
+	case shell == shellPowerShell:
+		return fmt.Sprintf("Add '. %s' to your PowerShell $PROFILE.", path)
+	default:
+		return "Start a new shell to load it."
+	}
+}
+
+// isRoot reports whether the command runs as root. It is always false on Windows.
+func isRoot() bool {
+	return os.Geteuid() == 0
+}
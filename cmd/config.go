@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/lucasdecamargo/go-appservice-example/pkg/config"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/control"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/ui"
+	"github.com/spf13/cobra"
+)
+
+// NewConfigCmd creates a command for reading and editing the configuration file
+func NewConfigCmd() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "config",
+		Short: "Read and edit the configuration file",
+	}
+
+	c.AddCommand(newConfigGetCmd(), newConfigSetCmd(), newConfigSchemaCmd())
+
+	return c
+}
+
+// newConfigGetCmd creates a command printing a configuration value
+func newConfigGetCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:     "get <key>",
+		Short:   "Print the value at a dotted key, such as profiles.prod.args",
+		Example: "  svcapp config get profiles.prod.limits",
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			v, err := config.Get(config.DefaultPath(), args[0])
+			if err != nil {
+				return err
+			}
+
+			// Print strings bare, everything else as JSON
+			if s, ok := v.(string); ok {
+				fmt.Println(s)
+				return nil
+			}
+			data, err := json.MarshalIndent(v, "", "    ")
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(data))
+			return nil
+		},
+	}
+}
+
+// newConfigSchemaCmd creates a command printing the JSON Schema of the configuration file
+func newConfigSchemaCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "schema",
+		Short: "Print the JSON Schema of the configuration file, for editor completion",
+		Long: `Print the JSON Schema the configuration file is validated against when it is
+loaded. Point an editor at it for completion and inline errors, for example with a
+"$schema" key in the file or the json.schemas setting of VS Code.`,
+		Example: "  svcapp config schema > /etc/svcapp/config.schema.json",
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			_, err := os.Stdout.Write(config.Schema())
+			return err
+		},
+	}
+}
+
+// newConfigSetCmd creates a command changing a configuration value
+func newConfigSetCmd() *cobra.Command {
+	var apply bool
+
+	c := &cobra.Command{
+		Use:   "set <key> <value>",
+		Short: "Set the value at a dotted key, validating the result and keeping a backup",
+		Long: `Set the value at a dotted key. The value is parsed as JSON when valid, as a string
+otherwise. The file is only replaced when the result is a valid configuration, and the
+previous version is kept with a .bak suffix.`,
+		Example: `  svcapp config set profile prod
+  svcapp config set profiles.prod.limits.startTimeout 30s --apply
+  svcapp config set profiles.prod.args '["--timeout", "1m"]'`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := config.Set(config.DefaultPath(), args[0], args[1]); err != nil {
+				return err
+			}
+			ui.Success("Set %s.", args[0])
+
+			if !apply {
+				return nil
+			}
+			return reloadDaemon(cmd.Context())
+		},
+	}
+
+	c.Flags().BoolVar(&apply, "apply", false, "Reload the running daemon with the new configuration")
+
+	return c
+}
+
+// reloadDaemon asks the running daemon to rebuild the child from the configuration file.
+// It waits for the child to be recycled, bounded by the control client timeout.
+func reloadDaemon(ctx context.Context) error {
+	return ui.Spin(os.Stdout, "Reloading the daemon", func() error {
+		_, err := control.NewClient(control.DefaultAddr()).Reload(ctx)
+		return err
+	})
+}
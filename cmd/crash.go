@@ -0,0 +1,207 @@
+package cmd
+
+import (
+	"archive/tar"
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/lucasdecamargo/go-appservice-example/pkg/archive"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/atomicfile"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/config"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/crash"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/state"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/ui"
+	"github.com/spf13/cobra"
+)
+
+// NewCrashCmd creates a command listing the crash reports and exporting them for sharing
+func NewCrashCmd() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "crash",
+		Short: "List and export the crash reports of the application",
+	}
+	c.AddCommand(newCrashListCmd(), newCrashExportCmd())
+	return c
+}
+
+// newCrashListCmd creates a command listing the crash reports, oldest first
+func newCrashListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List the crash reports, oldest first",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dir := crash.DefaultDir()
+			ids, err := crash.List(dir)
+			if err != nil {
+				return err
+			}
+			if len(ids) == 0 {
+				fmt.Println("No crash reports.")
+				return nil
+			}
+
+			tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(tw, "ID\tTIME\tREASON")
+			for _, id := range ids {
+				r, err := crash.Load(dir, id)
+				if err != nil {
+					fmt.Fprintf(tw, "%s\t-\t%v\n", id, err)
+					continue
+				}
+				reason, _, _ := strings.Cut(cmp.Or(r.Panic, r.Exit), "\n")
+				fmt.Fprintf(tw, "%s\t%s\t%s\n", id, r.Time.Format(time.RFC3339), reason)
+			}
+			return tw.Flush()
+		},
+	}
+}
+
+// newCrashExportCmd creates a command bundling a crash report with the context needed to
+// investigate it into a single archive
+func newCrashExportCmd() *cobra.Command {
+	var output string
+
+	c := &cobra.Command{
+		Use:   "export <id>",
+		Short: "Bundle a crash report, the daemon state and the child logs into one archive",
+		Long: `Bundle a crash report with the daemon state and the current and last rotated child
+log files into a single tar archive to share.
+
+The archive is compressed with the configured algorithm, gzip by default.`,
+		Args: cobra.ExactArgs(1),
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			ids, _ := crash.List(crash.DefaultDir())
+			return ids, cobra.ShellCompDirectiveNoFileComp
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := config.Load(config.DefaultPath())
+			if err != nil {
+				return err
+			}
+			compress := c.Compression.Options()
+			if compress.Algorithm == archive.None {
+				compress = archive.Options{Algorithm: archive.Gzip}
+			}
+			if output == "" {
+				output = fmt.Sprintf("svcapp-crash-%s.tar%s", args[0], compress.Ext())
+			}
+
+			report, err := crash.Path(crash.DefaultDir(), args[0])
+			if err != nil {
+				return err
+			}
+			if err := exportCrash(output, report, c.Output, compress); err != nil {
+				return err
+			}
+			ui.Success("Exported crash %s to %s.", args[0], output)
+			return nil
+		},
+	}
+
+	c.Flags().StringVarP(&output, "output", "o", "", "Archive path, svcapp-crash-<id>.tar.gz by default")
+
+	return c
+}
+
+// exportCrash writes the archive of the crash report at report to path. Files are stored
+// decompressed, so the archive only needs to be unpacked once.
+func exportCrash(path, report string, out config.Output, compress archive.Options) error {
+	files := map[string]string{
+		"report.json": report,
+		"state.json":  state.DefaultPath(),
+	}
+	for _, s := range []config.Stream{out.Stdout, out.Stderr} {
+		if s.File == "" {
+			continue
+		}
+		files["logs/"+filepath.Base(s.File)] = s.File
+		for _, ext := range append([]string{""}, archive.Exts...) {
+			if rotated := s.File + ".1" + ext; fileExists(rotated) {
+				files["logs/"+filepath.Base(s.File)+".1"] = rotated
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	zw, err := archive.NewWriter(&buf, compress)
+	if err != nil {
+		return err
+	}
+	tw := tar.NewWriter(zw)
+
+	info, err := json.MarshalIndent(map[string]any{
+		"exportedAt": time.Now().UTC(),
+		"os":         runtime.GOOS,
+		"arch":       runtime.GOARCH,
+		"goVersion":  runtime.Version(),
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := addTarFile(tw, "info.json", info); err != nil {
+		return err
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(files)) {
+		data, err := readDecompressed(files[name])
+		if errors.Is(err, os.ErrNotExist) && name != "report.json" {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if err := addTarFile(tw, name, data); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	if err := atomicfile.WriteFile(path, buf.Bytes(), 0o600, false); err != nil {
+		return fmt.Errorf("failed to write crash archive: %w", err)
+	}
+	return nil
+}
+
+// addTarFile adds a regular file holding data to tw
+func addTarFile(tw *tar.Writer, name string, data []byte) error {
+	hdr := &tar.Header{Name: name, Mode: 0o644, Size: int64(len(data)), ModTime: time.Now()}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err := tw.Write(data)
+	return err
+}
+
+// readDecompressed reads the file at path, decompressing it according to its extension
+func readDecompressed(path string) ([]byte, error) {
+	f, err := archive.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
+
+// fileExists reports whether path names an existing file
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
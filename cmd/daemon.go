@@ -1,14 +1,48 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"log/slog"
 	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/lucasdecamargo/go-appservice-example/pkg/audit"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/config"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/control"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/crash"
 	"github.com/lucasdecamargo/go-appservice-example/pkg/daemon"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/dbus"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/detach"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/dirs"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/fleet"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/kv"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/lbhook"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/loglevel"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/netcheck"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/paths"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/pidfile"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/ports"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/rlimit"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/secretfd"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/state"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/store"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/sysshutdown"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/systemd"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/update"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/webhook"
 	"github.com/lucasdecamargo/kardianos"
 	"github.com/spf13/cobra"
 )
 
+// webhookCloseTimeout bounds the delivery of the last lifecycle events on exit
+const webhookCloseTimeout = 5 * time.Second
+
 // NewDaemonCmd creates a command for running the application as a daemon process supervisor.
 // The daemon command runs the application in service mode, supervising child processes
 // and managing their lifecycle. It requires root privileges for proper service operation.
@@ -19,46 +53,629 @@ import (
 // - Handles graceful shutdowns and signal management
 // - Supports additional command-line arguments passed to the child process
 //
+// The supervision, logging, storage and integrations are set in the config file, see
+// the config package. The flags of the daemon itself are described in its help.
+//
 // Usage:
 //
 //	svcapp daemon                    # Run with default configuration
 //	svcapp daemon -v --flag val      # Run with additional arguments
+//	svcapp daemon --profile staging  # Run with the "staging" config profile
 //	sudo svcapp daemon               # Run with root privileges (recommended)
 //
 // Parameters:
 //
 //	d:   The daemon instance that implements process supervision
 //	cfg: Service configuration for the target operating system
+//	wrappers: Decorators of the daemon Start and Stop calls, outermost first
 //
 // Returns:
 //
 //	A configured cobra.Command that handles daemon execution
-func NewDaemonCmd(d *daemon.Daemon, cfg *kardianos.Config) *cobra.Command {
+func NewDaemonCmd(d *daemon.Daemon, cfg *kardianos.Config, wrappers ...daemon.Wrapper) *cobra.Command {
 	c := &cobra.Command{
-		Use:                "daemon",
-		Short:              "Manage the daemon service. Requires root privileges.",
-		Long:               "Run the application as a daemon process supervisor that monitors and restarts child processes.",
+		Use:   "daemon",
+		Short: "Manage the daemon service. Requires root privileges.",
+		Long: `Run the application as a daemon process supervisor that monitors and restarts child processes.
+
+Arguments are passed to the child, except for the daemon flags:
+  --profile <name>        Apply a config profile, also selected by SVCAPP_PROFILE
+  --pidfile <path>        Write the PID file at path instead of the service one
+  --detach                Run in the background, where there is no service manager
+  --log-file <path>       Append the output of a detached daemon to path
+  --adopt-pid <pid>       Supervise the running process pid as the first child
+  --adopt-pidfile <path>  Supervise the running process whose PID is in path
+  --stdin env|fd          Read secrets as KEY=VALUE lines from stdin, passed to the
+                          child in its environment or as sealed files`,
+		Example: `  svcapp daemon --detach --log-file /tmp/svcapp.log
+  svcapp daemon --adopt-pid 4242
+  vault read ... | svcapp daemon --stdin env`,
 		DisableFlagParsing: true, // Allow passing arbitrary arguments to child process
 		Run: func(cmd *cobra.Command, args []string) {
-			// Append any additional arguments to the daemon's argument list
-			if len(args) > 0 {
-				d.Args = append(d.Args, args...)
+			c, err := config.Load(config.DefaultPath())
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(ExitCode(err))
+			}
+
+			profile, args := takeFlag(args, "profile")
+			stdin, args := takeFlag(args, "stdin")
+			pidFile, args := takeFlag(args, "pidfile")
+			detached, args := takeSwitch(args, "detach")
+			logFile, args := takeFlag(args, "log-file")
+			adoptPID, args := takeFlag(args, "adopt-pid")
+			adoptPIDFile, args := takeFlag(args, "adopt-pidfile")
+
+			// Name the service in the state, hooks, logs and audit records, with its instance
+			d.Service = serviceName(cfg)
+
+			// Hold the PID file the service manager tracks the daemon with
+			d.PIDFile, _ = cfg.Option["PIDFile"].(string)
+			if pidFile != "" {
+				d.PIDFile = pidFile
+			}
+
+			// Relaunch in the background when there's no service manager to do it
+			if detached {
+				if err := startDetached(d.PIDFile, logFile, stdin != ""); err != nil {
+					fmt.Println(err)
+					os.Exit(ExitCode(err))
+				}
+				return
+			}
+
+			// Supervise a process already running, such as one left by a previous supervisor
+			if d.Adopt, err = adoptTarget(adoptPID, adoptPIDFile); err != nil {
+				fmt.Println(err)
+				os.Exit(ExitCode(err))
+			}
+
+			// Trade observability for memory on constrained devices
+			d.Lean = c.Lean
+
+			// Leave the resource limits to systemd, which sets them on the daemon for the child
+			// to inherit. Elsewhere the daemon applies them to the child itself.
+			if systemd.Managed() || !rlimit.Supported {
+				d.Limits = rlimit.Limits{}
+			}
+
+			// Fail fast when the directories the service relies on are unusable, but keep
+			// supervising from memory when they are on a read-only file system
+			if err := prepareDirs(cfg, c.Output); dirs.IsReadOnly(err) {
+				slog.Error("Running on a read-only file system, keeping the state and logs in memory where needed", "error", err)
+			} else if err != nil {
+				fmt.Println(err)
+				os.Exit(ExitCode(err))
+			}
+
+			// Route the child output to the console and log files
+			closeOutput, err := applyOutput(d, c.Output, c.Compression.Options())
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(ExitCode(err))
+			}
+			defer closeOutput()
+
+			// Schedule the jobs, each logging to its own file
+			jobList, closeJobs, err := configureJobs(c.Jobs, c.Compression.Options())
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(ExitCode(err))
+			}
+			defer closeJobs()
+			d.Jobs = jobList
+
+			// Report children killed by a signal or an exception, which can't report themselves
+			d.CrashDir, d.CrashCompression = crash.DefaultDir(), c.Compression.Options()
+
+			// Ping children that can't serve a health endpoint through their stdin
+			d.Heartbeat = heartbeat(c.Heartbeat)
+
+			// Warn the child before stopping it, so it stops accepting new work
+			if d.LameDuck, err = lameDuck(c.LameDuck); err != nil {
+				fmt.Println(err)
+				os.Exit(ExitCode(err))
+			}
+
+			// Shift the load balancer traffic away from the child around its restarts
+			d.Traffic = traffic(d.Service, c.LoadBalancer)
+
+			// Snapshot the service data before reloads and updates
+			d.Snapshots = snapshots(c.Snapshot)
+
+			// Execute the child in its own SELinux context or AppArmor profile
+			d.Confinement = c.Confinement
+
+			// Give the child a private /tmp, read-only paths and bind mounts
+			d.Mounts = c.Mounts
+
+			// Find the ports of children that pick them at run time
+			if d.Ports, err = portDetection(c.Ports); err != nil {
+				fmt.Println(err)
+				os.Exit(ExitCode(err))
+			}
+
+			// Let a stopping child flush within the exit timeout on a loaded host
+			d.ShutdownBoost = c.ShutdownBoost
+
+			// Keep tiny bits of child state, such as a cursor, in a key/value store
+			if d.KV, err = kv.Open(kv.DefaultPath()); err != nil {
+				slog.Warn("Key/value store disabled", "error", err)
 			}
 
-			// Create and start the service
-			s, err := kardianos.New(d, cfg)
+			// Bound the history and crash reports kept on disk
+			d.Retention = daemon.Retention{History: c.Retention.History.Policy(), Crashes: c.Retention.Crashes.Policy()}
+
+			// Persist the state and history in the configured backend
+			st, err := store.Open(c.Storage)
 			if err != nil {
 				fmt.Println(err)
-				os.Exit(1)
+				os.Exit(ExitCode(err))
+			}
+			defer st.Close()
+			d.Store = st
+
+			// Read secrets piped on stdin, keeping them out of the unit file and disk
+			child := &childConfig{args: d.Args, env: d.EnvVars, profile: profile, extraArgs: args}
+			if err := child.readStdin(stdin); err != nil {
+				fmt.Println(err)
+				os.Exit(ExitCode(err))
 			}
+			d.Secrets = child.secrets
+
+			// Build the child command line from the profile and the daemon arguments
+			if d.Args, d.EnvVars, err = child.build(c); err != nil {
+				fmt.Println(err)
+				os.Exit(ExitCode(err))
+			}
+			applyLimits(d, c, profile)
+
+			// Hold the child until the network is usable, network-online.target isn't reliable
+			d.WaitForNetwork = netcheck.Check{Route: c.WaitForNetwork.Route, Resolve: c.WaitForNetwork.Resolve, Reach: c.WaitForNetwork.Reach}
+			d.NetworkTimeout = time.Duration(c.WaitForNetwork.Timeout)
+
+			// Post the child lifecycle events to the configured webhooks
+			hooks, err := startWebhooks(d.Service, c.Webhooks)
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(ExitCode(err))
+			}
+			if hooks != nil {
+				d.OnLifecycle = hooks.Notify
+				defer hooks.Close(webhookCloseTimeout)
+				d.RegisterFootprint(func() daemon.Footprint {
+					bytes, goroutines := hooks.Footprint()
+					return daemon.Footprint{Subsystem: "webhooks", Enabled: true, Bytes: bytes, Goroutines: goroutines}
+				})
+			}
+
+			// Fingerprint the files each child is built from, for "service restart --if-changed"
+			d.Sources = func() state.Sources { return childSources(d.Executable, config.DefaultPath()) }
+
+			// Rebuild it from the current config file on reload
+			d.Reconfigure = func() ([]string, []string, error) {
+				c, err := config.Load(config.DefaultPath())
+				if err != nil {
+					return nil, nil, err
+				}
+				return child.build(c)
+			}
+
+			// Create and start the service, decorated by the wrappers composed in main
+			svc := daemon.Wrap(d, wrappers...)
+			s, err := kardianos.New(svc, cfg)
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(ExitCode(err))
+			}
+
+			// Change the log level on SIGUSR1 and SIGUSR2 for as long as the service runs
+			defer loglevel.HandleSignals()()
+
+			// Ping the systemd watchdog for as long as the service runs, the supervisor
+			// is responsive and the control socket accepts connections
+			ctx, cancel := context.WithCancel(cmd.Context())
+			defer cancel()
+			go systemd.RunWatchdog(ctx, d.Healthy, func() error {
+				if err := control.Check(ctx, control.DefaultAddr()); err != nil {
+					return fmt.Errorf("control socket: %w", err)
+				}
+				return nil
+			})
+
+			// Record who changes the daemon through the control socket, D-Bus or the fleet
+			auditLog, err := audit.Open(c.Audit, d.Service)
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(ExitCode(err))
+			}
+			defer auditLog.Close()
+
+			// Serve the control API for as long as the service runs
+			check := time.Duration(c.Control.SelfCheck)
+			server := control.NewServer(d)
+			server.SetAudit(auditLog)
+			go server.Supervise(ctx, control.DefaultAddr(), check, reportControl(d, control.DefaultAddr()))
+			if addr := control.StatusAddr(c.Control.Readers); addr != "" {
+				go control.NewStatusServer(d).Supervise(ctx, addr, check, reportControl(d, addr))
+			}
+			go serveDBus(ctx, d, auditLog)
+			if c.DelayShutdown {
+				go delayShutdown(ctx, d.Service, func() { svc.Stop(s) })
+			}
+			go runFleet(ctx, d, c.Fleet, auditLog)
+
+			// Check the release feed, rolling back an update interrupted by a stop or crash first
+			recoverUpdate(d.Executable)
+			go runUpdates(ctx, d, c.Updates, cmd.Root().Version)
+
+			// Show what is about to run: platform, executable, child, limits and log destinations
+			newStartupSummary(d, cfg, c, child, cmd.Root().Version).Print()
 
 			// Run the service (this blocks until the service stops)
-			if err := s.Run(); err != nil {
+			if err := sysshutdown.Run(d.Service, s, svc); err != nil {
 				fmt.Println(err)
-				os.Exit(1)
+				// Deferred calls don't run on exit, deliver the last events and logs and
+				// write the pending state first
+				if hooks != nil {
+					hooks.Close(webhookCloseTimeout)
+				}
+				closeOutput()
+				closeJobs()
+				st.Close()
+				os.Exit(ExitCode(err))
 			}
 		},
 	}
 
 	return c
 }
+
+// takeFlag removes "--name value" or "--name=value" from args, returning its value and
+// the remaining arguments. Flag parsing is disabled on the daemon command, so flags
+// meant for the supervisor itself are extracted by hand.
+func takeFlag(args []string, name string) (string, []string) {
+	flag := "--" + name
+	rest := make([]string, 0, len(args))
+	value := ""
+
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == flag && i+1 < len(args):
+			value = args[i+1]
+			i++
+		case strings.HasPrefix(args[i], flag+"="):
+			value = strings.TrimPrefix(args[i], flag+"=")
+		default:
+			rest = append(rest, args[i])
+		}
+	}
+
+	return value, rest
+}
+
+// adoptTarget returns the PID given by --adopt-pid or read from --adopt-pidfile, zero
+// without either
+func adoptTarget(pid, pidFile string) (int, error) {
+	switch {
+	case pid != "" && pidFile != "":
+		return 0, errors.New("--adopt-pid and --adopt-pidfile are mutually exclusive")
+	case pid != "":
+		n, err := strconv.Atoi(pid)
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("invalid --adopt-pid %q", pid)
+		}
+		return n, nil
+	case pidFile != "":
+		return pidfile.Read(pidFile)
+	}
+	return 0, nil
+}
+
+// takeSwitch removes the boolean flag "--name" from args, reporting whether it was present
+func takeSwitch(args []string, name string) (bool, []string) {
+	flag := "--" + name
+	rest := slices.DeleteFunc(slices.Clone(args), func(a string) bool { return a == flag })
+	return len(rest) != len(args), rest
+}
+
+// startDetached relaunches the daemon command in the background, without --detach and
+// with its output appended to logFile. The relaunched daemon writes pidFile, which
+// defaults to <app>.pid next to the state file. Stdin is handed over for --stdin.
+func startDetached(pidFile, logFile string, withStdin bool) error {
+	dir := filepath.Dir(state.DefaultPath())
+	if pidFile == "" {
+		pidFile = filepath.Join(dir, paths.App()+".pid")
+	}
+	if logFile == "" {
+		logFile = filepath.Join(dir, "daemon.log")
+	}
+
+	_, args := takeSwitch(os.Args[1:], "detach")
+	_, args = takeFlag(args, "log-file")
+	_, args = takeFlag(args, "pidfile")
+	opts := detach.Options{Args: append(args, "--pidfile="+pidFile), LogFile: logFile}
+	if withStdin {
+		opts.Stdin = os.Stdin
+	}
+
+	pid, err := detach.Start(opts)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Daemon detached with PID %d, logging to %s\n", pid, logFile)
+	return nil
+}
+
+// childConfig holds what the child command line is built from, so it can be rebuilt
+// when the config file changes
+type childConfig struct {
+	args, env           []string          // Base arguments and environment of the daemon
+	profile             string            // Profile selected on the command line
+	stdinArgs, stdinEnv []string          // Secrets read from stdin, kept in memory only
+	secrets             []secretfd.Secret // Secrets read from stdin, passed as file descriptors
+	extraArgs           []string          // Additional daemon arguments passed through to the child
+}
+
+// readStdin reads an environment block ("env"), argument list ("args") or secrets passed
+// as file descriptors ("fd") from stdin. The values only ever live in memory.
+func (cc *childConfig) readStdin(mode string) error {
+	var err error
+	switch mode {
+	case "":
+	case "env":
+		if cc.stdinEnv, err = config.ParseEnv(os.Stdin); err != nil {
+			return fmt.Errorf("failed to read environment from stdin: %w", err)
+		}
+	case "args":
+		if cc.stdinArgs, err = config.ParseArgs(os.Stdin); err != nil {
+			return fmt.Errorf("failed to read arguments from stdin: %w", err)
+		}
+	case "fd":
+		if !secretfd.Supported {
+			return secretfd.ErrUnsupported
+		}
+		vars, err := config.ParseEnv(os.Stdin)
+		if err != nil {
+			return fmt.Errorf("failed to read secrets from stdin: %w", err)
+		}
+		cc.secrets = secretfd.Parse(vars)
+	default:
+		return fmt.Errorf("invalid --stdin %q: expected env, args or fd", mode)
+	}
+	return nil
+}
+
+// build returns the child arguments and environment: the base ones, then the selected
+// profile, the managed variables, the stdin secrets, the Go runtime settings and the
+// additional arguments
+func (cc *childConfig) build(c *config.Config) (args, env []string, err error) {
+	p, _, err := c.SelectProfile(cc.profile)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	vars, err := childEnv(c, cc.env, cc.profile, cc.stdinEnv)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	args = slices.Clone(cc.args)
+	if p != nil {
+		args = append(args, p.Args...)
+	}
+	args = append(args, cc.stdinArgs...)
+
+	return append(args, cc.extraArgs...), config.EnvPairs(vars), nil
+}
+
+// applyLimits overrides the daemon limits with those of the selected profile
+func applyLimits(d *daemon.Daemon, c *config.Config, name string) {
+	p, _, err := c.SelectProfile(name)
+	if err != nil || p == nil {
+		return
+	}
+
+	if p.Limits.ExitTimeout > 0 {
+		d.ExitTimeout = time.Duration(p.Limits.ExitTimeout)
+	}
+	if p.Limits.DrainTimeout > 0 {
+		d.DrainTimeout = time.Duration(p.Limits.DrainTimeout)
+	}
+	if p.Limits.StartTimeout > 0 {
+		d.StartTimeout = time.Duration(p.Limits.StartTimeout)
+	}
+	if p.Limits.StartRetries > 0 {
+		d.StartRetries = p.Limits.StartRetries
+	}
+	if p.Limits.MaxRuntime > 0 {
+		d.MaxRuntime = time.Duration(p.Limits.MaxRuntime)
+		d.MaxRuntimeJitter = time.Duration(p.Limits.MaxRuntimeJitter)
+	}
+}
+
+// heartbeat converts the configured heartbeat to the daemon settings
+func heartbeat(c config.Heartbeat) daemon.Heartbeat {
+	hb := daemon.Heartbeat{Interval: time.Duration(c.Interval), Timeout: time.Duration(c.Timeout)}
+	if c.OnFailure == "stop" {
+		hb.OnFailure = daemon.LivenessStop
+	}
+	return hb
+}
+
+// lameDuck converts the configured lame duck notice to the daemon settings
+func lameDuck(c config.LameDuck) (daemon.LameDuck, error) {
+	ld := daemon.LameDuck{Period: time.Duration(c.Period)}
+	if c.Signal != "" {
+		sig, err := daemon.ParseSignal(c.Signal)
+		if err != nil {
+			return ld, fmt.Errorf("lameDuck: %w", err)
+		}
+		ld.Signal = sig
+	}
+	return ld, nil
+}
+
+// traffic converts the configured load balancer hooks to the daemon settings
+func traffic(service string, lb config.LoadBalancer) daemon.Traffic {
+	hostname, _ := os.Hostname()
+	run := func(phase string, hooks []config.Hook) func(context.Context, int) error {
+		if len(hooks) == 0 {
+			return nil
+		}
+		list := make([]lbhook.Hook, len(hooks))
+		for i, h := range hooks {
+			list[i] = h.Hook()
+		}
+		return func(ctx context.Context, pid int) error {
+			return lbhook.Run(ctx, list, lbhook.Payload{Phase: phase, Service: service, Hostname: hostname, PID: pid})
+		}
+	}
+	return daemon.Traffic{
+		Deregister: run(lbhook.PhaseDeregister, lb.Deregister),
+		Register:   run(lbhook.PhaseRegister, lb.Register),
+		Delay:      time.Duration(lb.Delay),
+	}
+}
+
+// snapshots converts the configured snapshot commands to the daemon hooks
+func snapshots(s config.Snapshot) daemon.Snapshots {
+	if len(s.Command) == 0 {
+		return daemon.Snapshots{}
+	}
+	h := s.Hook()
+	snap := daemon.Snapshots{Take: h.Take, Required: s.Required}
+	if len(s.Restore) > 0 {
+		snap.Restore = h.Restore
+	}
+	return snap
+}
+
+// portDetection converts the configured port detection to the daemon settings
+func portDetection(c config.Ports) (daemon.PortDetection, error) {
+	p := daemon.PortDetection{Poll: time.Duration(c.Poll)}
+	if c.Pattern != "" {
+		m, err := ports.Compile(c.Pattern)
+		if err != nil {
+			return p, fmt.Errorf("ports: %w", err)
+		}
+		p.Match = m
+	}
+	return p, nil
+}
+
+// startWebhooks starts delivering lifecycle events to the webhooks, if any are configured
+func startWebhooks(service string, hooks []config.Webhook) (*webhook.Sender, error) {
+	if len(hooks) == 0 {
+		return nil, nil
+	}
+	endpoints := make([]webhook.Endpoint, len(hooks))
+	for i, h := range hooks {
+		endpoints[i] = webhook.Endpoint{URL: h.URL, Secret: h.Secret, Events: h.Events}
+	}
+	return webhook.New(service, endpoints)
+}
+
+// runFleet reports to the fleet management server until ctx is done, when configured
+func runFleet(ctx context.Context, d *daemon.Daemon, f config.Fleet, auditLog *audit.Logger) {
+	if f.URL == "" {
+		return
+	}
+
+	opts := fleet.Options{
+		URL:      f.URL,
+		ID:       f.ID,
+		Token:    f.Token,
+		Interval: time.Duration(f.Interval),
+		Audit:    auditLog,
+		SeenFile: filepath.Join(filepath.Dir(state.DefaultPath()), "fleet-commands.json"),
+	}
+	if f.PublicKey != "" {
+		key, err := fleet.ParsePublicKey(f.PublicKey)
+		if err != nil {
+			fmt.Println("Fleet mode disabled:", err)
+			return
+		}
+		opts.PublicKey = key
+	}
+
+	a, err := fleet.New(opts, d)
+	if err != nil {
+		fmt.Println("Fleet mode disabled:", err)
+		return
+	}
+	a.Run(ctx)
+}
+
+// recoverUpdate rolls back an automatic update of exe, the running executable when
+// empty, that didn't pass its health check before the daemon stopped
+func recoverUpdate(exe string) {
+	if exe == "" {
+		var err error
+		if exe, err = os.Executable(); err != nil {
+			return
+		}
+	}
+	version, err := update.Recover(exe)
+	switch {
+	case err != nil:
+		fmt.Printf("Failed to roll back the unconfirmed update to %s: %v\n", version, err)
+	case version != "":
+		fmt.Printf("Rolled back the unconfirmed update to %s\n", version)
+	}
+}
+
+// runUpdates checks the release feed until ctx is done, when configured
+func runUpdates(ctx context.Context, d *daemon.Daemon, u config.Updates, version string) {
+	if u.Feed == "" {
+		return
+	}
+
+	window, _ := update.ParseWindow(u.Window) // Checked by Validate
+	opts := update.Options{
+		Feed:          u.Feed,
+		Current:       version,
+		Executable:    d.Executable,
+		Interval:      time.Duration(u.Interval),
+		Auto:          u.Auto,
+		Window:        window,
+		HealthTimeout: time.Duration(u.HealthTimeout),
+	}
+	if u.PublicKey != "" {
+		key, err := update.ParsePublicKey(u.PublicKey)
+		if err != nil {
+			fmt.Println("Update checks disabled:", err)
+			return
+		}
+		opts.PublicKey = key
+	}
+
+	up, err := update.New(opts, d)
+	if err != nil {
+		fmt.Println("Update checks disabled:", err)
+		return
+	}
+	up.Run(ctx)
+}
+
+// serveDBus exports the daemon on the system D-Bus until ctx is done. The daemon
+// keeps running without it where there is no system bus.
+func serveDBus(ctx context.Context, d *daemon.Daemon, auditLog *audit.Logger) {
+	if err := dbus.Serve(ctx, d.Service, d, auditLog); err != nil && !errors.Is(err, dbus.ErrUnsupported) {
+		fmt.Println("D-Bus API disabled:", err)
+	}
+}
+
+// delayShutdown holds up system shutdowns until drain has stopped the child. The daemon
+// keeps running without it where there is no systemd-logind or Windows service.
+func delayShutdown(ctx context.Context, name string, drain func()) {
+	if err := sysshutdown.Delay(ctx, name, drain); err != nil {
+		slog.Warn("System shutdowns not delayed", "error", err)
+	}
+}
+
+// reportControl returns a function recording in the daemon status whether the control
+// socket at addr accepts connections
+func reportControl(d *daemon.Daemon, addr string) func(error) {
+	return func(err error) { d.ReportControl(addr, err) }
+}
@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"path/filepath"
+
+	"github.com/lucasdecamargo/go-appservice-example/pkg/config"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/dirs"
+	"github.com/lucasdecamargo/kardianos"
+)
+
+// prepareDirs creates the working, log and state directories used by the service and
+// hands them to the run-as user, failing when any of them is unusable. The state and
+// log directories are then restricted to administrators, the run-as user and, for
+// logs, the configured read group.
+func prepareDirs(cfg *kardianos.Config, out config.Output) error {
+	workDir, err := dirs.ExpandHome(cfg.WorkingDirectory, cfg.UserName)
+	if err != nil {
+		return err
+	}
+
+	stateDir := filepath.Dir(statePath(cfg))
+	var logDirs []string
+	if logDir, ok := cfg.Option["LogDirectory"].(string); ok {
+		logDirs = append(logDirs, logDir)
+	}
+	for _, s := range []config.Stream{out.Stdout, out.Stderr} {
+		if s.File != "" {
+			logDirs = append(logDirs, filepath.Dir(s.File))
+		}
+	}
+
+	if err := dirs.Ensure(cfg.UserName, append([]string{workDir, stateDir}, logDirs...)...); err != nil {
+		return err
+	}
+
+	if err := dirs.Restrict(stateDir, cfg.UserName); err != nil {
+		return err
+	}
+	for _, dir := range logDirs {
+		if err := dirs.Restrict(dir, cfg.UserName, out.ReadGroup); err != nil {
+			return err
+		}
+	}
+	return nil
+}
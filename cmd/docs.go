@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/lucasdecamargo/go-appservice-example/pkg/configdoc"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// Formats of the docs config command
+const (
+	docsMarkdown   = "markdown"
+	docsJSON       = "json"
+	docsJSONSchema = "json-schema"
+)
+
+// commandDoc documents the flags of a command
+type commandDoc struct {
+	Command string    `json:"command"`
+	Flags   []flagDoc `json:"flags"`
+}
+
+// flagDoc documents a command line flag
+type flagDoc struct {
+	Name      string `json:"name"`
+	Shorthand string `json:"shorthand,omitempty"`
+	Type      string `json:"type"`
+	Default   string `json:"default,omitempty"`
+	Usage     string `json:"usage"`
+}
+
+// NewDocsCmd creates a command generating the reference documentation of root
+func NewDocsCmd(root *cobra.Command) *cobra.Command {
+	c := &cobra.Command{
+		Use:   "docs",
+		Short: "Generate reference documentation from the code",
+	}
+
+	var format string
+	config := &cobra.Command{
+		Use:   "config",
+		Short: "Print the reference of the config file, environment variables and flags",
+		Long: `Print the reference of every config file key, environment variable and command
+line flag. It is generated from the config structs and their comments, and from the
+commands themselves, so it always matches the running version.
+
+--format json-schema prints the JSON Schema of the config file instead, for editors.`,
+		Example: `  svcapp docs config > REFERENCE.md
+  svcapp docs config --format json-schema > svcapp.schema.json`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			switch format {
+			case docsMarkdown:
+				return writeDocsMarkdown(os.Stdout, root)
+			case docsJSON:
+				return writeJSON(os.Stdout, map[string]any{
+					"config":   configdoc.Fields(),
+					"env":      configdoc.Env(),
+					"commands": commandDocs(root),
+				})
+			case docsJSONSchema:
+				return writeJSON(os.Stdout, configdoc.Schema())
+			}
+			return fmt.Errorf("unknown format %q, use %s, %s or %s", format, docsMarkdown, docsJSON, docsJSONSchema)
+		},
+	}
+	config.Flags().StringVar(&format, "format", docsMarkdown, "Output format: markdown, json or json-schema")
+	config.RegisterFlagCompletionFunc("format", cobra.FixedCompletions(
+		[]string{docsMarkdown, docsJSON, docsJSONSchema}, cobra.ShellCompDirectiveNoFileComp))
+
+	c.AddCommand(config)
+	return c
+}
+
+// writeDocsMarkdown writes the config, environment and flag reference of root
+func writeDocsMarkdown(w io.Writer, root *cobra.Command) error {
+	if err := configdoc.WriteMarkdown(w, configdoc.Fields(), configdoc.Env()); err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	b.WriteString("\n## Command Line Flags\n")
+	for _, cd := range commandDocs(root) {
+		fmt.Fprintf(&b, "\n### %s\n\n| Flag | Type | Default | Description |\n| --- | --- | --- | --- |\n", cd.Command)
+		for _, f := range cd.Flags {
+			name := "--" + f.Name
+			if f.Shorthand != "" {
+				name = "-" + f.Shorthand + ", " + name
+			}
+			def := ""
+			if f.Default != "" {
+				def = "`" + f.Default + "`"
+			}
+			fmt.Fprintf(&b, "| `%s` | %s | %s | %s |\n", name, f.Type, def, strings.ReplaceAll(f.Usage, "|", `\|`))
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// commandDocs lists the flags of root and its visible subcommands, depth first
+func commandDocs(root *cobra.Command) []commandDoc {
+	var docs []commandDoc
+	var visit func(c *cobra.Command)
+	visit = func(c *cobra.Command) {
+		if c.Hidden || c.Name() == "help" {
+			return
+		}
+		cd := commandDoc{Command: c.CommandPath()}
+		c.LocalFlags().VisitAll(func(f *pflag.Flag) {
+			if f.Hidden || f.Name == "help" {
+				return
+			}
+			def := f.DefValue
+			if def == "[]" || (f.Value.Type() == "bool" && def == "false") {
+				def = ""
+			}
+			cd.Flags = append(cd.Flags, flagDoc{Name: f.Name, Shorthand: f.Shorthand, Type: f.Value.Type(), Default: def, Usage: f.Usage})
+		})
+		if len(cd.Flags) > 0 {
+			docs = append(docs, cd)
+		}
+		for _, sub := range c.Commands() {
+			visit(sub)
+		}
+	}
+	visit(root)
+	return docs
+}
+
+// writeJSON writes v as indented JSON
+func writeJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "    ")
+	return enc.Encode(v)
+}
@@ -0,0 +1,413 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"os"
+	"os/exec"
+	"reflect"
+	"runtime"
+	"slices"
+	"strings"
+
+	"github.com/lucasdecamargo/go-appservice-example/pkg/config"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/svcctl"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/systemd"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/ui"
+	"github.com/lucasdecamargo/kardianos"
+	"github.com/spf13/cobra"
+)
+
+// Service settings that aren't kardianos options
+const (
+	settingUserName     = "userName"
+	settingWorkDir      = "workingDirectory"
+	settingDependencies = "dependencies"
+)
+
+// hiddenOptions are generated options left out of the editable settings
+var hiddenOptions = []string{"SystemdScript"}
+
+// ApplyServiceOverrides applies the service settings saved by "service edit" in the
+// config file to cfg
+func ApplyServiceOverrides(cfg *kardianos.Config) error {
+	c, err := config.Load(config.DefaultPath())
+	if err != nil {
+		return err
+	}
+	return applyServiceSettings(cfg, c.Service)
+}
+
+// newServiceEditCmd creates a command changing the installed service definition in place
+func newServiceEditCmd(i kardianos.Interface, cfg *kardianos.Config) *cobra.Command {
+	var (
+		sets    []string
+		restart bool
+	)
+
+	c := &cobra.Command{
+		Use:   "edit",
+		Short: "Change the service options without reinstalling it",
+		Long: `Open the effective service options in $VISUAL or $EDITOR, or change them with --set,
+then validate them, save them to the "service" section of the config file and update
+the installed service definition.
+
+Settings are the run-as userName, the workingDirectory, the dependencies (after,
+requires, wants and bindsTo, named as systemd units), and the platform options such
+as Restart on Linux or OnFailure on Windows. A null value removes an option. The running service picks the changes up on its next restart.`,
+		Example: `  svcapp service edit
+  svcapp service edit --set Restart=always --set RestartSec=5
+  svcapp service edit --set dependencies.after=network-online.target,postgresql.service --restart`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true, // Missing privileges aren't usage errors
+		RunE: func(cmd *cobra.Command, args []string) error {
+			current, err := serviceSettings(cfg)
+			if err != nil {
+				return err
+			}
+
+			var edited map[string]any
+			if len(sets) > 0 {
+				edited, err = setServiceSettings(current, sets)
+			} else {
+				edited, err = editServiceSettings(current)
+			}
+			if err != nil {
+				return err
+			}
+
+			changes := diffSettings(current, edited)
+			if len(changes) == 0 {
+				fmt.Println("No changes.")
+				return nil
+			}
+
+			next := cloneServiceConfig(cfg)
+			if err := applyServiceSettings(next, changes); err != nil {
+				return err
+			}
+			if err := validateServiceConfig(next); err != nil {
+				return err
+			}
+			printChanges(current, changes)
+
+			s, err := kardianos.New(i, cfg)
+			if err != nil {
+				return err
+			}
+			_, err = s.Status()
+			installed := !errors.Is(err, kardianos.ErrNotInstalled)
+			if installed {
+				if err := checkPrivileges(cfg.Name, "edit"); err != nil {
+					return err
+				}
+			}
+
+			if err := saveServiceOverrides(changes); err != nil {
+				return err
+			}
+			if !installed {
+				ui.Success("Saved. The options apply when the service is installed.")
+				return nil
+			}
+			msg := fmt.Sprintf("Updating %s", s)
+			if err := ui.Spin(os.Stdout, msg, func() error { return svcctl.Reconfigure(i, cfg, next) }); err != nil {
+				return handleServiceError(err)
+			}
+
+			if !restart {
+				p, err := ui.NewPrompter()
+				if err != nil {
+					ui.Warn("Restart the service to apply the changes.")
+					return nil
+				}
+				if restart, err = p.Confirm("Restart the service now to apply the changes?", false); err != nil || !restart {
+					return err
+				}
+			}
+			return handleServiceCommand(cmd.Context(), i, next, "restart", svcctl.DefaultRetryConfig())
+		},
+	}
+
+	c.Flags().StringArrayVar(&sets, "set", nil, "Set a service option as key=value instead of opening an editor")
+	c.Flags().BoolVar(&restart, "restart", false, "Restart the service after the update without asking")
+
+	return c
+}
+
+// serviceSettings returns the editable settings of cfg, as JSON values
+func serviceSettings(cfg *kardianos.Config) (map[string]any, error) {
+	deps, err := svcctl.ParseDependencies(kardianos.Platform(), cfg.Dependencies)
+	if err != nil {
+		return nil, err
+	}
+	m := map[string]any{
+		settingUserName:     cfg.UserName,
+		settingWorkDir:      cfg.WorkingDirectory,
+		settingDependencies: deps,
+	}
+	for k, v := range cfg.Option {
+		if !slices.Contains(hiddenOptions, k) {
+			m[k] = v
+		}
+	}
+
+	// Round trip through JSON, so the settings compare with edited ones
+	data, err := json.Marshal(m)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode service options: %w", err)
+	}
+	var out map[string]any
+	return out, json.Unmarshal(data, &out)
+}
+
+// setServiceSettings returns settings with the key=value pairs of sets applied. Values
+// are parsed as JSON when valid and taken as strings otherwise. Dependency lists are
+// set with dependencies.<kind> keys and separated by commas.
+func setServiceSettings(settings map[string]any, sets []string) (map[string]any, error) {
+	out := maps.Clone(settings)
+	for _, set := range sets {
+		key, value, ok := strings.Cut(set, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid --set %q: expected key=value", set)
+		}
+
+		if kind, ok := strings.CutPrefix(key, settingDependencies+"."); ok {
+			deps, _ := out[settingDependencies].(map[string]any)
+			deps = maps.Clone(deps)
+			if deps == nil {
+				deps = map[string]any{}
+			}
+			names := []any{}
+			for _, d := range strings.Split(value, ",") {
+				if d = strings.TrimSpace(d); d != "" {
+					names = append(names, d)
+				}
+			}
+			deps[kind] = names
+			if len(names) == 0 {
+				delete(deps, kind)
+			}
+			out[settingDependencies] = deps
+			continue
+		}
+
+		var v any
+		switch {
+		case key == settingUserName || key == settingWorkDir:
+			v = value
+		case json.Unmarshal([]byte(value), &v) != nil:
+			v = value
+		}
+		out[key] = v
+	}
+	return out, nil
+}
+
+// editServiceSettings opens settings in the user's editor and returns the edited ones
+func editServiceSettings(settings map[string]any) (map[string]any, error) {
+	data, err := json.MarshalIndent(settings, "", "    ")
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.CreateTemp("", "svcapp-service-*.json")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(f.Name())
+	_, err = f.Write(append(data, '\n'))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	editor := strings.Fields(editorCommand())
+	c := exec.Command(editor[0], append(editor[1:], f.Name())...)
+	c.Stdin, c.Stdout, c.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := c.Run(); err != nil {
+		return nil, fmt.Errorf("editor failed: %w", err)
+	}
+
+	data, err = os.ReadFile(f.Name())
+	if err != nil {
+		return nil, err
+	}
+	var edited map[string]any
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if err := dec.Decode(&edited); err != nil {
+		return nil, fmt.Errorf("invalid service options: %w", err)
+	}
+	return edited, nil
+}
+
+// editorCommand returns the editor named by VISUAL or EDITOR, or the platform default
+func editorCommand() string {
+	for _, env := range []string{"VISUAL", "EDITOR"} {
+		if e := strings.TrimSpace(os.Getenv(env)); e != "" {
+			return e
+		}
+	}
+	if runtime.GOOS == "windows" {
+		return "notepad"
+	}
+	return "vi"
+}
+
+// diffSettings returns the settings of edited that differ from current. Options
+// missing from edited are returned as nil, which removes them.
+func diffSettings(current, edited map[string]any) map[string]any {
+	changes := map[string]any{}
+	for k, v := range edited {
+		if !reflect.DeepEqual(current[k], v) {
+			changes[k] = v
+		}
+	}
+	for k := range current {
+		if _, ok := edited[k]; !ok && !isBaseSetting(k) {
+			changes[k] = nil
+		}
+	}
+	return changes
+}
+
+// isBaseSetting reports whether key is a Config field rather than an option
+func isBaseSetting(key string) bool {
+	return key == settingUserName || key == settingWorkDir || key == settingDependencies
+}
+
+// applyServiceSettings sets the given settings on cfg
+func applyServiceSettings(cfg *kardianos.Config, settings map[string]any) error {
+	for k, v := range settings {
+		var ok bool
+		switch k {
+		case settingUserName:
+			cfg.UserName, ok = v.(string)
+		case settingWorkDir:
+			cfg.WorkingDirectory, ok = v.(string)
+		case settingDependencies:
+			deps, err := dependencies(v)
+			if err != nil {
+				return err
+			}
+			cfg.Dependencies = deps
+			ok = true
+		default:
+			ok = true
+			if v == nil {
+				delete(cfg.Option, k)
+			} else {
+				cfg.Option[k] = optionValue(v)
+			}
+		}
+		if !ok {
+			return fmt.Errorf("invalid service setting %s: %v", k, v)
+		}
+	}
+	return nil
+}
+
+// dependencies converts the decoded JSON dependencies setting to the kardianos
+// dependencies of the init system, rejecting those it can't express
+func dependencies(v any) ([]string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var deps svcctl.Dependencies
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&deps); err != nil && v != nil {
+		return nil, fmt.Errorf("invalid service setting %s: %w", settingDependencies, err)
+	}
+	lines, err := deps.Render(kardianos.Platform())
+	if err != nil {
+		return nil, fmt.Errorf("invalid service setting %s: %w", settingDependencies, err)
+	}
+	return lines, nil
+}
+
+// optionValue converts a decoded JSON number back to the int kardianos expects for
+// integer options
+func optionValue(v any) any {
+	if f, ok := v.(float64); ok && f == math.Trunc(f) {
+		return int(f)
+	}
+	return v
+}
+
+// cloneServiceConfig returns a copy of cfg that can be changed without affecting it
+func cloneServiceConfig(cfg *kardianos.Config) *kardianos.Config {
+	next := *cfg
+	next.Arguments = slices.Clone(cfg.Arguments)
+	next.Dependencies = slices.Clone(cfg.Dependencies)
+	next.Option = maps.Clone(cfg.Option)
+	next.EnvVars = maps.Clone(cfg.EnvVars)
+	if next.Option == nil {
+		next.Option = kardianos.KeyValue{}
+	}
+	return &next
+}
+
+// validateServiceConfig checks the restart policy, the Windows start type and failure
+// actions and, on systemd, renders the unit
+func validateServiceConfig(cfg *kardianos.Config) error {
+	key, choices := restartOption()
+	if v, ok := cfg.Option[key]; ok && !slices.Contains(choices, fmt.Sprint(v)) {
+		return fmt.Errorf("invalid %s %v: expected one of %s", key, v, strings.Join(choices, ", "))
+	}
+	if err := svcctl.ValidateWindowsServiceOptions(cfg.Option); err != nil {
+		return err
+	}
+	if strings.HasSuffix(kardianos.Platform(), "systemd") {
+		if _, err := systemd.Render(cfg); err != nil {
+			return fmt.Errorf("invalid service options: %w", err)
+		}
+	}
+	return nil
+}
+
+// printChanges lists the changed settings with their previous values
+func printChanges(current, changes map[string]any) {
+	t := ui.NewTable(os.Stdout)
+	for _, k := range slices.Sorted(maps.Keys(changes)) {
+		t.Row(k, fmt.Sprintf("%s → %s", formatSetting(current[k]), formatSetting(changes[k])))
+	}
+	t.Flush()
+}
+
+// formatSetting renders a setting value for printChanges
+func formatSetting(v any) string {
+	if v == nil {
+		return ui.Colorize(ui.Gray, "unset")
+	}
+	data, _ := json.Marshal(v)
+	return string(data)
+}
+
+// saveServiceOverrides merges changes into the service section of the config file
+func saveServiceOverrides(changes map[string]any) error {
+	path := config.DefaultPath()
+	c, err := config.Load(path)
+	if err != nil {
+		return err
+	}
+
+	overrides := maps.Clone(c.Service)
+	if overrides == nil {
+		overrides = map[string]any{}
+	}
+	maps.Copy(overrides, changes)
+
+	data, err := json.Marshal(overrides)
+	if err != nil {
+		return err
+	}
+	return config.Set(path, "service", string(data))
+}
@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/lucasdecamargo/go-appservice-example/pkg/config"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/daemon"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/tuning"
+	"github.com/spf13/cobra"
+)
+
+// NewEnvCmd creates a command inspecting the environment the daemon gives the child
+func NewEnvCmd(d *daemon.Daemon) *cobra.Command {
+	c := &cobra.Command{
+		Use:   "env",
+		Short: "Inspect the child environment",
+	}
+	c.AddCommand(newEnvShowCmd(d))
+	return c
+}
+
+// newEnvShowCmd creates a command listing the child environment with its sources
+func newEnvShowCmd(d *daemon.Daemon) *cobra.Command {
+	var (
+		profile  string
+		redacted bool
+	)
+
+	c := &cobra.Command{
+		Use:   "show",
+		Short: "Show the environment variables set for the child and where they come from",
+		Long: `Resolve the child environment as the daemon does, from the compiled defaults,
+the selected profile, the managed variables of the config file and the Go runtime
+settings, and list each variable with the source that set it. When several sources
+set a variable, the one listed wins and the others are named as overridden.
+
+Variables read from stdin by "daemon --stdin env" only exist in the daemon and
+aren't listed.`,
+		Example: `  svcapp env show --redacted
+  svcapp env show --profile staging`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := config.Load(config.DefaultPath())
+			if err != nil {
+				return err
+			}
+			vars, err := childEnv(c, d.EnvVars, profile, nil)
+			if err != nil {
+				return err
+			}
+			printEnv(os.Stdout, effectiveEnv(vars), redacted)
+			return nil
+		},
+	}
+
+	c.Flags().StringVar(&profile, "profile", "", "Resolve the environment of this profile")
+	c.Flags().BoolVar(&redacted, "redacted", false, "Hide the values of secrets and sensitive variables")
+
+	return c
+}
+
+// childEnv returns the child environment in the order it is applied: the base
+// variables, the selected profile, the managed variables, the stdin variables and
+// the Go runtime settings. Later entries override earlier ones with the same name.
+func childEnv(c *config.Config, base []string, profile string, stdinEnv []string) ([]config.ResolvedVar, error) {
+	p, selected, err := c.SelectProfile(profile)
+	if err != nil {
+		return nil, err
+	}
+
+	var vars []config.ResolvedVar
+	add := func(pairs []string, source string, sensitive bool) {
+		for _, pair := range pairs {
+			name, value, _ := strings.Cut(pair, "=")
+			vars = append(vars, config.ResolvedVar{Name: name, Value: value, Source: source, Sensitive: sensitive})
+		}
+	}
+
+	add(base, "compiled", false)
+	if p != nil {
+		add(p.EnvVars(), "profile "+selected, false)
+		add([]string{config.EnvProfile + "=" + selected}, "profile "+selected, false)
+	}
+	managed, err := c.ResolveEnv()
+	if err != nil {
+		return nil, err
+	}
+	vars = append(vars, managed...)
+	add(stdinEnv, "stdin", true)
+
+	// Propagate Go runtime settings derived from the cgroup limits
+	add(tuning.Env(c.Runtime, config.EnvPairs(vars)), "runtime", false)
+
+	return vars, nil
+}
+
+// effectiveEnv keeps the last entry of each name, recording the sources it overrides,
+// sorted by name
+func effectiveEnv(vars []config.ResolvedVar) []config.ResolvedVar {
+	byName := map[string]config.ResolvedVar{}
+	for _, v := range vars {
+		if prev, ok := byName[v.Name]; ok {
+			v.Overrides = append(append(slices.Clone(prev.Overrides), prev.Source), v.Overrides...)
+		}
+		byName[v.Name] = v
+	}
+
+	effective := make([]config.ResolvedVar, 0, len(byName))
+	for _, v := range byName {
+		effective = append(effective, v)
+	}
+	slices.SortFunc(effective, func(a, b config.ResolvedVar) int { return cmp.Compare(a.Name, b.Name) })
+	return effective
+}
+
+// printEnv lists vars, hiding sensitive values when redacted is set
+func printEnv(w io.Writer, vars []config.ResolvedVar, redacted bool) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tVALUE\tSOURCE")
+	for _, v := range vars {
+		value := v.Value
+		if redacted && v.Sensitive {
+			value = config.Redacted
+		}
+		source := v.Source
+		if len(v.Overrides) > 0 {
+			source += " (overrides " + strings.Join(v.Overrides, ", ") + ")"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", v.Name, value, source)
+	}
+	tw.Flush()
+}
@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+
+	"github.com/lucasdecamargo/go-appservice-example/pkg/daemon"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/svcctl"
+	"github.com/lucasdecamargo/kardianos"
+)
+
+// Exit statuses of the CLI commands
+const (
+	ExitOK               = 0
+	ExitUnknown          = 1
+	ExitNotInstalled     = 3
+	ExitPermissionDenied = 4
+	ExitTimeout          = 5
+)
+
+// exitCodesHelp documents the exit statuses in the usage of every command
+const exitCodesHelp = `
+Exit Codes:
+  0   Success
+  1   Unknown error
+  3   Service not installed
+  4   Permission denied
+  5   Timeout
+  70  Application panic (run only)
+`
+
+// permissionMessages are the messages of service manager errors that only carry text
+var permissionMessages = []string{
+	"permission denied",
+	"access denied",
+	"access is denied",
+	"authentication is required",
+	"interactive authentication required",
+}
+
+// ExitCode maps err to the exit status reporting its reason
+func ExitCode(err error) int {
+	switch {
+	case err == nil:
+		return ExitOK
+	case errors.Is(err, kardianos.ErrNotInstalled):
+		return ExitNotInstalled
+	case errors.Is(err, os.ErrPermission):
+		return ExitPermissionDenied
+	case errors.Is(err, svcctl.ErrTimeout),
+		errors.Is(err, context.DeadlineExceeded),
+		errors.Is(err, ErrShutdownStalled),
+		errors.Is(err, daemon.ErrStartTimeout):
+		return ExitTimeout
+	}
+
+	msg := strings.ToLower(err.Error())
+	for _, m := range permissionMessages {
+		if strings.Contains(msg, m) {
+			return ExitPermissionDenied
+		}
+	}
+	return ExitUnknown
+}
@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/lucasdecamargo/go-appservice-example/pkg/config"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/crash"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/retention"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/store"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/ui"
+	"github.com/spf13/cobra"
+)
+
+// NewHistoryCmd creates a command managing the daemon history and crash reports
+func NewHistoryCmd() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "history",
+		Short: "Manage the daemon history and crash reports",
+	}
+	c.AddCommand(newHistoryPruneCmd())
+	return c
+}
+
+// newHistoryPruneCmd creates a command applying the retention policies now
+func newHistoryPruneCmd() *cobra.Command {
+	var (
+		keep   config.Keep
+		maxAge time.Duration
+	)
+
+	c := &cobra.Command{
+		Use:   "prune",
+		Short: "Delete the history events and crash reports beyond the retention policies",
+		Long: `Delete the oldest history events and crash reports that don't fit the retention
+policies of the config file, as the daemon does every hour. The flags replace the
+configured policies for both, for a one-off cleanup.`,
+		Example: `  svcapp history prune
+  svcapp history prune --max-age 720h --max-count 1000`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := config.Load(config.DefaultPath())
+			if err != nil {
+				return err
+			}
+
+			history, crashes := c.Retention.History.Policy(), c.Retention.Crashes.Policy()
+			flags := cmd.Flags()
+			if flags.Changed("max-age") || flags.Changed("max-count") || flags.Changed("max-size-mb") {
+				keep.MaxAge = config.Duration(maxAge)
+				history, crashes = keep.Policy(), keep.Policy()
+			}
+			if history.IsZero() && crashes.IsZero() {
+				ui.Warn("No retention policy is configured, nothing to prune.")
+				return nil
+			}
+
+			events, reports, err := prune(c.Storage, history, crashes)
+			if err != nil {
+				return err
+			}
+			ui.Success("Pruned %d history events and %d crash reports.", events, reports)
+			return nil
+		},
+	}
+
+	c.Flags().DurationVar(&maxAge, "max-age", 0, "Delete records older than this")
+	c.Flags().IntVar(&keep.MaxCount, "max-count", 0, "Keep the newest records up to this count")
+	c.Flags().IntVar(&keep.MaxSizeMB, "max-size-mb", 0, "Keep the newest records up to this total size")
+
+	return c
+}
+
+// prune applies the history policy to the configured store and the crashes policy
+// to the crash reports, returning the numbers of records deleted
+func prune(storage store.Config, history, crashes retention.Policy) (events, reports int, err error) {
+	if !history.IsZero() {
+		st, err := store.Open(storage)
+		if err != nil {
+			return 0, 0, err
+		}
+		events, err = st.PruneEvents(history)
+		if cerr := st.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to prune history: %w", err)
+		}
+	}
+	if !crashes.IsZero() {
+		if reports, err = crash.Prune(crash.DefaultDir(), crashes); err != nil {
+			return events, 0, err
+		}
+	}
+	return events, reports, nil
+}
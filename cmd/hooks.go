@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// Hooks observes the run loop of the application, on each tick and when it ends. The
+// time left on a tick is NoDeadline when the loop has no timeout.
+type Hooks interface {
+	OnTick(ctx context.Context, remaining time.Duration)
+	OnTimeout(ctx context.Context)
+	OnCancel(ctx context.Context)
+}
+
+// HookFuncs implements Hooks with functions, any of which may be nil
+type HookFuncs struct {
+	Tick    func(ctx context.Context, remaining time.Duration)
+	Timeout func(ctx context.Context)
+	Cancel  func(ctx context.Context)
+}
+
+func (h HookFuncs) OnTick(ctx context.Context, remaining time.Duration) {
+	if h.Tick != nil {
+		h.Tick(ctx, remaining)
+	}
+}
+
+func (h HookFuncs) OnTimeout(ctx context.Context) {
+	if h.Timeout != nil {
+		h.Timeout(ctx)
+	}
+}
+
+func (h HookFuncs) OnCancel(ctx context.Context) {
+	if h.Cancel != nil {
+		h.Cancel(ctx)
+	}
+}
+
+// multiHooks calls each of its hooks in order
+type multiHooks []Hooks
+
+// MultiHooks combines hooks, calling them in the order given
+func MultiHooks(hooks ...Hooks) Hooks {
+	return multiHooks(hooks)
+}
+
+func (m multiHooks) OnTick(ctx context.Context, remaining time.Duration) {
+	for _, h := range m {
+		h.OnTick(ctx, remaining)
+	}
+}
+
+func (m multiHooks) OnTimeout(ctx context.Context) {
+	for _, h := range m {
+		h.OnTimeout(ctx)
+	}
+}
+
+func (m multiHooks) OnCancel(ctx context.Context) {
+	for _, h := range m {
+		h.OnCancel(ctx)
+	}
+}
+
+// LoggingHooks logs the time left on each tick and why the loop ended
+func LoggingHooks() Hooks {
+	return HookFuncs{
+		Tick: func(ctx context.Context, remaining time.Duration) {
+			if remaining == NoDeadline {
+				slog.Info("Running...")
+				return
+			}
+			slog.Info("Running...", "timeLeft", remaining.Truncate(time.Millisecond))
+		},
+		Timeout: func(ctx context.Context) {
+			slog.Info("Timed out.")
+		},
+		Cancel: func(ctx context.Context) {
+			slog.Info("Context canceled.")
+		},
+	}
+}
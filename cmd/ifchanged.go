@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"context"
+	"os"
+
+	"github.com/lucasdecamargo/go-appservice-example/pkg/config"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/control"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/state"
+	"github.com/lucasdecamargo/kardianos"
+)
+
+// childSources fingerprints what a child of exe is built from: the executable, the
+// config file at configPath and the files its managed variables are read from
+func childSources(exe, configPath string) state.Sources {
+	sources := state.Sources{
+		Executable: state.FingerprintFiles(exe),
+		Config:     state.FingerprintFiles(configPath),
+	}
+	if c, err := config.Load(configPath); err == nil {
+		sources.Env = state.FingerprintFiles(c.EnvFiles()...)
+	}
+	return sources
+}
+
+// sourcesChanged returns why the service configured by cfg needs a restart: the sources
+// changed since its child started, or the daemon can't tell. It returns nil when the
+// child is up to date.
+func sourcesChanged(ctx context.Context, cfg *kardianos.Config) []string {
+	ctx, cancel := context.WithTimeout(ctx, controlTimeout)
+	defer cancel()
+
+	st, err := control.NewClient(controlAddr(cfg)).Status(ctx)
+	switch {
+	case err != nil:
+		return []string{"not running"}
+	case st.Child == nil || st.Child.Sources == (state.Sources{}):
+		return []string{"sources of the child unknown"}
+	}
+
+	exe := cfg.Executable
+	if exe == "" {
+		exe, _ = os.Executable()
+	}
+	changed := st.Child.Sources.Changed(childSources(exe, configPath(cfg)))
+	for i := range changed {
+		changed[i] += " changed"
+	}
+	return changed
+}
@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"maps"
+	"path/filepath"
+	"strings"
+
+	"github.com/lucasdecamargo/go-appservice-example/pkg/config"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/control"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/paths"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/state"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/svcctl"
+	"github.com/lucasdecamargo/kardianos"
+)
+
+// Placeholders replaced by the instance name in the configuration of an instance
+const (
+	instancePlaceholder         = "%i"
+	instanceTemplatePlaceholder = "{{.Instance}}"
+)
+
+// instanceConfig derives the configuration of a named instance of the service from cfg,
+// as in svcapp@a. Each instance gets its own state file, control socket and PID file.
+// The instance placeholders are substituted in the arguments, working directory and
+// PID file. A PID file without a placeholder gets the instance name as a suffix.
+func instanceConfig(cfg *kardianos.Config, instance string) *kardianos.Config {
+	expand := strings.NewReplacer(instancePlaceholder, instance, instanceTemplatePlaceholder, instance).Replace
+
+	c := *cfg
+	c.Name = svcctl.InstanceName(cfg.Name, instance)
+	c.DisplayName = svcctl.InstanceName(cfg.DisplayName, instance)
+	c.WorkingDirectory = expand(cfg.WorkingDirectory)
+
+	c.Arguments = make([]string, len(cfg.Arguments))
+	for i, arg := range cfg.Arguments {
+		c.Arguments[i] = expand(arg)
+	}
+
+	c.EnvVars = maps.Clone(cfg.EnvVars)
+	if c.EnvVars == nil {
+		c.EnvVars = map[string]string{}
+	}
+	c.EnvVars[paths.EnvInstance] = instance
+	c.EnvVars[state.EnvState] = filepath.Join(filepath.Dir(state.DefaultPath()), instance, "state.json")
+	c.EnvVars[control.EnvControlAddr] = control.InstanceAddr(instance)
+
+	c.Option = maps.Clone(cfg.Option)
+	if pidFile, ok := c.Option["PIDFile"].(string); ok {
+		if expanded := expand(pidFile); expanded != pidFile {
+			c.Option["PIDFile"] = expanded
+		} else {
+			ext := filepath.Ext(pidFile)
+			c.Option["PIDFile"] = strings.TrimSuffix(pidFile, ext) + paths.InstanceSeparator + instance + ext
+		}
+		// The daemon reads its PID file from the base configuration otherwise
+		c.Arguments = append(c.Arguments, "--pidfile="+c.Option["PIDFile"].(string))
+	}
+	return &c
+}
+
+// serviceName returns the name of the service configured by cfg, followed by the
+// instance the process belongs to, if any, as in svcapp@a
+func serviceName(cfg *kardianos.Config) string {
+	if instance := paths.Instance(); instance != "" && !strings.Contains(cfg.Name, paths.InstanceSeparator) {
+		return svcctl.InstanceName(cfg.Name, instance)
+	}
+	return cfg.Name
+}
+
+// controlAddr returns the control socket address of the service configured by cfg
+func controlAddr(cfg *kardianos.Config) string {
+	if addr := cfg.EnvVars[control.EnvControlAddr]; addr != "" {
+		return addr
+	}
+	return control.DefaultAddr()
+}
+
+// statePath returns the state file path of the service configured by cfg
+func statePath(cfg *kardianos.Config) string {
+	if path := cfg.EnvVars[state.EnvState]; path != "" {
+		return path
+	}
+	return state.DefaultPath()
+}
+
+// configPath returns the config file path of the service configured by cfg
+func configPath(cfg *kardianos.Config) string {
+	if path := cfg.EnvVars[config.EnvConfig]; path != "" {
+		return path
+	}
+	return config.DefaultPath()
+}
@@ -0,0 +1,158 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+
+	"github.com/lucasdecamargo/go-appservice-example/pkg/archive"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/config"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/control"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/jobs"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/logfile"
+	"github.com/spf13/cobra"
+)
+
+// NewJobsCmd creates a command listing the scheduled jobs of the running daemon and
+// running them on demand
+func NewJobsCmd() *cobra.Command {
+	var upcoming bool
+
+	c := &cobra.Command{
+		Use:   "jobs",
+		Short: "List the scheduled jobs of the running daemon",
+		Long: `List the jobs the running daemon schedules next to the child, with their next
+and last runs. Jobs are defined in the "jobs" section of the config file and are
+loaded when the service starts. Run times are shown in the time zone of the job.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, cancel := context.WithTimeout(cmd.Context(), controlTimeout)
+			defer cancel()
+			j, err := control.NewClient(control.DefaultAddr()).Jobs(ctx)
+			if err != nil {
+				return err
+			}
+			if err := printJobs(os.Stdout, j); err != nil || !upcoming {
+				return err
+			}
+			printUpcoming(os.Stdout, j)
+			return nil
+		},
+	}
+	c.Flags().BoolVar(&upcoming, "upcoming", false, "Also list the next run times of each job")
+
+	c.AddCommand(&cobra.Command{
+		Use:   "run <name>",
+		Short: "Run a job now, outside of its schedule",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, cancel := context.WithTimeout(cmd.Context(), controlTimeout)
+			defer cancel()
+			if _, err := control.NewClient(control.DefaultAddr()).RunJob(ctx, args[0]); err != nil {
+				return err
+			}
+			fmt.Printf("Job %s started.\n", args[0])
+			return nil
+		},
+	})
+
+	return c
+}
+
+// printJobs renders the job statuses as a table
+func printJobs(w io.Writer, statuses []jobs.Status) error {
+	if len(statuses) == 0 {
+		fmt.Fprintln(w, "No jobs configured.")
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tSCHEDULE\tNEXT\tLAST RUN\tRESULT")
+	for _, s := range statuses {
+		next, last, result := "-", "-", "-"
+		if !s.Next.IsZero() {
+			next = formatJobTime(s.Next, s.TimeZone)
+		}
+		if !s.LastRun.IsZero() {
+			last = s.LastRun.Local().Format(time.DateTime)
+			result = "ok in " + s.LastDuration.Round(time.Millisecond).String()
+			if s.LastError != "" {
+				result = s.LastError
+			}
+		}
+		if s.Running {
+			result = "running"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", s.Name, s.Schedule, next, last, result)
+	}
+	return tw.Flush()
+}
+
+// printUpcoming lists the next run times of each job
+func printUpcoming(w io.Writer, statuses []jobs.Status) {
+	for _, s := range statuses {
+		fmt.Fprintf(w, "\n%s:\n", s.Name)
+		if len(s.Upcoming) == 0 {
+			fmt.Fprintln(w, "  only run on demand")
+		}
+		for _, t := range s.Upcoming {
+			fmt.Fprintf(w, "  %s\n", formatJobTime(t, s.TimeZone))
+		}
+	}
+}
+
+// formatJobTime formats t in the time zone tz with its abbreviation, or in the local
+// time zone when tz is empty
+func formatJobTime(t time.Time, tz string) string {
+	if tz == "" {
+		return t.Local().Format(time.DateTime)
+	}
+	if loc, err := time.LoadLocation(tz); err == nil {
+		t = t.In(loc)
+	}
+	return t.Format(time.DateTime + " MST")
+}
+
+// configureJobs builds the daemon jobs from the config, each logging to its own rotated
+// file. The returned function closes the log files once the daemon has stopped.
+func configureJobs(cfgJobs []config.Job, compress archive.Options) ([]jobs.Job, func() error, error) {
+	var logs []io.Closer
+	closeAll := func() error {
+		var errs []error
+		for _, l := range logs {
+			errs = append(errs, l.Close())
+		}
+		return errors.Join(errs...)
+	}
+
+	list := make([]jobs.Job, 0, len(cfgJobs))
+	for _, j := range cfgJobs {
+		path := j.Log
+		if path == "" {
+			path = filepath.Join(defaultLogDir(), "jobs", j.Name+".log")
+		}
+		w, err := logfile.New(path, 0, 0, compress)
+		if err != nil {
+			closeAll()
+			return nil, nil, fmt.Errorf("job %q: %w", j.Name, err)
+		}
+		logs = append(logs, w)
+
+		list = append(list, jobs.Job{
+			Name:     j.Name,
+			Command:  j.Command,
+			Schedule: j.Schedule,
+			TimeZone: j.TimeZone,
+			DST:      jobs.DSTPolicy(j.DST),
+			Timeout:  time.Duration(j.Timeout),
+			Env:      j.EnvVars(),
+			Output:   w,
+		})
+	}
+	return list, closeAll, nil
+}
@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/lucasdecamargo/go-appservice-example/pkg/control"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/ui"
+	"github.com/spf13/cobra"
+)
+
+// NewKVCmd creates a command reading and writing the key/value store kept for the child
+func NewKVCmd() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "kv",
+		Short: "Read and write the key/value store the daemon keeps for the child",
+		Long: `Read and write the key/value store the daemon keeps for the child, next to its
+state file. The child reads the file named by SVCAPP_KV_FILE, or uses the control
+socket like these commands do.`,
+		Example: `  svcapp kv list
+  svcapp kv set sync/cursor 42
+  svcapp kv get sync/cursor
+  svcapp kv delete sync/cursor`,
+	}
+	c.AddCommand(
+		&cobra.Command{
+			Use:   "list",
+			Short: "List the keys and values",
+			Args:  cobra.NoArgs,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return withKV(cmd.Context(), func(ctx context.Context, client *control.Client) error {
+					all, err := client.KV(ctx)
+					if err != nil {
+						return err
+					}
+					t := ui.NewTable(cmd.OutOrStdout())
+					for _, key := range slices.Sorted(maps.Keys(all)) {
+						t.Row(key, all[key])
+					}
+					return t.Flush()
+				})
+			},
+		},
+		&cobra.Command{
+			Use:   "get <key>",
+			Short: "Print the value of a key",
+			Args:  cobra.ExactArgs(1),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return withKV(cmd.Context(), func(ctx context.Context, client *control.Client) error {
+					v, err := client.KVGet(ctx, args[0])
+					if err != nil {
+						return err
+					}
+					fmt.Fprintln(cmd.OutOrStdout(), v)
+					return nil
+				})
+			},
+		},
+		&cobra.Command{
+			Use:   "set <key> <value>",
+			Short: "Store a value under a key",
+			Args:  cobra.ExactArgs(2),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return withKV(cmd.Context(), func(ctx context.Context, client *control.Client) error {
+					return client.KVSet(ctx, args[0], args[1])
+				})
+			},
+		},
+		&cobra.Command{
+			Use:   "delete <key>",
+			Short: "Remove a key",
+			Args:  cobra.ExactArgs(1),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return withKV(cmd.Context(), func(ctx context.Context, client *control.Client) error {
+					return client.KVDelete(ctx, args[0])
+				})
+			},
+		},
+	)
+	for _, sub := range c.Commands() {
+		sub.SilenceUsage = true // Missing keys and daemon errors aren't usage errors
+	}
+	return c
+}
+
+// withKV calls f with a client of the running daemon, bounded by the control timeout
+func withKV(ctx context.Context, f func(context.Context, *control.Client) error) error {
+	ctx, cancel := context.WithTimeout(ctx, controlTimeout)
+	defer cancel()
+	return f(ctx, control.NewClient(control.DefaultAddr()))
+}
@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"errors"
+	"log/slog"
+	"runtime"
+	"time"
+)
+
+// leakGracePeriod is how long goroutines started by the application get to end after it returns
+const leakGracePeriod = time.Second
+
+// ErrShutdownStalled reports an application that didn't return within the shutdown timeout
+var ErrShutdownStalled = errors.New("shutdown stalled")
+
+// goroutineLeaks waits up to leakGracePeriod for the goroutines started by the
+// application to end and returns how many are left above baseline
+func goroutineLeaks(baseline int) int {
+	deadline := time.Now().Add(leakGracePeriod)
+	for {
+		n := runtime.NumGoroutine() - baseline
+		if n <= 0 {
+			return 0
+		}
+		if time.Now().After(deadline) {
+			return n
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// reportLeaks logs the goroutines the application left running, dumping their stacks when dumpStacks is set
+func reportLeaks(baseline int, dumpStacks bool) {
+	n := goroutineLeaks(baseline)
+	if n == 0 {
+		return
+	}
+	if !dumpStacks {
+		slog.Warn("Goroutines left running after shutdown, rerun with --debug to dump them", "count", n)
+		return
+	}
+	slog.Warn("Goroutines left running after shutdown", "count", n, "stacks", goroutineStacks())
+}
+
+// goroutineStacks returns the stack traces of all goroutines
+func goroutineStacks() string {
+	buf := make([]byte, 64<<10)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			return string(buf[:n])
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+}
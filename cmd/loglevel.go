@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/lucasdecamargo/go-appservice-example/pkg/control"
+	"github.com/spf13/cobra"
+)
+
+// NewLogLevelCmd creates a command showing or changing the log level of the running daemon
+func NewLogLevelCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "loglevel [debug|info|warn|error]",
+		Short: "Show or change the log level of the running daemon",
+		Long: `Show or change the log level of the running daemon, without restarting it.
+
+The supervisor and the run command also lower their level by one step on SIGUSR1
+and raise it on SIGUSR2. "systemctl kill -s USR1 svcapp" signals both of them.`,
+		ValidArgs: []string{"debug", "info", "warn", "error"},
+		Args:      cobra.MatchAll(cobra.OnlyValidArgs, cobra.MaximumNArgs(1)),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, cancel := context.WithTimeout(cmd.Context(), controlTimeout)
+			defer cancel()
+			client := control.NewClient(control.DefaultAddr())
+
+			var level string
+			var err error
+			if len(args) == 0 {
+				level, err = client.LogLevel(ctx)
+			} else {
+				level, err = client.SetLogLevel(ctx, args[0])
+			}
+			if err != nil {
+				return err
+			}
+			fmt.Println(level)
+			return nil
+		},
+	}
+}
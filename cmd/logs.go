@@ -0,0 +1,185 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/lucasdecamargo/go-appservice-example/pkg/atomicfile"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/config"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/control"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/logexport"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/ui"
+	"github.com/lucasdecamargo/kardianos"
+	"github.com/spf13/cobra"
+)
+
+// NewLogsCmd creates a command exporting the supervisor and child logs
+func NewLogsCmd(cfg *kardianos.Config) *cobra.Command {
+	c := &cobra.Command{
+		Use:   "logs",
+		Short: "Export the supervisor and child logs",
+	}
+	c.AddCommand(newLogsExportCmd(cfg))
+	return c
+}
+
+// newLogsExportCmd creates a command merging the logs of a time range into one file
+func newLogsExportCmd(cfg *kardianos.Config) *cobra.Command {
+	var (
+		since, until string
+		format       string
+		output       string
+		noSystem     bool
+	)
+
+	c := &cobra.Command{
+		Use:   "export",
+		Short: "Merge the supervisor and child logs of a time range into one ordered file",
+		Long: `Merge the supervisor and child logs of a time range into a single file ordered by
+time, to attach to a support ticket. The lines are read from:
+
+  - the child log files of the config file, with their rotated backups
+  - the latest lines the running daemon holds in memory, including the console output
+  - journald on Linux, or the Application event log on Windows
+
+A line found in several places is exported once. --since and --until take a time,
+such as 2024-05-01T10:00:00Z or "2024-05-01 10:00", or a duration before now.`,
+		Example: `  svcapp logs export --since 2h
+  svcapp logs export --since "2024-05-01 10:00" --until "2024-05-01 11:00" --format text -o incident.log`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			now := time.Now()
+			var rg logexport.Range
+			var err error
+			if rg.Since, err = parseLogTime(since, now); err != nil {
+				return fmt.Errorf("invalid --since: %w", err)
+			}
+			if rg.Until, err = parseLogTime(until, now); err != nil {
+				return fmt.Errorf("invalid --until: %w", err)
+			}
+			if format != logexport.FormatJSONL && format != logexport.FormatText {
+				return fmt.Errorf("unknown format %q, use %s or %s", format, logexport.FormatJSONL, logexport.FormatText)
+			}
+			if output == "" {
+				ext := "jsonl"
+				if format == logexport.FormatText {
+					ext = "log"
+				}
+				output = fmt.Sprintf("svcapp-logs-%s.%s", now.Format("20060102-150405"), ext)
+			}
+
+			// Keep stdout for the logs when exporting to it
+			warn := ui.Warn
+			if output == "-" {
+				warn = func(format string, a ...any) { fmt.Fprintf(os.Stderr, format+"\n", a...) }
+			}
+
+			entries, err := collectLogs(cmd.Context(), cfg.Name, rg, !noSystem, warn)
+			if err != nil {
+				return err
+			}
+
+			var buf bytes.Buffer
+			if err := logexport.Write(&buf, entries, format); err != nil {
+				return err
+			}
+			if output == "-" {
+				_, err := os.Stdout.Write(buf.Bytes())
+				return err
+			}
+			if err := atomicfile.WriteFile(output, buf.Bytes(), 0o600, false); err != nil {
+				return fmt.Errorf("failed to write logs: %w", err)
+			}
+			ui.Success("Exported %d lines to %s%s.", len(entries), output, formatCounts(logexport.Count(entries)))
+			return nil
+		},
+	}
+
+	c.Flags().StringVar(&since, "since", "", "Export the lines from this time, or this long ago")
+	c.Flags().StringVar(&until, "until", "", "Export the lines up to this time, or this long ago")
+	c.Flags().StringVar(&format, "format", logexport.FormatJSONL, "Output format: jsonl or text")
+	c.Flags().StringVarP(&output, "output", "o", "", `File to write, "-" for stdout, svcapp-logs-<time>.jsonl by default`)
+	c.Flags().BoolVar(&noSystem, "no-system", false, "Skip journald and the event log")
+	c.RegisterFlagCompletionFunc("format", cobra.FixedCompletions(
+		[]string{logexport.FormatJSONL, logexport.FormatText}, cobra.ShellCompDirectiveNoFileComp))
+
+	return c
+}
+
+// collectLogs reads the lines within rg from every available place and merges them.
+// Places that can't be read are reported with warn and skipped.
+func collectLogs(ctx context.Context, name string, rg logexport.Range, system bool, warn func(string, ...any)) ([]logexport.Entry, error) {
+	c, err := config.Load(config.DefaultPath())
+	if err != nil {
+		return nil, err
+	}
+
+	var sets [][]logexport.Entry
+	for source, s := range map[string]config.Stream{logexport.SourceStdout: c.Output.Stdout, logexport.SourceStderr: c.Output.Stderr} {
+		if s.File == "" {
+			continue
+		}
+		entries, err := logexport.ReadFile(s.File, source, rg)
+		if err != nil {
+			warn("Skipping the %s log file: %v", source, err)
+			continue
+		}
+		sets = append(sets, entries)
+	}
+
+	recentCtx, cancel := context.WithTimeout(ctx, controlTimeout)
+	defer cancel()
+	if entries, err := control.NewClient(control.DefaultAddr()).Logs(recentCtx, rg); err != nil {
+		warn("Skipping the lines held by the daemon, it isn't reachable: %v", err)
+	} else {
+		sets = append(sets, entries)
+	}
+
+	if system {
+		entries, err := logexport.System(ctx, name, rg)
+		switch {
+		case errors.Is(err, logexport.ErrUnsupported):
+		case err != nil:
+			warn("Skipping the system log: %v", err)
+		default:
+			sets = append(sets, entries)
+		}
+	}
+
+	return logexport.Merge(sets...), nil
+}
+
+// parseLogTime parses an absolute time, or a duration before now. Empty is the zero time.
+func parseLogTime(s string, now time.Time) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%q is neither a time nor a duration", s)
+}
+
+// formatCounts lists the number of lines of each source, such as " (stdout 10, system 2)"
+func formatCounts(counts map[string]int) string {
+	if len(counts) == 0 {
+		return ""
+	}
+	var parts []string
+	for _, source := range slices.Sorted(maps.Keys(counts)) {
+		parts = append(parts, fmt.Sprintf("%s %d", source, counts[source]))
+	}
+	return " (" + strings.Join(parts, ", ") + ")"
+}
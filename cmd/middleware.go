@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"context"
+	"log/slog"
+	"runtime/debug"
+	"time"
+)
+
+// Middleware wraps a RunFunc to add cross-cutting behavior such as logging or retries
+type Middleware func(next RunFunc) RunFunc
+
+// Chain wraps f with the middlewares. The first middleware is the outermost one.
+func Chain(f RunFunc, mws ...Middleware) RunFunc {
+	for i := len(mws) - 1; i >= 0; i-- {
+		f = mws[i](f)
+	}
+	return f
+}
+
+// Logging logs when the application starts and stops, with its duration and error
+func Logging() Middleware {
+	return func(next RunFunc) RunFunc {
+		return func(ctx context.Context, args []string) error {
+			start := time.Now()
+			slog.Info("Application starting", "args", args)
+
+			err := next(ctx, args)
+
+			if err != nil {
+				slog.Error("Application stopped", "duration", time.Since(start), "error", err)
+			} else {
+				slog.Info("Application stopped", "duration", time.Since(start))
+			}
+			return err
+		}
+	}
+}
+
+// Recover converts a panic into a *PanicError carrying the stack trace, so the run
+// command still writes a crash report and exits with ExitCodePanic
+func Recover() Middleware {
+	return func(next RunFunc) RunFunc {
+		return func(ctx context.Context, args []string) (err error) {
+			defer func() {
+				if r := recover(); r != nil {
+					err = &PanicError{Value: r, Stack: debug.Stack()}
+				}
+			}()
+			return next(ctx, args)
+		}
+	}
+}
+
+// Timeout cancels the application context after d
+func Timeout(d time.Duration) Middleware {
+	return func(next RunFunc) RunFunc {
+		return func(ctx context.Context, args []string) error {
+			ctx, cancel := context.WithTimeout(ctx, d)
+			defer cancel()
+			return next(ctx, args)
+		}
+	}
+}
+
+// Retry runs the application up to attempts times while it returns an error, waiting
+// backoff between attempts. It stops retrying once the context is canceled.
+func Retry(attempts int, backoff time.Duration) Middleware {
+	return func(next RunFunc) RunFunc {
+		return func(ctx context.Context, args []string) error {
+			var err error
+			for attempt := 1; attempt <= attempts; attempt++ {
+				if err = next(ctx, args); err == nil || attempt == attempts {
+					return err
+				}
+
+				slog.Warn("Application failed, retrying", "attempt", attempt, "error", err)
+				select {
+				case <-ctx.Done():
+					return err
+				case <-time.After(backoff):
+				}
+			}
+			return err
+		}
+	}
+}
+
+// Metrics reports the duration and result of every run to observe
+func Metrics(observe func(duration time.Duration, err error)) Middleware {
+	return func(next RunFunc) RunFunc {
+		return func(ctx context.Context, args []string) error {
+			start := time.Now()
+			err := next(ctx, args)
+			observe(time.Since(start), err)
+			return err
+		}
+	}
+}
@@ -0,0 +1,271 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/lucasdecamargo/go-appservice-example/pkg/archive"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/config"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/daemon"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/dirs"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/logexport"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/logfile"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/logship"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/outbuf"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/paths"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/state"
+)
+
+// applyOutput sets the daemon output writers from the output configuration, behind a
+// bounded buffer, rotated log files being compressed with compress. The returned function
+// flushes the buffer and closes any log files that were opened. In lean mode, the buffer
+// and the log forwarder hold less in memory.
+func applyOutput(d *daemon.Daemon, out config.Output, compress archive.Options) (func() error, error) {
+	var files []io.Closer
+	closeAll := func() error {
+		var errs []error
+		for _, f := range files {
+			errs = append(errs, f.Close())
+		}
+		return errors.Join(errs...)
+	}
+
+	// Move spliced streams from the child straight to their file, bypassing the rest
+	splicedOut, err := splicedStream(d, "stdout", out.Stdout, compress, &files)
+	if err != nil {
+		closeAll()
+		return nil, err
+	}
+	splicedErr, err := splicedStream(d, "stderr", out.Stderr, compress, &files)
+	if err != nil {
+		closeAll()
+		return nil, err
+	}
+	off := false
+	if splicedOut != nil {
+		out.Stdout = config.Stream{Console: &off}
+	}
+	if splicedErr != nil {
+		out.Stderr = config.Stream{Console: &off}
+	}
+
+	stdout, err := streamWriter(out.Stdout, os.Stdout, compress, &files)
+	if err != nil {
+		closeAll()
+		return nil, err
+	}
+	stderr, err := streamWriter(out.Stderr, os.Stderr, compress, &files)
+	if err != nil {
+		closeAll()
+		return nil, err
+	}
+
+	// Ship both streams to the cloud logging service, if any
+	if out.Forward.Sink != "" {
+		f, err := newForwarder(out.Forward, d.Service, d.Lean)
+		if err != nil {
+			closeAll()
+			return nil, err
+		}
+		files = append(files, f)
+		d.RegisterFootprint(func() daemon.Footprint {
+			return daemon.Footprint{Subsystem: "log-forwarding", Enabled: true, Bytes: f.Footprint(), Goroutines: 1, Detail: out.Forward.Sink}
+		})
+		stdout = io.MultiWriter(stdout, f.Writer("stdout"))
+		stderr = io.MultiWriter(stderr, f.Writer("stderr"))
+	}
+
+	// Keep the latest lines in memory for svcapp logs export
+	if d.Logs != nil {
+		if d.Lean {
+			d.Logs.Resize(logexport.LeanRingSize)
+		}
+		d.RegisterFootprint(func() daemon.Footprint {
+			return daemon.Footprint{Subsystem: "recent-logs", Enabled: true, Bytes: d.Logs.Footprint(), Detail: "latest log lines"}
+		})
+		stdout = io.MultiWriter(stdout, d.Logs.Writer(logexport.SourceStdout))
+		stderr = io.MultiWriter(stderr, d.Logs.Writer(logexport.SourceStderr))
+	}
+
+	// Keep slow destinations from stalling the child, dropping what doesn't fit
+	opts, err := bufferOptions(out.Buffer, d.Lean)
+	if err != nil {
+		closeAll()
+		return nil, err
+	}
+	bufOut, bufErr := outbuf.New(stdout, "stdout", opts), outbuf.New(stderr, "stderr", opts)
+	files = append([]io.Closer{bufOut, bufErr}, files...) // Flushed before the files close
+	d.RegisterFootprint(func() daemon.Footprint {
+		_, droppedOut := bufOut.Dropped()
+		_, droppedErr := bufErr.Dropped()
+		return daemon.Footprint{Subsystem: "output-buffer", Enabled: true, Bytes: int64(bufOut.Buffered() + bufErr.Buffered()),
+			Goroutines: 2, Detail: fmt.Sprintf("%d bytes dropped", droppedOut+droppedErr)}
+	})
+
+	d.OutWriter, d.ErrWriter = sampleStream(d, "stdout", out.Stdout, bufOut), sampleStream(d, "stderr", out.Stderr, bufErr)
+	if splicedOut != nil {
+		d.OutWriter = splicedOut
+	}
+	if splicedErr != nil {
+		d.ErrWriter = splicedErr
+	}
+	return closeAll, nil
+}
+
+// splicedStream opens the log file of a stream the child output is spliced to, counting
+// the bytes spliced, and returns nil when the stream isn't spliced. Where splice isn't
+// supported, or the file is on a read-only file system, nil is returned as well, for
+// the stream to be written as usual.
+func splicedStream(d *daemon.Daemon, name string, s config.Stream, compress archive.Options, files *[]io.Closer) (io.Writer, error) {
+	if !s.Splice {
+		return nil, nil
+	}
+	if !logfile.SpliceSupported {
+		slog.Warn("Splicing the child output is only supported on Linux, copying it instead", "stream", name)
+		return nil, nil
+	}
+	f, err := logfile.New(s.File, int64(s.MaxSizeMB)<<20, s.MaxBackups, compress)
+	switch {
+	case dirs.IsReadOnly(err):
+		return nil, nil // Reported by streamWriter
+	case err != nil:
+		return nil, err
+	}
+	*files = append(*files, f)
+	spliced := f.Spliced()
+	d.RegisterCounter("output."+name+".splicedBytes", spliced.SplicedBytes)
+	return spliced, nil
+}
+
+// sampleStream returns w behind the sampler discarding the stream, or the lines left out
+// of its sample, counting the bytes discarded. It returns w when the stream is kept whole.
+func sampleStream(d *daemon.Daemon, name string, s config.Stream, w io.Writer) io.Writer {
+	var sampler *outbuf.Sampler
+	switch {
+	case s.Discard:
+		sampler = outbuf.NewDiscard()
+	case s.Sample > 1:
+		sampler = outbuf.NewSampler(w, s.Sample)
+	default:
+		return w
+	}
+	d.RegisterCounter("output."+name+".discardedBytes", sampler.Discarded)
+	return sampler
+}
+
+// bufferOptions converts the output buffer configuration, shrinking the default size in lean mode
+func bufferOptions(b config.Buffer, lean bool) (outbuf.Options, error) {
+	opts := outbuf.Options{Size: b.SizeKB << 10, MaxBlock: time.Duration(b.MaxBlock)}
+	switch b.Policy {
+	case "", "block":
+	case "drop":
+		opts.Drop = true
+	default:
+		return opts, fmt.Errorf("unknown output buffer policy %q: expected block or drop", b.Policy)
+	}
+	if lean && opts.Size == 0 {
+		opts.Size = outbuf.LeanSize
+	}
+	return opts, nil
+}
+
+// newForwarder creates the log forwarder for the configured sink, labeling the entries
+// with service unless a service label is configured. Batches are buffered next to the
+// state file while the sink is unreachable.
+func newForwarder(c config.Forward, service string, lean bool) (*logship.Forwarder, error) {
+	labels := maps.Clone(c.Labels)
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	if _, ok := labels["service"]; !ok {
+		labels["service"] = service
+	}
+
+	var sink logship.Sink
+	switch c.Sink {
+	case "loki":
+		if c.URL == "" {
+			return nil, errors.New("log forwarding to loki requires a url")
+		}
+		sink = &logship.Loki{URL: c.URL, Labels: labels, Token: c.Token}
+	case "cloudwatch":
+		creds, err := logship.AWSCredentialsFromEnv()
+		if err != nil {
+			return nil, fmt.Errorf("log forwarding to cloudwatch: %w", err)
+		}
+		if c.Region == "" || c.LogGroup == "" {
+			return nil, errors.New("log forwarding to cloudwatch requires a region and logGroup")
+		}
+		stream := c.LogStream
+		if stream == "" {
+			stream, _ = os.Hostname()
+		}
+		sink = &logship.CloudWatch{Region: c.Region, LogGroup: c.LogGroup, LogStream: stream, Credentials: creds, Endpoint: c.URL}
+	case "gcp":
+		if c.Project == "" {
+			return nil, errors.New("log forwarding to gcp requires a project")
+		}
+		name := c.LogName
+		if name == "" {
+			name = paths.App()
+		}
+		sink = &logship.CloudLogging{Project: c.Project, LogName: name, Labels: labels, Token: c.Token, Endpoint: c.URL}
+	default:
+		return nil, fmt.Errorf("unknown log sink %q: expected cloudwatch, gcp or loki", c.Sink)
+	}
+
+	opts := logship.Options{
+		BatchSize:      c.BatchSize,
+		FlushInterval:  time.Duration(c.FlushInterval),
+		MaxBytesPerSec: c.MaxBytesPerSec,
+		BufferDir:      filepath.Join(filepath.Dir(state.DefaultPath()), "logship"),
+		BufferMax:      int64(c.BufferMaxMB) << 20,
+	}
+	if lean {
+		opts.QueueSize = logship.LeanQueueSize
+		if opts.BatchSize == 0 || opts.BatchSize > logship.LeanQueueSize {
+			opts.BatchSize = logship.LeanQueueSize
+		}
+	}
+	return logship.New(sink, opts)
+}
+
+// streamWriter builds the writer for one stream, tee-ing to console and file as configured.
+// A file on a read-only file system is replaced by the console.
+func streamWriter(s config.Stream, console io.Writer, compress archive.Options, files *[]io.Closer) (io.Writer, error) {
+	var writers []io.Writer
+	if s.ConsoleEnabled() {
+		writers = append(writers, console)
+	}
+
+	if s.File != "" {
+		f, err := logfile.New(s.File, int64(s.MaxSizeMB)<<20, s.MaxBackups, compress)
+		switch {
+		case dirs.IsReadOnly(err):
+			slog.Error("Log file is on a read-only file system, writing to the console instead", "file", s.File, "error", err)
+			if !s.ConsoleEnabled() {
+				writers = append(writers, console)
+			}
+		case err != nil:
+			return nil, err
+		default:
+			*files = append(*files, f)
+			writers = append(writers, f)
+		}
+	}
+
+	switch len(writers) {
+	case 0:
+		return io.Discard, nil
+	case 1:
+		return writers[0], nil
+	default:
+		return io.MultiWriter(writers...), nil
+	}
+}
@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/lucasdecamargo/go-appservice-example/pkg/atomicfile"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/config"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/profile"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/ui"
+	"github.com/spf13/cobra"
+)
+
+// NewProfileCmd creates a command fetching a profile from the pprof handlers of the child
+func NewProfileCmd() *cobra.Command {
+	var (
+		addr     string
+		duration time.Duration
+		output   string
+	)
+
+	c := &cobra.Command{
+		Use:   "profile {cpu|heap|goroutine}",
+		Short: "Fetch a CPU, heap or goroutine profile from the child",
+		Long: `Fetch a profile from the net/http/pprof handlers of the child and save it for
+go tool pprof.
+
+The handlers are found at --url, then at the pprof.url of the config file, then at
+/debug/pprof on each port the running child listens on, as shown by service status.`,
+		Example: `  svcapp profile heap
+  svcapp profile cpu --duration 10s -o cpu.pprof
+  svcapp profile goroutine --url localhost:6060`,
+		ValidArgs:    profile.Kinds,
+		Args:         cobra.MatchAll(cobra.OnlyValidArgs, cobra.ExactArgs(1)),
+		SilenceUsage: true, // Unreachable handlers aren't usage errors
+		RunE: func(cmd *cobra.Command, args []string) error {
+			kind := args[0]
+			base, err := pprofURL(cmd, addr)
+			if err != nil {
+				return err
+			}
+
+			var data []byte
+			msg := fmt.Sprintf("Fetching the %s profile from %s", kind, base)
+			if kind == profile.KindCPU {
+				msg += fmt.Sprintf(" for %s", duration)
+			}
+			err = ui.Spin(os.Stdout, msg, func() (err error) {
+				data, err = profile.Fetch(cmd.Context(), base, kind, duration)
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("failed to fetch the %s profile: %w", kind, err)
+			}
+
+			if output == "" {
+				output = fmt.Sprintf("%s-%s.pprof", kind, time.Now().Format("20060102-150405"))
+			}
+			if err := atomicfile.WriteFile(output, data, 0o600, false); err != nil {
+				return fmt.Errorf("failed to write profile: %w", err)
+			}
+			ui.Success("Saved to %s, view it with: go tool pprof %s", output, output)
+			return nil
+		},
+	}
+
+	c.Flags().StringVar(&addr, "url", "", "Base URL or host:port of the pprof handlers")
+	c.Flags().DurationVar(&duration, "duration", 30*time.Second, "Sampling time of a CPU profile")
+	c.Flags().StringVarP(&output, "output", "o", "", "File to write, <kind>-<time>.pprof by default")
+
+	return c
+}
+
+// pprofURL returns the base URL of the child pprof handlers, from flag, the config
+// file, or the ports the child listens on
+func pprofURL(cmd *cobra.Command, flag string) (string, error) {
+	if flag != "" {
+		return profile.ParseURL(flag)
+	}
+	c, err := config.Load(config.DefaultPath())
+	if err != nil {
+		return "", err
+	}
+	if c.Pprof.URL != "" {
+		return profile.ParseURL(c.Pprof.URL)
+	}
+
+	st, err := daemonState(cmd.Context())
+	if err != nil {
+		return "", err
+	}
+	if len(st.Ports) == 0 {
+		return "", errors.New("the child ports aren't known, set pprof.url in the config file or use --url")
+	}
+	base, err := profile.Detect(cmd.Context(), st.Ports)
+	if err != nil {
+		return "", fmt.Errorf("%w on ports %s, set pprof.url in the config file or use --url", err, formatPorts(st.Ports))
+	}
+	return base, nil
+}
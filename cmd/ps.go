@@ -0,0 +1,160 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/lucasdecamargo/go-appservice-example/pkg/config"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/control"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/pidfile"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/procinfo"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/state"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/store"
+	"github.com/spf13/cobra"
+)
+
+// NewPsCmd creates a command showing the supervisor process tree
+func NewPsCmd() *cobra.Command {
+	var pid int
+
+	c := &cobra.Command{
+		Use:   "ps",
+		Short: "Show the supervisor, child and grandchildren as a process tree",
+		Long: `Show the supervisor process tree with PIDs, CPU and memory usage, and start times.
+
+The supervisor PID is read from the running daemon, or from its state file when the
+control socket is unavailable, provided that process still runs this executable.
+Use --pid to inspect another process tree.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if pid == 0 {
+				st, err := daemonState(cmd.Context())
+				if err != nil {
+					return err
+				}
+				pid = st.PID
+			}
+			if pid == 0 {
+				return fmt.Errorf("daemon is not running")
+			}
+
+			procs, err := procinfo.List()
+			if err != nil {
+				return fmt.Errorf("failed to list processes: %w", err)
+			}
+
+			root := procinfo.Tree(procs, pid)
+			if root == nil {
+				return fmt.Errorf("process %d is not running", pid)
+			}
+
+			return printProcessTree(os.Stdout, root)
+		},
+	}
+
+	c.Flags().IntVar(&pid, "pid", 0, "Root process ID (defaults to the supervisor)")
+
+	return c
+}
+
+// daemonState returns the daemon state from the control socket, falling back to the
+// store while the supervisor saved there still runs
+func daemonState(ctx context.Context) (*state.State, error) {
+	ctx, cancel := context.WithTimeout(ctx, controlTimeout)
+	defer cancel()
+
+	if st, err := control.NewClient(control.DefaultAddr()).Status(ctx); err == nil {
+		return st, nil
+	}
+	st, err := storedState()
+	if err != nil {
+		return nil, err
+	}
+	if st.PID == 0 {
+		return nil, errors.New("daemon is not running")
+	}
+	if reason := pidfile.Stale(st.PID, st.StartedAt); reason != "" {
+		return nil, fmt.Errorf("daemon is not running: supervisor %d from the state file: %s", st.PID, reason)
+	}
+	return st, nil
+}
+
+// storedState returns the daemon state last saved to the store
+func storedState() (*state.State, error) {
+	st, err := openStore()
+	if err != nil {
+		return nil, err
+	}
+	defer st.Close()
+	return st.LoadState()
+}
+
+// openStore opens the state and history store selected by the configuration file
+func openStore() (store.Store, error) {
+	c, err := config.Load(config.DefaultPath())
+	if err != nil {
+		return nil, err
+	}
+	return store.Open(c.Storage)
+}
+
+// printProcessTree renders the tree as an aligned table
+func printProcessTree(w io.Writer, root *procinfo.Node) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "PID\tCPU\t%CPU\tMEM\tSTARTED\t\tCOMMAND")
+
+	var walk func(n *procinfo.Node, prefix, branch string)
+	walk = func(n *procinfo.Node, prefix, branch string) {
+		fmt.Fprintf(tw, "%d\t%s\t%.1f\t%s\t%s\t\t%s%s\n",
+			n.PID, n.CPUTime.Truncate(10*time.Millisecond), n.CPUPercent(),
+			formatBytes(n.RSS), formatStart(n.StartTime), prefix+branch, n.Name)
+
+		for i, c := range n.Children {
+			childPrefix := prefix
+			switch branch {
+			case "├─ ":
+				childPrefix += "│  "
+			case "└─ ":
+				childPrefix += "   "
+			}
+			if i == len(n.Children)-1 {
+				walk(c, childPrefix, "└─ ")
+			} else {
+				walk(c, childPrefix, "├─ ")
+			}
+		}
+	}
+	walk(root, "", "")
+
+	return tw.Flush()
+}
+
+// formatBytes renders a byte count in binary units
+func formatBytes(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%dB", n)
+	}
+	div, exp := uint64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// formatStart renders a start time, omitting the date for processes started today
+func formatStart(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	if now := time.Now(); t.Year() == now.Year() && t.YearDay() == now.YearDay() {
+		return t.Format(time.TimeOnly)
+	}
+	return t.Format(time.DateOnly)
+}
@@ -0,0 +1,167 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/lucasdecamargo/go-appservice-example/pkg/config"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/listener"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/paths"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/svcctl"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/ui"
+	"github.com/lucasdecamargo/kardianos"
+)
+
+// Kinds of files left behind by an uninstall
+const (
+	leftoverDefinition = "definition"
+	leftoverPIDFile    = "pid file"
+	leftoverSocket     = "socket"
+	leftoverState      = "state"
+	leftoverLogs       = "logs"
+	leftoverRuntime    = "runtime"
+	leftoverConfig     = "config"
+)
+
+// leftover is a file or directory of the service that still exists
+type leftover struct {
+	Kind string
+	Path string
+	Keep string // Why it is never purged, empty when it can be
+}
+
+// leftovers returns the files and directories of the service configured by cfg that
+// still exist, files before the directories that may hold them. Directories are only
+// listed within the default svcapp ones, others only have the service files listed.
+// What the instances share is kept while one of them is installed, and the
+// configuration file and the directories holding it are always kept.
+func leftovers(cfg *kardianos.Config) []leftover {
+	var found []leftover
+	seen := map[string]bool{}
+	add := func(kind, path, keep string) {
+		if path == "" || seen[path] {
+			return
+		}
+		if _, err := os.Lstat(path); err != nil {
+			return
+		}
+		seen[path] = true
+		found = append(found, leftover{Kind: kind, Path: path, Keep: keep})
+	}
+
+	configPath := config.DefaultPath()
+	add(leftoverConfig, configPath, "never purged")
+	for _, path := range svcctl.DefinitionPaths(cfg.Name, kardianos.Platform()) {
+		add(leftoverDefinition, path, "")
+	}
+	if pidFile, _ := cfg.Option["PIDFile"].(string); pidFile != "" {
+		add(leftoverPIDFile, pidFile, "")
+	}
+	if a, err := listener.Parse(controlAddr(cfg)); err == nil && a.Network == listener.SchemeUnix {
+		add(leftoverSocket, a.Address, "")
+	}
+
+	isInstance := strings.Contains(cfg.Name, paths.InstanceSeparator)
+	shared := ""
+	if !isInstance {
+		if names, _ := svcctl.Instances(cfg.Name); len(names) > 0 {
+			shared = "used by " + strings.Join(names, ", ")
+		}
+	}
+	dir := func(kind, path, keep string) {
+		if within(configPath, path) {
+			keep = "holds the configuration"
+		}
+		add(kind, path, keep)
+	}
+	defaults := paths.Default()
+	stateDir := filepath.Dir(statePath(cfg))
+	owned := within(stateDir, defaults.State) && (isInstance || stateDir == defaults.State)
+	add(leftoverState, statePath(cfg), "")
+
+	if isInstance {
+		if owned && stateDir != defaults.State {
+			dir(leftoverState, stateDir, "")
+		}
+		return found
+	}
+
+	c, err := config.Load(configPath)
+	if err == nil {
+		for _, s := range []config.Stream{c.Output.Stdout, c.Output.Stderr} {
+			if s.File == "" {
+				continue
+			}
+			backups, _ := filepath.Glob(s.File + ".*")
+			for _, path := range append([]string{s.File}, backups...) {
+				add(leftoverLogs, path, shared)
+			}
+		}
+	}
+	if owned {
+		dir(leftoverState, stateDir, shared)
+	}
+	dir(leftoverLogs, defaults.Logs, shared)
+	dir(leftoverRuntime, defaults.Runtime, shared)
+	return found
+}
+
+// within reports whether path is dir or lies within it
+func within(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && filepath.IsLocal(rel)
+}
+
+// reportLeftovers lists what the uninstall of the service configured by cfg left
+// behind. With purge, everything but what must be kept is removed.
+func reportLeftovers(cfg *kardianos.Config, purge bool) error {
+	found := leftovers(cfg)
+	if len(found) == 0 {
+		ui.Success("Nothing was left behind.")
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "KIND\tPATH\tSTATUS")
+	var errs []error
+	purgeable := 0
+	for _, l := range found {
+		status := "kept"
+		switch {
+		case l.Keep != "":
+			status = "kept, " + l.Keep
+		case !purge:
+			purgeable++
+		default:
+			if err := os.RemoveAll(l.Path); err != nil {
+				status = "failed: " + err.Error()
+				errs = append(errs, err)
+			} else {
+				status = "removed"
+			}
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", l.Kind, l.Path, status)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if purgeable > 0 {
+		fmt.Println("Run it again with --purge to remove what can be.")
+	}
+	return errors.Join(errs...)
+}
+
+// isInstalled reports whether the service configured by cfg is installed
+func isInstalled(i kardianos.Interface, cfg *kardianos.Config) bool {
+	s, err := kardianos.New(i, cfg)
+	if err != nil {
+		return false
+	}
+	_, err = s.Status()
+	return !errors.Is(err, kardianos.ErrNotInstalled)
+}
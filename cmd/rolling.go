@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/lucasdecamargo/go-appservice-example/pkg/control"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/svcctl"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/ui"
+	"github.com/lucasdecamargo/kardianos"
+)
+
+const rollingPollInterval = 500 * time.Millisecond
+
+// rollingRestart restarts the instances one at a time, waiting for each to report a ready
+// child before moving on. With no instances given, every installed instance is restarted.
+// It stops at the first instance that fails, leaving the remaining ones untouched.
+func rollingRestart(ctx context.Context, i kardianos.Interface, cfg *kardianos.Config, instances []string, retry svcctl.RetryConfig, timeout time.Duration) error {
+	if len(instances) == 0 {
+		var err error
+		if instances, err = svcctl.Instances(cfg.Name); err != nil {
+			ui.Error("Error: %v. Name the instances with --instance.", err)
+			return err
+		}
+		if len(instances) == 0 {
+			ui.Error("Error: no instances of %s are installed.", cfg.Name)
+			return kardianos.ErrNotInstalled
+		}
+	}
+
+	for n, instance := range instances {
+		icfg := instanceConfig(cfg, instance)
+		s, err := kardianos.New(i, icfg)
+		if err != nil {
+			return err
+		}
+		if err := restartAndWait(ctx, s, controlAddr(icfg), retry, timeout); err != nil {
+			ui.Error("Error: rolling restart stopped at %s: %v", s, err)
+			if rest := instances[n+1:]; len(rest) > 0 {
+				ui.Warn("Not restarted: %s.", strings.Join(rest, ", "))
+			}
+			return err
+		}
+	}
+
+	ui.Success("Restarted %d instances.", len(instances))
+	return nil
+}
+
+// restartAndWait restarts s and waits until its new supervisor, reached at addr, reports
+// a ready child
+func restartAndWait(ctx context.Context, s kardianos.Service, addr string, retry svcctl.RetryConfig, timeout time.Duration) error {
+	client := control.NewClient(addr)
+
+	// Remember the current supervisor so its stale status isn't mistaken for readiness
+	prev := 0
+	sctx, cancel := context.WithTimeout(ctx, controlTimeout)
+	if st, err := client.Status(sctx); err == nil {
+		prev = st.PID
+	}
+	cancel()
+
+	msg := fmt.Sprintf("Running restart on %s", s)
+	if err := ui.Spin(os.Stdout, msg, func() error { return svcctl.Control(ctx, s, "restart", retry) }); err != nil {
+		return err
+	}
+
+	msg = fmt.Sprintf("Waiting for %s to become ready", s)
+	return ui.Spin(os.Stdout, msg, func() error { return waitReady(ctx, client, prev, timeout) })
+}
+
+// waitReady polls the daemon at client until a supervisor other than prev reports a ready child
+func waitReady(ctx context.Context, client *control.Client, prev int, timeout time.Duration) error {
+	deadline := time.After(timeout)
+	ticker := time.NewTicker(rollingPollInterval)
+	defer ticker.Stop()
+
+	var lastErr error
+	for {
+		pctx, cancel := context.WithTimeout(ctx, controlTimeout)
+		st, err := client.Status(pctx)
+		cancel()
+		switch {
+		case err != nil:
+			lastErr = err
+		case st.PID == prev:
+			lastErr = errors.New("supervisor not restarted yet")
+		case !st.Ready:
+			lastErr = errors.New("child not ready")
+		default:
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-deadline:
+			return fmt.Errorf("%w: not ready after %v: %v", svcctl.ErrTimeout, timeout, lastErr)
+		case <-ticker.C:
+		}
+	}
+}
@@ -1,13 +1,28 @@
 package cmd
 
 import (
+	"github.com/lucasdecamargo/go-appservice-example/pkg/ui"
 	"github.com/spf13/cobra"
 )
 
 // RootCmd represents the base command when called without any subcommands
 func NewRootCmd() *cobra.Command {
-	return &cobra.Command{
+	var noColor bool
+
+	c := &cobra.Command{
 		Use:   "svcapp",
 		Short: "A simple example of a Go application that can be installed as a service",
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			if noColor {
+				ui.SetColor(false)
+			}
+		},
 	}
+
+	// Subcommands inherit the usage template, and with it the exit statuses
+	c.SetUsageTemplate(c.UsageTemplate() + exitCodesHelp)
+
+	c.PersistentFlags().BoolVar(&noColor, "no-color", false, "Disable colored output (also honors NO_COLOR)")
+
+	return c
 }
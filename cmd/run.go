@@ -2,50 +2,125 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log/slog"
 	"os"
 	"os/signal"
+	"runtime"
+	"runtime/debug"
 	"sync"
 	"syscall"
 	"time"
 
+	"github.com/lucasdecamargo/go-appservice-example/pkg/archive"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/config"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/crash"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/loglevel"
 	"github.com/spf13/cobra"
 )
 
 const (
 	signalBufferSize = 3
 	shutdownTimeout  = 60 * time.Second
+
+	// ExitCodePanic is the exit status of the run command when the application panics
+	ExitCodePanic = 70
 )
 
 // RunFunc represents the function signature for the main application logic
 type RunFunc func(ctx context.Context, args []string) error
 
-// NewRunCmd creates a command for running the application with signal handling
-func NewRunCmd(f RunFunc) *cobra.Command {
-	return &cobra.Command{
+// PanicError reports a panic recovered from the application
+type PanicError struct {
+	Value any
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("application panic: %v", e.Value)
+}
+
+// NewRunCmd creates a command for running the application with signal handling.
+// The middlewares wrap f, the first one being the outermost.
+func NewRunCmd(f RunFunc, mws ...Middleware) *cobra.Command {
+	f = Chain(f, mws...)
+	var debug bool
+
+	c := &cobra.Command{
 		Use:   "run",
 		Short: "Run the application and exit with the specified status",
 		Long: `Run the application with signal handling and graceful shutdown.
 
 The run command executes the application with proper signal handling for SIGINT (Ctrl+C) 
 and SIGTERM. It ensures graceful shutdown by canceling the context and waiting for 
-the application to complete.`,
+the application to complete. Shutdown phases registered with OnShutdown run in order
+first, each bounded by its own timeout.
+
+If the application panics, the stack trace is logged as structured JSON, a crash
+report is written to the crash directory, and the command exits with status 70.
+
+Goroutines the application leaves running once it returns are reported, with their
+stacks under --debug. An application that doesn't return within the shutdown timeout
+fails with ErrShutdownStalled.
+
+SIGUSR1 lowers the log level by one step and SIGUSR2 raises it.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runWithSignals(cmd.Context(), f, args)
+			stopLogSignals := loglevel.HandleSignals()
+			err := runWithSignals(cmd.Context(), f, args, debug)
+			stopLogSignals()
+
+			var perr *PanicError
+			if errors.As(err, &perr) {
+				reportCrash(perr)
+				os.Exit(ExitCodePanic)
+			}
+			return err
 		},
 	}
+
+	c.Flags().BoolVar(&debug, "debug", false, "Dump the stacks of goroutines left running after shutdown")
+
+	return c
+}
+
+// reportCrash logs a recovered panic and writes its crash report
+func reportCrash(perr *PanicError) {
+	r := crash.NewReport(perr.Value, perr.Stack)
+	slog.Error("Application panicked", "panic", r.Panic, "stack", r.Stack, "crashId", r.ID)
+
+	// Compression is best effort, a broken config file must not cost the report
+	var compress archive.Options
+	if c, err := config.Load(config.DefaultPath()); err == nil {
+		compress = c.Compression.Options()
+	}
+
+	path, err := crash.Write(crash.DefaultDir(), r, compress)
+	if err != nil {
+		slog.Error("Failed to write crash report", "error", err)
+		return
+	}
+	slog.Info("Crash report written", "path", path)
 }
 
-// runWithSignals executes the application with signal handling
-func runWithSignals(ctx context.Context, f RunFunc, args []string) error {
+// runWithSignals executes the application with signal handling, then reports the
+// goroutines it left running
+func runWithSignals(ctx context.Context, f RunFunc, args []string, dumpStacks bool) error {
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
+	ctx, phases := withShutdownPhases(ctx)
 
 	// Set up signal handling
 	sigChan := make(chan os.Signal, signalBufferSize)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
 	defer signal.Stop(sigChan)
 
+	baseline := runtime.NumGoroutine()
+	defer func() {
+		cancel()
+		reportLeaks(baseline, dumpStacks)
+	}()
+
 	// Run application in goroutine
 	done := make(chan struct{})
 	var runErr error
@@ -53,6 +128,11 @@ func runWithSignals(ctx context.Context, f RunFunc, args []string) error {
 
 	wg.Go(func() {
 		defer close(done)
+		defer func() {
+			if r := recover(); r != nil {
+				runErr = &PanicError{Value: r, Stack: debug.Stack()}
+			}
+		}()
 		runErr = f(ctx, args)
 	})
 
@@ -61,13 +141,17 @@ func runWithSignals(ctx context.Context, f RunFunc, args []string) error {
 	case <-done:
 		return runErr
 	case sig := <-sigChan:
-		return handleShutdown(cancel, &wg, sig, runErr)
+		return handleShutdown(cancel, phases, &wg, sig, &runErr)
 	}
 }
 
-// handleShutdown manages graceful shutdown
-func handleShutdown(cancel context.CancelFunc, wg *sync.WaitGroup, sig os.Signal, runErr error) error {
+// handleShutdown manages graceful shutdown. The registered phases run in order once the
+// context is canceled, then the application gets shutdownTimeout to return. runErr is read
+// once the application returns.
+func handleShutdown(cancel context.CancelFunc, phases *shutdownPhases, wg *sync.WaitGroup, sig os.Signal, runErr *error) error {
+	slog.Info("Shutting down", "signal", sig.String())
 	cancel()
+	phaseErr := phases.run()
 
 	shutdownDone := make(chan struct{})
 	go func() {
@@ -77,11 +161,11 @@ func handleShutdown(cancel context.CancelFunc, wg *sync.WaitGroup, sig os.Signal
 
 	select {
 	case <-shutdownDone:
-		if runErr != nil {
-			return fmt.Errorf("application error: %w", runErr)
+		if *runErr != nil {
+			return errors.Join(fmt.Errorf("application error: %w", *runErr), phaseErr)
 		}
-		return fmt.Errorf("shutdown by signal: %v", sig)
+		return errors.Join(fmt.Errorf("shutdown by signal: %v", sig), phaseErr)
 	case <-time.After(shutdownTimeout):
-		return fmt.Errorf("shutdown timeout exceeded after %v", shutdownTimeout)
+		return errors.Join(fmt.Errorf("%w: timeout exceeded after %v", ErrShutdownStalled, shutdownTimeout), phaseErr)
 	}
 }
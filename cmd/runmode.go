@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// NoDeadline is the time left passed to Hooks.OnTick by loops without a deadline
+const NoDeadline time.Duration = -1
+
+// RunMode selects when the run loop of the application ends
+type RunMode int
+
+const (
+	// RunTimeout runs until --timeout elapses or a signal arrives
+	RunTimeout RunMode = iota
+	// RunOnce runs the work once, to completion
+	RunOnce
+	// RunForever runs until a signal arrives
+	RunForever
+)
+
+func (m RunMode) String() string {
+	switch m {
+	case RunOnce:
+		return "once"
+	case RunForever:
+		return "forever"
+	default:
+		return "timeout"
+	}
+}
+
+// AddRunModeFlags adds the --once and --forever flags selecting mode to c. They
+// exclude each other, and --timeout when c has it.
+func AddRunModeFlags(c *cobra.Command, mode *RunMode) {
+	c.Flags().Var(&runModeFlag{mode: mode, value: RunOnce}, "once", "Run the work once, to completion, then exit")
+	c.Flags().Var(&runModeFlag{mode: mode, value: RunForever}, "forever", "Run until signaled, without a timeout")
+	c.Flags().Lookup("once").NoOptDefVal = "true"
+	c.Flags().Lookup("forever").NoOptDefVal = "true"
+
+	exclusive := []string{"once", "forever"}
+	if c.Flags().Lookup("timeout") != nil {
+		exclusive = append(exclusive, "timeout")
+	}
+	c.MarkFlagsMutuallyExclusive(exclusive...)
+}
+
+// runModeFlag is a boolean flag selecting one run mode
+type runModeFlag struct {
+	mode  *RunMode
+	value RunMode
+}
+
+func (f *runModeFlag) String() string {
+	return strconv.FormatBool(f.mode != nil && *f.mode == f.value)
+}
+
+func (f *runModeFlag) Set(s string) error {
+	on, err := strconv.ParseBool(s)
+	if err != nil {
+		return err
+	}
+	switch {
+	case on:
+		*f.mode = f.value
+	case *f.mode == f.value:
+		*f.mode = RunTimeout
+	}
+	return nil
+}
+
+func (f *runModeFlag) Type() string {
+	return "bool"
+}
+
+// IsBoolFlag lets the flag be given without a value
+func (f *runModeFlag) IsBoolFlag() bool {
+	return true
+}
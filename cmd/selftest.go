@@ -0,0 +1,149 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/lucasdecamargo/go-appservice-example/pkg/control"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/paths"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/state"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/svcctl"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/ui"
+	"github.com/lucasdecamargo/kardianos"
+	"github.com/spf13/cobra"
+)
+
+const (
+	selftestSuffix       = "-selftest"
+	selftestInstance     = "selftest"
+	selftestPollInterval = 500 * time.Millisecond
+)
+
+// NewSelftestCmd creates a command that runs a full service lifecycle under a temporary name
+func NewSelftestCmd(i kardianos.Interface, cfg *kardianos.Config) *cobra.Command {
+	var timeout time.Duration
+
+	c := &cobra.Command{
+		Use:   "selftest",
+		Short: "Verify platform support with an install, start, health, stop and uninstall cycle. Requires root privileges.",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := runSelftest(cmd.Context(), i, cfg, timeout); err != nil {
+				ui.Error("Selftest failed: %v", err)
+				os.Exit(ExitCode(err))
+			}
+			ui.Success("Selftest passed.")
+		},
+	}
+
+	c.Flags().DurationVar(&timeout, "timeout", 30*time.Second, "Time allowed for the service to become healthy")
+
+	return c
+}
+
+// runSelftest installs a temporary copy of the service and walks it through its lifecycle.
+// The temporary service is uninstalled even when a step fails.
+func runSelftest(ctx context.Context, i kardianos.Interface, cfg *kardianos.Config, timeout time.Duration) (err error) {
+	dir, err := os.MkdirTemp("", "svcapp-selftest-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	addr := "unix://" + filepath.Join(dir, "control.sock")
+	s, err := kardianos.New(i, selftestConfig(cfg, dir, addr))
+	if err != nil {
+		return err
+	}
+
+	retry := svcctl.DefaultRetryConfig()
+	step := func(action string) error {
+		msg := fmt.Sprintf("Running %s on %s", action, s)
+		return ui.Spin(os.Stdout, msg, func() error { return svcctl.Control(ctx, s, action, retry) })
+	}
+
+	if err := step("install"); err != nil {
+		return err
+	}
+	defer func() {
+		s.Stop()
+		err = errors.Join(err, step("uninstall"))
+	}()
+
+	if err := step("start"); err != nil {
+		return err
+	}
+
+	msg := fmt.Sprintf("Checking health of %s", s)
+	if err := ui.Spin(os.Stdout, msg, func() error { return waitHealthy(ctx, s, addr, timeout) }); err != nil {
+		return err
+	}
+
+	return step("stop")
+}
+
+// selftestConfig derives the temporary service configuration from cfg. The state file,
+// control socket and PID file are moved into dir, and the service runs as the selftest
+// instance, so its bus name, logs, audit and crash directories and status pipe don't
+// clash with the real service.
+func selftestConfig(cfg *kardianos.Config, dir, addr string) *kardianos.Config {
+	c := *cfg
+	c.Name += selftestSuffix
+	c.DisplayName += selftestSuffix
+
+	c.EnvVars = maps.Clone(cfg.EnvVars)
+	if c.EnvVars == nil {
+		c.EnvVars = map[string]string{}
+	}
+	c.EnvVars[state.EnvState] = filepath.Join(dir, "state.json")
+	c.EnvVars[control.EnvControlAddr] = addr
+	c.EnvVars[paths.EnvInstance] = selftestInstance
+
+	c.Option = maps.Clone(cfg.Option)
+	if _, ok := c.Option["PIDFile"]; ok {
+		c.Option["PIDFile"] = filepath.Join(dir, paths.App()+".pid")
+	}
+	return &c
+}
+
+// waitHealthy polls until the service runs and its daemon reports a live child
+func waitHealthy(ctx context.Context, s kardianos.Service, addr string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	client := control.NewClient(addr)
+	ticker := time.NewTicker(selftestPollInterval)
+	defer ticker.Stop()
+
+	var lastErr error
+	for {
+		status, err := s.Status()
+		switch {
+		case err != nil:
+			lastErr = err
+		case status != kardianos.StatusRunning:
+			lastErr = errors.New("service is not running")
+		default:
+			st, err := client.Status(ctx)
+			switch {
+			case err != nil:
+				lastErr = err
+			case st.ChildPID == 0:
+				lastErr = errors.New("no child process")
+			default:
+				return nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("not healthy after %v: %w", timeout, lastErr)
+		case <-ticker.C:
+		}
+	}
+}
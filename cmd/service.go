@@ -1,9 +1,25 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"maps"
 	"os"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/lucasdecamargo/go-appservice-example/pkg/audit"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/config"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/control"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/daemon"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/dbus"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/state"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/svcctl"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/ui"
 	"github.com/lucasdecamargo/kardianos"
 	"github.com/spf13/cobra"
 )
@@ -15,47 +31,527 @@ const (
 	errAlreadyInstalled    = "Already installed."
 )
 
+// Service actions handled on top of kardianos.ControlAction
+const actionStatus = "status"
+
+const controlTimeout = 3 * time.Second
+
 // NewServiceCmd creates a command for managing the application service
 func NewServiceCmd(i kardianos.Interface, cfg *kardianos.Config) *cobra.Command {
-	return &cobra.Command{
-		Use:       "service {start|stop|restart|install|uninstall}",
+	var (
+		after     string
+		cancel    bool
+		retry     = svcctl.DefaultRetryConfig()
+		configSrc string
+		configPin string
+		wizard    bool
+		instances []string
+		rolling   bool
+		delayed   bool
+		triggers  []string
+		now       bool
+		enable    bool
+		purge     bool
+		ifChanged bool
+
+		readyTimeout = time.Minute
+	)
+
+	c := &cobra.Command{
+		Use:       "service {start|stop|restart|install|uninstall|status}",
 		Short:     "Manage the application service. Requires root privileges.",
-		ValidArgs: []string{"start", "stop", "restart", "install", "uninstall"},
+		ValidArgs: []string{"start", "stop", "restart", "install", "uninstall", actionStatus},
 		Args:      cobra.MatchAll(cobra.OnlyValidArgs, cobra.ExactArgs(1)),
+		Example: `  svcapp service stop --after 30m          # Stop the service in 30 minutes
+  svcapp service restart --after 2026-01-02T03:00:00Z
+  svcapp service stop --cancel             # Cancel a deferred stop or restart
+  svcapp service install --config https://example.com/svcapp.json --config-sha256 <hex>
+  svcapp service install --wizard          # Guided install with a preview
+  svcapp service install --instance a --instance b   # Install svcapp@a and svcapp@b
+  svcapp service install --delayed-auto-start --trigger network   # On Windows
+  svcapp service install --now             # Install, enable and start, like systemctl enable --now
+  svcapp service install --enable=false    # Install without starting at boot
+  svcapp service uninstall --now           # Stop and uninstall
+  svcapp service uninstall --purge         # Uninstall and remove the state, logs and sockets
+  svcapp service restart --rolling         # Restart the instances one at a time
+  svcapp service restart --if-changed      # Restart only when the executable, config or env files changed
+  svcapp service edit --set Restart=always # Change an option without reinstalling
+  svcapp service verify                    # Check the installed definition
+  svcapp service export -o svcapp.json     # Export the definition to move it to another machine`,
 		Run: func(cmd *cobra.Command, args []string) {
-			if err := handleServiceCommand(i, cfg, args[0]); err != nil {
-				os.Exit(1)
+			var err error
+			if after == "" && !cancel {
+				if err := checkPrivileges(cfg.Name, args[0]); err != nil {
+					os.Exit(ExitCode(err))
+				}
+			}
+			if wizard {
+				if err := installWizard(args[0], cfg); err != nil {
+					os.Exit(ExitCode(err))
+				}
+			}
+			if configSrc != "" {
+				if err := installConfig(cmd.Context(), args[0], configSrc, configPin); err != nil {
+					os.Exit(ExitCode(err))
+				}
+			}
+			if delayed || len(triggers) > 0 {
+				if err := installWindowsOptions(args[0], cfg, delayed, triggers); err != nil {
+					os.Exit(ExitCode(err))
+				}
+			}
+
+			if now || cmd.Flags().Changed("enable") {
+				if err := checkNowFlags(args[0], now, cmd.Flags().Changed("enable")); err != nil {
+					os.Exit(ExitCode(err))
+				}
+			}
+
+			if purge && args[0] != "uninstall" {
+				ui.Error("Error: --purge is only supported with uninstall.")
+				os.Exit(ExitUnknown)
+			}
+
+			if ifChanged && args[0] != daemon.ActionRestart {
+				ui.Error("Error: --if-changed is only supported with restart.")
+				os.Exit(ExitUnknown)
+			}
+
+			act := func(cfg *kardianos.Config) error {
+				if after != "" || cancel {
+					return handleDeferredCommand(cmd.Context(), controlAddr(cfg), args[0], after, cancel)
+				}
+				if ifChanged {
+					changed := sourcesChanged(cmd.Context(), cfg)
+					if len(changed) == 0 {
+						ui.Success("%s is up to date, not restarted.", cfg.Name)
+						return nil
+					}
+					fmt.Printf("Restarting %s, %s.\n", cfg.Name, strings.Join(changed, ", "))
+				}
+				if purge && !isInstalled(i, cfg) {
+					ui.Warn("%s is not installed, looking for what an uninstall left behind.", cfg.Name)
+					return reportLeftovers(cfg, purge)
+				}
+				var err error
+				if now || cmd.Flags().Changed("enable") {
+					err = installNow(cmd.Context(), i, cfg, args[0], now, enable, retry)
+				} else {
+					err = handleServiceCommand(cmd.Context(), i, cfg, args[0], retry)
+				}
+				if err != nil || args[0] != "uninstall" {
+					return err
+				}
+				return reportLeftovers(cfg, purge)
+			}
+
+			switch {
+			case rolling:
+				if args[0] != daemon.ActionRestart {
+					ui.Error("Error: --rolling is only supported with restart.")
+					os.Exit(ExitUnknown)
+				}
+				err = rollingRestart(cmd.Context(), i, cfg, instances, retry, readyTimeout)
+			case len(instances) > 0:
+				for _, instance := range instances {
+					if err = act(instanceConfig(cfg, instance)); err != nil {
+						break
+					}
+				}
+			default:
+				err = act(cfg)
+			}
+			if err != nil {
+				os.Exit(ExitCode(err))
 			}
 		},
 	}
+
+	c.Flags().StringVar(&after, "after", "", "Defer a stop or restart by a duration (30m) or until a time (RFC 3339)")
+	c.Flags().BoolVar(&cancel, "cancel", false, "Cancel a deferred stop or restart")
+	c.MarkFlagsMutuallyExclusive("after", "cancel")
+	c.Flags().IntVar(&retry.Attempts, "retries", retry.Attempts, "Attempts made before giving up on a transient failure")
+	c.Flags().DurationVar(&retry.Backoff, "retry-backoff", retry.Backoff, "Delay before the first retry, doubled on each retry")
+	c.Flags().DurationVar(&retry.Timeout, "timeout", retry.Timeout, "Timeout of a single attempt")
+	c.Flags().StringVar(&configSrc, "config", "", "Install the config from an https:// URL or a local path")
+	c.Flags().StringVar(&configPin, "config-sha256", "", "Expected SHA-256 checksum of the --config document")
+	c.Flags().BoolVar(&wizard, "wizard", false, "Ask for the install settings and preview them before installing")
+	c.MarkFlagsMutuallyExclusive("wizard", "config")
+	c.Flags().StringSliceVar(&instances, "instance", nil, "Act on the named instances (svcapp@<name>) instead of the service")
+	c.Flags().BoolVar(&rolling, "rolling", false, "Restart the instances one at a time, waiting for each to become ready")
+	c.Flags().DurationVar(&readyTimeout, "ready-timeout", readyTimeout, "Time allowed for each instance to become ready with --rolling")
+	c.MarkFlagsMutuallyExclusive("rolling", "after")
+	c.Flags().BoolVar(&delayed, "delayed-auto-start", false, "Start the service a while after boot, on Windows")
+	c.Flags().StringArrayVar(&triggers, "trigger", nil, "Also start the service on network or device:<interface class GUID>[:<hardware ID>], on Windows")
+	c.Flags().BoolVar(&now, "now", false, "Also start the service after install, or stop it before uninstall")
+	c.Flags().BoolVar(&enable, "enable", true, "Start the installed service at boot, --enable=false to only start it by hand")
+	c.MarkFlagsMutuallyExclusive("now", "after")
+	c.Flags().BoolVar(&purge, "purge", false, "Also remove the files the uninstall leaves behind, such as the state and logs")
+	c.MarkFlagsMutuallyExclusive("purge", "after")
+	c.Flags().BoolVar(&ifChanged, "if-changed", false, "Only restart when the executable, config file or env files changed since the child started")
+	c.MarkFlagsMutuallyExclusive("if-changed", "after")
+	c.MarkFlagsMutuallyExclusive("if-changed", "rolling")
+
+	c.AddCommand(newServiceEditCmd(i, cfg), newServiceVerifyCmd(i, cfg), newServiceExportCmd(cfg), newServiceImportCmd(i, cfg))
+
+	return c
+}
+
+// checkPrivileges checks that the process can run a service action before anything
+// is changed, and prints what it lacks with the command to run it again
+func checkPrivileges(name, action string) error {
+	err := svcctl.CheckPrivileges(name, action)
+	var perr *svcctl.PrivilegeError
+	if errors.As(err, &perr) {
+		ui.Error("Error: %s.", perr)
+		fmt.Println(perr.Hint)
+	}
+	return err
+}
+
+// checkNowFlags checks that --now is used with install or uninstall, and --enable with install
+func checkNowFlags(action string, now, enable bool) error {
+	if now && action != "install" && action != "uninstall" {
+		ui.Error("Error: --now is only supported with install and uninstall.")
+		return errors.New("--now without install or uninstall")
+	}
+	if enable && action != "install" {
+		ui.Error("Error: --enable is only supported with install.")
+		return errors.New("--enable without install")
+	}
+	return nil
+}
+
+// installNow installs the service, choosing whether it starts at boot, then starts it
+// with now, like systemctl enable --now. On uninstall, now stops the service first.
+func installNow(ctx context.Context, i kardianos.Interface, cfg *kardianos.Config, action string, now, enable bool, retry svcctl.RetryConfig) error {
+	if action == "uninstall" {
+		if s, err := kardianos.New(i, cfg); err == nil {
+			if status, _ := s.Status(); status == kardianos.StatusRunning {
+				if err := handleServiceCommand(ctx, i, cfg, "stop", retry); err != nil {
+					return err
+				}
+			}
+		}
+		return handleServiceCommand(ctx, i, cfg, action, retry)
+	}
+
+	svcctl.ApplyEnable(cfg, enable)
+	if err := handleServiceCommand(ctx, i, cfg, action, retry); err != nil {
+		return err
+	}
+	if !enable {
+		if err := svcctl.Disable(cfg.Name, kardianos.Platform()); err != nil {
+			ui.Warn("Warning: the service still starts at boot: %v", err)
+		}
+	}
+	if !now {
+		return nil
+	}
+	return handleServiceCommand(ctx, i, cfg, "start", retry)
 }
 
 // handleServiceCommand processes service management commands
-func handleServiceCommand(i kardianos.Interface, cfg *kardianos.Config, action string) error {
+func handleServiceCommand(ctx context.Context, i kardianos.Interface, cfg *kardianos.Config, action string, retry svcctl.RetryConfig) error {
 	s, err := kardianos.New(i, cfg)
 	if err != nil {
 		panic(err) // not supposed to happen in production
 	}
 
-	if err := kardianos.Control(s, action); err != nil {
+	if action == actionStatus {
+		return printServiceStatus(s, controlAddr(cfg))
+	}
+
+	if action == "install" {
+		c, err := config.Load(config.DefaultPath())
+		if err != nil {
+			ui.Error("Error: %v", err)
+			return err
+		}
+		if err := prepareDirs(cfg, c.Output); err != nil {
+			ui.Error("Error: %v", err)
+			return err
+		}
+		c.Windows.Apply(cfg.Option) // After the install flags, which take precedence
+	}
+
+	msg := fmt.Sprintf("Running %s on %s", action, s)
+	err = ui.Spin(os.Stdout, msg, func() error { return svcctl.Control(ctx, s, action, retry) })
+	auditServiceAction(cfg.Name, action, err)
+	if err != nil {
 		return handleServiceError(err)
 	}
 
+	// Let the daemon own its D-Bus name, or clean up after it
+	switch action {
+	case "install":
+		err = dbus.InstallPolicy(cfg.Name)
+	case "uninstall":
+		err = dbus.RemovePolicy(cfg.Name)
+	}
+	if err != nil {
+		ui.Warn("Warning: D-Bus policy not updated: %v", err)
+	}
+	if triggers := svcctl.Triggers(cfg.Option); action == "install" && len(triggers) > 0 {
+		if err := svcctl.SetTriggers(cfg.Name, triggers); err != nil {
+			ui.Warn("Warning: service triggers not set: %v", err)
+		}
+	}
+
 	return nil
 }
 
+// auditServiceAction records a service action run from this command, and its outcome,
+// to the audit log
+func auditServiceAction(name, action string, err error) {
+	c, cerr := config.Load(config.DefaultPath())
+	if cerr != nil {
+		return // Reported by the action itself
+	}
+	l, aerr := audit.Open(c.Audit, name)
+	if aerr == nil {
+		defer l.Close()
+		aerr = l.Record(audit.Record{Source: audit.SourceCLI, Action: action, Target: name, Actor: audit.Self()}, err)
+	}
+	if aerr != nil {
+		ui.Warn("Warning: %s not audited: %v", action, aerr)
+	}
+}
+
+// printServiceStatus prints the service state as a two-column table, with the daemon
+// state read from the control socket at addr
+func printServiceStatus(s kardianos.Service, addr string) error {
+	status, err := s.Status()
+
+	t := ui.NewTable(os.Stdout)
+	t.Row("Service", s.String())
+	t.Row("Platform", s.Platform())
+	t.Row("Status", formatStatus(status, err))
+
+	// The daemon state is only available while the daemon runs
+	ctx, cancel := context.WithTimeout(context.Background(), controlTimeout)
+	defer cancel()
+	if st, err := control.NewClient(addr).Status(ctx); err == nil {
+		addStateRows(t, st)
+	} else if status == kardianos.StatusRunning {
+		// The daemon saves why its control socket is down, when it watches it
+		if st, err := storedState(); err == nil {
+			addControlRow(t, st)
+		}
+	}
+
+	if err := t.Flush(); err != nil {
+		return err
+	}
+	if errors.Is(err, kardianos.ErrNotInstalled) {
+		return err
+	}
+	return nil
+}
+
+// formatPorts lists ports, such as "8080, 9090"
+func formatPorts(ports []int) string {
+	s := make([]string, len(ports))
+	for i, p := range ports {
+		s[i] = strconv.Itoa(p)
+	}
+	return strings.Join(s, ", ")
+}
+
+// addControlRow adds the control sockets that don't accept connections to t
+func addControlRow(t *ui.Table, st *state.State) {
+	for _, addr := range slices.Sorted(maps.Keys(st.ControlDown)) {
+		t.Row("Control API", ui.Colorize(ui.Red, fmt.Sprintf("down on %s, %s", addr, st.ControlDown[addr])))
+	}
+}
+
+// addStateRows adds the daemon state reported on the control socket to t
+func addStateRows(t *ui.Table, st *state.State) {
+	t.Row("Supervisor", fmt.Sprintf("PID %d since %s", st.PID, st.StartedAt.Format(time.RFC3339)))
+	if st.ChildPID != 0 {
+		t.Row("Child", fmt.Sprintf("PID %d", st.ChildPID))
+	}
+	if len(st.Ports) > 0 {
+		t.Row("Ports", formatPorts(st.Ports))
+	}
+	t.Row("Restarts", st.Restarts)
+	if st.Degraded != "" {
+		t.Row("Storage", ui.Colorize(ui.Red, "memory only, "+st.Degraded))
+	}
+	if len(st.RestartReasons) > 0 {
+		t.Row("Restart reasons", formatRestartReasons(st.RestartReasons))
+	}
+	addControlRow(t, st)
+	if st.Scheduled != nil {
+		t.Row("Scheduled", ui.Colorize(ui.Yellow, fmt.Sprintf("%s at %s", st.Scheduled.Action, st.Scheduled.At.Format(time.RFC3339))))
+	}
+	if u := st.Update; u != nil {
+		version := u.Current
+		if u.Available != "" {
+			version += ui.Colorize(ui.Yellow, fmt.Sprintf(", %s available", u.Available))
+		} else if u.Error == "" {
+			version += ", up to date"
+		}
+		if u.Error != "" {
+			version += ui.Colorize(ui.Red, ", "+u.Error)
+		}
+		t.Row("Version", version)
+	}
+}
+
+// formatRestartReasons lists the restart counters by reason, such as "crash 2, manual 1"
+func formatRestartReasons(reasons map[string]int) string {
+	var parts []string
+	for _, reason := range slices.Sorted(maps.Keys(reasons)) {
+		parts = append(parts, fmt.Sprintf("%s %d", reason, reasons[reason]))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// installConfig fetches, verifies and stores the configuration used by the installed service
+func installConfig(ctx context.Context, action, src, pin string) error {
+	if action != "install" {
+		ui.Error("Error: --config is only supported with install.")
+		return fmt.Errorf("--config used with %s", action)
+	}
+
+	path := config.DefaultPath()
+	msg := fmt.Sprintf("Installing config from %s", src)
+	if err := ui.Spin(os.Stdout, msg, func() error { return config.Install(ctx, src, pin, path) }); err != nil {
+		ui.Error("Error: %v", err)
+		return err
+	}
+	return nil
+}
+
+// installWindowsOptions sets the Windows options given by the install flags on cfg
+func installWindowsOptions(action string, cfg *kardianos.Config, delayed bool, triggers []string) error {
+	if action != "install" {
+		ui.Error("Error: --delayed-auto-start and --trigger are only supported with install.")
+		return fmt.Errorf("windows options used with %s", action)
+	}
+	if runtime.GOOS != "windows" {
+		ui.Warn("Warning: --delayed-auto-start and --trigger only apply on Windows.")
+	}
+
+	if delayed {
+		cfg.Option["DelayedAutoStart"] = true
+	}
+	var parsed []svcctl.Trigger
+	for _, s := range triggers {
+		t, err := svcctl.ParseTrigger(s)
+		if err != nil {
+			ui.Error("Error: --trigger: %v", err)
+			return err
+		}
+		parsed = append(parsed, t)
+	}
+	if len(parsed) > 0 {
+		cfg.Option[svcctl.OptionTriggers] = parsed
+	}
+	return nil
+}
+
+// installWizard runs the install wizard, exiting without error when the user declines
+func installWizard(action string, cfg *kardianos.Config) error {
+	if action != "install" {
+		ui.Error("Error: --wizard is only supported with install.")
+		return fmt.Errorf("--wizard used with %s", action)
+	}
+
+	err := runInstallWizard(cfg)
+	if errors.Is(err, errWizardCanceled) {
+		ui.Warn("Install canceled.")
+		os.Exit(ExitOK)
+	}
+	if err != nil {
+		ui.Error("Error: %v", err)
+	}
+	return err
+}
+
+// handleDeferredCommand schedules or cancels a deferred stop or restart on the daemon
+// running at addr
+func handleDeferredCommand(ctx context.Context, addr, action, after string, cancel bool) error {
+	if action != daemon.ActionStop && action != daemon.ActionRestart {
+		ui.Error("Error: only stop and restart can be deferred.")
+		return fmt.Errorf("cannot defer %s", action)
+	}
+
+	ctx, done := context.WithTimeout(ctx, controlTimeout)
+	defer done()
+	client := control.NewClient(addr)
+
+	if cancel {
+		if _, err := client.CancelSchedule(ctx); err != nil {
+			ui.Error("Error: %v", err)
+			return err
+		}
+		ui.Success("Deferred action canceled.")
+		return nil
+	}
+
+	at, err := parseAfter(after)
+	if err != nil {
+		ui.Error("Error: %v", err)
+		return err
+	}
+
+	if _, err := client.Schedule(ctx, action, at); err != nil {
+		ui.Error("Error: %v", err)
+		return err
+	}
+
+	ui.Success("Scheduled %s at %s.", action, at.Format(time.RFC3339))
+	return nil
+}
+
+// parseAfter parses a duration from now or an RFC 3339 timestamp
+func parseAfter(after string) (time.Time, error) {
+	if d, err := time.ParseDuration(after); err == nil {
+		if d <= 0 {
+			return time.Time{}, fmt.Errorf("--after %q is in the past", after)
+		}
+		return time.Now().Add(d), nil
+	}
+	at, err := time.Parse(time.RFC3339, after)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid --after %q: expected a duration or an RFC 3339 time", after)
+	}
+	if at.Before(time.Now()) {
+		return time.Time{}, fmt.Errorf("--after %q is in the past", after)
+	}
+	return at, nil
+}
+
+// formatStatus renders a kardianos status with a color matching its state
+func formatStatus(status kardianos.Status, err error) string {
+	switch {
+	case errors.Is(err, kardianos.ErrNotInstalled):
+		return ui.Colorize(ui.Gray, "not installed")
+	case status == kardianos.StatusRunning:
+		return ui.Colorize(ui.Green, "active (running)")
+	case status == kardianos.StatusStopped:
+		return ui.Colorize(ui.Yellow, "inactive (stopped)")
+	case err != nil:
+		return ui.Colorize(ui.Red, fmt.Sprintf("unknown (%v)", err))
+	default:
+		return ui.Colorize(ui.Red, "unknown")
+	}
+}
+
 // handleServiceError processes service-related errors and provides user-friendly messages
 func handleServiceError(err error) error {
-	switch err {
-	case kardianos.ErrNotInstalled:
-		fmt.Println(errServiceNotInstalled)
-	case kardianos.ErrNoServiceSystemDetected:
-		fmt.Println(errNoServiceSystem)
-	case kardianos.ErrServiceExists:
-		fmt.Println(errAlreadyInstalled)
+	switch {
+	case errors.Is(err, kardianos.ErrNotInstalled):
+		ui.Error(errServiceNotInstalled)
+	case errors.Is(err, kardianos.ErrNoServiceSystemDetected):
+		ui.Error(errNoServiceSystem)
+	case errors.Is(err, kardianos.ErrServiceExists):
+		ui.Warn(errAlreadyInstalled)
 		return nil // Not an error, just informational
 	default:
-		fmt.Printf("Service error: %v\n", err)
+		ui.Error("Service error: %v", err)
 	}
 
 	return err
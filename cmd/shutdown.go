@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Conventional shutdown phase names, in the order they are usually registered
+const (
+	PhaseStopAccepting = "stop-accepting"
+	PhaseDrain         = "drain"
+	PhaseFlush         = "flush"
+	PhaseClose         = "close"
+)
+
+// ShutdownPhase is a named step of the graceful shutdown with its own timeout
+type ShutdownPhase struct {
+	Name    string
+	Timeout time.Duration
+	Run     func(ctx context.Context) error
+}
+
+// shutdownPhases holds the phases registered by a running application
+type shutdownPhases struct {
+	mu     sync.Mutex
+	phases []ShutdownPhase
+}
+
+type shutdownKey struct{}
+
+// OnShutdown registers a phase run in registration order when the run command is
+// signaled. It does nothing when ctx doesn't come from the run command.
+func OnShutdown(ctx context.Context, name string, timeout time.Duration, f func(ctx context.Context) error) {
+	s, ok := ctx.Value(shutdownKey{}).(*shutdownPhases)
+	if !ok {
+		slog.Warn("Shutdown phase ignored outside the run command", "phase", name)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.phases = append(s.phases, ShutdownPhase{Name: name, Timeout: timeout, Run: f})
+}
+
+// withShutdownPhases returns a context the application registers its phases on
+func withShutdownPhases(ctx context.Context) (context.Context, *shutdownPhases) {
+	s := &shutdownPhases{}
+	return context.WithValue(ctx, shutdownKey{}, s), s
+}
+
+// run executes the phases in order, each bounded by its timeout. A failed
+// phase is logged and doesn't prevent the next ones from running.
+func (s *shutdownPhases) run() error {
+	s.mu.Lock()
+	phases := append([]ShutdownPhase(nil), s.phases...)
+	s.mu.Unlock()
+
+	var errs []error
+	for _, p := range phases {
+		if err := runPhase(p); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// runPhase executes a single phase, giving up on it after its timeout
+func runPhase(p ShutdownPhase) error {
+	slog.Info("Shutdown phase started", "phase", p.Name, "timeout", p.Timeout)
+	start := time.Now()
+
+	ctx, cancel := context.WithTimeout(context.Background(), p.Timeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() { done <- p.Run(ctx) }()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	if err != nil {
+		slog.Error("Shutdown phase failed", "phase", p.Name, "duration", time.Since(start), "error", err)
+		return &PhaseError{Phase: p.Name, Err: err}
+	}
+	slog.Info("Shutdown phase completed", "phase", p.Name, "duration", time.Since(start))
+	return nil
+}
+
+// PhaseError reports a shutdown phase that failed or timed out
+type PhaseError struct {
+	Phase string
+	Err   error
+}
+
+func (e *PhaseError) Error() string {
+	return "shutdown phase " + e.Phase + ": " + e.Err.Error()
+}
+
+func (e *PhaseError) Unwrap() error {
+	return e.Err
+}
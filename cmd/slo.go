@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/lucasdecamargo/go-appservice-example/pkg/history"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/metrics"
+	"github.com/spf13/cobra"
+)
+
+// NewSloCmd creates a command summarizing start and stop latencies from the daemon history
+func NewSloCmd() *cobra.Command {
+	var since time.Duration
+
+	c := &cobra.Command{
+		Use:   "slo",
+		Short: "Summarize start and stop latency percentiles from the daemon history",
+		Long: `Summarize start and stop latencies recorded by the daemon.
+
+Start latency runs from a start or restart request to the child being ready. Stop
+latency runs from a stop request to the child having exited.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			st, err := openStore()
+			if err != nil {
+				return err
+			}
+			defer st.Close()
+
+			events, err := st.Events()
+			if err != nil {
+				return err
+			}
+			if since > 0 {
+				cutoff := time.Now().Add(-since)
+				events = filterEvents(events, func(e history.Event) bool { return e.Time.After(cutoff) })
+			}
+
+			return printSLO(os.Stdout, events)
+		},
+	}
+
+	c.Flags().DurationVar(&since, "since", 0, "Only include events newer than this duration (default all)")
+
+	return c
+}
+
+// filterEvents returns the events matching keep
+func filterEvents(events []history.Event, keep func(history.Event) bool) []history.Event {
+	var out []history.Event
+	for _, e := range events {
+		if keep(e) {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// printSLO renders latency percentiles per event kind as an aligned table
+func printSLO(w io.Writer, events []history.Event) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "KIND\tCOUNT\tFAILED\tP50\tP95\tP99\tMAX\t")
+
+	for _, kind := range []string{history.KindStart, history.KindStop} {
+		var samples []time.Duration
+		failed := 0
+		for _, e := range events {
+			switch {
+			case e.Kind != kind:
+			case e.Error != "":
+				failed++
+			default:
+				samples = append(samples, e.Duration)
+			}
+		}
+
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%s\t%s\t%s\t%s\t\n", kind, len(samples), failed,
+			formatLatency(metrics.Percentile(samples, 50)),
+			formatLatency(metrics.Percentile(samples, 95)),
+			formatLatency(metrics.Percentile(samples, 99)),
+			formatLatency(metrics.Percentile(samples, 100)))
+	}
+
+	return tw.Flush()
+}
+
+// formatLatency renders a latency with millisecond precision
+func formatLatency(d time.Duration) string {
+	if d == 0 {
+		return "-"
+	}
+	return d.Round(time.Millisecond).String()
+}
@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/lucasdecamargo/go-appservice-example/pkg/control"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/history"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/ui"
+	"github.com/spf13/cobra"
+)
+
+// snapshotTimeout bounds the snapshot requests, which wait for the snapshot command
+const snapshotTimeout = 10 * time.Minute
+
+// NewSnapshotCmd creates a command listing, taking and restoring the snapshots of the
+// service data
+func NewSnapshotCmd() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "snapshot",
+		Short: "List the snapshots of the service data taken by the daemon",
+		Long: `List the snapshots the daemon took before reloads and updates, and the restores,
+from its history. Snapshots are taken by the commands of the "snapshot" section of
+the config file.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			st, err := openStore()
+			if err != nil {
+				return err
+			}
+			defer st.Close()
+
+			events, err := st.Events()
+			if err != nil {
+				return err
+			}
+			return printSnapshots(os.Stdout, filterEvents(events, func(e history.Event) bool {
+				return e.Kind == history.KindSnapshot
+			}))
+		},
+	}
+
+	c.AddCommand(&cobra.Command{
+		Use:   "take",
+		Short: "Take a snapshot of the service data now",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, cancel := context.WithTimeout(cmd.Context(), snapshotTimeout)
+			defer cancel()
+			name, err := control.NewClient(control.DefaultAddr()).TakeSnapshot(ctx)
+			if err != nil {
+				return err
+			}
+			ui.Success("Took snapshot %s.", name)
+			return nil
+		},
+	}, &cobra.Command{
+		Use:   "restore <name>",
+		Short: "Restart the child on a restored snapshot of the service data",
+		Long: `Stop the child, run the restore command of the config file for the snapshot,
+then start the child again.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, cancel := context.WithTimeout(cmd.Context(), snapshotTimeout)
+			defer cancel()
+			if err := control.NewClient(control.DefaultAddr()).RollbackToSnapshot(ctx, args[0]); err != nil {
+				return err
+			}
+			ui.Success("Restarting the child on snapshot %s.", args[0])
+			return nil
+		},
+	})
+
+	return c
+}
+
+// printSnapshots renders the snapshot events as a table
+func printSnapshots(w io.Writer, events []history.Event) error {
+	if len(events) == 0 {
+		fmt.Fprintln(w, "No snapshots recorded.")
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TIME\tEVENT\tDURATION\tRESULT")
+	for _, e := range events {
+		result := "ok"
+		if e.Error != "" {
+			result = e.Error
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", e.Time.Local().Format(time.DateTime), e.Detail,
+			e.Duration.Round(time.Millisecond), result)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,159 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/lucasdecamargo/go-appservice-example/pkg/config"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/control"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/daemon"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/state"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/ui"
+	"github.com/lucasdecamargo/kardianos"
+)
+
+// startupSummary describes what the daemon is about to run, as groups of settings
+type startupSummary []slog.Attr
+
+// newStartupSummary collects the effective settings of the daemon. Argument values
+// read from stdin are redacted, and only the names of environment variables are listed.
+func newStartupSummary(d *daemon.Daemon, cfg *kardianos.Config, c *config.Config, cc *childConfig, version string) startupSummary {
+	wd, _ := os.Getwd()
+	exe := d.Executable
+	if exe == "" {
+		exe, _ = os.Executable()
+	}
+	_, profile, _ := c.SelectProfile(cc.profile)
+
+	storage := c.Storage.Path
+	if storage == "" {
+		storage = filepath.Dir(state.DefaultPath())
+	}
+	backend := c.Storage.Backend
+	if backend == "" {
+		backend = "json"
+	}
+
+	return startupSummary{
+		slog.Group("service",
+			slog.String("name", cfg.Name),
+			slog.String("version", version),
+			slog.Int("pid", os.Getpid()),
+			slog.String("platform", kardianos.Platform()),
+			slog.Bool("interactive", kardianos.Interactive()),
+			slog.String("executable", exe),
+			slog.String("workingDirectory", wd),
+			slog.String("config", config.DefaultPath()),
+		),
+		slog.Group("child",
+			slog.String("profile", orNone(profile)),
+			slog.String("args", strings.Join(cc.redactArgs(d.Args), " ")),
+			slog.String("env", orNone(strings.Join(envNames(d.EnvVars), ", "))),
+			slog.Int("secretFiles", len(d.Secrets)),
+			slog.String("confinement", orNone(d.Confinement.String())),
+			slog.String("mounts", orNone(d.Mounts.String())),
+			slog.String("ports", orNone(d.Ports.String())),
+		),
+		slog.Group("limits",
+			slog.String("exitTimeout", d.ExitTimeout.String()),
+			slog.String("drainTimeout", orNone(durationString(d.DrainTimeout))),
+			slog.String("startTimeout", orNone(durationString(d.StartTimeout))),
+			slog.Int("startRetries", d.StartRetries),
+			slog.String("maxRuntime", orNone(durationString(d.MaxRuntime))),
+			slog.String("heartbeat", orNone(durationString(d.Heartbeat.Interval))),
+			slog.String("lameDuck", orNone(durationString(d.LameDuck.Period))),
+			slog.String("shutdownBoost", orNone(d.ShutdownBoost.String())),
+			slog.String("resources", orNone(strings.Join(d.Limits.Directives(), " "))),
+		),
+		slog.Group("logs",
+			slog.String("stdout", streamDestinations(c.Output.Stdout)),
+			slog.String("stderr", streamDestinations(c.Output.Stderr)),
+			slog.String("forward", orNone(c.Output.Forward.Sink)),
+			slog.String("storage", backend+" in "+storage),
+			slog.String("crashes", orNone(d.CrashDir)),
+			slog.String("control", control.DefaultAddr()),
+		),
+	}
+}
+
+// Print writes the summary as tables on a terminal, and as a single structured log
+// record otherwise, for the service manager journal
+func (s startupSummary) Print() {
+	if !ui.Interactive() {
+		slog.LogAttrs(context.Background(), slog.LevelInfo, "Daemon starting", s...)
+		return
+	}
+	for _, group := range s {
+		fmt.Println(ui.Colorize(ui.Bold, strings.ToUpper(group.Key[:1])+group.Key[1:]))
+		t := ui.NewTable(os.Stdout)
+		for _, a := range group.Value.Group() {
+			t.Row(a.Key, a.Value)
+		}
+		t.Flush()
+	}
+	fmt.Println()
+}
+
+// redactArgs returns args with the values read from stdin replaced
+func (cc *childConfig) redactArgs(args []string) []string {
+	redacted := slices.Clone(args)
+	for i, a := range redacted {
+		if slices.Contains(cc.stdinArgs, a) {
+			redacted[i] = config.Redacted
+		}
+	}
+	return redacted
+}
+
+// envNames returns the sorted, unique names of the KEY=VALUE pairs env
+func envNames(env []string) []string {
+	names := make([]string, 0, len(env))
+	for _, kv := range env {
+		name, _, _ := strings.Cut(kv, "=")
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return slices.Compact(names)
+}
+
+// streamDestinations lists where a child output stream goes
+func streamDestinations(s config.Stream) string {
+	if s.Discard {
+		return "discard"
+	}
+	var dests []string
+	if s.ConsoleEnabled() {
+		dests = append(dests, "console")
+	}
+	if s.File != "" && s.Splice {
+		dests = append(dests, s.File+" (spliced)")
+	} else if s.File != "" {
+		dests = append(dests, s.File)
+	}
+	if s.Sample > 1 && len(dests) > 0 {
+		dests = append(dests, fmt.Sprintf("1 line in %d", s.Sample))
+	}
+	return orNone(strings.Join(dests, ", "))
+}
+
+// durationString formats d, empty when zero
+func durationString(d time.Duration) string {
+	if d == 0 {
+		return ""
+	}
+	return d.String()
+}
+
+// orNone returns s, or "none" when empty
+func orNone(s string) string {
+	if s == "" {
+		return "none"
+	}
+	return s
+}
@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/lucasdecamargo/go-appservice-example/pkg/control"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/daemon"
+	"github.com/lucasdecamargo/kardianos"
+	"github.com/spf13/cobra"
+)
+
+// NewStatusCmd creates a command showing the service status and, optionally, the
+// resources used by the supervisor
+func NewStatusCmd(i kardianos.Interface, cfg *kardianos.Config) *cobra.Command {
+	var (
+		resources bool
+		watch     time.Duration
+	)
+
+	c := &cobra.Command{
+		Use:   "status",
+		Short: "Show the service status",
+		Long: `Show the service status, as "service status" does.
+
+With --resources, also show the memory used by the supervisor and the estimated
+footprint of each of its subsystems, to decide what to disable with lean mode.
+
+With --watch, refresh the status until interrupted, like top, with the health,
+restarts, memory and CPU usage of the supervisor and its children. Changes since
+the previous refresh are highlighted.`,
+		Example: `  svcapp status --watch        # Refresh every 2s
+  svcapp status --watch=500ms`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			s, err := kardianos.New(i, cfg)
+			if err != nil {
+				return err
+			}
+			if watch > 0 {
+				return watchStatus(cmd.Context(), os.Stdout, s, control.DefaultAddr(), watch)
+			}
+			if err := printServiceStatus(s, control.DefaultAddr()); err != nil || !resources {
+				return err
+			}
+
+			ctx, cancel := context.WithTimeout(cmd.Context(), controlTimeout)
+			defer cancel()
+			r, err := control.NewClient(control.DefaultAddr()).Resources(ctx)
+			if err != nil {
+				return fmt.Errorf("daemon is not running: %w", err)
+			}
+
+			fmt.Println()
+			return printResources(os.Stdout, r)
+		},
+	}
+
+	c.Flags().BoolVar(&resources, "resources", false, "Show the supervisor memory usage by subsystem")
+	c.Flags().DurationVarP(&watch, "watch", "w", 0, "Refresh the status at this interval until interrupted")
+	c.Flags().Lookup("watch").NoOptDefVal = defaultWatchInterval.String()
+	c.MarkFlagsMutuallyExclusive("watch", "resources")
+
+	return c
+}
+
+// printResources renders the supervisor totals and the subsystem footprints as tables
+func printResources(w io.Writer, r *daemon.Resources) error {
+	mode := "full"
+	if r.Lean {
+		mode = "lean"
+	}
+	fmt.Fprintf(w, "Supervisor: %s heap, %s from the OS, %d goroutines, %s mode\n\n",
+		formatBytes(r.HeapBytes), formatBytes(r.SysBytes), r.Goroutines, mode)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SUBSYSTEM\tENABLED\tMEMORY\tGOROUTINES\tDETAIL")
+	for _, f := range r.Subsystems {
+		enabled := "no"
+		if f.Enabled {
+			enabled = "yes"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\n", f.Subsystem, enabled, formatBytes(uint64(f.Bytes)), f.Goroutines, f.Detail)
+	}
+	return tw.Flush()
+}
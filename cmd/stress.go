@@ -0,0 +1,422 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/lucasdecamargo/go-appservice-example/pkg/crash"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/daemon"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/daemon/daemontest"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/ui"
+	"github.com/spf13/cobra"
+)
+
+const (
+	stressStepTimeout  = 15 * time.Second       // Longest wait for a child transition
+	stressExitTimeout  = 3 * time.Second        // Time a stopped child has to exit
+	stressLongRun      = "10m"                  // Child run time, for children stopped by the scenario
+	stressEventsBuffer = 64                     // Lifecycle events held until a scenario reads them
+	stressMaxRuntime   = 500 * time.Millisecond // Recycle period of the recycle scenario
+)
+
+// Exit modes taken by the --exit-with flag main adds to the run command
+const (
+	stressExitNil   = "nil"
+	stressExitErr   = "err"
+	stressExitPanic = "panic"
+	stressExitFatal = "fatal"
+)
+
+// stressScenario exercises one supervisor policy against real children
+type stressScenario struct {
+	name string
+	desc string
+	run  func(ctx context.Context, exe, dir string) error
+}
+
+// stressScenarios covers every exit mode of the run command and the timing edge cases
+// of the supervisor
+var stressScenarios = []stressScenario{
+	{"clean-exit", "A child exiting with success stops the service without error", func(ctx context.Context, exe, dir string) error {
+		r := startStress(exe, dir, []string{"--exit-with", stressExitNil, "--timeout", "300ms"}, nil)
+		if _, err := r.wait(daemon.EventReady); err != nil {
+			return err
+		}
+		if err := r.exit(); err != nil {
+			return fmt.Errorf("service failed: %w", err)
+		}
+		return r.stoppedOnce()
+	}},
+	{"error-exit", "A child exiting with an error is reported as crashed and fails the service", func(ctx context.Context, exe, dir string) error {
+		return expectCrash(startStress(exe, dir, []string{"--exit-with", stressExitErr, "--timeout", "300ms"}, nil), "exit status 1")
+	}},
+	{"fatal-exit", "A child calling log.Fatal is reported as crashed and fails the service", func(ctx context.Context, exe, dir string) error {
+		return expectCrash(startStress(exe, dir, []string{"--exit-with", stressExitFatal, "--timeout", "300ms"}, nil), "exit status 1")
+	}},
+	{"panic", "A panicking child exits with status 70 and writes a crash report", func(ctx context.Context, exe, dir string) error {
+		r := startStress(exe, dir, []string{"--exit-with", stressExitPanic, "--timeout", "300ms"}, nil)
+		if err := expectCrash(r, fmt.Sprintf("exit status %d", ExitCodePanic)); err != nil {
+			return err
+		}
+		if ids, err := crash.List(dir); err != nil || len(ids) == 0 {
+			return fmt.Errorf("no crash report written: %v", err)
+		}
+		return nil
+	}},
+	{"immediate-exit", "A child exiting before it is ready stops the service", func(ctx context.Context, exe, dir string) error {
+		r := startStress(exe, dir, []string{"--exit-with", stressExitNil, "--timeout", "0s"}, nil)
+		if err := r.exit(); err != nil {
+			return fmt.Errorf("service failed: %w", err)
+		}
+		return r.stoppedOnce()
+	}},
+	{"stop", "Stopping the service stops a running child within the exit timeout", func(ctx context.Context, exe, dir string) error {
+		r := startStress(exe, dir, []string{"--exit-with", stressExitNil, "--timeout", stressLongRun}, nil)
+		if _, err := r.wait(daemon.EventReady); err != nil {
+			return err
+		}
+		return r.stopWithin(stressExitTimeout, true)
+	}},
+	{"stop-while-starting", "Stopping the service before the child is ready stops it within the exit timeout", func(ctx context.Context, exe, dir string) error {
+		r := startStress(exe, dir, []string{"--exit-with", stressExitNil, "--timeout", stressLongRun}, nil)
+		if _, err := r.wait(daemon.EventStarted); err != nil {
+			return err
+		}
+		return r.stopWithin(stressExitTimeout, false)
+	}},
+	{"restart", "A manual restart replaces the child and is counted", func(ctx context.Context, exe, dir string) error {
+		r := startStress(exe, dir, []string{"--exit-with", stressExitNil, "--timeout", stressLongRun}, nil)
+		first, err := r.wait(daemon.EventReady)
+		if err != nil {
+			return err
+		}
+		if err := r.d.RestartChild(); err != nil {
+			return fmt.Errorf("restart failed: %w", err)
+		}
+		if err := r.expectNewChild(first.PID); err != nil {
+			return err
+		}
+		if n := r.d.Status().RestartReasons[daemon.RestartManual]; n != 1 {
+			return fmt.Errorf("%d manual restarts counted, want 1", n)
+		}
+		return r.stopWithin(stressExitTimeout, true)
+	}},
+	{"recycle", "A child running past maxRuntime is recycled", func(ctx context.Context, exe, dir string) error {
+		r := startStress(exe, dir, []string{"--exit-with", stressExitNil, "--timeout", stressLongRun}, func(c *daemon.DaemonConfig) {
+			c.MaxRuntime = stressMaxRuntime
+		})
+		first, err := r.wait(daemon.EventReady)
+		if err != nil {
+			return err
+		}
+		if err := r.expectNewChild(first.PID); err != nil {
+			return err
+		}
+		if n := r.d.Status().RestartReasons[daemon.RestartRecycle]; n < 1 {
+			return errors.New("no recycle counted")
+		}
+		return r.stopWithin(stressExitTimeout, true)
+	}},
+	{"killed", "A child killed from outside is reported as a crash and fails the service", func(ctx context.Context, exe, dir string) error {
+		r := startStress(exe, dir, []string{"--exit-with", stressExitNil, "--timeout", stressLongRun}, nil)
+		ready, err := r.wait(daemon.EventReady)
+		if err != nil {
+			return err
+		}
+		p, err := os.FindProcess(ready.PID)
+		if err != nil {
+			return err
+		}
+		if err := p.Kill(); err != nil {
+			return fmt.Errorf("failed to kill child: %w", err)
+		}
+		if _, err := r.wait(daemon.EventCrashed); err != nil {
+			return err
+		}
+		if err := r.exit(); err == nil {
+			return errors.New("service succeeded, want a failure")
+		}
+		if reason := r.d.Status().Crashed; reason != daemon.RestartCrash && reason != daemon.RestartOOM {
+			return fmt.Errorf("crash reason %q, want %s", reason, daemon.RestartCrash)
+		}
+		return nil
+	}},
+	{"start-timeout", "A child missing its start timeout is retried, then fails the service", func(ctx context.Context, exe, dir string) error {
+		r := startStress(exe, dir, []string{"--exit-with", stressExitNil, "--timeout", stressLongRun}, func(c *daemon.DaemonConfig) {
+			c.StartTimeout = time.Millisecond
+			c.OnStartFailure = daemon.StartFailureRetry
+			c.StartRetries = 1
+		})
+		if err := r.exit(); !errors.Is(err, daemon.ErrStartTimeout) {
+			return fmt.Errorf("service returned %v, want %v", err, daemon.ErrStartTimeout)
+		}
+		if n := r.count(daemon.EventStarted); n != 2 {
+			return fmt.Errorf("%d children started, want 2", n)
+		}
+		return nil
+	}},
+}
+
+// NewStressCmd creates a command running the supervisor policies against real children
+// repeatedly, to qualify a platform
+func NewStressCmd() *cobra.Command {
+	var (
+		iterations int
+		only       []string
+		list       bool
+	)
+
+	c := &cobra.Command{
+		Use:   "stress",
+		Short: "Exercise the supervisor policies with children covering every exit mode",
+		Long: `Run an in-process supervisor against real children of the run command, once per
+scenario and iteration, and check that its policies hold: exits, crashes, panics,
+stops, restarts, recycling and start timeouts. Nothing is installed, the state is
+kept in memory and crash reports go to a temporary directory.
+
+Use it to qualify a new platform, or with many iterations to shake out races. The
+command fails when any scenario fails.`,
+		Example: `  svcapp stress
+  svcapp stress --iterations 50 --scenario restart --scenario stop`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if list {
+				t := ui.NewTable(os.Stdout)
+				for _, s := range stressScenarios {
+					t.Row(s.name, s.desc)
+				}
+				return t.Flush()
+			}
+
+			scenarios := stressScenarios
+			if len(only) > 0 {
+				scenarios = nil
+				for _, name := range only {
+					i := slices.IndexFunc(stressScenarios, func(s stressScenario) bool { return s.name == name })
+					if i < 0 {
+						return fmt.Errorf("unknown scenario %q, use one of %s", name, strings.Join(stressNames(), ", "))
+					}
+					scenarios = append(scenarios, stressScenarios[i])
+				}
+			}
+			return runStress(cmd.Context(), scenarios, iterations)
+		},
+	}
+
+	c.Flags().IntVarP(&iterations, "iterations", "n", 1, "Times to run each scenario")
+	c.Flags().StringArrayVar(&only, "scenario", nil, "Run only this scenario, repeatable")
+	c.Flags().BoolVar(&list, "list", false, "List the scenarios and exit")
+	c.RegisterFlagCompletionFunc("scenario", cobra.FixedCompletions(stressNames(), cobra.ShellCompDirectiveNoFileComp))
+
+	return c
+}
+
+// runStress runs each scenario iterations times, reporting each run, and fails when
+// any run failed
+func runStress(ctx context.Context, scenarios []stressScenario, iterations int) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("executable path not found: %w", err)
+	}
+
+	failed := 0
+	for i := 1; i <= iterations; i++ {
+		for _, s := range scenarios {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			dir, err := os.MkdirTemp("", "svcapp-stress-*")
+			if err != nil {
+				return err
+			}
+			begin := time.Now()
+			err = s.run(ctx, exe, dir)
+			os.RemoveAll(dir)
+
+			took := time.Since(begin).Round(time.Millisecond)
+			if err != nil {
+				failed++
+				ui.Error("✗ [%d/%d] %s (%s): %v", i, iterations, s.name, took, err)
+				continue
+			}
+			ui.Success("✓ [%d/%d] %s (%s)", i, iterations, s.name, took)
+		}
+	}
+
+	total := len(scenarios) * iterations
+	if failed > 0 {
+		return fmt.Errorf("%d of %d stress runs failed", failed, total)
+	}
+	ui.Success("All %d stress runs passed.", total)
+	return nil
+}
+
+// stressNames returns the names of the scenarios
+func stressNames() []string {
+	names := make([]string, len(stressScenarios))
+	for i, s := range stressScenarios {
+		names[i] = s.name
+	}
+	return names
+}
+
+// stressRun is a supervisor running under a fake service manager
+type stressRun struct {
+	d      *daemon.Daemon
+	s      *daemontest.Service
+	done   chan error
+	events chan daemon.LifecycleEvent
+	seen   []daemon.LifecycleEvent
+}
+
+// startStress starts a supervisor of "run" with runArgs, crash reports going to dir.
+// tune adjusts its configuration, when not nil.
+func startStress(exe, dir string, runArgs []string, tune func(*daemon.DaemonConfig)) *stressRun {
+	r := &stressRun{done: make(chan error, 1), events: make(chan daemon.LifecycleEvent, stressEventsBuffer)}
+	cfg := &daemon.DaemonConfig{
+		Executable:  exe,
+		Args:        append([]string{"run"}, runArgs...),
+		EnvVars:     []string{crash.EnvCrashDir + "=" + dir},
+		OutWriter:   io.Discard,
+		ErrWriter:   io.Discard,
+		ExitTimeout: stressExitTimeout,
+
+		// Track readiness, for the scenarios to act on ready children
+		StartTimeout: stressStepTimeout,
+		OnLifecycle: func(e daemon.LifecycleEvent) {
+			select {
+			case r.events <- e:
+			default:
+			}
+		},
+	}
+	if tune != nil {
+		tune(cfg)
+	}
+	r.d = daemon.NewDaemon(cfg)
+	r.s = daemontest.NewService(r.d)
+	go func() { r.done <- r.s.Run() }()
+	return r
+}
+
+// wait returns the next lifecycle event of type kind
+func (r *stressRun) wait(kind string) (daemon.LifecycleEvent, error) {
+	timeout := time.After(stressStepTimeout)
+	for {
+		select {
+		case e := <-r.events:
+			r.seen = append(r.seen, e)
+			if e.Type == kind {
+				return e, nil
+			}
+		case err := <-r.done:
+			r.done <- err
+			select {
+			case e := <-r.events: // Emitted before the service ended
+				r.events <- e
+				continue
+			default:
+			}
+			return daemon.LifecycleEvent{}, fmt.Errorf("service ended before the child was %s: %v", kind, err)
+		case <-timeout:
+			return daemon.LifecycleEvent{}, fmt.Errorf("child not %s after %s", kind, stressStepTimeout)
+		}
+	}
+}
+
+// exit waits for the service to end and returns its error
+func (r *stressRun) exit() error {
+	select {
+	case err := <-r.done:
+		r.done <- err
+		r.drain()
+		return err
+	case <-time.After(stressStepTimeout):
+		r.s.Stop()
+		return fmt.Errorf("service still running after %s", stressStepTimeout)
+	}
+}
+
+// stopWithin stops the service and checks that it ends within timeout, plus a margin
+// for the supervisor itself. On a graceful stop the child must also exit on its own
+// rather than die of the signal, which a child stopped before it handles signals may.
+func (r *stressRun) stopWithin(timeout time.Duration, graceful bool) error {
+	begin := time.Now()
+	r.s.Stop()
+	err := r.exit()
+	if info, ok := daemon.DescribeExit(err); graceful && ok && info.Abnormal {
+		return fmt.Errorf("child didn't exit on its own: %s", info.Reason)
+	} else if err != nil && !ok {
+		return fmt.Errorf("stop failed: %w", err)
+	}
+	if took := time.Since(begin); took > timeout+time.Second {
+		return fmt.Errorf("stop took %s, exit timeout is %s", took.Round(time.Millisecond), timeout)
+	}
+	return nil
+}
+
+// expectNewChild waits for a child other than pid to be ready
+func (r *stressRun) expectNewChild(pid int) error {
+	next, err := r.wait(daemon.EventReady)
+	if err != nil {
+		return err
+	}
+	if next.PID == pid {
+		return fmt.Errorf("child PID %d was not replaced", pid)
+	}
+	return nil
+}
+
+// stoppedOnce checks that the supervisor asked the service manager to stop once
+func (r *stressRun) stoppedOnce() error {
+	if n := r.s.Count(daemontest.ActionStop); n != 1 {
+		return fmt.Errorf("%d service stops requested, want 1", n)
+	}
+	return nil
+}
+
+// count returns the number of lifecycle events of type kind seen so far
+func (r *stressRun) count(kind string) int {
+	r.drain()
+	n := 0
+	for _, e := range r.seen {
+		if e.Type == kind {
+			n++
+		}
+	}
+	return n
+}
+
+// drain moves the pending lifecycle events to seen
+func (r *stressRun) drain() {
+	for {
+		select {
+		case e := <-r.events:
+			r.seen = append(r.seen, e)
+		default:
+			return
+		}
+	}
+}
+
+// expectCrash checks that the child is reported as crashed with an error containing
+// want, and that the service then fails
+func expectCrash(r *stressRun, want string) error {
+	e, err := r.wait(daemon.EventCrashed)
+	if err != nil {
+		return err
+	}
+	if !strings.Contains(e.Error, want) {
+		return fmt.Errorf("child crashed with %q, want %q", e.Error, want)
+	}
+	if err := r.exit(); err == nil {
+		return errors.New("service succeeded, want a failure")
+	}
+	return r.stoppedOnce()
+}
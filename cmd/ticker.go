@@ -0,0 +1,27 @@
+package cmd
+
+import "time"
+
+const (
+	// adaptiveTickRatio is the share of the remaining time waited between adaptive ticks
+	adaptiveTickRatio = 10
+	// maxAdaptiveTick caps the adaptive interval, so long runs still show signs of life
+	maxAdaptiveTick = time.Minute
+)
+
+// TickSchedule spaces the ticks of the run loop. An adaptive schedule waits a tenth of
+// the time left until the deadline, between Interval and a minute, so long timeouts
+// log less while the last stretch is still reported at Interval.
+type TickSchedule struct {
+	Interval time.Duration
+	Adaptive bool
+}
+
+// Next returns the delay before the tick following one with remaining time left
+func (t TickSchedule) Next(remaining time.Duration) time.Duration {
+	interval := max(t.Interval, time.Millisecond)
+	if !t.Adaptive {
+		return interval
+	}
+	return max(interval, min(remaining/adaptiveTickRatio, maxAdaptiveTick)).Truncate(interval)
+}
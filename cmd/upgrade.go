@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/lucasdecamargo/go-appservice-example/pkg/svcctl"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/ui"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/update"
+	"github.com/lucasdecamargo/kardianos"
+	"github.com/spf13/cobra"
+)
+
+// NewUpgradeCmd creates a command installing a new executable into the versions directory
+func NewUpgradeCmd(i kardianos.Interface, cfg *kardianos.Config) *cobra.Command {
+	var (
+		version   string
+		noRestart bool
+	)
+
+	c := &cobra.Command{
+		Use:   "upgrade <executable>",
+		Short: "Install a new version of the executable and switch to it",
+		Long: `Copy the executable into versions/<version>/ of the versions directory the service
+runs from, point the current link to it, and restart the service when it is running.
+The version is read from "<executable> --version" unless --version is given.
+"svcapp rollback" switches back.`,
+		Example: `  sudo svcapp upgrade ./svcapp-1.4.0
+  sudo svcapp upgrade --version 1.4.0-rc.1 ./svcapp`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			v, ok := update.DetectVersions(cfg.Executable)
+			if !ok {
+				ui.Error("Error: %v, see the README to set one up.", update.ErrNotVersioned)
+				return update.ErrNotVersioned
+			}
+			if version == "" {
+				var err error
+				if version, err = executableVersion(args[0]); err != nil {
+					return err
+				}
+			}
+
+			if err := v.Install(version, args[0]); err != nil {
+				return err
+			}
+			if err := v.Switch(version); err != nil {
+				return err
+			}
+			ui.Success("Switched to %s.", version)
+			if noRestart {
+				return nil
+			}
+			return restartIfRunning(cmd, i, cfg)
+		},
+	}
+
+	c.Flags().StringVar(&version, "version", "", "Version of the executable, read from its --version output by default")
+	c.Flags().BoolVar(&noRestart, "no-restart", false, "Don't restart the running service")
+
+	return c
+}
+
+// NewRollbackCmd creates a command switching back to the previous version
+func NewRollbackCmd(i kardianos.Interface, cfg *kardianos.Config) *cobra.Command {
+	var noRestart bool
+
+	c := &cobra.Command{
+		Use:   "rollback",
+		Short: "Switch back to the previous version and restart the service",
+		Long: `Point the current link of the versions directory back to the previous version, and
+restart the service when it is running. Without a versions directory, restore the
+executable an automatic update replaced in place.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			version, err := update.Rollback(cfg.Executable)
+			if errors.Is(err, update.ErrNoPrevious) {
+				ui.Error("Error: %v.", update.ErrNoPrevious)
+				return err
+			}
+			if err != nil {
+				return err
+			}
+
+			if version == "" {
+				version = "the previous executable"
+			}
+			ui.Success("Rolled back to %s.", version)
+			if noRestart {
+				return nil
+			}
+			return restartIfRunning(cmd, i, cfg)
+		},
+	}
+
+	c.Flags().BoolVar(&noRestart, "no-restart", false, "Don't restart the running service")
+
+	return c
+}
+
+// executableVersion reads the version printed by "<path> --version"
+func executableVersion(path string) (string, error) {
+	out, err := exec.Command(path, "--version").Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to read the version of %s, pass --version: %w", path, err)
+	}
+	fields := strings.Fields(string(out))
+	if len(fields) == 0 {
+		return "", fmt.Errorf("%s printed no version, pass --version", path)
+	}
+	version := fields[len(fields)-1]
+	if _, err := update.ParseVersion(version); err != nil {
+		return "", fmt.Errorf("%s: %w, pass --version", path, err)
+	}
+	return version, nil
+}
+
+// restartIfRunning restarts the service so the supervisor and the child run the
+// current version
+func restartIfRunning(cmd *cobra.Command, i kardianos.Interface, cfg *kardianos.Config) error {
+	s, err := kardianos.New(i, cfg)
+	if err != nil {
+		return err
+	}
+	if status, err := s.Status(); err != nil || status != kardianos.StatusRunning {
+		return nil
+	}
+	return handleServiceCommand(cmd.Context(), i, cfg, "restart", svcctl.DefaultRetryConfig())
+}
@@ -0,0 +1,182 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/lucasdecamargo/go-appservice-example/pkg/svcctl"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/ui"
+	"github.com/lucasdecamargo/kardianos"
+	"github.com/spf13/cobra"
+)
+
+// errVerifyFailed reports an installed service that doesn't match its configuration
+var errVerifyFailed = errors.New("service verification failed")
+
+// verifyCheck is the outcome of one verification step, with an empty problem when it passed
+type verifyCheck struct {
+	name    string
+	problem string
+}
+
+// newServiceVerifyCmd creates a command checking the installed service against its configuration
+func newServiceVerifyCmd(i kardianos.Interface, cfg *kardianos.Config) *cobra.Command {
+	return &cobra.Command{
+		Use:   "verify",
+		Short: "Check that the installed service definition is complete and usable",
+		Long: `Check the installed service after an install: the unit file or SCM entry exists,
+the executable it starts and the directories it references exist, the run-as user
+exists, and the service starts at boot as configured. Every discrepancy is printed.`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true, // Failed checks aren't usage errors
+		RunE: func(cmd *cobra.Command, args []string) error {
+			s, err := kardianos.New(i, cfg)
+			if err != nil {
+				return err
+			}
+			if _, err := s.Status(); errors.Is(err, kardianos.ErrNotInstalled) {
+				ui.Error(errServiceNotInstalled)
+				return err
+			}
+
+			def, err := svcctl.Inspect(cfg.Name, kardianos.Platform())
+			if errors.Is(err, svcctl.ErrInspectUnsupported) {
+				ui.Warn("The service is installed, but its definition can't be read on %s.", s.Platform())
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+
+			checks := verifyService(cfg, def)
+			t := ui.NewTable(os.Stdout)
+			failed := 0
+			for _, c := range checks {
+				if c.problem == "" {
+					t.Row(c.name, ui.Colorize(ui.Green, "ok"))
+				} else {
+					t.Row(c.name, ui.Colorize(ui.Red, c.problem))
+					failed++
+				}
+			}
+			t.Flush()
+
+			if failed > 0 {
+				ui.Error("%d of %d checks failed.", failed, len(checks))
+				return errVerifyFailed
+			}
+			ui.Success("%s is installed correctly.", s)
+			return nil
+		},
+	}
+}
+
+// verifyService compares the installed definition def with cfg and the filesystem
+func verifyService(cfg *kardianos.Config, def svcctl.Definition) []verifyCheck {
+	definition := "SCM entry"
+	if def.Path != "" {
+		definition = def.Path
+	}
+	checks := []verifyCheck{
+		{name: fmt.Sprintf("Definition (%s)", definition)},
+		{name: "Executable", problem: checkExecutable(cfg, def.Executable)},
+	}
+	if dir := cfg.WorkingDirectory; dir != "" && !strings.HasPrefix(dir, "~") {
+		checks = append(checks, verifyCheck{name: "Working directory", problem: checkDir(dir)})
+	}
+	if pidFile, _ := cfg.Option["PIDFile"].(string); pidFile != "" {
+		checks = append(checks, verifyCheck{name: "PID file directory", problem: checkDir(filepath.Dir(pidFile))})
+	}
+
+	checks = append(checks, verifyCheck{name: "Run-as user", problem: checkUser(cfg.UserName, def.UserName)})
+
+	want := expectedEnabled(cfg)
+	var problem string
+	if def.Enabled != want {
+		problem = fmt.Sprintf("starts at boot: %t, expected %t", def.Enabled, want)
+	}
+	checks = append(checks, verifyCheck{name: "Enabled", problem: problem})
+
+	return checks
+}
+
+// checkExecutable checks that the installed executable exists, can be run, and is the
+// one the configuration names
+func checkExecutable(cfg *kardianos.Config, path string) string {
+	if path == "" {
+		return "no executable in the definition"
+	}
+	info, err := os.Stat(path)
+	switch {
+	case err != nil:
+		return err.Error()
+	case info.IsDir():
+		return path + " is a directory"
+	case runtime.GOOS != "windows" && info.Mode()&0o111 == 0:
+		return path + " is not executable"
+	}
+
+	want := cfg.Executable
+	if want == "" {
+		if want, err = os.Executable(); err != nil {
+			return ""
+		}
+	}
+	if !sameFile(path, want) {
+		return fmt.Sprintf("%s is installed, expected %s", path, want)
+	}
+	return ""
+}
+
+// sameFile reports whether the paths a and b name the same file
+func sameFile(a, b string) bool {
+	ia, err := os.Stat(a)
+	if err != nil {
+		return false
+	}
+	ib, err := os.Stat(b)
+	return err == nil && os.SameFile(ia, ib)
+}
+
+// checkDir checks that dir exists and is a directory
+func checkDir(dir string) string {
+	info, err := os.Stat(dir)
+	switch {
+	case err != nil:
+		return err.Error()
+	case !info.IsDir():
+		return dir + " is not a directory"
+	}
+	return ""
+}
+
+// checkUser checks that the installed run-as user matches the configured one and exists
+func checkUser(want, installed string) string {
+	if !strings.EqualFold(want, installed) {
+		return fmt.Sprintf("runs as %q, expected %q", installed, want)
+	}
+	if installed == "" {
+		return ""
+	}
+	name := installed
+	if runtime.GOOS == "windows" {
+		name = strings.TrimPrefix(name, `.\`) // Local accounts
+	}
+	if _, err := user.Lookup(name); err != nil {
+		return fmt.Sprintf("user %s doesn't exist: %v", installed, err)
+	}
+	return ""
+}
+
+// expectedEnabled reports whether the configuration has the service start at boot
+func expectedEnabled(cfg *kardianos.Config) bool {
+	if v, ok := cfg.Option[svcctl.OptionStartType].(string); ok {
+		return svcctl.StartType(v) == svcctl.StartAutomatic
+	}
+	return true
+}
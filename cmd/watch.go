@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/lucasdecamargo/go-appservice-example/pkg/control"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/procinfo"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/state"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/ui"
+	"github.com/lucasdecamargo/kardianos"
+)
+
+// defaultWatchInterval is the refresh interval of a bare --watch
+const defaultWatchInterval = 2 * time.Second
+
+// clearScreen moves the cursor home and clears the terminal
+const clearScreen = "\033[H\033[2J"
+
+// watchSample is what a status --watch frame shows, kept to highlight the changes
+// in the next frame
+type watchSample struct {
+	at       time.Time
+	status   string
+	health   string
+	state    *state.State  // Nil when the daemon doesn't answer
+	rss      uint64        // Supervisor and its descendants
+	cpuTime  time.Duration // Supervisor and its descendants
+	cpu      float64       // Percent over the last interval, or since start on the first frame
+	children int           // Descendants of the supervisor
+}
+
+// watchStatus redraws the status every interval until interrupted. On a terminal the
+// screen is cleared between frames; otherwise frames are appended.
+func watchStatus(ctx context.Context, w io.Writer, s kardianos.Service, addr string, interval time.Duration) error {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+	defer stop()
+
+	client := control.NewClient(addr)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var prev *watchSample
+	for {
+		cur := sampleStatus(ctx, s, client, prev)
+		if ui.Interactive() {
+			fmt.Fprint(w, clearScreen)
+		} else if prev != nil {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "%s every %v, %s. Press Ctrl+C to exit.\n\n", s, interval, cur.at.Format(time.TimeOnly))
+		printWatchFrame(w, cur, prev)
+		prev = cur
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// sampleStatus collects a frame, computing the CPU usage since prev
+func sampleStatus(ctx context.Context, s kardianos.Service, client *control.Client, prev *watchSample) *watchSample {
+	status, err := s.Status()
+	cur := &watchSample{at: time.Now(), status: formatStatus(status, err), health: ui.Colorize(ui.Red, "down")}
+
+	ctx, cancel := context.WithTimeout(ctx, controlTimeout)
+	defer cancel()
+	st, err := client.Status(ctx)
+	if err != nil {
+		return cur
+	}
+	cur.state = st
+	switch {
+	case st.ChildPID != 0 && st.Ready:
+		cur.health = ui.Colorize(ui.Green, "healthy")
+	case st.ChildPID != 0:
+		cur.health = ui.Colorize(ui.Yellow, "starting")
+	}
+
+	procs, err := procinfo.List()
+	if err != nil {
+		return cur
+	}
+	root := procinfo.Tree(procs, st.PID)
+	if root == nil {
+		return cur
+	}
+	var walk func(n *procinfo.Node)
+	walk = func(n *procinfo.Node) {
+		cur.rss += n.RSS
+		cur.cpuTime += n.CPUTime
+		for _, c := range n.Children {
+			cur.children++
+			walk(c)
+		}
+	}
+	walk(root)
+
+	if prev != nil && prev.state != nil && prev.state.PID == st.PID && cur.cpuTime >= prev.cpuTime {
+		cur.cpu = 100 * (cur.cpuTime - prev.cpuTime).Seconds() / cur.at.Sub(prev.at).Seconds()
+	} else {
+		cur.cpu = root.CPUPercent()
+	}
+	return cur
+}
+
+// printWatchFrame renders cur, highlighting what changed since prev
+func printWatchFrame(w io.Writer, cur, prev *watchSample) {
+	t := ui.NewTable(w)
+	t.Row("Status", cur.status)
+	t.Row("Health", cur.health)
+
+	if st := cur.state; st != nil {
+		t.Row("Supervisor", fmt.Sprintf("PID %d, up %s", st.PID, time.Since(st.StartedAt).Truncate(time.Second)))
+		child := ui.Colorize(ui.Gray, "none")
+		if st.ChildPID != 0 {
+			child = fmt.Sprintf("PID %d", st.ChildPID)
+		}
+		if prev != nil && prev.state != nil && prev.state.ChildPID != st.ChildPID {
+			child = ui.Colorize(ui.Yellow, child+" (new)")
+		}
+		t.Row("Child", child)
+
+		restarts := fmt.Sprint(st.Restarts)
+		if prev != nil && prev.state != nil && st.Restarts > prev.state.Restarts {
+			restarts = ui.Colorize(ui.Yellow, fmt.Sprintf("%d (+%d)", st.Restarts, st.Restarts-prev.state.Restarts))
+		}
+		t.Row("Restarts", restarts)
+		t.Row("Memory", fmt.Sprintf("%s in %d processes", formatBytes(cur.rss), cur.children+1))
+		t.Row("CPU", fmt.Sprintf("%.1f%%", cur.cpu))
+		if st.Scheduled != nil {
+			t.Row("Scheduled", ui.Colorize(ui.Yellow, fmt.Sprintf("%s at %s", st.Scheduled.Action, st.Scheduled.At.Format(time.RFC3339))))
+		}
+	}
+	t.Flush()
+}
@@ -0,0 +1,184 @@
+package cmd
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/lucasdecamargo/go-appservice-example/pkg/config"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/paths"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/svcctl"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/systemd"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/ui"
+	"github.com/lucasdecamargo/kardianos"
+)
+
+// Log destinations offered by the install wizard
+const (
+	logConsole = "console"
+	logFile    = "file"
+	logBoth    = "both"
+)
+
+// errWizardCanceled reports an install the user declined after the preview
+var errWizardCanceled = errors.New("install canceled")
+
+// runInstallWizard asks for the run-as user, working directory, restart policy and log
+// destination, applies them to cfg and the output section of the config file, and
+// previews both. The config file is only written once the user confirms.
+func runInstallWizard(cfg *kardianos.Config) error {
+	p, err := ui.NewPrompter()
+	if err != nil {
+		return err
+	}
+
+	path := config.DefaultPath()
+	c, err := config.Load(path)
+	if err != nil {
+		return err
+	}
+
+	account := defaultAccount()
+	user, err := p.Ask("Run as user", cmp.Or(cfg.UserName, account))
+	if err != nil {
+		return err
+	}
+	if user == account {
+		user = ""
+	}
+
+	workDir, err := p.Ask("Working directory", cfg.WorkingDirectory)
+	if err != nil {
+		return err
+	}
+
+	restartKey, restartChoices := restartOption()
+	restart, _ := cfg.Option[restartKey].(string)
+	if restart, err = p.Choose("Restart policy", restartChoices, cmp.Or(restart, restartChoices[0])); err != nil {
+		return err
+	}
+
+	out, err := askOutput(p, c.Output)
+	if err != nil {
+		return err
+	}
+
+	cfg.UserName = user
+	cfg.WorkingDirectory = workDir
+	cfg.Option[restartKey] = restart
+
+	outJSON, err := json.MarshalIndent(out, "", "    ")
+	if err != nil {
+		return err
+	}
+	if err := previewInstall(cfg, path, outJSON); err != nil {
+		return err
+	}
+
+	ok, err := p.Confirm("Write the config and install?", false)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errWizardCanceled
+	}
+	return config.Set(path, "output", string(outJSON))
+}
+
+// askOutput asks where the child output goes and returns the updated output section
+func askOutput(p *ui.Prompter, out config.Output) (config.Output, error) {
+	def := logConsole
+	switch {
+	case out.Stdout.File != "" && out.Stdout.ConsoleEnabled():
+		def = logBoth
+	case out.Stdout.File != "":
+		def = logFile
+	}
+
+	dest, err := p.Choose("Log destination", []string{logConsole, logFile, logBoth}, def)
+	if err != nil {
+		return out, err
+	}
+	if dest == logConsole {
+		out.Stdout, out.Stderr = config.Stream{}, config.Stream{}
+		return out, nil
+	}
+
+	dir := defaultLogDir()
+	if out.Stdout.File != "" {
+		dir = filepath.Dir(out.Stdout.File)
+	}
+	if dir, err = p.Ask("Log directory", dir); err != nil {
+		return out, err
+	}
+
+	var console *bool
+	if dest == logFile {
+		console = new(bool)
+	}
+	out.Stdout = config.Stream{File: filepath.Join(dir, "child.out"), Console: console,
+		MaxSizeMB: out.Stdout.MaxSizeMB, MaxBackups: out.Stdout.MaxBackups}
+	out.Stderr = config.Stream{File: filepath.Join(dir, "child.err"), Console: console,
+		MaxSizeMB: out.Stderr.MaxSizeMB, MaxBackups: out.Stderr.MaxBackups}
+	return out, nil
+}
+
+// previewInstall prints the service settings, the unit file on systemd, and the output
+// section written to the config file at path
+func previewInstall(cfg *kardianos.Config, path string, outJSON []byte) error {
+	fmt.Println()
+	if strings.HasSuffix(kardianos.Platform(), "systemd") {
+		unit, err := systemd.Render(cfg)
+		if err != nil {
+			return fmt.Errorf("failed to render unit: %w", err)
+		}
+		fmt.Println(ui.Colorize(ui.Bold, "Unit file:"))
+		fmt.Println(unit)
+	} else {
+		t := ui.NewTable(os.Stdout)
+		t.Row("Service", cfg.Name)
+		t.Row("User", cmp.Or(cfg.UserName, defaultAccount()))
+		t.Row("Working directory", cfg.WorkingDirectory)
+		key, _ := restartOption()
+		t.Row(key, cfg.Option[key])
+		if err := t.Flush(); err != nil {
+			return err
+		}
+		fmt.Println()
+	}
+
+	fmt.Println(ui.Colorize(ui.Bold, fmt.Sprintf("Output section of %s:", path)))
+	fmt.Println(string(outJSON))
+	fmt.Println()
+	return nil
+}
+
+// restartOption returns the kardianos option holding the restart policy and its values
+func restartOption() (string, []string) {
+	if runtime.GOOS == "windows" {
+		actions := make([]string, len(svcctl.FailureActions))
+		for i, a := range svcctl.FailureActions {
+			actions[i] = string(a)
+		}
+		return svcctl.OptionOnFailure, actions
+	}
+	return "Restart", []string{"always", "on-failure", "on-success", "on-abnormal", "no"}
+}
+
+// defaultAccount returns the account services run as when no user is configured
+func defaultAccount() string {
+	if runtime.GOOS == "windows" {
+		return "LocalSystem"
+	}
+	return "root"
+}
+
+// defaultLogDir returns the directory suggested for child log files
+func defaultLogDir() string {
+	return paths.LogDir()
+}
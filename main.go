@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"fmt"
+	"io"
 	"log"
 	"log/slog"
 	"math/rand/v2"
@@ -11,7 +12,17 @@ import (
 	"time"
 
 	"github.com/lucasdecamargo/go-appservice-example/cmd"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/control"
 	"github.com/lucasdecamargo/go-appservice-example/pkg/daemon"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/kv"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/logexport"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/loglevel"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/mountns"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/paths"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/rlimit"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/svcctl"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/systemd"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/update"
 	"github.com/lucasdecamargo/kardianos"
 )
 
@@ -22,8 +33,13 @@ const (
 	serviceDescription = "A simple example of a Go application that can be installed as a service"
 
 	// Default timeouts
-	defaultExitTimeout = 5 * time.Second
-	defaultRunTimeout  = 30 * time.Second
+	defaultExitTimeout  = 5 * time.Second
+	defaultStartTimeout = 10 * time.Second
+	defaultStartRetries = 3
+	defaultRunTimeout   = 30 * time.Second
+	defaultTickInterval = 1 * time.Second
+	defaultWatchdogSec  = 30 * time.Second
+	defaultFailureDelay = 10 * time.Second
 
 	// Exit modes
 	exitModeNil   = "nil"
@@ -34,32 +50,106 @@ const (
 )
 
 var (
+	// Version is the release version, set with -ldflags "-X main.Version=1.2.3"
+	Version = "0.0.0-dev"
+
 	ExitWith string
 	Timeout  time.Duration
+	Mode     cmd.RunMode // Set with --once and --forever, RunTimeout by default
+
+	// Ticks spaces the calls to LoopHooks.OnTick, set with --tick and --adaptive-tick
+	Ticks = cmd.TickSchedule{Interval: defaultTickInterval}
+
+	// LoopHooks is called by the run loop; combine with cmd.MultiHooks to attach reporting or polling
+	LoopHooks cmd.Hooks = cmd.LoggingHooks()
+
+	// serviceLimits are the resource limits of the service, allowing core dumps of crashed children
+	serviceLimits = rlimit.Limits{
+		Core: rlimit.Same(rlimit.Infinity),
+	}
+
+	// serviceDependencies orders the service after the network, where the init system allows it
+	serviceDependencies = svcctl.Dependencies{
+		After: []string{"network-online.target"},
+		Wants: []string{"network-online.target"},
+	}
+
+	// ServiceWrappers decorate the daemon Start and Stop calls, outermost first; add
+	// daemon.WithMetrics or daemon.WithTracing to observe them
+	ServiceWrappers = []daemon.Wrapper{daemon.WithLogging(nil)}
+
+	// Aliases maps top-level commands kept for older wrappers to the command lines they run
+	Aliases = map[string]string{
+		"start":     "service start",
+		"stop":      "service stop",
+		"restart":   "service restart",
+		"install":   "service install",
+		"uninstall": "service uninstall",
+	}
 )
 
 func main() {
+	// Set up the mount namespace of a child first, when started as its helper
+	mountns.Init()
+
+	// Keep the latest log lines in memory as well, for svcapp logs export
+	recentLogs := logexport.NewRing(logexport.DefaultRingSize)
+	logOutput := io.MultiWriter(os.Stdout, recentLogs.Writer(logexport.SourceSupervisor))
+	logger := slog.New(slog.NewJSONHandler(logOutput, &slog.HandlerOptions{Level: loglevel.Level}))
+	slog.SetDefault(logger)
+
+	// Name the directories, PID file and sockets after the service, apart from other
+	// applications built from this one
+	if err := paths.SetApp(serviceName); err != nil {
+		log.Fatal(err)
+	}
+
 	cfg := getServiceConfig()
+	if exe, err := update.Executable(); err == nil {
+		cfg.Executable = exe // Through the current link of a versions directory, to follow upgrades
+	}
+	if err := cmd.ApplyServiceOverrides(cfg); err != nil {
+		log.Print("Ignoring the service overrides: ", err)
+	}
 
 	d := daemon.NewDaemon(&daemon.DaemonConfig{
-		Args:        []string{"run"},
-		ExitTimeout: defaultExitTimeout,
+		Executable:     cfg.Executable,
+		Args:           []string{"run"},
+		ExitTimeout:    defaultExitTimeout,
+		StartTimeout:   defaultStartTimeout,
+		OnStartFailure: daemon.StartFailureRetry,
+		StartRetries:   defaultStartRetries,
+		Limits:         serviceLimits,
+		Logs:           recentLogs,
 	})
 
 	rootCmd := cmd.NewRootCmd()
+	rootCmd.Version = Version
 	serviceCmd := cmd.NewServiceCmd(d, cfg)
-	daemonCmd := cmd.NewDaemonCmd(d, cfg)
+	daemonCmd := cmd.NewDaemonCmd(d, cfg, ServiceWrappers...)
 
-	runCmd := cmd.NewRunCmd(run)
+	runCmd := cmd.NewRunCmd(run, cmd.Logging())
 	runCmd.Flags().StringVarP(&ExitWith, "exit-with", "e", exitModeRand,
 		fmt.Sprintf("Exit the program with the specified status: %s, %s, %s, %s, %s",
 			exitModeNil, exitModeRand, exitModeErr, exitModePanic, exitModeFatal))
 	runCmd.Flags().DurationVarP(&Timeout, "timeout", "t", defaultRunTimeout, "Time to run before exiting")
+	cmd.AddRunModeFlags(runCmd, &Mode)
+	runCmd.Flags().DurationVar(&Ticks.Interval, "tick", defaultTickInterval, "Interval between progress logs")
+	runCmd.Flags().BoolVar(&Ticks.Adaptive, "adaptive-tick", false, "Log progress less often while the deadline is far away")
 
-	rootCmd.AddCommand(runCmd, serviceCmd, daemonCmd)
+	rootCmd.AddCommand(runCmd, serviceCmd, daemonCmd, cmd.NewPsCmd(), cmd.NewSelftestCmd(d, cfg), cmd.NewSloCmd(),
+		cmd.NewConfigCmd(), cmd.NewStatusCmd(d, cfg), cmd.NewLogLevelCmd(),
+		cmd.NewCrashCmd(), cmd.NewJobsCmd(), cmd.NewHistoryCmd(), cmd.NewEnvCmd(d),
+		cmd.NewUpgradeCmd(d, cfg), cmd.NewRollbackCmd(d, cfg), cmd.NewLogsCmd(cfg), cmd.NewStressCmd(), cmd.NewKVCmd(), cmd.NewSnapshotCmd(), cmd.NewProfileCmd(), cmd.NewDocsCmd(rootCmd))
+	cmd.AddCompletionInstall(rootCmd)
+	if err := cmd.AddAliases(rootCmd, Aliases); err != nil {
+		log.Fatal("Failed to add command aliases: ", err)
+	}
 
+	rootCmd.SetArgs(cmd.ExpandAlias(rootCmd, os.Args[1:]))
 	if err := rootCmd.Execute(); err != nil {
-		log.Fatal("Failed to execute command:", err)
+		log.Print("Failed to execute command: ", err)
+		os.Exit(cmd.ExitCode(err))
 	}
 }
 
@@ -80,17 +170,27 @@ func linuxServiceConfig() *kardianos.Config {
 
 		Option: kardianos.KeyValue{
 			"LogOutput":         false,
-			"PIDFile":           "/var/run/svcapp.pid",
+			"PIDFile":           paths.PIDFile(),
 			"Restart":           "on-success",
 			"SuccessExitStatus": "0 2 SIGKILL",
 			"LimitNOFILE":       -1,
+			"SystemdScript": systemd.Script(systemd.ServiceOptions{
+				WatchdogSec: defaultWatchdogSec,
+				Limits:      serviceLimits,
+			}),
 		},
 
-		Dependencies: []string{
-			"After=network-online.target",
-			"Wants=network-online.target",
-		},
+		Dependencies: renderDependencies(serviceDependencies),
+	}
+}
+
+// renderDependencies renders deps for the init system in use
+func renderDependencies(deps svcctl.Dependencies) []string {
+	lines, err := deps.Render(kardianos.Platform())
+	if err != nil {
+		log.Fatal("Invalid service dependencies: ", err)
 	}
+	return lines
 }
 
 func windowsServiceConfig() *kardianos.Config {
@@ -101,26 +201,51 @@ func windowsServiceConfig() *kardianos.Config {
 		WorkingDirectory: "~/.",
 		Arguments:        []string{"daemon"},
 
-		Option: kardianos.KeyValue{
-			"StartType":              "automatic",
-			"OnFailure":              "restart",
-			"OnFailureDelayDuration": "10s",
-		},
+		Option: windowsServiceOptions(svcctl.WindowsServiceOptions{
+			StartType:      svcctl.StartAutomatic,
+			OnFailure:      svcctl.FailureRestart,
+			OnFailureDelay: defaultFailureDelay,
+		}),
 	}
 }
 
-func run(ctx context.Context, args []string) error {
-	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
-	slog.SetDefault(logger)
+// windowsServiceOptions returns the kardianos options of o, failing on unknown values
+func windowsServiceOptions(o svcctl.WindowsServiceOptions) kardianos.KeyValue {
+	opts, err := o.Options()
+	if err != nil {
+		log.Fatal("Invalid Windows service options: ", err)
+	}
+	return opts
+}
 
+func run(ctx context.Context, args []string) error {
 	// Determine exit mode
 	exitMode := determineExitMode(ExitWith)
 	if exitMode != exitModeNil {
 		slog.Info("Process will exit with", "mode", exitMode)
 	}
 
+	// Report readiness to the supervisor, if any
+	if err := daemon.NotifyReady(); err != nil {
+		slog.Warn("Failed to notify readiness", "error", err)
+	}
+
+	// Remember this start in the supervisor key/value store
+	recordLastRun(ctx)
+
+	// Answer the supervisor heartbeats, if enabled
+	if err := daemon.AnswerHeartbeats(); err != nil {
+		slog.Warn("Failed to answer heartbeats", "error", err)
+	}
+
+	// Stop taking new work once the supervisor announces a stop, if it does
+	lameDuck, err := daemon.NotifyLameDuck()
+	if err != nil {
+		slog.Warn("Failed to watch for lame duck notices", "error", err)
+	}
+
 	// Run the main loop
-	return runMainLoop(ctx, exitMode)
+	return runMainLoop(ctx, exitMode, lameDuck)
 }
 
 func determineExitMode(mode string) string {
@@ -131,23 +256,56 @@ func determineExitMode(mode string) string {
 	return mode
 }
 
-func runMainLoop(ctx context.Context, exitMode string) error {
-	ticker := time.NewTicker(1 * time.Second)
-	defer ticker.Stop()
+// recordLastRun stores the start time under lastRun in the key/value store of the
+// supervising daemon, when there is one
+func recordLastRun(ctx context.Context) {
+	if os.Getenv(kv.EnvFile) == "" {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	if err := control.NewClient(control.DefaultAddr()).KVSet(ctx, "lastRun", time.Now().Format(time.RFC3339)); err != nil {
+		slog.Warn("Failed to record the last run", "error", err)
+	}
+}
+
+func runMainLoop(ctx context.Context, exitMode string, lameDuck <-chan time.Time) error {
+	if Mode == cmd.RunOnce {
+		LoopHooks.OnTick(ctx, cmd.NoDeadline)
+		return exitWithMode(exitMode)
+	}
 
+	// Without a timeout, the loop only ends when canceled
 	deadline := time.Now().Add(Timeout)
-	timeoutChan := time.After(Timeout)
+	var timeoutChan <-chan time.Time
+	if Mode == cmd.RunTimeout {
+		timeoutChan = time.After(Timeout)
+	}
+	timeLeft := func() time.Duration {
+		if timeoutChan == nil {
+			return cmd.NoDeadline
+		}
+		return time.Until(deadline)
+	}
+
+	ticker := time.NewTimer(Ticks.Next(timeLeft()))
+	defer ticker.Stop()
 
 	for {
 		select {
 		case <-ticker.C:
-			remaining := time.Until(deadline).Truncate(time.Millisecond)
-			slog.Info("Running...", "timeLeft", remaining)
+			remaining := timeLeft()
+			LoopHooks.OnTick(ctx, remaining)
+			ticker.Reset(Ticks.Next(remaining))
+		case stopAt := <-lameDuck:
+			slog.Info("Entering lame duck mode, no new work", "stopIn", time.Until(stopAt).Round(time.Millisecond))
+			ticker.Stop()
+			lameDuck = nil
 		case <-timeoutChan:
-			slog.Info("Timed out.")
+			LoopHooks.OnTimeout(ctx)
 			return exitWithMode(exitMode)
 		case <-ctx.Done():
-			slog.Info("Context canceled.")
+			LoopHooks.OnCancel(ctx)
 			return exitWithMode(exitMode)
 		}
 	}
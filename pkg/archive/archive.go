@@ -0,0 +1,204 @@
+// Package archive compresses crash reports and rotated logs with gzip or zstd
+package archive
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/atomicfile"
+)
+
+// Supported compression algorithms
+const (
+	None = ""
+	Gzip = "gzip"
+	Zstd = "zstd"
+)
+
+// Options selects the compression algorithm and its level. Level zero selects the
+// algorithm default, gzip levels range from 1 to 9 and zstd levels from 1 to 22.
+type Options struct {
+	Algorithm string
+	Level     int
+}
+
+// Validate checks the algorithm and level
+func (o Options) Validate() error {
+	switch o.Algorithm {
+	case None:
+		return nil
+	case Gzip:
+		if o.Level < 0 || o.Level > gzip.BestCompression {
+			return fmt.Errorf("invalid gzip level %d: expected 1 to 9", o.Level)
+		}
+	case Zstd:
+		if o.Level < 0 || o.Level > 22 {
+			return fmt.Errorf("invalid zstd level %d: expected 1 to 22", o.Level)
+		}
+	default:
+		return fmt.Errorf("unknown compression %q: expected gzip or zstd", o.Algorithm)
+	}
+	return nil
+}
+
+// Ext returns the file extension of the algorithm, "" without compression
+func (o Options) Ext() string {
+	return ext(o.Algorithm)
+}
+
+// ext returns the file extension of algorithm
+func ext(algorithm string) string {
+	switch algorithm {
+	case Gzip:
+		return ".gz"
+	case Zstd:
+		return ".zst"
+	}
+	return ""
+}
+
+// Exts lists the extensions of the compressed files, for lookups by name
+var Exts = []string{ext(Gzip), ext(Zstd)}
+
+// NewWriter returns a writer compressing to w. Closing it doesn't close w.
+func NewWriter(w io.Writer, o Options) (io.WriteCloser, error) {
+	switch o.Algorithm {
+	case Gzip:
+		level := o.Level
+		if level == 0 {
+			level = gzip.DefaultCompression
+		}
+		return gzip.NewWriterLevel(w, level)
+	case Zstd:
+		level := zstd.SpeedDefault
+		if o.Level > 0 {
+			level = zstd.EncoderLevelFromZstd(o.Level)
+		}
+		return zstd.NewWriter(w, zstd.WithEncoderLevel(level))
+	case None:
+		return nopCloser{w}, nil
+	}
+	return nil, o.Validate()
+}
+
+// NewReader returns a reader decompressing r according to the extension of name
+func NewReader(r io.Reader, name string) (io.ReadCloser, error) {
+	switch {
+	case strings.HasSuffix(name, ext(Gzip)):
+		return gzip.NewReader(r)
+	case strings.HasSuffix(name, ext(Zstd)):
+		d, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return d.IOReadCloser(), nil
+	}
+	return io.NopCloser(r), nil
+}
+
+// Open opens the file at path, decompressing it according to its extension
+func Open(path string) (io.ReadCloser, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	r, err := NewReader(f, path)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to decompress %s: %w", path, err)
+	}
+	return readCloser{r, f}, nil
+}
+
+// WriteFile compresses data to path plus the extension of the algorithm, atomically,
+// and returns the path written
+func WriteFile(path string, data []byte, perm os.FileMode, o Options) (string, error) {
+	var b bytes.Buffer
+	w, err := NewWriter(&b, o)
+	if err != nil {
+		return "", err
+	}
+	if _, err := w.Write(data); err != nil {
+		return "", err
+	}
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+
+	path += o.Ext()
+	if err := atomicfile.WriteFile(path, b.Bytes(), perm, false); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// CompressFile replaces the file at path with its compressed copy and returns the new path
+func CompressFile(path string, o Options) (string, error) {
+	if o.Algorithm == None {
+		return path, nil
+	}
+	src, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+
+	dst := path + o.Ext()
+	if err := compressTo(dst, src, o); err != nil {
+		return "", fmt.Errorf("failed to compress %s: %w", path, err)
+	}
+	return dst, os.Remove(path)
+}
+
+// compressTo streams src compressed into a temporary file renamed to dst once complete
+func compressTo(dst string, src *os.File, o Options) error {
+	fi, err := src.Stat()
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(dst), filepath.Base(dst)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	w, err := NewWriter(tmp, o)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(w, src); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	if err := tmp.Chmod(fi.Mode().Perm()); err != nil {
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), dst)
+}
+
+type nopCloser struct{ io.Writer }
+
+func (nopCloser) Close() error { return nil }
+
+// readCloser closes both the decompressor and the file under it
+type readCloser struct {
+	io.ReadCloser
+	f *os.File
+}
+
+func (r readCloser) Close() error {
+	r.ReadCloser.Close()
+	return r.f.Close()
+}
@@ -0,0 +1,84 @@
+package atomicfile
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// WriteFile writes data to a temporary file next to path and renames it over path,
+// so readers see either the previous or the new content, never a torn file. With
+// sync, the data and the rename are flushed to storage before returning, so the
+// file survives a power loss, at the cost of extra flash wear.
+func WriteFile(path string, data []byte, perm os.FileMode, sync bool) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	f, err := os.CreateTemp(dir, filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+
+	_, err = f.Write(data)
+	if err == nil && sync {
+		err = f.Sync()
+	}
+	err = errors.Join(err, f.Close())
+	if err == nil {
+		err = os.Chmod(tmp, perm)
+	}
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	if sync {
+		return syncDir(dir)
+	}
+	return nil
+}
+
+// Append appends data to path while holding an exclusive lock on it, so concurrent
+// writers never interleave. With sync, the file is opened with O_SYNC.
+func Append(path string, data []byte, perm os.FileMode, sync bool) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	flag := os.O_WRONLY | os.O_APPEND | os.O_CREATE
+	if sync {
+		flag |= os.O_SYNC
+	}
+	f, err := os.OpenFile(path, flag, perm)
+	if err != nil {
+		return err
+	}
+
+	if err := Lock(f); err != nil {
+		f.Close()
+		return err
+	}
+	_, err = f.Write(data)
+	return errors.Join(err, Unlock(f), f.Close())
+}
+
+// syncDir flushes a directory entry change, such as a rename, to storage. Windows
+// has no directory handles to flush, renames are journaled by NTFS.
+func syncDir(dir string) error {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	return errors.Join(d.Sync(), d.Close())
+}
@@ -0,0 +1,15 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd || windows)
+
+package atomicfile
+
+import "os"
+
+// Lock does nothing where advisory locks are not supported
+func Lock(f *os.File) error {
+	return nil
+}
+
+// Unlock does nothing where advisory locks are not supported
+func Unlock(f *os.File) error {
+	return nil
+}
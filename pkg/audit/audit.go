@@ -0,0 +1,137 @@
+// Package audit records who invoked each control action, and with what result, to an
+// append-only JSON lines file and optionally the system log, for change tracking
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/lucasdecamargo/go-appservice-example/pkg/atomicfile"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/state"
+)
+
+// Where an action was invoked from
+const (
+	SourceCLI     = "cli"     // A service command run on the host
+	SourceControl = "control" // The control socket
+	SourceDBus    = "dbus"    // The D-Bus API
+	SourceFleet   = "fleet"   // A signed fleet command
+)
+
+// Action results
+const (
+	ResultOK    = "ok"
+	ResultError = "error"
+)
+
+// Config selects where the audit records go
+type Config struct {
+	File   string `json:"file,omitempty"`   // Append-only JSON lines file, audit.jsonl in the state directory by default
+	Syslog bool   `json:"syslog,omitempty"` // Also send each record to syslog, or the Application event log on Windows
+}
+
+// Actor identifies who invoked an action. Fields that can't be known are empty.
+type Actor struct {
+	UID      string `json:"uid,omitempty"`      // User ID, or SID on Windows
+	User     string `json:"user,omitempty"`     // User name
+	SudoUser string `json:"sudoUser,omitempty"` // User who ran sudo
+	PID      int    `json:"pid,omitempty"`      // Invoking process
+	Terminal string `json:"terminal,omitempty"` // Controlling terminal of the invoking process
+	Identity string `json:"identity,omitempty"` // D-Bus sender or fleet command and server
+}
+
+// Record is one audited action
+type Record struct {
+	Time   time.Time `json:"time"`
+	Source string    `json:"source"`           // cli, control, dbus or fleet
+	Action string    `json:"action"`           // Such as install, stop or schedule
+	Target string    `json:"target,omitempty"` // Service, job or key acted on
+	Actor  Actor     `json:"actor"`
+	Result string    `json:"result"` // ok or error
+	Error  string    `json:"error,omitempty"`
+}
+
+// DefaultPath returns the audit file path, next to the state file
+func DefaultPath() string {
+	return filepath.Join(filepath.Dir(state.DefaultPath()), "audit.jsonl")
+}
+
+// Logger appends records to the audit file and the system log
+type Logger struct {
+	path   string
+	syslog sink
+}
+
+// Open returns a logger for cfg. name tags the system log entries.
+func Open(cfg Config, name string) (*Logger, error) {
+	l := &Logger{path: cfg.File}
+	if l.path == "" {
+		l.path = DefaultPath()
+	}
+	if cfg.Syslog {
+		s, err := openSink(name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open the system log: %w", err)
+		}
+		l.syslog = s
+	}
+	return l, nil
+}
+
+// Path returns the audit file path
+func (l *Logger) Path() string {
+	return l.path
+}
+
+// Record appends r, timestamped now, with the result of err. A nil logger records
+// nothing.
+func (l *Logger) Record(r Record, err error) error {
+	if l == nil {
+		return nil
+	}
+	r.Time = time.Now()
+	r.Result = ResultOK
+	if err != nil {
+		r.Result, r.Error = ResultError, err.Error()
+	}
+
+	data, merr := json.Marshal(r)
+	if merr != nil {
+		return merr
+	}
+	if l.syslog != nil {
+		l.syslog.write(r.Result == ResultOK, string(data))
+	}
+	if err := atomicfile.Append(l.path, append(data, '\n'), 0o640, true); err != nil {
+		return fmt.Errorf("failed to write the audit log: %w", err)
+	}
+	return nil
+}
+
+// Close closes the system log
+func (l *Logger) Close() error {
+	if l == nil || l.syslog == nil {
+		return nil
+	}
+	return l.syslog.close()
+}
+
+// Self returns the actor of the current process, with the user who ran sudo
+func Self() Actor {
+	a := Actor{UID: strconv.Itoa(os.Getuid()), PID: os.Getpid(), SudoUser: os.Getenv("SUDO_USER"), Terminal: terminal(os.Getpid())}
+	if u, err := user.Current(); err == nil {
+		a.UID, a.User = u.Uid, u.Username
+	}
+	return a
+}
+
+// sink is a system log
+type sink interface {
+	write(ok bool, msg string)
+	close() error
+}
@@ -0,0 +1,46 @@
+package audit
+
+import (
+	"net"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// Peer returns the actor at the other end of the Unix socket connection c, from its
+// credentials. It is empty for other connections.
+func Peer(c net.Conn) Actor {
+	uc, ok := c.(*net.UnixConn)
+	if !ok {
+		return Actor{}
+	}
+	raw, err := uc.SyscallConn()
+	if err != nil {
+		return Actor{}
+	}
+	var cred *unix.Ucred
+	raw.Control(func(fd uintptr) {
+		cred, err = unix.GetsockoptUcred(int(fd), unix.SOL_SOCKET, unix.SO_PEERCRED)
+	})
+	if err != nil || cred == nil {
+		return Actor{}
+	}
+
+	a := Actor{UID: strconv.Itoa(int(cred.Uid)), PID: int(cred.Pid), Terminal: terminal(int(cred.Pid))}
+	if u, err := user.LookupId(a.UID); err == nil {
+		a.User = u.Username
+	}
+	return a
+}
+
+// terminal returns the terminal on the standard input of the process pid, if any
+func terminal(pid int) string {
+	tty, err := os.Readlink("/proc/" + strconv.Itoa(pid) + "/fd/0")
+	if err != nil || (!strings.HasPrefix(tty, "/dev/pts/") && !strings.HasPrefix(tty, "/dev/tty")) {
+		return ""
+	}
+	return tty
+}
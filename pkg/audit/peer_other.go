@@ -0,0 +1,15 @@
+//go:build !linux
+
+package audit
+
+import "net"
+
+// Peer returns an empty actor, connection credentials are only read on Linux
+func Peer(c net.Conn) Actor {
+	return Actor{}
+}
+
+// terminal returns "", the terminal of a process is only looked up on Linux
+func terminal(pid int) string {
+	return ""
+}
@@ -0,0 +1,31 @@
+//go:build !windows
+
+package audit
+
+import "log/syslog"
+
+// syslogSink writes records to syslog as authpriv messages
+type syslogSink struct {
+	w *syslog.Writer
+}
+
+// openSink connects to the local syslog daemon
+func openSink(name string) (sink, error) {
+	w, err := syslog.New(syslog.LOG_AUTHPRIV|syslog.LOG_NOTICE, name)
+	if err != nil {
+		return nil, err
+	}
+	return &syslogSink{w: w}, nil
+}
+
+func (s *syslogSink) write(ok bool, msg string) {
+	if ok {
+		s.w.Notice(msg)
+	} else {
+		s.w.Warning(msg)
+	}
+}
+
+func (s *syslogSink) close() error {
+	return s.w.Close()
+}
@@ -0,0 +1,32 @@
+package audit
+
+import "golang.org/x/sys/windows/svc/eventlog"
+
+// auditEventID is the event ID of audit records in the Application event log
+const auditEventID = 100
+
+// eventLogSink writes records to the Application event log
+type eventLogSink struct {
+	l *eventlog.Log
+}
+
+// openSink opens the event source registered for the service
+func openSink(name string) (sink, error) {
+	l, err := eventlog.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &eventLogSink{l: l}, nil
+}
+
+func (s *eventLogSink) write(ok bool, msg string) {
+	if ok {
+		s.l.Info(auditEventID, msg)
+	} else {
+		s.l.Warning(auditEventID, msg)
+	}
+}
+
+func (s *eventLogSink) close() error {
+	return s.l.Close()
+}
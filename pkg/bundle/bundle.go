@@ -0,0 +1,113 @@
+// Package bundle packs the effective service definition into a single portable
+// document, to move a service to another machine or restore it after a disaster
+package bundle
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/lucasdecamargo/go-appservice-example/pkg/config"
+)
+
+// Version is the bundle format written by Write
+const Version = 1
+
+// Kinds of the sources the managed environment reads outside the config file
+const (
+	RefFile    = "file"    // A file on the host
+	RefSecret  = "secret"  // A file in the secrets directory
+	RefHostEnv = "hostEnv" // A variable of the daemon environment
+)
+
+// Bundle is the effective definition of a service
+type Bundle struct {
+	Version    int             `json:"version"`
+	Name       string          `json:"name"`           // Service name
+	Platform   string          `json:"platform"`       // Init system it was exported from, such as linux-systemd
+	ExportedAt time.Time       `json:"exportedAt"`     // When it was exported
+	Config     json.RawMessage `json:"config"`         // The config file, with the effective service settings
+	Refs       []Ref           `json:"refs,omitempty"` // Sources the environment reads, which the bundle doesn't carry
+	Unit       string          `json:"unit,omitempty"` // Rendered unit file, for reference
+}
+
+// Ref is a source outside the config file that a managed variable is read from. Only
+// its location is exported, never its value.
+type Ref struct {
+	Env    string `json:"env"`    // Variable it sets
+	Kind   string `json:"kind"`   // RefFile, RefSecret or RefHostEnv
+	Source string `json:"source"` // Path, secret name or variable name
+}
+
+// Refs lists the sources outside the config file that the managed environment of c
+// reads
+func Refs(c *config.Config) []Ref {
+	var refs []Ref
+	for _, v := range c.Env {
+		switch {
+		case v.File != "":
+			refs = append(refs, Ref{Env: v.Name, Kind: RefFile, Source: v.File})
+		case v.SecretRef != "":
+			refs = append(refs, Ref{Env: v.Name, Kind: RefSecret, Source: v.SecretRef})
+		case v.HostEnv != "":
+			refs = append(refs, Ref{Env: v.Name, Kind: RefHostEnv, Source: v.HostEnv})
+		}
+	}
+	return refs
+}
+
+// Check reports whether the source of r is available on this machine
+func (r Ref) Check() error {
+	switch r.Kind {
+	case RefFile:
+		_, err := os.Stat(r.Source)
+		return err
+	case RefSecret:
+		_, err := os.Stat(filepath.Join(config.SecretsDir(), r.Source))
+		return err
+	case RefHostEnv:
+		if _, ok := os.LookupEnv(r.Source); !ok {
+			return fmt.Errorf("%s is not set", r.Source)
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown reference kind %q", r.Kind)
+}
+
+// Write encodes b as indented JSON
+func (b *Bundle) Write(w io.Writer) error {
+	b.Version = Version
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "    ")
+	return enc.Encode(b)
+}
+
+// Read decodes a bundle and validates its config, which is indented as a file
+func Read(r io.Reader) (*Bundle, error) {
+	var b Bundle
+	if err := json.NewDecoder(r).Decode(&b); err != nil {
+		return nil, fmt.Errorf("invalid bundle: %w", err)
+	}
+	switch {
+	case b.Version == 0 || b.Name == "":
+		return nil, errors.New("invalid bundle: missing version or name")
+	case b.Version > Version:
+		return nil, fmt.Errorf("bundle version %d is newer than the supported version %d", b.Version, Version)
+	}
+	if _, err := config.Parse(b.Config); err != nil {
+		return nil, fmt.Errorf("invalid bundle config: %w", err)
+	}
+
+	// Indent the config as a file of its own
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, b.Config, "", "    "); err != nil {
+		return nil, fmt.Errorf("invalid bundle config: %w", err)
+	}
+	b.Config = buf.Bytes()
+	return &b, nil
+}
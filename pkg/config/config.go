@@ -0,0 +1,364 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/lucasdecamargo/go-appservice-example/pkg/archive"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/atomicfile"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/audit"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/jobs"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/lbhook"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/lsm"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/mountns"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/paths"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/ports"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/priority"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/profile"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/retention"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/snapshot"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/store"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/svcctl"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/tuning"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/update"
+)
+
+const (
+	// EnvConfig overrides the configuration file path
+	EnvConfig = "SVCAPP_CONFIG"
+	// EnvProfile selects the active profile when no --profile flag is given
+	EnvProfile = "SVCAPP_PROFILE"
+
+	configFileName = "config.json"
+)
+
+// Config is the svcapp configuration file
+type Config struct {
+	SchemaURL string `json:"$schema,omitempty"` // JSON Schema of the file, for editors, see "svcapp config schema"
+
+	Profile  string             `json:"profile,omitempty"`  // Profile used when none is selected
+	Profiles map[string]Profile `json:"profiles,omitempty"` // Named child configurations
+	Output   Output             `json:"output,omitzero"`    // Child output destinations
+	Runtime  tuning.Config      `json:"runtime,omitzero"`   // Go runtime tuning for the child
+	Storage  store.Config       `json:"storage,omitzero"`   // Daemon state and history storage
+	Control  Control            `json:"control,omitzero"`   // Control API access
+	Audit    audit.Config       `json:"audit,omitzero"`     // Record of the control actions and who invoked them
+	Fleet    Fleet              `json:"fleet,omitzero"`     // Fleet management server
+	Webhooks []Webhook          `json:"webhooks,omitempty"` // Lifecycle event receivers
+	Lean     bool               `json:"lean,omitempty"`     // Disable metrics and history, and shrink buffers
+
+	WaitForNetwork Network        `json:"waitForNetwork,omitzero"` // Network conditions checked before each child start
+	Compression    Compression    `json:"compression,omitzero"`    // Compression of crash reports and rotated logs
+	Jobs           []Job          `json:"jobs,omitempty"`          // Commands run on a schedule next to the child
+	Heartbeat      Heartbeat      `json:"heartbeat,omitzero"`      // Liveness pings through the child stdin
+	LameDuck       LameDuck       `json:"lameDuck,omitzero"`       // Notice sent to the child before it is stopped
+	LoadBalancer   LoadBalancer   `json:"loadBalancer,omitzero"`   // Hooks shifting traffic away from the child around restarts
+	Snapshot       Snapshot       `json:"snapshot,omitzero"`       // Snapshot of the service data taken before reloads and updates
+	ShutdownBoost  priority.Boost `json:"shutdownBoost,omitzero"`  // CPU and I/O priority of the child while it stops
+	DelayShutdown  bool           `json:"delayShutdown,omitempty"` // Hold system shutdowns until the child has stopped
+	Retention      Retention      `json:"retention,omitzero"`      // History events and crash reports kept
+	Env            []EnvVar       `json:"env,omitempty"`           // Managed child environment variables
+	Updates        Updates        `json:"updates,omitzero"`        // Release feed checks and automatic updates
+	Confinement    lsm.Label      `json:"confinement,omitzero"`    // SELinux context or AppArmor profile of the child
+	Mounts         mountns.Spec   `json:"mounts,omitzero"`         // Private /tmp, read-only paths and bind mounts of the child
+	Ports          Ports          `json:"ports,omitzero"`          // Detection of the TCP ports the child listens on
+	Pprof          Pprof          `json:"pprof,omitzero"`          // Profiling endpoint of the child
+
+	// Windows holds service control manager settings applied by "service install"
+	Windows svcctl.WindowsOptions `json:"windows,omitzero"`
+
+	// Service overrides the compiled service definition by setting, written by "service edit"
+	Service map[string]any `json:"service,omitempty"`
+}
+
+// Job is a short-lived command, such as a backup, the daemon runs on a schedule
+type Job struct {
+	Name     string            `json:"name"`               // Unique name, used by "jobs run"
+	Command  []string          `json:"command"`            // Executable and arguments
+	Schedule string            `json:"schedule"`           // Cron expression, descriptor such as @daily, or "@every 1h"
+	TimeZone string            `json:"timeZone,omitempty"` // IANA time zone of a cron schedule, such as Europe/Berlin, the local one by default
+	DST      string            `json:"dst,omitempty"`      // Daylight saving handling: skip, run-once (default) or run-twice
+	Timeout  Duration          `json:"timeout,omitempty"`  // Kills the job after this long, no limit by default
+	Env      map[string]string `json:"env,omitempty"`      // Environment variables added for the job
+	Log      string            `json:"log,omitempty"`      // Log file, jobs/<name>.log in the log directory by default
+}
+
+// EnvVars returns the job environment as sorted KEY=VALUE pairs
+func (j *Job) EnvVars() []string {
+	return envVars(j.Env)
+}
+
+// Retention bounds the history events and crash reports the daemon keeps
+type Retention struct {
+	History Keep `json:"history,omitzero"`
+	Crashes Keep `json:"crashes,omitzero"`
+}
+
+// Keep is a retention policy. Zero values don't limit.
+type Keep struct {
+	MaxAge    Duration `json:"maxAge,omitempty"`    // Delete records older than this
+	MaxCount  int      `json:"maxCount,omitempty"`  // Keep the newest records up to this count
+	MaxSizeMB int      `json:"maxSizeMB,omitempty"` // Keep the newest records up to this total size
+}
+
+// Policy returns the retention policy
+func (k Keep) Policy() retention.Policy {
+	return retention.Policy{MaxAge: time.Duration(k.MaxAge), MaxCount: k.MaxCount, MaxSize: int64(k.MaxSizeMB) << 20}
+}
+
+// Heartbeat checks that the child answers pings written to its stdin
+type Heartbeat struct {
+	Interval  Duration `json:"interval,omitempty"`  // Time between pings, zero to disable
+	Timeout   Duration `json:"timeout,omitempty"`   // Time the child has to answer, the interval by default
+	OnFailure string   `json:"onFailure,omitempty"` // restart or stop, restart by default
+}
+
+// LameDuck warns the child some time before it is stopped or restarted
+type LameDuck struct {
+	Period Duration `json:"period,omitempty"` // Time between the notice and the stop request, zero to disable
+	Signal string   `json:"signal,omitempty"` // Signal also sent with the notice, such as SIGURG, none by default
+}
+
+// LoadBalancer runs hooks taking the child out of an external load balancer before it is
+// stopped or restarted on purpose, and putting it back once the new child is ready
+type LoadBalancer struct {
+	Deregister []Hook   `json:"deregister,omitempty"` // Run before the child is stopped or restarted
+	Register   []Hook   `json:"register,omitempty"`   // Run each time a child becomes ready
+	Delay      Duration `json:"delay,omitempty"`      // Wait after the deregister hooks, for the balancer to stop sending requests
+}
+
+// Hook is an HTTP request or a command run by the load balancer hooks
+type Hook struct {
+	URL     string   `json:"url,omitempty"`     // http:// or https:// URL receiving the phase, service, hostname and child PID as JSON
+	Method  string   `json:"method,omitempty"`  // HTTP method, POST by default
+	Command []string `json:"command,omitempty"` // Executable and arguments run instead of calling url, with SVCAPP_LB_PHASE and SVCAPP_LB_PID set
+	Timeout Duration `json:"timeout,omitempty"` // Time the hook has to complete, 10s by default
+}
+
+// Hook returns the hook to run
+func (h Hook) Hook() lbhook.Hook {
+	return lbhook.Hook{URL: h.URL, Method: h.Method, Command: h.Command, Timeout: time.Duration(h.Timeout)}
+}
+
+// Snapshot runs commands taking a crash-consistent snapshot of the service data, such as
+// a database dump or an LVM or ZFS snapshot, before reloads and updates, and restoring it
+// when an update is rolled back
+type Snapshot struct {
+	Command  []string `json:"command,omitempty"`  // Executable and arguments printing the snapshot name as their last output line, with SVCAPP_SNAPSHOT_REASON set
+	Restore  []string `json:"restore,omitempty"`  // Executable and arguments restoring the snapshot named by SVCAPP_SNAPSHOT, restores are manual when empty
+	Timeout  Duration `json:"timeout,omitempty"`  // Time each command has to complete, 5m by default
+	Required bool     `json:"required,omitempty"` // Cancel the reload or update when the snapshot fails, instead of going on without one
+}
+
+// Hook returns the snapshot commands to run
+func (s Snapshot) Hook() snapshot.Hook {
+	return snapshot.Hook{Command: s.Command, RestoreCommand: s.Restore, Timeout: time.Duration(s.Timeout)}
+}
+
+// Ports finds the TCP ports the child listens on, for children picking ephemeral ports
+type Ports struct {
+	Pattern string   `json:"pattern,omitempty"` // Regular expression matched on each child output line, its first group capturing the port
+	Poll    Duration `json:"poll,omitempty"`    // Interval of the scans of the child listening sockets, on Linux and Windows, zero to disable
+}
+
+// Pprof locates the net/http/pprof handlers of the child for "svcapp profile"
+type Pprof struct {
+	URL string `json:"url,omitempty"` // Base URL or host:port of the handlers, detected on the child ports when empty
+}
+
+// Compression selects how crash reports and rotated log files are compressed
+type Compression struct {
+	Algorithm string `json:"algorithm,omitempty"` // gzip or zstd, empty to disable
+	Level     int    `json:"level,omitempty"`     // 1-9 for gzip, 1-22 for zstd, the algorithm default when zero
+}
+
+// Options returns the compression as archive options
+func (c Compression) Options() archive.Options {
+	return archive.Options{Algorithm: c.Algorithm, Level: c.Level}
+}
+
+// Network lists the conditions the network must meet before the child starts
+type Network struct {
+	Route   bool     `json:"route,omitempty"`   // A route to the outside must exist
+	Resolve []string `json:"resolve,omitempty"` // Host names that must resolve
+	Reach   []string `json:"reach,omitempty"`   // host:port addresses that must accept TCP connections
+	Timeout Duration `json:"timeout,omitempty"` // Longest wait before starting the child anyway, unlimited by default
+}
+
+// Control configures access to the control API
+type Control struct {
+	// Readers are accounts granted read-only status access through a separate named
+	// pipe on Windows. The control pipe itself is limited to SYSTEM and Administrators.
+	Readers []string `json:"readers,omitempty"`
+
+	// SelfCheck is the interval between checks that the control sockets accept
+	// connections, reopening them with backoff when they don't. Zero disables it.
+	SelfCheck Duration `json:"selfCheck,omitempty"`
+}
+
+// Fleet registers the daemon with a management server that receives its status and
+// may send it signed commands
+type Fleet struct {
+	URL       string   `json:"url,omitempty"`       // Management endpoint, https only, empty to disable
+	ID        string   `json:"id,omitempty"`        // Agent ID, the hostname by default
+	Token     string   `json:"token,omitempty"`     // Bearer token sent with every request
+	PublicKey string   `json:"publicKey,omitempty"` // Base64 ed25519 key verifying commands, empty to ignore them
+	Interval  Duration `json:"interval,omitempty"`  // Heartbeat interval, 30s by default
+}
+
+// Updates checks a release feed for new versions of the executable and, when enabled,
+// installs them during the maintenance window
+type Updates struct {
+	Feed          string   `json:"feed,omitempty"`          // Release feed URL, https only, empty to disable
+	Interval      Duration `json:"interval,omitempty"`      // Time between checks, 6h by default
+	Auto          bool     `json:"auto,omitempty"`          // Install new versions, requires PublicKey
+	Window        string   `json:"window,omitempty"`        // Local HH:MM-HH:MM maintenance window, any time when empty
+	HealthTimeout Duration `json:"healthTimeout,omitempty"` // Time the updated child has to stay ready, 1m by default
+	PublicKey     string   `json:"publicKey,omitempty"`     // Base64 ed25519 key verifying the releases
+}
+
+// Webhook receives the child lifecycle events as signed JSON POST requests
+type Webhook struct {
+	URL    string   `json:"url"`              // Receiver, https only
+	Secret string   `json:"secret,omitempty"` // HMAC-SHA256 key signing the requests
+	Events []string `json:"events,omitempty"` // started, ready, crashed, restarted, stopped or update; all when empty
+}
+
+// DefaultPath returns the configuration file path, honoring EnvConfig
+func DefaultPath() string {
+	if path := os.Getenv(EnvConfig); path != "" {
+		return path
+	}
+	return filepath.Join(paths.ConfigDir(), configFileName)
+}
+
+// Load reads the configuration file at path. A missing file yields an empty configuration.
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &Config{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	c, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config %s: %w", path, err)
+	}
+	return c, nil
+}
+
+// Parse decodes and validates a configuration document, first against the schema
+func Parse(data []byte) (*Config, error) {
+	if err := validateSchema(data); err != nil {
+		return nil, err
+	}
+
+	var c Config
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&c); err != nil {
+		return nil, err
+	}
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// Validate checks the consistency of the configuration
+func (c *Config) Validate() error {
+	if c.Profile != "" {
+		if _, ok := c.Profiles[c.Profile]; !ok {
+			return fmt.Errorf("default profile %q is not defined", c.Profile)
+		}
+	}
+	for i, j := range c.Jobs {
+		if j.Name == "" || len(j.Command) == 0 {
+			return fmt.Errorf("job %d: name and command are required", i+1)
+		}
+		if slices.ContainsFunc(c.Jobs[:i], func(o Job) bool { return o.Name == j.Name }) {
+			return fmt.Errorf("job %q is defined twice", j.Name)
+		}
+		if _, err := jobs.NewSchedule(j.Schedule, j.TimeZone, jobs.DSTPolicy(j.DST)); err != nil {
+			return fmt.Errorf("job %q: %w", j.Name, err)
+		}
+	}
+	if err := c.Output.Stdout.validate(); err != nil {
+		return fmt.Errorf("output.stdout: %w", err)
+	}
+	if err := c.Output.Stderr.validate(); err != nil {
+		return fmt.Errorf("output.stderr: %w", err)
+	}
+	if f := c.Heartbeat.OnFailure; f != "" && f != "restart" && f != "stop" {
+		return fmt.Errorf("heartbeat: unknown failure policy %q", f)
+	}
+	if _, err := update.ParseWindow(c.Updates.Window); err != nil {
+		return fmt.Errorf("updates: %w", err)
+	}
+	if c.Updates.Auto && c.Updates.PublicKey == "" {
+		return errors.New("updates: auto requires publicKey")
+	}
+	for _, v := range c.Env {
+		if err := v.validate(); err != nil {
+			return err
+		}
+	}
+	for i, h := range c.LoadBalancer.Deregister {
+		if err := h.Hook().Validate(); err != nil {
+			return fmt.Errorf("loadBalancer: deregister hook %d: %w", i+1, err)
+		}
+	}
+	for i, h := range c.LoadBalancer.Register {
+		if err := h.Hook().Validate(); err != nil {
+			return fmt.Errorf("loadBalancer: register hook %d: %w", i+1, err)
+		}
+	}
+	if len(c.Snapshot.Command) > 0 || len(c.Snapshot.Restore) > 0 {
+		if err := c.Snapshot.Hook().Validate(); err != nil {
+			return fmt.Errorf("snapshot: %w", err)
+		}
+	}
+	if err := c.Confinement.Validate(); err != nil {
+		return fmt.Errorf("confinement: %w", err)
+	}
+	if err := c.Mounts.Validate(); err != nil {
+		return fmt.Errorf("mounts: %w", err)
+	}
+	if c.Pprof.URL != "" {
+		if _, err := profile.ParseURL(c.Pprof.URL); err != nil {
+			return fmt.Errorf("pprof: %w", err)
+		}
+	}
+	if c.Ports.Pattern != "" {
+		if _, err := ports.Compile(c.Ports.Pattern); err != nil {
+			return fmt.Errorf("ports: %w", err)
+		}
+		if c.Output.Stdout.Splice || c.Output.Stderr.Splice {
+			return errors.New("ports: pattern can't match the output of a spliced stream")
+		}
+	}
+	if err := c.ShutdownBoost.Validate(); err != nil {
+		return fmt.Errorf("shutdownBoost: %w", err)
+	}
+	if err := c.Windows.Validate(); err != nil {
+		return fmt.Errorf("windows: %w", err)
+	}
+	return c.Compression.Options().Validate()
+}
+
+// Save writes a configuration document to path, replacing the previous file atomically
+// and durably
+func Save(path string, data []byte) error {
+	if err := atomicfile.WriteFile(path, data, 0o644, true); err != nil {
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+	return nil
+}
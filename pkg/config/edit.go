@@ -0,0 +1,143 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Get returns the value at a dotted key, such as "profiles.prod.args", in the
+// configuration file at path
+func Get(path, key string) (any, error) {
+	doc, err := readDocument(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var v any = doc
+	for _, part := range strings.Split(key, ".") {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("key %q not found", key)
+		}
+		if v, ok = m[part]; !ok {
+			return nil, fmt.Errorf("key %q not found", key)
+		}
+	}
+	return v, nil
+}
+
+// Set stores value at a dotted key in the configuration file at path, creating
+// intermediate objects. The value is parsed as JSON when that yields a valid
+// configuration, and taken as a string otherwise, so `1` can set both a number and
+// an environment variable. The previous file is kept as path.bak.
+func Set(path, key, value string) error {
+	doc, err := readDocument(path)
+	if err != nil {
+		return err
+	}
+
+	var v any
+	if err := decodeJSON([]byte(value), &v); err != nil {
+		v = value
+	}
+
+	data, err := setValue(doc, key, v)
+	if err != nil && v != any(value) {
+		data, err = setValue(doc, key, value)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid value for %s: %w", key, err)
+	}
+
+	if err := backup(path); err != nil {
+		return err
+	}
+	return Save(path, data)
+}
+
+// setValue stores v at a dotted key in doc and returns the validated document
+func setValue(doc map[string]any, key string, v any) ([]byte, error) {
+	parts := strings.Split(key, ".")
+	m := doc
+	for _, part := range parts[:len(parts)-1] {
+		next, ok := m[part].(map[string]any)
+		if !ok {
+			next = map[string]any{}
+			m[part] = next
+		}
+		m = next
+	}
+	m[parts[len(parts)-1]] = v
+
+	data, err := json.MarshalIndent(doc, "", "    ")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := Parse(data); err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// readDocument reads the configuration file at path as a generic JSON object.
+// A missing file yields an empty object.
+func readDocument(path string) (map[string]any, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return map[string]any{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	doc := map[string]any{}
+	if err := decodeJSON(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse config %s: %w", path, err)
+	}
+	return doc, nil
+}
+
+// decodeJSON decodes a single JSON value from data into v, keeping numbers as written
+func decodeJSON(data []byte, v any) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return errors.New("unexpected data after JSON value")
+	}
+	return nil
+}
+
+// backup copies the configuration file at path to path.bak, if it exists
+func backup(path string) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read config: %w", err)
+	}
+	if err := os.WriteFile(path+".bak", data, 0o644); err != nil {
+		return fmt.Errorf("failed to back up config: %w", err)
+	}
+	return nil
+}
+
+// Replace validates data as a whole configuration file and writes it to path. The
+// previous file is kept as path.bak.
+func Replace(path string, data []byte) error {
+	if _, err := Parse(data); err != nil {
+		return err
+	}
+	if err := backup(path); err != nil {
+		return err
+	}
+	return Save(path, data)
+}
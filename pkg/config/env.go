@@ -0,0 +1,39 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ParseEnv reads KEY=VALUE lines from r, skipping blank lines and # comments.
+// Errors reference line numbers only, so values never leak into logs.
+func ParseEnv(r io.Reader) ([]string, error) {
+	var vars []string
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, _, ok := strings.Cut(line, "=")
+		if !ok || strings.TrimSpace(key) == "" {
+			return nil, fmt.Errorf("line %d: expected KEY=VALUE", n)
+		}
+		vars = append(vars, line)
+	}
+	return vars, scanner.Err()
+}
+
+// ParseArgs reads one argument per line from r, so arguments may contain spaces
+func ParseArgs(r io.Reader) ([]string, error) {
+	var args []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if line := scanner.Text(); line != "" {
+			args = append(args, line)
+		}
+	}
+	return args, scanner.Err()
+}
@@ -0,0 +1,172 @@
+package config
+
+import (
+	"cmp"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// EnvCredentials is set by systemd to the directory holding the service credentials
+const EnvCredentials = "CREDENTIALS_DIRECTORY"
+
+// Redacted replaces sensitive values in listings
+const Redacted = "<redacted>"
+
+// EnvVar is a managed environment variable of the child, read from exactly one source
+type EnvVar struct {
+	Name      string `json:"name"`                // Variable name
+	Value     string `json:"value,omitempty"`     // Literal value
+	File      string `json:"file,omitempty"`      // File holding the value, trailing newlines trimmed
+	SecretRef string `json:"secretRef,omitempty"` // Secret file in SecretsDir, always sensitive
+	HostEnv   string `json:"hostEnv,omitempty"`   // Variable of the daemon environment
+
+	// Precedence orders the entries setting the same name: the highest wins, and the
+	// last one among equals
+	Precedence int  `json:"precedence,omitempty"`
+	Sensitive  bool `json:"sensitive,omitempty"` // Redact the value in listings
+}
+
+// ResolvedVar is a managed variable with its value and where it came from
+type ResolvedVar struct {
+	Name       string
+	Value      string
+	Source     string // Such as "literal", "file /run/token" or "hostEnv HOME"
+	Precedence int
+	Sensitive  bool
+	Overrides  []string // Sources of the entries it won over
+}
+
+// SecretsDir returns where secretRef values are read from: the systemd credentials
+// directory when set, the secrets directory next to the config file otherwise
+func SecretsDir() string {
+	if dir := os.Getenv(EnvCredentials); dir != "" {
+		return dir
+	}
+	return filepath.Join(filepath.Dir(DefaultPath()), "secrets")
+}
+
+// source describes where the variable is read from, or returns an error unless
+// exactly one source is set
+func (v *EnvVar) source() (string, error) {
+	var sources []string
+	if v.Value != "" {
+		sources = append(sources, "literal")
+	}
+	if v.File != "" {
+		sources = append(sources, "file "+v.File)
+	}
+	if v.SecretRef != "" {
+		sources = append(sources, "secretRef "+v.SecretRef)
+	}
+	if v.HostEnv != "" {
+		sources = append(sources, "hostEnv "+v.HostEnv)
+	}
+	if len(sources) != 1 {
+		return "", fmt.Errorf("env %s: expected exactly one of value, file, secretRef or hostEnv", v.Name)
+	}
+	return sources[0], nil
+}
+
+// validate checks the name and the source without reading the value
+func (v *EnvVar) validate() error {
+	if v.Name == "" || strings.ContainsAny(v.Name, "=\x00") {
+		return fmt.Errorf("invalid env name %q", v.Name)
+	}
+	if v.SecretRef != "" && (strings.ContainsAny(v.SecretRef, `/\`) || v.SecretRef == ".." || v.SecretRef == ".") {
+		return fmt.Errorf("env %s: secretRef must be a plain name", v.Name)
+	}
+	_, err := v.source()
+	return err
+}
+
+// resolve reads the value from its source. Errors never include values.
+func (v *EnvVar) resolve() (string, error) {
+	switch {
+	case v.File != "":
+		data, err := os.ReadFile(v.File)
+		if err != nil {
+			return "", fmt.Errorf("env %s: %w", v.Name, err)
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	case v.SecretRef != "":
+		data, err := os.ReadFile(filepath.Join(SecretsDir(), v.SecretRef))
+		if err != nil {
+			return "", fmt.Errorf("env %s: secret %s: %w", v.Name, v.SecretRef, err)
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	case v.HostEnv != "":
+		value, ok := os.LookupEnv(v.HostEnv)
+		if !ok {
+			return "", fmt.Errorf("env %s: host variable %s is not set", v.Name, v.HostEnv)
+		}
+		return value, nil
+	}
+	return v.Value, nil
+}
+
+// ResolveEnv reads the managed variables from their sources and keeps, for each
+// name, the entry with the highest precedence. The result is sorted by name.
+func (c *Config) ResolveEnv() ([]ResolvedVar, error) {
+	byName := map[string]*ResolvedVar{}
+	for _, v := range c.Env {
+		source, err := v.source()
+		if err != nil {
+			return nil, err
+		}
+		value, err := v.resolve()
+		if err != nil {
+			return nil, err
+		}
+
+		r := ResolvedVar{
+			Name:       v.Name,
+			Value:      value,
+			Source:     source,
+			Precedence: v.Precedence,
+			Sensitive:  v.Sensitive || v.SecretRef != "",
+		}
+		prev, ok := byName[v.Name]
+		switch {
+		case !ok:
+			byName[v.Name] = &r
+		case r.Precedence >= prev.Precedence:
+			r.Overrides = append(prev.Overrides, prev.Source)
+			byName[v.Name] = &r
+		default:
+			prev.Overrides = append(prev.Overrides, r.Source)
+		}
+	}
+
+	vars := make([]ResolvedVar, 0, len(byName))
+	for _, r := range byName {
+		vars = append(vars, *r)
+	}
+	slices.SortFunc(vars, func(a, b ResolvedVar) int { return cmp.Compare(a.Name, b.Name) })
+	return vars, nil
+}
+
+// EnvPairs returns the resolved variables as KEY=VALUE pairs
+func EnvPairs(vars []ResolvedVar) []string {
+	pairs := make([]string, len(vars))
+	for i, v := range vars {
+		pairs[i] = v.Name + "=" + v.Value
+	}
+	return pairs
+}
+
+// EnvFiles returns the files the managed variables are read from, in the config order
+func (c *Config) EnvFiles() []string {
+	var files []string
+	for _, v := range c.Env {
+		switch {
+		case v.File != "":
+			files = append(files, v.File)
+		case v.SecretRef != "":
+			files = append(files, filepath.Join(SecretsDir(), v.SecretRef))
+		}
+	}
+	return files
+}
@@ -0,0 +1,79 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Output configures where the child output streams go
+type Output struct {
+	Stdout Stream `json:"stdout,omitzero"`
+	Stderr Stream `json:"stderr,omitzero"`
+
+	// ReadGroup is granted read access to the log directories, which are otherwise
+	// restricted to SYSTEM and Administrators on Windows
+	ReadGroup string `json:"readGroup,omitempty"`
+
+	// Forward ships both streams to a cloud logging service
+	Forward Forward `json:"forward,omitzero"`
+
+	// Buffer bounds the output held while a destination is slow
+	Buffer Buffer `json:"buffer,omitzero"`
+}
+
+// Buffer sets what happens to the child output when its destinations can't keep up
+type Buffer struct {
+	SizeKB   int      `json:"sizeKB,omitempty"`   // Per stream, 1024 by default and 64 in lean mode
+	Policy   string   `json:"policy,omitempty"`   // block (default) waits up to maxBlock before dropping, drop doesn't wait
+	MaxBlock Duration `json:"maxBlock,omitempty"` // Longest wait for space with the block policy, 1s by default
+}
+
+// Forward configures log shipping to CloudWatch Logs, GCP Cloud Logging or Loki.
+// AWS credentials are read from the AWS_* environment variables.
+type Forward struct {
+	Sink           string            `json:"sink,omitempty"`           // cloudwatch, gcp or loki, empty to disable
+	URL            string            `json:"url,omitempty"`            // Loki push URL, or an endpoint override for the others
+	Token          string            `json:"token,omitempty"`          // Bearer token for Loki, or OAuth token for GCP
+	Region         string            `json:"region,omitempty"`         // CloudWatch region
+	LogGroup       string            `json:"logGroup,omitempty"`       // CloudWatch log group, which must exist
+	LogStream      string            `json:"logStream,omitempty"`      // CloudWatch log stream, the hostname by default
+	Project        string            `json:"project,omitempty"`        // GCP project ID
+	LogName        string            `json:"logName,omitempty"`        // GCP log ID, the app name by default
+	Labels         map[string]string `json:"labels,omitempty"`         // Labels added to Loki streams and GCP entries, with the service name as "service" by default
+	BatchSize      int               `json:"batchSize,omitempty"`      // Entries per request, 500 by default
+	FlushInterval  Duration          `json:"flushInterval,omitempty"`  // Longest wait for a batch to fill, 5s by default
+	MaxBytesPerSec int               `json:"maxBytesPerSec,omitempty"` // Upload bandwidth limit, unlimited by default
+	BufferMaxMB    int               `json:"bufferMaxMB,omitempty"`    // Disk buffer used while offline, 64 MiB by default
+}
+
+// Stream configures a single child output stream. It is mirrored to the supervisor
+// output, which the service manager forwards to journald or the event log, and
+// optionally to a rotated file.
+type Stream struct {
+	Console    *bool  `json:"console,omitempty"`    // Mirror to the supervisor output, true by default
+	File       string `json:"file,omitempty"`       // Rotated log file, empty to disable
+	MaxSizeMB  int    `json:"maxSizeMB,omitempty"`  // Rotation size, 10 MiB by default
+	MaxBackups int    `json:"maxBackups,omitempty"` // Rotated files kept, 5 by default
+	Discard    bool   `json:"discard,omitempty"`    // Drop the stream without writing it anywhere
+	Sample     int    `json:"sample,omitempty"`     // Keep one line in every sample, all by default
+	Splice     bool   `json:"splice,omitempty"`     // Move the output to file within the kernel on Linux, for very chatty children
+}
+
+// validate checks that a discarded stream has no destination and the sample rate
+func (s Stream) validate() error {
+	if s.Discard && (s.File != "" || s.Sample > 0) {
+		return errors.New("discard excludes file and sample")
+	}
+	if s.Sample < 0 {
+		return fmt.Errorf("invalid sample %d", s.Sample)
+	}
+	if s.Splice && (s.File == "" || s.Sample > 0 || (s.Console != nil && *s.Console)) {
+		return errors.New("splice requires file, and excludes console and sample")
+	}
+	return nil
+}
+
+// ConsoleEnabled reports whether the stream is mirrored to the supervisor output
+func (s Stream) ConsoleEnabled() bool {
+	return !s.Splice && (s.Console == nil || *s.Console)
+}
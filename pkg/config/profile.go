@@ -0,0 +1,84 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+)
+
+// Profile overrides the child configuration for one environment, such as dev or prod
+type Profile struct {
+	Args   []string          `json:"args,omitempty"` // Arguments appended to the child command line
+	Env    map[string]string `json:"env,omitempty"`  // Environment variables set for the child
+	Limits Limits            `json:"limits,omitempty"`
+}
+
+// Limits overrides supervisor timeouts and retries. Zero values keep the defaults.
+type Limits struct {
+	ExitTimeout  Duration `json:"exitTimeout,omitempty"`  // Time the child has to exit once asked to stop
+	DrainTimeout Duration `json:"drainTimeout,omitempty"` // Extend exitTimeout up to this while the child holds connections
+	StartTimeout Duration `json:"startTimeout,omitempty"` // Time the child has to report ready
+	StartRetries int      `json:"startRetries,omitempty"` // Restarts allowed when the child doesn't report ready in time
+
+	MaxRuntime       Duration `json:"maxRuntime,omitempty"`       // Recycle the child after running this long
+	MaxRuntimeJitter Duration `json:"maxRuntimeJitter,omitempty"` // Random extra runtime before recycling
+}
+
+// EnvVars returns the profile environment as sorted KEY=VALUE pairs
+func (p *Profile) EnvVars() []string {
+	return envVars(p.Env)
+}
+
+// envVars returns env as sorted KEY=VALUE pairs
+func envVars(env map[string]string) []string {
+	vars := make([]string, 0, len(env))
+	for k, v := range env {
+		vars = append(vars, k+"="+v)
+	}
+	slices.Sort(vars)
+	return vars
+}
+
+// SelectProfile returns the profile named by name, EnvProfile, or the configured
+// default, in that order. It returns nil when no profile is selected.
+func (c *Config) SelectProfile(name string) (*Profile, string, error) {
+	if name == "" {
+		name = os.Getenv(EnvProfile)
+	}
+	if name == "" {
+		name = c.Profile
+	}
+	if name == "" {
+		return nil, "", nil
+	}
+
+	p, ok := c.Profiles[name]
+	if !ok {
+		return nil, "", fmt.Errorf("profile %q not found in config", name)
+	}
+	return &p, name, nil
+}
+
+// Duration is a time.Duration encoded as a string like "10s" in JSON
+type Duration time.Duration
+
+// MarshalJSON implements json.Marshaler
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be a string like \"10s\": %w", err)
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
@@ -0,0 +1,34 @@
+package config
+
+import (
+	_ "embed"
+	"sync"
+
+	"github.com/lucasdecamargo/go-appservice-example/pkg/jsonschema"
+)
+
+// schemaJSON is the JSON Schema of the config file, generated from the config structs
+// by go generate ./pkg/configdoc
+//
+//go:embed schema.json
+var schemaJSON []byte
+
+// compiledSchema compiles schemaJSON once
+var compiledSchema = sync.OnceValues(func() (*jsonschema.Schema, error) {
+	return jsonschema.Compile(schemaJSON)
+})
+
+// Schema returns the JSON Schema of the config file
+func Schema() []byte {
+	return schemaJSON
+}
+
+// validateSchema checks the config document data against the schema, reporting
+// every violation with its key path
+func validateSchema(data []byte) error {
+	s, err := compiledSchema()
+	if err != nil {
+		return err
+	}
+	return s.Validate(data)
+}
@@ -0,0 +1,241 @@
+// Package configdoc documents the config file and the environment variables from the
+// code: the keys and types come from the config structs, their descriptions from the
+// comments of the struct fields, extracted by go generate.
+package configdoc
+
+//go:generate go run ./gen
+//go:generate go run ./gen -schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"reflect"
+	"strings"
+
+	"github.com/lucasdecamargo/go-appservice-example/pkg/config"
+)
+
+// Value types of the config keys, as named by JSON Schema
+const (
+	TypeString  = "string"
+	TypeBoolean = "boolean"
+	TypeInteger = "integer"
+	TypeNumber  = "number"
+	TypeObject  = "object"
+	TypeArray   = "array"
+	TypeAny     = "any"
+)
+
+// Field is a key of the config file
+type Field struct {
+	Key  string `json:"key"`  // Dotted path, with [] for array items and <name> for map keys
+	Type string `json:"type"` // One of the Type constants
+	Doc  string `json:"doc,omitempty"`
+}
+
+// EnvVar is an environment variable read or set by svcapp
+type EnvVar struct {
+	Name string `json:"name"`
+	Doc  string `json:"doc,omitempty"`
+}
+
+// unmarshaler is implemented by types with their own JSON form, such as durations
+var unmarshaler = reflect.TypeFor[json.Unmarshaler]()
+
+// Fields lists the keys of the config file, in declaration order
+func Fields() []Field {
+	var fields []Field
+	walk(reflect.TypeFor[config.Config](), "", func(f Field) { fields = append(fields, f) })
+	return fields
+}
+
+// Env lists the environment variables, sorted by name
+func Env() []EnvVar {
+	return envDocs
+}
+
+// walk calls visit for each key of the struct t, prefixing them with prefix
+func walk(t reflect.Type, prefix string, visit func(Field)) {
+	for i := range t.NumField() {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if !f.IsExported() || name == "-" {
+			continue
+		}
+		if name == "" && f.Anonymous && isStruct(elem(f.Type)) {
+			walk(elem(f.Type), prefix, visit) // Embedded fields are promoted
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		key := prefix + name
+		doc := fieldDoc(t, f)
+
+		ft := elem(f.Type)
+		visit(Field{Key: key, Type: typeName(ft), Doc: doc})
+		switch {
+		case isStruct(ft):
+			walk(ft, key+".", visit)
+		case ft.Kind() == reflect.Slice && isStruct(elem(ft.Elem())):
+			walk(elem(ft.Elem()), key+"[].", visit)
+		case ft.Kind() == reflect.Map && isStruct(elem(ft.Elem())):
+			walk(elem(ft.Elem()), key+".<name>.", visit)
+		}
+	}
+}
+
+// fieldDoc returns the comment of the field f of t, or the doc of its type
+func fieldDoc(t reflect.Type, f reflect.StructField) string {
+	if doc := fieldDocs[typeKey(t)+"."+f.Name]; doc != "" {
+		return doc
+	}
+	return fieldDocs[typeKey(elem(f.Type))]
+}
+
+// typeKey names t as package.Type, the key of its doc
+func typeKey(t reflect.Type) string {
+	return path.Base(t.PkgPath()) + "." + t.Name()
+}
+
+// elem dereferences pointer types
+func elem(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}
+
+// isStruct reports whether t is a struct documented key by key
+func isStruct(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && !reflect.PointerTo(t).Implements(unmarshaler)
+}
+
+// typeName returns the JSON type of t
+func typeName(t reflect.Type) string {
+	t = elem(t)
+	if reflect.PointerTo(t).Implements(unmarshaler) {
+		return TypeString
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return TypeString
+	case reflect.Bool:
+		return TypeBoolean
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return TypeInteger
+	case reflect.Float32, reflect.Float64:
+		return TypeNumber
+	case reflect.Struct, reflect.Map:
+		return TypeObject
+	case reflect.Slice, reflect.Array:
+		return TypeArray
+	}
+	return TypeAny
+}
+
+// WriteMarkdown writes the reference of fields and vars as Markdown tables
+func WriteMarkdown(w io.Writer, fields []Field, vars []EnvVar) error {
+	var b strings.Builder
+	b.WriteString("## Config File\n\n| Key | Type | Description |\n| --- | --- | --- |\n")
+	for _, f := range fields {
+		fmt.Fprintf(&b, "| `%s` | %s | %s |\n", f.Key, f.Type, cell(f.Doc))
+	}
+	b.WriteString("\n## Environment Variables\n\n| Variable | Description |\n| --- | --- |\n")
+	for _, v := range vars {
+		fmt.Fprintf(&b, "| `%s` | %s |\n", v.Name, cell(v.Doc))
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// cell escapes s for a Markdown table cell
+func cell(s string) string {
+	return strings.ReplaceAll(s, "|", `\|`)
+}
+
+// SchemaURL is the JSON Schema dialect of Schema
+const SchemaURL = "https://json-schema.org/draft/2020-12/schema"
+
+// durationPattern matches the strings time.ParseDuration accepts
+const durationPattern = `^[-+]?(0|([0-9]*(\.[0-9]*)?(ns|us|µs|ms|s|m|h))+)$`
+
+// customSchemas are the schemas of the types with their own JSON form
+var customSchemas = map[reflect.Type]map[string]any{
+	reflect.TypeFor[config.Duration](): {"type": TypeString, "format": "duration", "pattern": durationPattern},
+}
+
+// Schema returns the JSON Schema of the config file. Unknown keys are rejected, as
+// the config parser does, and keys without omitempty are required.
+func Schema() map[string]any {
+	t := reflect.TypeFor[config.Config]()
+	s := schema(t, fieldDocs[typeKey(t)])
+	s["$schema"] = SchemaURL
+	s["title"] = "svcapp config"
+	return s
+}
+
+// schema returns the JSON Schema of t, described by doc
+func schema(t reflect.Type, doc string) map[string]any {
+	t = elem(t)
+	s := map[string]any{}
+	if custom, ok := customSchemas[t]; ok {
+		for k, v := range custom {
+			s[k] = v
+		}
+	} else {
+		switch typ := typeName(t); typ {
+		case TypeObject:
+			s["type"] = typ
+			if t.Kind() == reflect.Map {
+				s["additionalProperties"] = schema(t.Elem(), "")
+				break
+			}
+			props, required := map[string]any{}, []string{}
+			properties(t, props, &required)
+			s["properties"], s["additionalProperties"] = props, false
+			if len(required) > 0 {
+				s["required"] = required
+			}
+		case TypeArray:
+			s["type"], s["items"] = typ, schema(t.Elem(), "")
+		case TypeAny:
+		default:
+			s["type"] = typ
+		}
+	}
+	if doc != "" {
+		s["description"] = doc
+	}
+	return s
+}
+
+// properties adds the schemas of the fields of the struct t to props, and the keys
+// without omitempty to required
+func properties(t reflect.Type, props map[string]any, required *[]string) {
+	for i := range t.NumField() {
+		f := t.Field(i)
+		name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if !f.IsExported() || name == "-" {
+			continue
+		}
+		if name == "" && f.Anonymous && isStruct(elem(f.Type)) {
+			properties(elem(f.Type), props, required)
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		s := schema(f.Type, fieldDoc(t, f))
+		if typ, ok := s["type"]; ok && f.Type.Kind() == reflect.Pointer {
+			s["type"] = []any{typ, "null"} // Unset
+		}
+		props[name] = s
+		if !strings.Contains(opts, "omitempty") && !strings.Contains(opts, "omitzero") {
+			*required = append(*required, name)
+		}
+	}
+}
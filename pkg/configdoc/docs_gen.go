@@ -0,0 +1,208 @@
+// Code generated by gen; DO NOT EDIT.
+
+package configdoc
+
+// fieldDocs holds the comments of the config types and of their fields
+var fieldDocs = map[string]string{
+	"audit.Config":                           "Config selects where the audit records go",
+	"audit.Config.File":                      "Append-only JSON lines file, audit.jsonl in the state directory by default",
+	"audit.Config.Syslog":                    "Also send each record to syslog, or the Application event log on Windows",
+	"config.Buffer":                          "Buffer sets what happens to the child output when its destinations can't keep up",
+	"config.Buffer.MaxBlock":                 "Longest wait for space with the block policy, 1s by default",
+	"config.Buffer.Policy":                   "block (default) waits up to maxBlock before dropping, drop doesn't wait",
+	"config.Buffer.SizeKB":                   "Per stream, 1024 by default and 64 in lean mode",
+	"config.Compression":                     "Compression selects how crash reports and rotated log files are compressed",
+	"config.Compression.Algorithm":           "gzip or zstd, empty to disable",
+	"config.Compression.Level":               "1-9 for gzip, 1-22 for zstd, the algorithm default when zero",
+	"config.Config":                          "Config is the svcapp configuration file",
+	"config.Config.Audit":                    "Record of the control actions and who invoked them",
+	"config.Config.Compression":              "Compression of crash reports and rotated logs",
+	"config.Config.Confinement":              "SELinux context or AppArmor profile of the child",
+	"config.Config.Control":                  "Control API access",
+	"config.Config.DelayShutdown":            "Hold system shutdowns until the child has stopped",
+	"config.Config.Env":                      "Managed child environment variables",
+	"config.Config.Fleet":                    "Fleet management server",
+	"config.Config.Heartbeat":                "Liveness pings through the child stdin",
+	"config.Config.Jobs":                     "Commands run on a schedule next to the child",
+	"config.Config.LameDuck":                 "Notice sent to the child before it is stopped",
+	"config.Config.Lean":                     "Disable metrics and history, and shrink buffers",
+	"config.Config.LoadBalancer":             "Hooks shifting traffic away from the child around restarts",
+	"config.Config.Mounts":                   "Private /tmp, read-only paths and bind mounts of the child",
+	"config.Config.Output":                   "Child output destinations",
+	"config.Config.Ports":                    "Detection of the TCP ports the child listens on",
+	"config.Config.Pprof":                    "Profiling endpoint of the child",
+	"config.Config.Profile":                  "Profile used when none is selected",
+	"config.Config.Profiles":                 "Named child configurations",
+	"config.Config.Retention":                "History events and crash reports kept",
+	"config.Config.Runtime":                  "Go runtime tuning for the child",
+	"config.Config.SchemaURL":                "JSON Schema of the file, for editors, see \"svcapp config schema\"",
+	"config.Config.Service":                  "Service overrides the compiled service definition by setting, written by \"service edit\"",
+	"config.Config.ShutdownBoost":            "CPU and I/O priority of the child while it stops",
+	"config.Config.Snapshot":                 "Snapshot of the service data taken before reloads and updates",
+	"config.Config.Storage":                  "Daemon state and history storage",
+	"config.Config.Updates":                  "Release feed checks and automatic updates",
+	"config.Config.WaitForNetwork":           "Network conditions checked before each child start",
+	"config.Config.Webhooks":                 "Lifecycle event receivers",
+	"config.Config.Windows":                  "Windows holds service control manager settings applied by \"service install\"",
+	"config.Control":                         "Control configures access to the control API",
+	"config.Control.Readers":                 "Readers are accounts granted read-only status access through a separate named pipe on Windows. The control pipe itself is limited to SYSTEM and Administrators.",
+	"config.Control.SelfCheck":               "SelfCheck is the interval between checks that the control sockets accept connections, reopening them with backoff when they don't. Zero disables it.",
+	"config.EnvVar":                          "EnvVar is a managed environment variable of the child, read from exactly one source",
+	"config.EnvVar.File":                     "File holding the value, trailing newlines trimmed",
+	"config.EnvVar.HostEnv":                  "Variable of the daemon environment",
+	"config.EnvVar.Name":                     "Variable name",
+	"config.EnvVar.Precedence":               "Precedence orders the entries setting the same name: the highest wins, and the last one among equals",
+	"config.EnvVar.SecretRef":                "Secret file in SecretsDir, always sensitive",
+	"config.EnvVar.Sensitive":                "Redact the value in listings",
+	"config.EnvVar.Value":                    "Literal value",
+	"config.Fleet":                           "Fleet registers the daemon with a management server that receives its status and may send it signed commands",
+	"config.Fleet.ID":                        "Agent ID, the hostname by default",
+	"config.Fleet.Interval":                  "Heartbeat interval, 30s by default",
+	"config.Fleet.PublicKey":                 "Base64 ed25519 key verifying commands, empty to ignore them",
+	"config.Fleet.Token":                     "Bearer token sent with every request",
+	"config.Fleet.URL":                       "Management endpoint, https only, empty to disable",
+	"config.Forward":                         "Forward configures log shipping to CloudWatch Logs, GCP Cloud Logging or Loki. AWS credentials are read from the AWS_* environment variables.",
+	"config.Forward.BatchSize":               "Entries per request, 500 by default",
+	"config.Forward.BufferMaxMB":             "Disk buffer used while offline, 64 MiB by default",
+	"config.Forward.FlushInterval":           "Longest wait for a batch to fill, 5s by default",
+	"config.Forward.Labels":                  "Labels added to Loki streams and GCP entries, with the service name as \"service\" by default",
+	"config.Forward.LogGroup":                "CloudWatch log group, which must exist",
+	"config.Forward.LogName":                 "GCP log ID, the app name by default",
+	"config.Forward.LogStream":               "CloudWatch log stream, the hostname by default",
+	"config.Forward.MaxBytesPerSec":          "Upload bandwidth limit, unlimited by default",
+	"config.Forward.Project":                 "GCP project ID",
+	"config.Forward.Region":                  "CloudWatch region",
+	"config.Forward.Sink":                    "cloudwatch, gcp or loki, empty to disable",
+	"config.Forward.Token":                   "Bearer token for Loki, or OAuth token for GCP",
+	"config.Forward.URL":                     "Loki push URL, or an endpoint override for the others",
+	"config.Heartbeat":                       "Heartbeat checks that the child answers pings written to its stdin",
+	"config.Heartbeat.Interval":              "Time between pings, zero to disable",
+	"config.Heartbeat.OnFailure":             "restart or stop, restart by default",
+	"config.Heartbeat.Timeout":               "Time the child has to answer, the interval by default",
+	"config.Hook":                            "Hook is an HTTP request or a command run by the load balancer hooks",
+	"config.Hook.Command":                    "Executable and arguments run instead of calling url, with SVCAPP_LB_PHASE and SVCAPP_LB_PID set",
+	"config.Hook.Method":                     "HTTP method, POST by default",
+	"config.Hook.Timeout":                    "Time the hook has to complete, 10s by default",
+	"config.Hook.URL":                        "http:// or https:// URL receiving the phase, service, hostname and child PID as JSON",
+	"config.Job":                             "Job is a short-lived command, such as a backup, the daemon runs on a schedule",
+	"config.Job.Command":                     "Executable and arguments",
+	"config.Job.DST":                         "Daylight saving handling: skip, run-once (default) or run-twice",
+	"config.Job.Env":                         "Environment variables added for the job",
+	"config.Job.Log":                         "Log file, jobs/<name>.log in the log directory by default",
+	"config.Job.Name":                        "Unique name, used by \"jobs run\"",
+	"config.Job.Schedule":                    "Cron expression, descriptor such as @daily, or \"@every 1h\"",
+	"config.Job.TimeZone":                    "IANA time zone of a cron schedule, such as Europe/Berlin, the local one by default",
+	"config.Job.Timeout":                     "Kills the job after this long, no limit by default",
+	"config.Keep":                            "Keep is a retention policy. Zero values don't limit.",
+	"config.Keep.MaxAge":                     "Delete records older than this",
+	"config.Keep.MaxCount":                   "Keep the newest records up to this count",
+	"config.Keep.MaxSizeMB":                  "Keep the newest records up to this total size",
+	"config.LameDuck":                        "LameDuck warns the child some time before it is stopped or restarted",
+	"config.LameDuck.Period":                 "Time between the notice and the stop request, zero to disable",
+	"config.LameDuck.Signal":                 "Signal also sent with the notice, such as SIGURG, none by default",
+	"config.Limits":                          "Limits overrides supervisor timeouts and retries. Zero values keep the defaults.",
+	"config.Limits.DrainTimeout":             "Extend exitTimeout up to this while the child holds connections",
+	"config.Limits.ExitTimeout":              "Time the child has to exit once asked to stop",
+	"config.Limits.MaxRuntime":               "Recycle the child after running this long",
+	"config.Limits.MaxRuntimeJitter":         "Random extra runtime before recycling",
+	"config.Limits.StartRetries":             "Restarts allowed when the child doesn't report ready in time",
+	"config.Limits.StartTimeout":             "Time the child has to report ready",
+	"config.LoadBalancer":                    "LoadBalancer runs hooks taking the child out of an external load balancer before it is stopped or restarted on purpose, and putting it back once the new child is ready",
+	"config.LoadBalancer.Delay":              "Wait after the deregister hooks, for the balancer to stop sending requests",
+	"config.LoadBalancer.Deregister":         "Run before the child is stopped or restarted",
+	"config.LoadBalancer.Register":           "Run each time a child becomes ready",
+	"config.Network":                         "Network lists the conditions the network must meet before the child starts",
+	"config.Network.Reach":                   "host:port addresses that must accept TCP connections",
+	"config.Network.Resolve":                 "Host names that must resolve",
+	"config.Network.Route":                   "A route to the outside must exist",
+	"config.Network.Timeout":                 "Longest wait before starting the child anyway, unlimited by default",
+	"config.Output":                          "Output configures where the child output streams go",
+	"config.Output.Buffer":                   "Buffer bounds the output held while a destination is slow",
+	"config.Output.Forward":                  "Forward ships both streams to a cloud logging service",
+	"config.Output.ReadGroup":                "ReadGroup is granted read access to the log directories, which are otherwise restricted to SYSTEM and Administrators on Windows",
+	"config.Ports":                           "Ports finds the TCP ports the child listens on, for children picking ephemeral ports",
+	"config.Ports.Pattern":                   "Regular expression matched on each child output line, its first group capturing the port",
+	"config.Ports.Poll":                      "Interval of the scans of the child listening sockets, on Linux and Windows, zero to disable",
+	"config.Pprof":                           "Pprof locates the net/http/pprof handlers of the child for \"svcapp profile\"",
+	"config.Pprof.URL":                       "Base URL or host:port of the handlers, detected on the child ports when empty",
+	"config.Profile":                         "Profile overrides the child configuration for one environment, such as dev or prod",
+	"config.Profile.Args":                    "Arguments appended to the child command line",
+	"config.Profile.Env":                     "Environment variables set for the child",
+	"config.Retention":                       "Retention bounds the history events and crash reports the daemon keeps",
+	"config.Snapshot":                        "Snapshot runs commands taking a crash-consistent snapshot of the service data, such as a database dump or an LVM or ZFS snapshot, before reloads and updates, and restoring it when an update is rolled back",
+	"config.Snapshot.Command":                "Executable and arguments printing the snapshot name as their last output line, with SVCAPP_SNAPSHOT_REASON set",
+	"config.Snapshot.Required":               "Cancel the reload or update when the snapshot fails, instead of going on without one",
+	"config.Snapshot.Restore":                "Executable and arguments restoring the snapshot named by SVCAPP_SNAPSHOT, restores are manual when empty",
+	"config.Snapshot.Timeout":                "Time each command has to complete, 5m by default",
+	"config.Stream":                          "Stream configures a single child output stream. It is mirrored to the supervisor output, which the service manager forwards to journald or the event log, and optionally to a rotated file.",
+	"config.Stream.Console":                  "Mirror to the supervisor output, true by default",
+	"config.Stream.Discard":                  "Drop the stream without writing it anywhere",
+	"config.Stream.File":                     "Rotated log file, empty to disable",
+	"config.Stream.MaxBackups":               "Rotated files kept, 5 by default",
+	"config.Stream.MaxSizeMB":                "Rotation size, 10 MiB by default",
+	"config.Stream.Sample":                   "Keep one line in every sample, all by default",
+	"config.Stream.Splice":                   "Move the output to file within the kernel on Linux, for very chatty children",
+	"config.Updates":                         "Updates checks a release feed for new versions of the executable and, when enabled, installs them during the maintenance window",
+	"config.Updates.Auto":                    "Install new versions, requires PublicKey",
+	"config.Updates.Feed":                    "Release feed URL, https only, empty to disable",
+	"config.Updates.HealthTimeout":           "Time the updated child has to stay ready, 1m by default",
+	"config.Updates.Interval":                "Time between checks, 6h by default",
+	"config.Updates.PublicKey":               "Base64 ed25519 key verifying the releases",
+	"config.Updates.Window":                  "Local HH:MM-HH:MM maintenance window, any time when empty",
+	"config.Webhook":                         "Webhook receives the child lifecycle events as signed JSON POST requests",
+	"config.Webhook.Events":                  "started, ready, crashed, restarted, stopped or update; all when empty",
+	"config.Webhook.Secret":                  "HMAC-SHA256 key signing the requests",
+	"config.Webhook.URL":                     "Receiver, https only",
+	"lsm.Label":                              "Label is the security context a child is executed in. At most one is set.",
+	"lsm.Label.AppArmor":                     "Name of a loaded profile",
+	"lsm.Label.SELinux":                      "Such as system_u:system_r:svcapp_t:s0",
+	"mountns.Bind":                           "Bind mounts Source on Target, which must exist",
+	"mountns.Bind.ReadOnly":                  "Mount it read-only",
+	"mountns.Bind.Source":                    "Host path",
+	"mountns.Bind.Target":                    "Path seen by the child",
+	"mountns.Spec":                           "Spec describes the mounts of the child namespace. Mounts never propagate to the host.",
+	"mountns.Spec.Bind":                      "Paths mounted elsewhere",
+	"mountns.Spec.PrivateTmp":                "Empty tmpfs on /tmp and /var/tmp",
+	"mountns.Spec.ProtectSystem":             "Read-only /usr, /boot and /efi",
+	"mountns.Spec.ReadOnly":                  "Paths made read-only",
+	"priority.Boost":                         "Boost is the scheduling priority given to a process. Zero values leave it unchanged.",
+	"priority.Boost.IOClass":                 "realtime, best-effort or idle, Linux only",
+	"priority.Boost.IOLevel":                 "From 0, the highest, to 7 within the I/O class",
+	"priority.Boost.Nice":                    "From -20, the highest, to 19. Mapped to a priority class on Windows.",
+	"store.Config":                           "Config selects the storage backend",
+	"store.Config.Backend":                   "json, bolt or sqlite, json by default",
+	"store.Config.Path":                      "Directory for json, database file otherwise, unique to each instance",
+	"store.Config.Sync":                      "Sync flushes every json write to storage, so it survives a power loss. The bolt and sqlite backends always do.",
+	"store.Config.WriteInterval":             "WriteInterval is the minimum time between state writes, such as \"30s\", to spare flash storage. Intermediate states are coalesced. Empty writes every change.",
+	"svcctl.Trigger":                         "Trigger is an event that starts the service, whatever its start type",
+	"svcctl.Trigger.Event":                   "TriggerNetwork or TriggerDevice",
+	"svcctl.Trigger.HardwareID":              "Device hardware ID, such as USB\\VID_0403&PID_6001",
+	"svcctl.Trigger.InterfaceClass":          "Device interface class GUID, for TriggerDevice",
+	"svcctl.WindowsOptions":                  "WindowsOptions are the service control manager settings the kardianos options can't express. They are ignored on other platforms.",
+	"svcctl.WindowsOptions.DelayedAutoStart": "Start automatic services a while after boot",
+	"svcctl.WindowsOptions.Triggers":         "Also start the service on these events",
+	"tuning.Config":                          "Config controls the Go runtime settings derived for the child",
+	"tuning.Config.Auto":                     "Derive GOMAXPROCS and GOMEMLIMIT from cgroup limits",
+	"tuning.Config.GOGC":                     "GOGC value, unset when zero",
+	"tuning.Config.MemoryLimitRatio":         "Share of the memory limit used as GOMEMLIMIT, 0.9 by default",
+}
+
+// envDocs holds the comments of the environment variable constants
+var envDocs = []EnvVar{
+	{Name: "SVCAPP_CONFIG", Doc: "Overrides the configuration file path"},
+	{Name: "SVCAPP_CONTROL_ADDR", Doc: "Overrides the control socket address"},
+	{Name: "SVCAPP_CRASH_DIR", Doc: "Overrides the directory crash reports are written to"},
+	{Name: "SVCAPP_HEARTBEAT_FD", Doc: "Names the environment variable holding the file descriptor, or the handle on Windows, the child writes its heartbeat answers to. See AnswerHeartbeats."},
+	{Name: "SVCAPP_INSTANCE", Doc: "Names the instance of the service the process belongs to, set in the environment of installed instances"},
+	{Name: "SVCAPP_KV_FILE", Doc: "Names the environment variable holding the store file path, which the child can read directly. Writes go through the control socket."},
+	{Name: "SVCAPP_LAMEDUCK_FD", Doc: "Names the environment variable holding the file descriptor, or the handle on Windows, the child reads lame duck notices from. See NotifyLameDuck."},
+	{Name: "SVCAPP_LB_PHASE", Doc: "Holds the phase a hook command runs for, deregister or register"},
+	{Name: "SVCAPP_LB_PID", Doc: "Holds the PID of the child a hook command runs for"},
+	{Name: "SVCAPP_MOUNTNS", Doc: "Passes the Spec to the helper process that sets the mounts up, see Init"},
+	{Name: "SVCAPP_PROFILE", Doc: "Selects the active profile when no --profile flag is given"},
+	{Name: "SVCAPP_READY_FILE", Doc: "Names the environment variable holding the path the child creates to report readiness. See NotifyReady."},
+	{Name: "SVCAPP_SCOPE", Doc: "Forces the system or user locations, see Scope"},
+	{Name: "SVCAPP_SNAPSHOT", Doc: "Holds the name of the snapshot to restore"},
+	{Name: "SVCAPP_SNAPSHOT_REASON", Doc: "Holds the operation a snapshot is taken before, such as reload"},
+	{Name: "SVCAPP_STATE", Doc: "Overrides the state file path"},
+}
@@ -0,0 +1,186 @@
+// Command gen writes docs_gen.go, with the comments of the config struct fields and of
+// the SVCAPP_ environment variable constants, for configdoc to document them. With
+// -schema, it writes the JSON Schema of the config file the config package embeds.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"log"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/lucasdecamargo/go-appservice-example/pkg/config"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/configdoc"
+)
+
+// pkgDir is the directory of the packages, relative to the configdoc package go
+// generate runs in
+const pkgDir = ".."
+
+// schemaFile is the JSON Schema embedded by the config package
+const schemaFile = "../config/schema.json"
+
+// envPrefix selects the constants documented as environment variables
+const envPrefix = "SVCAPP_"
+
+func main() {
+	schema := flag.Bool("schema", false, "Write the config JSON Schema instead of the docs")
+	flag.Parse()
+	if *schema {
+		writeSchema()
+		return
+	}
+
+	types := map[string]bool{} // package.Type of the types reachable from the config
+	collectTypes(reflect.TypeFor[config.Config](), types)
+
+	fields := map[string]string{}
+	env := map[string]string{}
+	dirs, err := os.ReadDir(pkgDir)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fset := token.NewFileSet()
+	for _, dir := range dirs {
+		if !dir.IsDir() {
+			continue
+		}
+		pkgs, err := parser.ParseDir(fset, filepath.Join(pkgDir, dir.Name()), func(fi os.FileInfo) bool {
+			return !strings.HasSuffix(fi.Name(), "_test.go")
+		}, parser.ParseComments)
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, pkg := range pkgs {
+			for _, file := range pkg.Files {
+				collectDocs(pkg.Name, file, types, fields, env)
+			}
+		}
+	}
+
+	var b bytes.Buffer
+	b.WriteString("// Code generated by gen; DO NOT EDIT.\n\npackage configdoc\n\n")
+	b.WriteString("// fieldDocs holds the comments of the config types and of their fields\nvar fieldDocs = map[string]string{\n")
+	for _, k := range slices.Sorted(maps.Keys(fields)) {
+		fmt.Fprintf(&b, "\t%q: %q,\n", k, fields[k])
+	}
+	b.WriteString("}\n\n// envDocs holds the comments of the environment variable constants\nvar envDocs = []EnvVar{\n")
+	for _, k := range slices.Sorted(maps.Keys(env)) {
+		fmt.Fprintf(&b, "\t{Name: %q, Doc: %q},\n", k, env[k])
+	}
+	b.WriteString("}\n")
+
+	out, err := format.Source(b.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile("docs_gen.go", out, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// writeSchema writes the JSON Schema of the config file. It runs separately, after
+// docs_gen.go is written, as the descriptions come from it.
+func writeSchema() {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "    ")
+	if err := enc.Encode(configdoc.Schema()); err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(schemaFile, b.Bytes(), 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// collectTypes adds the named struct types reachable from t to types
+func collectTypes(t reflect.Type, types map[string]bool) {
+	switch t.Kind() {
+	case reflect.Pointer, reflect.Slice, reflect.Array, reflect.Map:
+		collectTypes(t.Elem(), types)
+	case reflect.Struct:
+		key := filepath.Base(t.PkgPath()) + "." + t.Name()
+		if types[key] {
+			return
+		}
+		types[key] = true
+		for i := range t.NumField() {
+			collectTypes(t.Field(i).Type, types)
+		}
+	}
+}
+
+// collectDocs adds the comments of the types of file listed in types, and of their
+// fields, to fields, and the comments of its SVCAPP_ constants to env
+func collectDocs(pkg string, file *ast.File, types map[string]bool, fields, env map[string]string) {
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			switch spec := spec.(type) {
+			case *ast.TypeSpec:
+				st, ok := spec.Type.(*ast.StructType)
+				key := pkg + "." + spec.Name.Name
+				if !ok || !types[key] {
+					continue
+				}
+				if doc := text(spec.Doc, gen.Doc); doc != "" {
+					fields[key] = doc
+				}
+				for _, f := range st.Fields.List {
+					doc := text(f.Doc, f.Comment)
+					for _, name := range f.Names {
+						if doc != "" {
+							fields[key+"."+name.Name] = doc
+						}
+					}
+				}
+			case *ast.ValueSpec:
+				if gen.Tok != token.CONST {
+					continue
+				}
+				for _, v := range spec.Values {
+					lit, ok := v.(*ast.BasicLit)
+					if !ok || lit.Kind != token.STRING {
+						continue
+					}
+					name, err := strconv.Unquote(lit.Value)
+					if err != nil || !strings.HasPrefix(name, envPrefix) {
+						continue
+					}
+					// "EnvConfig overrides the path" becomes "Overrides the path"
+					doc := text(spec.Doc, spec.Comment, gen.Doc)
+					if rest, ok := strings.CutPrefix(doc, spec.Names[0].Name+" "); ok && rest != "" {
+						doc = strings.ToUpper(rest[:1]) + rest[1:]
+					}
+					env[name] = doc
+				}
+			}
+		}
+	}
+}
+
+// text returns the first non-empty comment group as a single line
+func text(groups ...*ast.CommentGroup) string {
+	for _, g := range groups {
+		if s := strings.Join(strings.Fields(g.Text()), " "); s != "" {
+			return s
+		}
+	}
+	return ""
+}
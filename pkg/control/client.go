@@ -0,0 +1,265 @@
+package control
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/lucasdecamargo/go-appservice-example/pkg/daemon"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/jobs"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/kv"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/listener"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/logexport"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/metrics"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/state"
+)
+
+const clientTimeout = 10 * time.Second
+
+// Client talks to a daemon control server
+type Client struct {
+	http *http.Client
+}
+
+// NewClient creates a client for the control server listening on addr
+func NewClient(addr string) *Client {
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return listener.Dial(ctx, addr)
+		},
+	}
+	return &Client{http: &http.Client{Transport: transport, Timeout: clientTimeout}}
+}
+
+// Status returns the daemon state
+func (c *Client) Status(ctx context.Context) (*state.State, error) {
+	var s state.State
+	return &s, c.do(ctx, http.MethodGet, routeStatus, nil, &s)
+}
+
+// Schedule schedules action to run at the given time
+func (c *Client) Schedule(ctx context.Context, action string, at time.Time) (*state.State, error) {
+	var s state.State
+	return &s, c.do(ctx, http.MethodPost, routeSchedule, ScheduleRequest{Action: action, At: at}, &s)
+}
+
+// CancelSchedule cancels the pending scheduled action
+func (c *Client) CancelSchedule(ctx context.Context) (*state.State, error) {
+	var s state.State
+	return &s, c.do(ctx, http.MethodDelete, routeSchedule, nil, &s)
+}
+
+// Reload makes the daemon re-read its configuration and restart the child with it
+func (c *Client) Reload(ctx context.Context) (*state.State, error) {
+	var s state.State
+	return &s, c.do(ctx, http.MethodPost, routeReload, nil, &s)
+}
+
+// Metrics returns the daemon lifecycle latency histograms
+func (c *Client) Metrics(ctx context.Context) (map[string]metrics.Snapshot, error) {
+	var m map[string]metrics.Snapshot
+	if err := c.do(ctx, http.MethodGet, routeMetrics, nil, &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Restarts returns the child restart counters by reason
+func (c *Client) Restarts(ctx context.Context) (map[string]int, error) {
+	var m map[string]int
+	if err := c.do(ctx, http.MethodGet, routeRestarts, nil, &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Counters returns the counters registered by the daemon, such as the child output
+// bytes discarded
+func (c *Client) Counters(ctx context.Context) (map[string]uint64, error) {
+	var m map[string]uint64
+	if err := c.do(ctx, http.MethodGet, routeCounters, nil, &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Resources returns the supervisor memory usage and the footprint of its subsystems
+func (c *Client) Resources(ctx context.Context) (*daemon.Resources, error) {
+	var r daemon.Resources
+	if err := c.do(ctx, http.MethodGet, routeResources, nil, &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// LogLevel returns the log level of the supervisor
+func (c *Client) LogLevel(ctx context.Context) (string, error) {
+	var l LogLevel
+	err := c.do(ctx, http.MethodGet, routeLogLevel, nil, &l)
+	return l.Level, err
+}
+
+// SetLogLevel changes the log level of the supervisor, returning the new level
+func (c *Client) SetLogLevel(ctx context.Context, level string) (string, error) {
+	var l LogLevel
+	err := c.do(ctx, http.MethodPut, routeLogLevel, LogLevel{Level: level}, &l)
+	return l.Level, err
+}
+
+// Jobs returns the status of the scheduled jobs
+func (c *Client) Jobs(ctx context.Context) ([]jobs.Status, error) {
+	var j []jobs.Status
+	if err := c.do(ctx, http.MethodGet, routeJobs, nil, &j); err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+// RunJob runs the named job now, outside of its schedule
+func (c *Client) RunJob(ctx context.Context, name string) ([]jobs.Status, error) {
+	var j []jobs.Status
+	route := strings.Replace(routeRunJob, "{name}", url.PathEscape(name), 1)
+	if err := c.do(ctx, http.MethodPost, route, nil, &j); err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+// TakeSnapshot snapshots the service data now and returns the snapshot name
+func (c *Client) TakeSnapshot(ctx context.Context) (string, error) {
+	var s Snapshot
+	if err := c.do(ctx, http.MethodPost, routeSnapshots, nil, &s); err != nil {
+		return "", err
+	}
+	return s.Name, nil
+}
+
+// RollbackToSnapshot restarts the child after restoring the snapshot name
+func (c *Client) RollbackToSnapshot(ctx context.Context, name string) error {
+	var s Snapshot
+	route := strings.Replace(routeRestore, "{name}", url.PathEscape(name), 1)
+	return c.do(ctx, http.MethodPost, route, nil, &s)
+}
+
+// Logs returns the latest supervisor and child log lines the daemon holds within rg
+func (c *Client) Logs(ctx context.Context, rg logexport.Range) ([]logexport.Entry, error) {
+	q := url.Values{}
+	if !rg.Since.IsZero() {
+		q.Set("since", rg.Since.Format(time.RFC3339Nano))
+	}
+	if !rg.Until.IsZero() {
+		q.Set("until", rg.Until.Format(time.RFC3339Nano))
+	}
+	route := routeLogs
+	if len(q) > 0 {
+		route += "?" + q.Encode()
+	}
+
+	var entries []logexport.Entry
+	if err := c.do(ctx, http.MethodGet, route, nil, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// KV returns every key and value of the child key/value store
+func (c *Client) KV(ctx context.Context) (map[string]string, error) {
+	var all map[string]string
+	if err := c.do(ctx, http.MethodGet, routeKV, nil, &all); err != nil {
+		return nil, err
+	}
+	return all, nil
+}
+
+// KVGet returns the value of key in the child key/value store, or kv.ErrNotFound
+func (c *Client) KVGet(ctx context.Context, key string) (string, error) {
+	route, err := kvRoute(key)
+	if err != nil {
+		return "", err
+	}
+	var v KVValue
+	err = c.do(ctx, http.MethodGet, route, nil, &v)
+	return v.Value, kvError(err)
+}
+
+// KVSet stores value under key in the child key/value store
+func (c *Client) KVSet(ctx context.Context, key, value string) error {
+	route, err := kvRoute(key)
+	if err != nil {
+		return err
+	}
+	var v KVValue
+	return kvError(c.do(ctx, http.MethodPut, route, KVValue{Value: value}, &v))
+}
+
+// KVDelete removes key from the child key/value store, or returns kv.ErrNotFound
+func (c *Client) KVDelete(ctx context.Context, key string) error {
+	route, err := kvRoute(key)
+	if err != nil {
+		return err
+	}
+	var out struct{}
+	return kvError(c.do(ctx, http.MethodDelete, route, nil, &out))
+}
+
+// kvRoute returns the route of key, whose slashes are kept as path separators. Keys
+// are validated first, since the server would see cleaned paths.
+func kvRoute(key string) (string, error) {
+	if err := kv.ValidateKey(key); err != nil {
+		return "", err
+	}
+	parts := strings.Split(key, "/")
+	for i, p := range parts {
+		parts[i] = url.PathEscape(p)
+	}
+	return routeKV + "/" + strings.Join(parts, "/"), nil
+}
+
+// kvError restores kv.ErrNotFound from the error message sent by the server
+func kvError(err error) error {
+	if err != nil && err.Error() == kv.ErrNotFound.Error() {
+		return kv.ErrNotFound
+	}
+	return err
+}
+
+// do sends a request with an optional JSON body and decodes the JSON response into out
+func (c *Client) do(ctx context.Context, method, route string, body, out any) error {
+	var r io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		r = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, "http://svcapp"+route, r)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("daemon not reachable: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var e errorResponse
+		if err := json.NewDecoder(resp.Body).Decode(&e); err != nil || e.Error == "" {
+			return fmt.Errorf("control request failed: %s", resp.Status)
+		}
+		return errors.New(e.Error)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,99 @@
+package control
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/lucasdecamargo/go-appservice-example/pkg/paths"
+)
+
+// EnvControlAddr overrides the control socket address
+const EnvControlAddr = "SVCAPP_CONTROL_ADDR"
+
+// API routes served on the control socket
+const (
+	routeStatus    = "/v1/status"
+	routeSchedule  = "/v1/schedule"
+	routeMetrics   = "/v1/metrics"
+	routeRestarts  = "/v1/metrics/restarts"
+	routeCounters  = "/v1/metrics/counters"
+	routeReload    = "/v1/reload"
+	routeResources = "/v1/resources"
+	routeLogLevel  = "/v1/loglevel"
+	routeJobs      = "/v1/jobs"
+	routeRunJob    = "/v1/jobs/{name}/run"
+	routeLogs      = "/v1/logs"
+	routeKV        = "/v1/kv"
+	routeKVKey     = "/v1/kv/{key...}"
+	routeSnapshots = "/v1/snapshots"
+	routeRestore   = "/v1/snapshots/{name}/restore"
+)
+
+// ScheduleRequest is the body of a schedule request
+type ScheduleRequest struct {
+	Action string    `json:"action"`
+	At     time.Time `json:"at"`
+}
+
+// KVValue is the body of key/value requests and responses
+type KVValue struct {
+	Value string `json:"value"`
+}
+
+// Snapshot is the response of snapshot requests
+type Snapshot struct {
+	Name string `json:"name"`
+}
+
+// LogLevel is the body of log level requests and responses
+type LogLevel struct {
+	Level string `json:"level"`
+}
+
+// errorResponse is the body returned by failed requests
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// DefaultAddr returns the control socket address, honoring EnvControlAddr, of the
+// instance the process belongs to if any
+func DefaultAddr() string {
+	if addr := os.Getenv(EnvControlAddr); addr != "" {
+		return addr
+	}
+	if instance := paths.Instance(); instance != "" {
+		return InstanceAddr(instance)
+	}
+	if runtime.GOOS == "windows" {
+		return "npipe://./pipe/" + paths.App() + "-control"
+	}
+	return socketAddr("control.sock")
+}
+
+// InstanceAddr returns the control socket address of a named instance of the service
+func InstanceAddr(instance string) string {
+	if runtime.GOOS == "windows" {
+		return "npipe://./pipe/" + paths.App() + "-control@" + instance
+	}
+	return socketAddr("control@" + instance + ".sock")
+}
+
+// socketAddr returns the address of the Unix socket name in the runtime directory,
+// reserved to the owner
+func socketAddr(name string) string {
+	u := url.URL{Scheme: "unix", Path: filepath.Join(paths.RuntimeDir(), name), RawQuery: "mode=0600"}
+	return u.String()
+}
+
+// StatusAddr returns the address of the read-only status pipe that readers may connect
+// to, or "" when there are no readers or no named pipes on this platform
+func StatusAddr(readers []string) string {
+	if runtime.GOOS != "windows" || len(readers) == 0 {
+		return ""
+	}
+	return "npipe://./pipe/" + paths.Service() + "-status?allow=" + url.QueryEscape(strings.Join(readers, ","))
+}
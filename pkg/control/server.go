@@ -0,0 +1,362 @@
+package control
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/lucasdecamargo/go-appservice-example/pkg/audit"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/daemon"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/jobs"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/kv"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/logexport"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/loglevel"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/metrics"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/state"
+)
+
+// Controller is the daemon surface exposed on the control socket
+type Controller interface {
+	Status() state.State
+	Schedule(action string, at time.Time) error
+	CancelSchedule() bool
+	Metrics() map[string]metrics.Snapshot
+	Counters() map[string]uint64
+	Reload() error
+	Resources() daemon.Resources
+	JobStatus() []jobs.Status
+	RunJob(name string) error
+	RecentLogs(rg logexport.Range) []logexport.Entry
+	KVList() (map[string]string, error)
+	KVGet(key string) (string, error)
+	KVSet(key, value string) error
+	KVDelete(key string) error
+	TakeSnapshot(reason string) (string, error)
+	RollbackToSnapshot(name string) error
+}
+
+// Server serves the control API for a Controller
+type Server struct {
+	c     Controller
+	srv   *http.Server
+	audit *audit.Logger
+}
+
+// connKey holds the connection of a request in its context
+type connKey struct{}
+
+// NewServer creates a control server for c
+func NewServer(c Controller) *Server {
+	s := &Server{c: c}
+
+	mux := s.readOnlyMux()
+	mux.HandleFunc("POST "+routeSchedule, s.audited("schedule", s.handleSchedule))
+	mux.HandleFunc("DELETE "+routeSchedule, s.audited("cancel-schedule", s.handleCancelSchedule))
+	mux.HandleFunc("POST "+routeReload, s.audited("reload", s.handleReload))
+	mux.HandleFunc("PUT "+routeLogLevel, s.audited("set-loglevel", s.handleSetLogLevel))
+	mux.HandleFunc("POST "+routeRunJob, s.audited("run-job", s.handleRunJob))
+	mux.HandleFunc("POST "+routeSnapshots, s.audited("snapshot", s.handleSnapshot))
+	mux.HandleFunc("POST "+routeRestore, s.audited("restore-snapshot", s.handleRestore))
+
+	// The key/value store belongs to the child, it isn't served to status readers
+	mux.HandleFunc("GET "+routeKV, s.handleKVList)
+	mux.HandleFunc("GET "+routeKVKey, s.handleKVGet)
+	mux.HandleFunc("PUT "+routeKVKey, s.audited("kv-set", s.handleKVSet))
+	mux.HandleFunc("DELETE "+routeKVKey, s.audited("kv-delete", s.handleKVDelete))
+
+	s.srv = &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+		ConnContext: func(ctx context.Context, c net.Conn) context.Context {
+			return context.WithValue(ctx, connKey{}, c)
+		},
+	}
+	return s
+}
+
+// SetAudit records the actions changing the daemon to l, with the credentials of the
+// caller where the socket provides them
+func (s *Server) SetAudit(l *audit.Logger) {
+	s.audit = l
+}
+
+// NewStatusServer creates a server for c that only serves the status and metrics
+func NewStatusServer(c Controller) *Server {
+	s := &Server{c: c}
+	s.srv = &http.Server{Handler: s.readOnlyMux(), ReadHeaderTimeout: 5 * time.Second}
+	return s
+}
+
+// readOnlyMux returns a mux serving the routes that don't change the daemon
+func (s *Server) readOnlyMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+routeStatus, s.handleStatus)
+	mux.HandleFunc("GET "+routeMetrics, s.handleMetrics)
+	mux.HandleFunc("GET "+routeRestarts, s.handleRestarts)
+	mux.HandleFunc("GET "+routeCounters, s.handleCounters)
+	mux.HandleFunc("GET "+routeResources, s.handleResources)
+	mux.HandleFunc("GET "+routeLogLevel, s.handleLogLevel)
+	mux.HandleFunc("GET "+routeJobs, s.handleJobs)
+	mux.HandleFunc("GET "+routeLogs, s.handleLogs)
+	return mux
+}
+
+// Serve accepts control connections on l until Close is called
+func (s *Server) Serve(l net.Listener) error {
+	if err := s.srv.Serve(l); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// Close stops the server
+func (s *Server) Close() error {
+	return s.srv.Close()
+}
+
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.c.Status())
+}
+
+func (s *Server) handleSchedule(w http.ResponseWriter, r *http.Request) {
+	var req ScheduleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := s.c.Schedule(req.Action, req.At); err != nil {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, s.c.Status())
+}
+
+func (s *Server) handleCancelSchedule(w http.ResponseWriter, r *http.Request) {
+	if !s.c.CancelSchedule() {
+		writeError(w, http.StatusNotFound, errors.New("no scheduled action"))
+		return
+	}
+	writeJSON(w, http.StatusOK, s.c.Status())
+}
+
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.c.Metrics())
+}
+
+func (s *Server) handleCounters(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.c.Counters())
+}
+
+func (s *Server) handleRestarts(w http.ResponseWriter, r *http.Request) {
+	restarts := s.c.Status().RestartReasons
+	if restarts == nil {
+		restarts = map[string]int{}
+	}
+	writeJSON(w, http.StatusOK, restarts)
+}
+
+func (s *Server) handleResources(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.c.Resources())
+}
+
+func (s *Server) handleReload(w http.ResponseWriter, r *http.Request) {
+	if err := s.c.Reload(); err != nil {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, s.c.Status())
+}
+
+func (s *Server) handleLogLevel(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, LogLevel{Level: loglevel.Level.Level().String()})
+}
+
+func (s *Server) handleSetLogLevel(w http.ResponseWriter, r *http.Request) {
+	var req LogLevel
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	l, err := loglevel.Parse(req.Level)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, LogLevel{Level: loglevel.Set(l).String()})
+}
+
+func (s *Server) handleJobs(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.c.JobStatus())
+}
+
+func (s *Server) handleRunJob(w http.ResponseWriter, r *http.Request) {
+	err := s.c.RunJob(r.PathValue("name"))
+	switch {
+	case errors.Is(err, jobs.ErrUnknownJob):
+		writeError(w, http.StatusNotFound, err)
+	case err != nil:
+		writeError(w, http.StatusConflict, err)
+	default:
+		writeJSON(w, http.StatusOK, s.c.JobStatus())
+	}
+}
+
+func (s *Server) handleSnapshot(w http.ResponseWriter, r *http.Request) {
+	name, err := s.c.TakeSnapshot(daemon.SnapshotManual)
+	if err != nil {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, Snapshot{Name: name})
+}
+
+func (s *Server) handleRestore(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if err := s.c.RollbackToSnapshot(name); err != nil {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, Snapshot{Name: name})
+}
+
+func (s *Server) handleLogs(w http.ResponseWriter, r *http.Request) {
+	var rg logexport.Range
+	for _, p := range []struct {
+		name string
+		t    *time.Time
+	}{{"since", &rg.Since}, {"until", &rg.Until}} {
+		v := r.URL.Query().Get(p.name)
+		if v == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid %s: %w", p.name, err))
+			return
+		}
+		*p.t = t
+	}
+	entries := s.c.RecentLogs(rg)
+	if entries == nil {
+		entries = []logexport.Entry{}
+	}
+	writeJSON(w, http.StatusOK, entries)
+}
+
+func (s *Server) handleKVList(w http.ResponseWriter, r *http.Request) {
+	all, err := s.c.KVList()
+	if err != nil {
+		writeKVError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, all)
+}
+
+func (s *Server) handleKVGet(w http.ResponseWriter, r *http.Request) {
+	v, err := s.c.KVGet(r.PathValue("key"))
+	if err != nil {
+		writeKVError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, KVValue{Value: v})
+}
+
+func (s *Server) handleKVSet(w http.ResponseWriter, r *http.Request) {
+	var req KVValue
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 2*kv.MaxValueSize)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := s.c.KVSet(r.PathValue("key"), req.Value); err != nil {
+		writeKVError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, req)
+}
+
+func (s *Server) handleKVDelete(w http.ResponseWriter, r *http.Request) {
+	if err := s.c.KVDelete(r.PathValue("key")); err != nil {
+		writeKVError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, struct{}{})
+}
+
+// writeKVError writes a key/value store error with its status code
+func writeKVError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, kv.ErrNotFound):
+		writeError(w, http.StatusNotFound, err)
+	case errors.Is(err, kv.ErrInvalidKey), errors.Is(err, kv.ErrTooLarge), errors.Is(err, kv.ErrFull):
+		writeError(w, http.StatusBadRequest, err)
+	default:
+		writeError(w, http.StatusConflict, err)
+	}
+}
+
+// audited wraps h to record action, the caller and the outcome to the audit log
+func (s *Server) audited(action string, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.audit == nil {
+			h(w, r)
+			return
+		}
+
+		rec := &auditRecorder{ResponseWriter: w, code: http.StatusOK}
+		h(rec, r)
+
+		var err error
+		if rec.code != http.StatusOK {
+			var e errorResponse
+			if json.Unmarshal(rec.body.Bytes(), &e) != nil || e.Error == "" {
+				e.Error = http.StatusText(rec.code)
+			}
+			err = errors.New(e.Error)
+		}
+		var actor audit.Actor
+		if c, ok := r.Context().Value(connKey{}).(net.Conn); ok {
+			actor = audit.Peer(c)
+		}
+		target := r.PathValue("name") + r.PathValue("key")
+		if aerr := s.audit.Record(audit.Record{Source: audit.SourceControl, Action: action, Target: target, Actor: actor}, err); aerr != nil {
+			slog.Warn("Failed to audit a control action", "action", action, "error", aerr)
+		}
+	}
+}
+
+// auditRecorder keeps the status code and error body of a response
+type auditRecorder struct {
+	http.ResponseWriter
+	code int
+	body bytes.Buffer
+}
+
+func (r *auditRecorder) WriteHeader(code int) {
+	r.code = code
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *auditRecorder) Write(b []byte) (int, error) {
+	if r.code != http.StatusOK {
+		r.body.Write(b)
+	}
+	return r.ResponseWriter.Write(b)
+}
+
+// writeJSON writes v as the JSON response body
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes err as a JSON error response
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, errorResponse{Error: err.Error()})
+}
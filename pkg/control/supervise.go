@@ -0,0 +1,112 @@
+package control
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/lucasdecamargo/go-appservice-example/pkg/listener"
+)
+
+// Socket reopening backoff
+const (
+	minRebindBackoff = time.Second
+	maxRebindBackoff = time.Minute
+)
+
+// selfCheckTimeout bounds a connection made by the self check
+const selfCheckTimeout = 5 * time.Second
+
+// Supervise serves on addr until ctx is done, checking every check interval that the
+// socket still accepts connections. When it can't be opened, fails, or stops accepting
+// connections, for example because its file was deleted, it is reopened with backoff.
+// report is called with the failure, and with nil once the socket serves again.
+// With a zero check, the socket is opened once and not watched.
+func (s *Server) Supervise(ctx context.Context, addr string, check time.Duration, report func(error)) {
+	go func() {
+		<-ctx.Done()
+		s.Close()
+	}()
+
+	backoff, failed := minRebindBackoff, false
+	for ctx.Err() == nil {
+		err := s.serveChecked(ctx, addr, check, func() {
+			if failed {
+				slog.Info("Control socket serving again", "addr", addr)
+				report(nil)
+			}
+			failed, backoff = false, minRebindBackoff
+		})
+		if ctx.Err() != nil || errors.Is(err, http.ErrServerClosed) {
+			return
+		}
+		if check <= 0 {
+			slog.Error("Control socket disabled", "addr", addr, "error", err)
+			return
+		}
+
+		slog.Error("Control socket lost, reopening it", "addr", addr, "retryIn", backoff, "error", err)
+		failed = true
+		report(err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxRebindBackoff)
+	}
+}
+
+// serveChecked opens addr and serves on it until it fails or the self check closes it.
+// serving is called once the socket is open.
+func (s *Server) serveChecked(ctx context.Context, addr string, check time.Duration, serving func()) error {
+	l, err := listener.Listen(addr)
+	if err != nil {
+		return err
+	}
+	serving()
+
+	lost := make(chan error, 1)
+	if check > 0 {
+		done := make(chan struct{})
+		defer close(done)
+		go func() {
+			ticker := time.NewTicker(check)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-done:
+					return
+				case <-ticker.C:
+				}
+				if err := Check(ctx, addr); err != nil {
+					lost <- fmt.Errorf("not accepting connections: %w", err)
+					l.Close()
+					return
+				}
+			}
+		}()
+	}
+
+	err = s.srv.Serve(l)
+	select {
+	case err = <-lost:
+	default:
+	}
+	return err
+}
+
+// Check connects to addr and closes the connection, reporting whether a server
+// accepts connections on it
+func Check(ctx context.Context, addr string) error {
+	ctx, cancel := context.WithTimeout(ctx, selfCheckTimeout)
+	defer cancel()
+	conn, err := listener.Dial(ctx, addr)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
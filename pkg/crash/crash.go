@@ -0,0 +1,174 @@
+package crash
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/lucasdecamargo/go-appservice-example/pkg/archive"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/retention"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/state"
+)
+
+// EnvCrashDir overrides the directory crash reports are written to
+const EnvCrashDir = "SVCAPP_CRASH_DIR"
+
+const reportExt = ".json"
+
+// Report describes an application crash
+type Report struct {
+	ID        string    `json:"id"`
+	Time      time.Time `json:"time"`
+	PID       int       `json:"pid"`
+	Args      []string  `json:"args"`
+	GoVersion string    `json:"goVersion"`
+	Panic     string    `json:"panic"`
+	Stack     string    `json:"stack"`
+	Exit      string    `json:"exit,omitempty"`    // How a child killed by a signal or an exception exited
+	Denials   []string  `json:"denials,omitempty"` // SELinux or AppArmor denials logged for the process
+}
+
+// NewReport creates a report for a recovered panic value and its stack trace
+func NewReport(value any, stack []byte) *Report {
+	now := time.Now().UTC()
+	return &Report{
+		ID:        fmt.Sprintf("%s-%d", now.Format("20060102T150405Z"), os.Getpid()),
+		Time:      now,
+		PID:       os.Getpid(),
+		Args:      os.Args,
+		GoVersion: runtime.Version(),
+		Panic:     fmt.Sprint(value),
+		Stack:     string(stack),
+	}
+}
+
+// NewExitReport creates a report for a child process that was killed by a signal or
+// an unhandled exception, which leaves no stack trace behind
+func NewExitReport(pid int, args []string, exit string) *Report {
+	now := time.Now().UTC()
+	return &Report{
+		ID:        fmt.Sprintf("%s-%d", now.Format("20060102T150405Z"), pid),
+		Time:      now,
+		PID:       pid,
+		Args:      args,
+		GoVersion: runtime.Version(),
+		Exit:      exit,
+	}
+}
+
+// DefaultDir returns the crash report directory, honoring EnvCrashDir
+func DefaultDir() string {
+	if dir := os.Getenv(EnvCrashDir); dir != "" {
+		return dir
+	}
+	return filepath.Join(filepath.Dir(state.DefaultPath()), "crashes")
+}
+
+// Write stores the report in dir, compressed with compress, and returns its path
+func Write(dir string, r *Report, compress archive.Options) (string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create crash directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	path, err := archive.WriteFile(filepath.Join(dir, r.ID+reportExt), data, 0o640, compress)
+	if err != nil {
+		return "", fmt.Errorf("failed to write crash report: %w", err)
+	}
+	return path, nil
+}
+
+// Path returns the path of the report with the given ID in dir, whatever its compression
+func Path(dir, id string) (string, error) {
+	for _, ext := range append([]string{""}, archive.Exts...) {
+		path := filepath.Join(dir, id+reportExt+ext)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("crash report %s: %w", id, os.ErrNotExist)
+}
+
+// Load reads the report with the given ID from dir
+func Load(dir, id string) (*Report, error) {
+	path, err := Path(dir, id)
+	if err != nil {
+		return nil, err
+	}
+	f, err := archive.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var r Report
+	if err := json.NewDecoder(f).Decode(&r); err != nil {
+		return nil, fmt.Errorf("failed to parse crash report %s: %w", id, err)
+	}
+	return &r, nil
+}
+
+// List returns the IDs of the reports in dir, oldest first
+func List(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []string
+	for _, e := range entries {
+		name := e.Name()
+		for _, ext := range archive.Exts {
+			name = strings.TrimSuffix(name, ext)
+		}
+		if id, ok := strings.CutSuffix(name, reportExt); ok && !e.IsDir() {
+			ids = append(ids, id)
+		}
+	}
+	slices.Sort(ids)
+	return slices.Compact(ids), nil
+}
+
+// Prune deletes the oldest reports of dir that don't fit p, and returns how many
+// were deleted
+func Prune(dir string, p retention.Policy) (int, error) {
+	ids, err := List(dir)
+	if err != nil {
+		return 0, err
+	}
+
+	paths := make([]string, 0, len(ids))
+	records := make([]retention.Record, 0, len(ids))
+	for _, id := range ids {
+		path, err := Path(dir, id)
+		if err != nil {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		paths = append(paths, path)
+		records = append(records, retention.Record{Time: info.ModTime(), Size: info.Size()})
+	}
+
+	n := p.Expired(records, time.Now())
+	for i, path := range paths[:n] {
+		if err := os.Remove(path); err != nil {
+			return i, fmt.Errorf("failed to delete crash report: %w", err)
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,69 @@
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+)
+
+// ErrAdoptedExited reports that an adopted process exited. Its exit status is only
+// known on Windows, elsewhere only the parent of a process can collect it.
+var ErrAdoptedExited = errors.New("adopted process exited")
+
+// adoptProcess supervises the running process pid as the child, until it exits, and
+// returns its PID. It returns 0 when the process can't be adopted, for a new child to
+// be spawned instead.
+func (d *Daemon) adoptProcess(pid int) (int, error) {
+	p, err := findAdoptable(pid)
+	if err != nil {
+		slog.Warn("Process not adopted, spawning a new child", "pid", pid, "error", err)
+		return 0, nil
+	}
+
+	d.mu.Lock()
+	if d.stopping {
+		d.mu.Unlock()
+		p.Release()
+		return 0, nil
+	}
+	d.cmd, d.notice = &exec.Cmd{Process: p}, nil
+	d.exited = make(chan struct{})
+	exited := d.exited
+	d.state.ChildPID = pid
+	d.state.Crashed = ""
+	d.state.Ports = nil
+	d.saveState()
+	d.mu.Unlock()
+
+	slog.Info("Adopted running process as the child", "pid", pid)
+	if err := tetherStarted(p); err != nil {
+		slog.Warn("Failed to tie the adopted process to the supervisor, it may outlive a crash", "pid", pid, "error", err)
+	}
+	d.emit(EventStarted, pid, nil)
+	d.armRecycle(exited)
+	go d.pollPorts(pid, exited)
+	d.markReady()
+
+	err = waitAdopted(p)
+	close(exited)
+	return pid, err
+}
+
+// findAdoptable returns the process pid, checking that it runs and isn't the
+// supervisor itself
+func findAdoptable(pid int) (*os.Process, error) {
+	if pid <= 0 || pid == os.Getpid() {
+		return nil, fmt.Errorf("invalid PID %d", pid)
+	}
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return nil, err
+	}
+	if !running(p) {
+		p.Release()
+		return nil, os.ErrProcessDone
+	}
+	return p, nil
+}
@@ -0,0 +1,31 @@
+//go:build !windows
+
+package daemon
+
+import (
+	"errors"
+	"os"
+	"syscall"
+	"time"
+)
+
+// adoptPollInterval is how often an adopted process is checked for exit
+const adoptPollInterval = 500 * time.Millisecond
+
+// running reports whether p still exists, even when owned by another user
+func running(p *os.Process) bool {
+	err := p.Signal(syscall.Signal(0))
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
+
+// waitAdopted polls until the adopted process p exits, as it isn't a child to wait for
+func waitAdopted(p *os.Process) error {
+	ticker := time.NewTicker(adoptPollInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if !running(p) {
+			break
+		}
+	}
+	return ErrAdoptedExited
+}
@@ -0,0 +1,35 @@
+package daemon
+
+import (
+	"fmt"
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// stillActive is the exit code GetExitCodeProcess reports for a running process
+const stillActive = 259
+
+// running reports whether p has not exited yet
+func running(p *os.Process) bool {
+	h, err := windows.OpenProcess(windows.PROCESS_QUERY_LIMITED_INFORMATION, false, uint32(p.Pid))
+	if err != nil {
+		return false
+	}
+	defer windows.CloseHandle(h)
+	var code uint32
+	return windows.GetExitCodeProcess(h, &code) == nil && code == stillActive
+}
+
+// waitAdopted waits for the adopted process p to exit. Windows lets any process with
+// access to it collect its exit status.
+func waitAdopted(p *os.Process) error {
+	ps, err := p.Wait()
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrAdoptedExited, err)
+	}
+	if !ps.Success() {
+		return fmt.Errorf("%w with %s", ErrAdoptedExited, ps)
+	}
+	return nil
+}
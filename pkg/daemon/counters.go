@@ -0,0 +1,31 @@
+package daemon
+
+import "sync"
+
+// counters holds the functions reporting the counters registered by the caller
+type counters struct {
+	mu    sync.Mutex
+	funcs map[string]func() uint64
+}
+
+// RegisterCounter adds a counter named name, such as "output.stdout.discardedBytes",
+// to the Counters report
+func (d *Daemon) RegisterCounter(name string, f func() uint64) {
+	d.counters.mu.Lock()
+	defer d.counters.mu.Unlock()
+	if d.counters.funcs == nil {
+		d.counters.funcs = make(map[string]func() uint64)
+	}
+	d.counters.funcs[name] = f
+}
+
+// Counters returns the current value of the registered counters
+func (d *Daemon) Counters() map[string]uint64 {
+	d.counters.mu.Lock()
+	defer d.counters.mu.Unlock()
+	values := make(map[string]uint64, len(d.counters.funcs))
+	for name, f := range d.counters.funcs {
+		values[name] = f()
+	}
+	return values
+}
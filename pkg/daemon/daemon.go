@@ -1,38 +1,208 @@
 package daemon
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
+	"maps"
 	"os"
 	"os/exec"
+	"slices"
+	"strings"
 	"sync"
-	"syscall"
 	"time"
 
+	"github.com/lucasdecamargo/go-appservice-example/pkg/archive"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/history"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/jobs"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/kv"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/logexport"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/lsm"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/metrics"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/mountns"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/netcheck"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/pidfile"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/priority"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/rlimit"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/secretfd"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/state"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/store"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/tuning"
 	"github.com/lucasdecamargo/kardianos"
 )
 
 const (
 	defaultExitTimeout = 10 * time.Second
+	readyPollInterval  = 100 * time.Millisecond
+	startRetryDelay    = 1 * time.Second
+	healthTimeout      = 5 * time.Second
+
+	// EnvReadyFile names the environment variable holding the path the child
+	// creates to report readiness. See NotifyReady.
+	EnvReadyFile = "SVCAPP_READY_FILE"
+)
+
+var (
+	// ErrStartTimeout is returned when the child does not become ready within StartTimeout
+	ErrStartTimeout = errors.New("child startup timeout")
+	// ErrNotStarted is returned by Stop when Start was never called
+	ErrNotStarted = errors.New("daemon not started")
+	// ErrNotRunning is returned when an action requires a running child
+	ErrNotRunning = errors.New("child not running")
+	// ErrReloadUnsupported is returned by Reload when no Reconfigure hook is set
+	ErrReloadUnsupported = errors.New("reload not supported")
+	// ErrSnapshotUnsupported is returned when no Snapshots hook is set for the action
+	ErrSnapshotUnsupported = errors.New("snapshots not supported")
+
+	errExitTimeout = errors.New("program exit timeout")
+)
+
+// StartFailurePolicy determines what happens when the child fails to become ready
+type StartFailurePolicy int
+
+const (
+	// StartFailureStop stops the service, leaving restarts to the service manager
+	StartFailureStop StartFailurePolicy = iota
+	// StartFailureRetry spawns a new child, up to StartRetries times
+	StartFailureRetry
 )
 
 // DaemonConfig holds configuration for the daemon process supervisor
 type DaemonConfig struct {
+	Service     string        // Name of the service, with the instance as in svcapp@a, reported in the state
 	Executable  string        // Path to the executable to run
 	Args        []string      // Command line arguments
 	EnvVars     []string      // Environment variables to set
 	OutWriter   io.Writer     // Stdout writer
 	ErrWriter   io.Writer     // Stderr writer
 	ExitTimeout time.Duration // Timeout for graceful shutdown
+
+	// DrainTimeout extends ExitTimeout, up to this long in total, for as long as the
+	// child still holds established connections. Zero or up to ExitTimeout disables it.
+	DrainTimeout time.Duration
+
+	// StartTimeout bounds the time the child has to call NotifyReady.
+	// Zero disables readiness tracking: the child is ready once spawned.
+	StartTimeout   time.Duration
+	OnStartFailure StartFailurePolicy // Policy applied when StartTimeout is exceeded
+	StartRetries   int                // Retries allowed by StartFailureRetry
+
+	// MaxRuntime recycles the child after it has run this long, zero to disable.
+	// A random extra of up to MaxRuntimeJitter spreads recycles across a fleet.
+	MaxRuntime       time.Duration
+	MaxRuntimeJitter time.Duration
+
+	Store   store.Store // Persists the daemon state and history, nil to disable
+	PIDFile string      // Path of the PID file held while running, empty to disable
+
+	// Reconfigure rebuilds the child arguments and environment on Reload, nil to disable
+	Reconfigure func() (args, env []string, err error)
+
+	// Sources fingerprints the files the next child is built from, recorded in the state
+	// with its arguments and environment, nil to disable
+	Sources func() state.Sources
+
+	// Lean disables the latency metrics and the history, for memory-constrained devices
+	Lean bool
+
+	// OnLifecycle is called on each child lifecycle transition, nil to disable. It must not block.
+	OnLifecycle func(LifecycleEvent)
+
+	// WaitForNetwork is checked before each child start, for up to NetworkTimeout,
+	// zero to wait until it passes
+	WaitForNetwork netcheck.Check
+	NetworkTimeout time.Duration
+
+	// Secrets are passed to the child as inherited file descriptors, see package secretfd
+	Secrets []secretfd.Secret
+
+	// Jobs are short-lived commands run on a schedule next to the child
+	Jobs []jobs.Job
+
+	// CrashDir receives a crash report when the child is killed by a signal or an
+	// unhandled exception, empty to disable
+	CrashDir         string
+	CrashCompression archive.Options
+
+	// Retention bounds the history events and crash reports kept, pruned hourly
+	Retention Retention
+
+	// Limits are applied to the child right after it starts, for when no service
+	// manager sets them on the daemon for the child to inherit
+	Limits rlimit.Limits
+
+	// Heartbeat pings the child through its stdin, for children that can't serve a
+	// health endpoint
+	Heartbeat Heartbeat
+
+	// LameDuck warns the child some time before it is stopped or restarted
+	LameDuck LameDuck
+
+	// Traffic takes the child out of an external load balancer around its stops and
+	// restarts
+	Traffic Traffic
+
+	// Snapshots takes a snapshot of the service data before reloads and updates, for
+	// restoring it when they are rolled back
+	Snapshots Snapshots
+
+	// Confinement is the SELinux context or AppArmor profile the child is executed in,
+	// on Linux
+	Confinement lsm.Label
+
+	// Mounts gives the child its own mount namespace, on Linux
+	Mounts mountns.Spec
+
+	// ShutdownBoost raises the child priority once it is asked to stop or restart, so it
+	// finishes flushing within the exit timeout on loaded hosts
+	ShutdownBoost priority.Boost
+
+	// Logs keeps the latest supervisor and child log lines for export, nil to disable
+	Logs *logexport.Ring
+
+	// KV is the key/value store kept for the child, nil to disable
+	KV *kv.Store
+
+	// Ports finds the TCP ports the child listens on, from its output or its sockets
+	Ports PortDetection
+
+	// Adopt is the PID of a running process to supervise as the first child instead of
+	// spawning one, zero to spawn. Its successors are spawned as usual.
+	Adopt int
 }
 
 // Daemon implements a process supervisor that can start, monitor, and stop child processes
 type Daemon struct {
 	DaemonConfig
-	wg     sync.WaitGroup
-	cmd    *exec.Cmd
-	retval error
+	wg         sync.WaitGroup
+	mu         sync.Mutex
+	service    kardianos.Service
+	cmd        *exec.Cmd
+	exited     chan struct{} // Closed when the current child exits
+	notice     *os.File      // Lame duck pipe of the current child, nil when disabled
+	started    bool
+	stopping   bool
+	restarting bool
+	reason     string // Reason of the pending restart request
+	restore    string // Snapshot restored before the next child starts
+	oomKills   int64  // Out-of-memory kills in the cgroup when the current child started
+	retval     error
+	state      state.State
+	schedule   *time.Timer
+	stopCtx    context.Context // Canceled when the daemon stops
+	stopCancel context.CancelFunc
+
+	startRequested time.Time                     // Pending start or restart request
+	latency        map[string]*metrics.Histogram // Lifecycle latencies by history event kind, unused when lean
+	footprints     footprints                    // Subsystems reported by Resources
+	counters       counters                      // Counters reported by Counters
+	jobs           *jobs.Scheduler               // Runs the Jobs, nil without any
+	jobsDone       chan struct{}                 // Closed once the jobs are stopped
+
+	stopOnce sync.Once
+	stopErr  error
 }
 
 // NewDaemon creates a new daemon instance with the given configuration
@@ -40,7 +210,23 @@ func NewDaemon(cfg *DaemonConfig) *Daemon {
 	if cfg.ExitTimeout == 0 {
 		cfg.ExitTimeout = defaultExitTimeout
 	}
-	return &Daemon{DaemonConfig: *cfg}
+	return &Daemon{
+		DaemonConfig: *cfg,
+		latency: map[string]*metrics.Histogram{
+			history.KindStart: metrics.NewHistogram(),
+			history.KindStop:  metrics.NewHistogram(),
+		},
+	}
+}
+
+// NotifyReady reports readiness to the supervising daemon.
+// It is a no-op when the process is not supervised.
+func NotifyReady() error {
+	path := os.Getenv(EnvReadyFile)
+	if path == "" {
+		return nil
+	}
+	return os.WriteFile(path, nil, 0o600)
 }
 
 // Start begins supervising the child process
@@ -53,20 +239,47 @@ func (d *Daemon) Start(s kardianos.Service) error {
 		d.Executable = executable
 	}
 
-	d.cmd = exec.Command(d.Executable, d.Args...)
-
-	// Setup environment and IO
-	if len(d.EnvVars) > 0 {
-		d.cmd.Env = append(os.Environ(), d.EnvVars...)
-	}
 	if d.OutWriter == nil {
 		d.OutWriter = os.Stdout
 	}
 	if d.ErrWriter == nil {
 		d.ErrWriter = os.Stderr
 	}
-	d.cmd.Stdout = d.OutWriter
-	d.cmd.Stderr = d.ErrWriter
+	if len(d.Jobs) > 0 {
+		sched, err := jobs.New(d.Jobs, d.recordJob)
+		if err != nil {
+			return err
+		}
+		d.jobs = sched
+	}
+
+	// Refuse to run next to a live instance, but recover from a stale PID file
+	if d.PIDFile != "" {
+		if err := pidfile.Acquire(d.PIDFile); errors.Is(err, pidfile.ErrRunning) {
+			return err
+		} else if err != nil {
+			slog.Warn("PID file not written", "error", err)
+		}
+	}
+
+	prev := d.loadState()
+
+	d.mu.Lock()
+	d.service = s
+	d.started = true
+	d.stopCtx, d.stopCancel = context.WithCancel(context.Background())
+	d.startRequested = time.Now()
+	d.state = state.State{Service: d.Service, PID: os.Getpid(), StartedAt: time.Now()}
+	if prev != nil {
+		d.state.Child = prev.Child // Compared with the first child to report drift across restarts
+	}
+	d.restoreRestarts(prev)
+	d.saveState()
+	d.mu.Unlock()
+
+	d.restoreSchedule(prev)
+	d.startJobs()
+	d.startPruner()
 
 	d.wg.Add(1)
 	go d.superviseProcess(s)
@@ -74,17 +287,315 @@ func (d *Daemon) Start(s kardianos.Service) error {
 	return nil
 }
 
-// Stop gracefully terminates the child process
+// Stop gracefully terminates the child process. It returns ErrNotStarted if Start
+// was never called, and repeated calls return the result of the first one.
 func (d *Daemon) Stop(s kardianos.Service) error {
-	if d.cmd.Process == nil {
+	d.mu.Lock()
+	started := d.started
+	d.mu.Unlock()
+
+	if !started {
+		return ErrNotStarted
+	}
+
+	d.stopOnce.Do(func() {
+		d.stopErr = d.stop()
+		d.waitJobs()
+		if d.PIDFile != "" {
+			pidfile.Release(d.PIDFile)
+		}
+	})
+	return d.stopErr
+}
+
+// stop signals the child process and waits for it to terminate
+func (d *Daemon) stop() error {
+	d.mu.Lock()
+	d.stopping = true
+	d.stopCancel()
+	d.cancelScheduleLocked()
+	cmd, notice, exited := d.cmd, d.notice, d.exited
+	d.mu.Unlock()
+
+	if cmd == nil || cmd.Process == nil {
+		return nil
+	}
+	defer d.emit(EventStopped, cmd.Process.Pid, nil)
+
+	d.deregister(cmd.Process.Pid)
+	begin := time.Now()
+	d.boostShutdown(cmd.Process)
+	d.enterLameDuck(cmd, notice, exited)
+	if err := terminate(cmd.Process); err != nil && !errors.Is(err, os.ErrProcessDone) {
+		return fmt.Errorf("failed to terminate child: %w", err)
+	}
+
+	err := d.waitForProcessTermination(cmd.Process.Pid)
+
+	// The child exit status is not a stop failure, only a timeout is
+	var stopErr error
+	if errors.Is(err, errExitTimeout) {
+		stopErr = err
+	}
+	d.record(history.KindStop, time.Since(begin), stopErr)
+	return err
+}
+
+// boostShutdown raises the priority of the child process p before asking it to exit
+func (d *Daemon) boostShutdown(p *os.Process) {
+	if d.ShutdownBoost.IsZero() {
+		return
+	}
+	if err := priority.Apply(p.Pid, d.ShutdownBoost); err != nil {
+		slog.Warn("Failed to boost the child priority for its shutdown", "pid", p.Pid, "error", err)
+		return
+	}
+	slog.Info("Boosted the child priority for its shutdown", "pid", p.Pid, "boost", d.ShutdownBoost.String())
+}
+
+// RestartChild gracefully stops the current child and lets the supervisor spawn a new
+// one, counting a manual restart
+func (d *Daemon) RestartChild() error {
+	return d.RestartChildFor(RestartManual)
+}
+
+// RestartChildFor is RestartChild counting a restart for reason, such as RestartUpgrade
+func (d *Daemon) RestartChildFor(reason string) error {
+	d.mu.Lock()
+	if d.stopping || d.cmd == nil || d.cmd.Process == nil {
+		d.mu.Unlock()
+		return ErrNotRunning
+	}
+	d.restarting = true
+	d.reason = reason
+	d.startRequested = time.Now()
+	cmd, notice, exited := d.cmd, d.notice, d.exited
+	d.mu.Unlock()
+
+	d.deregister(cmd.Process.Pid)
+	d.boostShutdown(cmd.Process)
+	d.enterLameDuck(cmd, notice, exited)
+	if err := terminate(cmd.Process); err != nil && !errors.Is(err, os.ErrProcessDone) {
+		return fmt.Errorf("failed to terminate child: %w", err)
+	}
+
+	begin := time.Now()
+	select {
+	case <-exited:
+		return nil
+	case <-time.After(d.ExitTimeout):
+		if d.awaitDrain(cmd.Process.Pid, begin, exited) {
+			return nil
+		}
+		cmd.Process.Kill()
+		return errExitTimeout
+	}
+}
+
+// Reload rebuilds the child arguments and environment with Reconfigure and restarts
+// the child with them, after taking a snapshot when Snapshots are set
+func (d *Daemon) Reload() error {
+	if d.Reconfigure == nil {
+		return ErrReloadUnsupported
+	}
+	args, env, err := d.Reconfigure()
+	if err != nil {
+		return fmt.Errorf("failed to reload: %w", err)
+	}
+	if _, err := d.SnapshotBefore(SnapshotReload); err != nil {
+		return fmt.Errorf("failed to reload: %w", err)
+	}
+
+	d.mu.Lock()
+	d.Args, d.EnvVars = args, env
+	d.mu.Unlock()
+
+	return d.RestartChild()
+}
+
+// Status returns a snapshot of the daemon state
+func (d *Daemon) Status() state.State {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	s := d.state
+	if s.Scheduled != nil {
+		scheduled := *s.Scheduled
+		s.Scheduled = &scheduled
+	}
+	if s.Update != nil {
+		update := *s.Update
+		s.Update = &update
+	}
+	s.RestartReasons = maps.Clone(s.RestartReasons)
+	s.ControlDown = maps.Clone(s.ControlDown)
+	if err := store.DegradedBy(d.Store); err != nil {
+		s.Degraded = err.Error()
+	}
+	return s
+}
+
+// Healthy reports whether the supervisor is responsive. It fails when the
+// supervisor state stays locked for longer than healthTimeout.
+func (d *Daemon) Healthy() error {
+	unlocked := make(chan struct{})
+	go func() {
+		d.mu.Lock()
+		d.mu.Unlock()
+		close(unlocked)
+	}()
+
+	select {
+	case <-unlocked:
 		return nil
+	case <-time.After(healthTimeout):
+		return fmt.Errorf("supervisor unresponsive for %v", healthTimeout)
 	}
+}
+
+// Metrics returns a snapshot of the lifecycle latency histograms, by history event kind.
+// It is empty in lean mode.
+func (d *Daemon) Metrics() map[string]metrics.Snapshot {
+	if d.Lean {
+		return map[string]metrics.Snapshot{}
+	}
+	m := make(map[string]metrics.Snapshot, len(d.latency))
+	for kind, h := range d.latency {
+		m[kind] = h.Snapshot()
+	}
+	return m
+}
+
+// markReady marks the child ready, records the latency of the pending start or restart
+// request, if any, and puts the child into the load balancer
+func (d *Daemon) markReady() {
+	d.mu.Lock()
+	begin := d.startRequested
+	d.startRequested = time.Time{}
+	pid := d.state.ChildPID
+	d.state.Ready = pid != 0
+	d.saveState()
+	d.mu.Unlock()
+
+	d.emit(EventReady, pid, nil)
+	if !begin.IsZero() {
+		d.record(history.KindStart, time.Since(begin), nil)
+	}
+	d.register(pid)
+}
+
+// record observes a lifecycle latency and appends it to the history
+func (d *Daemon) record(kind string, took time.Duration, err error) {
+	if h, ok := d.latency[kind]; ok && !d.Lean {
+		h.Observe(took)
+	}
+
+	e := history.Event{Time: time.Now(), Kind: kind, Duration: took}
+	if err != nil {
+		e.Error = err.Error()
+	}
+	d.appendEvent(e)
+}
+
+// checkDrift logs and records how the arguments and environment of the next child
+// differ from the previous one, by name only. It must be called with d.mu held.
+func (d *Daemon) checkDrift(spec *state.Spec) {
+	changes := d.state.Child.Diff(spec)
+	d.state.Child = spec
+	if len(changes) == 0 {
+		return
+	}
+
+	detail := strings.Join(changes, ", ")
+	slog.Info("Child configuration changed", "changes", detail)
+	d.appendEvent(history.Event{Time: time.Now(), Kind: history.KindDrift, Detail: detail})
+}
+
+// appendEvent adds an event to the stored history
+func (d *Daemon) appendEvent(e history.Event) {
+	if d.Store == nil || d.Lean {
+		return
+	}
+	if err := d.Store.AppendEvent(e); err != nil {
+		slog.Warn("Failed to record history", "error", err)
+	}
+}
+
+// loadState reads the state persisted by a previous run, if any
+func (d *Daemon) loadState() *state.State {
+	if d.Store == nil {
+		return nil
+	}
+	prev, err := d.Store.LoadState()
+	if err != nil {
+		slog.Warn("Failed to load daemon state", "error", err)
+		return nil
+	}
+	return prev
+}
+
+// saveState persists the daemon state. It must be called with d.mu held.
+func (d *Daemon) saveState() {
+	if d.Store == nil {
+		return
+	}
+	if err := d.Store.SaveState(&d.state); err != nil {
+		slog.Warn("Failed to save daemon state", "error", err)
+	}
+}
+
+// newCommand builds the child command along with the path it must create once ready
+func (d *Daemon) newCommand() (*exec.Cmd, string, error) {
+	d.mu.Lock()
+	args, env := slices.Clone(d.Args), slices.Clone(d.EnvVars) // Replaced by Reload
+	d.mu.Unlock()
 
-	if err := d.cmd.Process.Signal(syscall.SIGTERM); err != nil && !errors.Is(err, os.ErrProcessDone) {
-		return fmt.Errorf("failed to send SIGTERM: %w", err)
+	cmd := exec.Command(d.Executable, args...)
+	configureCommand(cmd)
+	tether(cmd)
+
+	// Setup environment and IO
+	readyFile := ""
+	if d.StartTimeout > 0 {
+		f, err := os.CreateTemp(mountns.TempDir(d.Mounts), "svcapp-ready-*")
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to create ready file: %w", err)
+		}
+		readyFile = f.Name()
+		f.Close()
+		os.Remove(readyFile) // The child recreates it when ready
+		env = append(env, EnvReadyFile+"="+readyFile)
+	}
+	if d.KV != nil {
+		env = append(env, kv.EnvFile+"="+d.KV.Path())
+	}
+	for _, secret := range d.Secrets {
+		f, err := secretfd.Open(secret)
+		if err != nil {
+			closeFiles(cmd.ExtraFiles)
+			return nil, "", err
+		}
+		env = append(env, secretfd.Env(secret.Name, 3+len(cmd.ExtraFiles)))
+		cmd.ExtraFiles = append(cmd.ExtraFiles, f)
+	}
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	cmd.Stdout = d.portWriter(cmd, d.OutWriter)
+	cmd.Stderr = d.portWriter(cmd, d.ErrWriter)
+	if err := mountns.Wrap(cmd, d.Mounts); err != nil {
+		closeFiles(cmd.ExtraFiles)
+		return nil, "", err
 	}
 
-	return d.waitForProcessTermination()
+	return cmd, readyFile, nil
+}
+
+// closeFiles closes the files passed to the child
+func closeFiles(files []*os.File) {
+	for _, f := range files {
+		f.Close()
+	}
 }
 
 // superviseProcess runs the child process and handles its lifecycle
@@ -93,23 +604,225 @@ func (d *Daemon) superviseProcess(s kardianos.Service) {
 		d.wg.Done()
 		d.handleProcessExit(s)
 	}()
-	d.retval = d.cmd.Run()
+
+	retries, adopt := 0, d.Adopt
+	for {
+		var pid int
+		if adopt != 0 {
+			pid, d.retval = d.adoptProcess(adopt)
+			adopt = 0
+			if pid == 0 && d.retval == nil {
+				continue // Not adopted, spawn a child instead
+			}
+		} else {
+			pid, d.retval = d.runProcess()
+		}
+		d.retval = explainExit(d.retval)
+		d.reportExit(pid, d.retval)
+		switch {
+		case d.restartRequested(pid):
+			retries = 0
+		case errors.Is(d.retval, ErrStartTimeout) && d.shouldRetryStart(retries):
+			retries++
+		default:
+			return
+		}
+	}
+}
+
+// runProcess spawns one child and waits for it to exit, returning its PID
+func (d *Daemon) runProcess() (int, error) {
+	d.waitForNetwork()
+	d.restorePending()
+
+	cmd, readyFile, err := d.newCommand()
+	if err != nil {
+		return 0, err
+	}
+	defer closeFiles(cmd.ExtraFiles) // The child holds its own copies
+
+	hb, err := d.attachHeartbeat(cmd)
+	if err != nil {
+		return 0, err
+	}
+	if hb != nil {
+		defer closeFiles(hb.child)
+	}
+	notice, noticeChild, err := d.attachLameDuck(cmd)
+	if err != nil {
+		if hb != nil {
+			hb.close()
+		}
+		return 0, err
+	}
+	if notice != nil {
+		defer closeFiles([]*os.File{notice, noticeChild})
+	}
+
+	oomKills := tuning.OOMKills()
+	var sources state.Sources
+	if d.Sources != nil {
+		sources = d.Sources()
+	}
+	d.mu.Lock()
+	if d.stopping {
+		d.mu.Unlock()
+		if hb != nil {
+			hb.close()
+		}
+		return 0, nil
+	}
+	spec := state.NewSpec(d.Args, d.EnvVars)
+	spec.Sources = sources
+	d.checkDrift(spec)
+	d.cmd, d.notice = cmd, notice
+	d.oomKills = oomKills
+	d.exited = make(chan struct{})
+	exited := d.exited
+	err = lsm.Exec(d.Confinement, cmd.Start)
+	if err == nil {
+		d.state.ChildPID = cmd.Process.Pid
+		d.state.Ready = false
+		d.state.Crashed = ""
+		d.state.Ports = nil
+		d.saveState()
+	}
+	d.mu.Unlock()
+	if err != nil {
+		if hb != nil {
+			hb.close()
+		}
+		return 0, fmt.Errorf("failed to start child: %w", err)
+	}
+	pid := cmd.Process.Pid
+	if err := rlimit.Apply(pid, d.Limits); err != nil {
+		slog.Warn("Failed to apply the resource limits to the child", "pid", pid, "error", err)
+	}
+	if err := tetherStarted(cmd.Process); err != nil {
+		slog.Warn("Failed to tie the child to the supervisor, it may outlive a crash", "pid", pid, "error", err)
+	}
+	d.emit(EventStarted, pid, nil)
+
+	exit := make(chan error, 1)
+	go func() {
+		exit <- cmd.Wait()
+		close(exited)
+	}()
+	d.armRecycle(exited)
+	go d.pollPorts(pid, exited)
+
+	if readyFile == "" {
+		d.markReady()
+	} else if err := d.waitReady(readyFile, exit); err != nil {
+		cmd.Process.Kill()
+		<-exit
+		if hb != nil {
+			hb.close()
+		}
+		return pid, err
+	}
+	if hb != nil {
+		go d.monitorHeartbeat(hb, exited, pid)
+	}
+
+	return pid, <-exit
+}
+
+// restartRequested consumes a pending RestartChild request for the child that exited
+func (d *Daemon) restartRequested(pid int) bool {
+	d.mu.Lock()
+	restart := d.restarting && !d.stopping
+	d.restarting = false
+	if restart {
+		d.state.Restarts++
+		d.countRestart(d.reason)
+		d.saveState()
+	}
+	d.mu.Unlock()
+
+	if restart {
+		d.emit(EventRestarted, pid, nil)
+	}
+	return restart
+}
+
+// waitReady polls for the ready file until the child creates it, exits, or times out
+func (d *Daemon) waitReady(readyFile string, exit chan error) error {
+	defer os.Remove(readyFile)
+
+	timeout := time.After(d.StartTimeout)
+	ticker := time.NewTicker(readyPollInterval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := os.Stat(readyFile); err == nil {
+			d.markReady()
+			return nil
+		}
+
+		select {
+		case err := <-exit:
+			exit <- err // Exited before becoming ready, let the caller collect it
+			return nil
+		case <-timeout:
+			return fmt.Errorf("%w after %v", ErrStartTimeout, d.StartTimeout)
+		case <-ticker.C:
+		}
+	}
+}
+
+// shouldRetryStart applies the start-failure policy after a startup timeout
+func (d *Daemon) shouldRetryStart(attempt int) bool {
+	if d.OnStartFailure != StartFailureRetry || attempt >= d.StartRetries {
+		return false
+	}
+
+	time.Sleep(startRetryDelay)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return !d.stopping
 }
 
 // handleProcessExit manages what happens when the child process exits
 func (d *Daemon) handleProcessExit(s kardianos.Service) {
-	if !kardianos.Interactive() {
+	d.mu.Lock()
+	d.state.ChildPID = 0
+	d.state.Ready = false
+	d.state.Ports = nil
+	d.saveState()
+	d.mu.Unlock()
+
+	stopService(s)
+}
+
+// stopService asks the service manager to stop the service, or terminates the
+// current process when running interactively
+func stopService(s kardianos.Service) {
+	if !Interactive(s) {
 		s.Stop() // In service mode, stop the service when child exits
+	} else if i, ok := s.(interface{ Interrupt() error }); ok {
+		i.Interrupt() // Test doubles record the interruption instead
 	} else {
 		// In interactive mode, terminate the current process
-		if proc, err := os.FindProcess(os.Getpid()); err == nil {
-			proc.Signal(syscall.SIGTERM)
-		}
+		interruptSelf()
+	}
+}
+
+// Interactive reports whether s runs from a terminal rather than under a service
+// manager. Services implementing Interactive, such as the daemontest fakes, decide
+// for themselves; the others defer to kardianos.Interactive.
+func Interactive(s kardianos.Service) bool {
+	if i, ok := s.(interface{ Interactive() bool }); ok {
+		return i.Interactive()
 	}
+	return kardianos.Interactive()
 }
 
-// waitForProcessTermination waits for the process to exit with timeout
-func (d *Daemon) waitForProcessTermination() error {
+// waitForProcessTermination waits for the child pid, just asked to exit, to exit within
+// the exit timeout, extended while it drains its connections
+func (d *Daemon) waitForProcessTermination(pid int) error {
+	begin := time.Now()
 	exit := make(chan struct{})
 	go func() {
 		d.wg.Wait()
@@ -120,9 +833,14 @@ func (d *Daemon) waitForProcessTermination() error {
 	case <-exit:
 		return d.retval
 	case <-time.After(d.ExitTimeout):
-		if d.cmd.Process != nil {
+		if d.awaitDrain(pid, begin, exit) {
+			return d.retval
+		}
+		d.mu.Lock()
+		if d.cmd != nil && d.cmd.Process != nil {
 			d.cmd.Process.Kill()
 		}
-		return errors.New("program exit timeout")
+		d.mu.Unlock()
+		return errExitTimeout
 	}
 }
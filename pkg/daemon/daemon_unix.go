@@ -0,0 +1,48 @@
+//go:build !windows
+
+package daemon
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// configureCommand prepares the child command before it is started
+func configureCommand(cmd *exec.Cmd) {}
+
+// terminate asks the child to exit gracefully with SIGTERM
+func terminate(p *os.Process) error {
+	return p.Signal(syscall.SIGTERM)
+}
+
+// interruptSelf asks the current process to exit gracefully with SIGTERM
+func interruptSelf() error {
+	proc, err := os.FindProcess(os.Getpid())
+	if err != nil {
+		return err
+	}
+	return proc.Signal(syscall.SIGTERM)
+}
+
+// passFile makes f available to the child and returns the descriptor it gets there
+func passFile(cmd *exec.Cmd, f *os.File) (uintptr, error) {
+	cmd.ExtraFiles = append(cmd.ExtraFiles, f)
+	return uintptr(2 + len(cmd.ExtraFiles)), nil
+}
+
+// ParseSignal returns the signal named name, such as "SIGURG" or "URG"
+func ParseSignal(name string) (os.Signal, error) {
+	name = strings.ToUpper(name)
+	if !strings.HasPrefix(name, "SIG") {
+		name = "SIG" + name
+	}
+	if sig := unix.SignalNum(name); sig != 0 {
+		return sig, nil
+	}
+	return nil, fmt.Errorf("unknown signal %q", name)
+}
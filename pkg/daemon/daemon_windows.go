@@ -0,0 +1,46 @@
+package daemon
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"syscall"
+
+	"golang.org/x/sys/windows"
+)
+
+// configureCommand starts the child in its own process group, so console control
+// events can target it without reaching the supervisor
+func configureCommand(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{CreationFlags: windows.CREATE_NEW_PROCESS_GROUP}
+}
+
+// terminate asks the child to exit gracefully with CTRL_BREAK, which Go programs
+// receive as os.Interrupt. Windows has no SIGTERM, and control events need a shared
+// console, so a child that can't be reached that way is killed.
+func terminate(p *os.Process) error {
+	if err := windows.GenerateConsoleCtrlEvent(windows.CTRL_BREAK_EVENT, uint32(p.Pid)); err != nil {
+		return p.Kill()
+	}
+	return nil
+}
+
+// interruptSelf asks the current process to exit gracefully with CTRL_BREAK
+func interruptSelf() error {
+	return windows.GenerateConsoleCtrlEvent(windows.CTRL_BREAK_EVENT, 0)
+}
+
+// passFile makes f inheritable by the child and returns the handle it gets there
+func passFile(cmd *exec.Cmd, f *os.File) (uintptr, error) {
+	h := windows.Handle(f.Fd())
+	if err := windows.SetHandleInformation(h, windows.HANDLE_FLAG_INHERIT, windows.HANDLE_FLAG_INHERIT); err != nil {
+		return 0, err
+	}
+	cmd.SysProcAttr.AdditionalInheritedHandles = append(cmd.SysProcAttr.AdditionalInheritedHandles, syscall.Handle(h))
+	return uintptr(h), nil
+}
+
+// ParseSignal fails, Windows has no signals to send to the child
+func ParseSignal(name string) (os.Signal, error) {
+	return nil, errors.New("signals are not supported on Windows")
+}
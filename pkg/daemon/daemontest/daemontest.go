@@ -0,0 +1,184 @@
+// Package daemontest provides test doubles for kardianos services and programs, to
+// unit test kardianos.Interface implementations such as daemon.Daemon without a
+// service manager.
+package daemontest
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/lucasdecamargo/kardianos"
+)
+
+// Actions recorded by Service
+const (
+	ActionStart     = "start"
+	ActionStop      = "stop"
+	ActionRestart   = "restart"
+	ActionInstall   = "install"
+	ActionUninstall = "uninstall"
+	ActionInterrupt = "interrupt"
+)
+
+// Service is a fake kardianos.Service recording the actions requested on it. Run
+// starts the program and stops it once Stop or Interrupt is called, like a service
+// manager would. Interactive is controlled with SetInteractive and is false by default.
+type Service struct {
+	Name string
+	Errs map[string]error // Error returned by each action, nil for success
+
+	program     kardianos.Interface
+	mu          sync.Mutex
+	actions     []string
+	interactive bool
+	status      kardianos.Status
+	stop        chan struct{}
+	stopOnce    sync.Once
+	logger      *Logger
+}
+
+// NewService creates a fake service running program, which may be nil
+func NewService(program kardianos.Interface) *Service {
+	return &Service{
+		Name:    "daemontest",
+		program: program,
+		status:  kardianos.StatusStopped,
+		stop:    make(chan struct{}),
+		logger:  &Logger{},
+	}
+}
+
+// Run starts the program and blocks until the service is stopped or interrupted,
+// then stops the program
+func (s *Service) Run() error {
+	if s.program == nil {
+		return fmt.Errorf("daemontest: no program to run")
+	}
+	if err := s.program.Start(s); err != nil {
+		return err
+	}
+	s.setStatus(kardianos.StatusRunning)
+	<-s.stop
+	defer s.setStatus(kardianos.StatusStopped)
+	return s.program.Stop(s)
+}
+
+// Start records a start request
+func (s *Service) Start() error {
+	return s.record(ActionStart)
+}
+
+// Stop records a stop request and ends Run
+func (s *Service) Stop() error {
+	s.stopOnce.Do(func() { close(s.stop) })
+	return s.record(ActionStop)
+}
+
+// Restart records a restart request
+func (s *Service) Restart() error {
+	return s.record(ActionRestart)
+}
+
+// Install records an install request
+func (s *Service) Install() error {
+	return s.record(ActionInstall)
+}
+
+// Uninstall records an uninstall request
+func (s *Service) Uninstall() error {
+	return s.record(ActionUninstall)
+}
+
+// Interrupt records the termination an interactive daemon requests instead of
+// signaling the test process, and ends Run
+func (s *Service) Interrupt() error {
+	s.stopOnce.Do(func() { close(s.stop) })
+	return s.record(ActionInterrupt)
+}
+
+// Logger returns the logger recording the messages of the service
+func (s *Service) Logger(errs chan<- error) (kardianos.Logger, error) {
+	return s.logger, nil
+}
+
+// SystemLogger returns the same logger as Logger
+func (s *Service) SystemLogger(errs chan<- error) (kardianos.Logger, error) {
+	return s.logger, nil
+}
+
+// String returns the service name
+func (s *Service) String() string {
+	return s.Name
+}
+
+// Platform returns "daemontest"
+func (s *Service) Platform() string {
+	return "daemontest"
+}
+
+// Status reports whether Run is in progress
+func (s *Service) Status() (kardianos.Status, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.status, nil
+}
+
+// Interactive reports the value set by SetInteractive. See daemon.Interactive.
+func (s *Service) Interactive() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.interactive
+}
+
+// SetInteractive switches the service between interactive and service manager mode
+func (s *Service) SetInteractive(interactive bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.interactive = interactive
+}
+
+// Actions returns the actions requested so far, in order
+func (s *Service) Actions() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.actions)
+}
+
+// Count returns the number of times action was requested
+func (s *Service) Count(action string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for _, a := range s.actions {
+		if a == action {
+			n++
+		}
+	}
+	return n
+}
+
+// Stopped is closed once Stop or Interrupt is called
+func (s *Service) Stopped() <-chan struct{} {
+	return s.stop
+}
+
+// LogMessages returns the messages logged through the service loggers
+func (s *Service) LogMessages() []string {
+	return s.logger.Messages()
+}
+
+// record appends action and returns its configured error
+func (s *Service) record(action string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.actions = append(s.actions, action)
+	return s.Errs[action]
+}
+
+// setStatus updates the status reported by Status
+func (s *Service) setStatus(status kardianos.Status) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status = status
+}
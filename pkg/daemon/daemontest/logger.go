@@ -0,0 +1,43 @@
+package daemontest
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// Logger is a kardianos.Logger recording its messages, prefixed with their level
+type Logger struct {
+	mu       sync.Mutex
+	messages []string
+}
+
+func (l *Logger) Error(v ...interface{}) error   { return l.log("error", fmt.Sprint(v...)) }
+func (l *Logger) Warning(v ...interface{}) error { return l.log("warning", fmt.Sprint(v...)) }
+func (l *Logger) Info(v ...interface{}) error    { return l.log("info", fmt.Sprint(v...)) }
+
+func (l *Logger) Errorf(format string, a ...interface{}) error {
+	return l.log("error", fmt.Sprintf(format, a...))
+}
+
+func (l *Logger) Warningf(format string, a ...interface{}) error {
+	return l.log("warning", fmt.Sprintf(format, a...))
+}
+
+func (l *Logger) Infof(format string, a ...interface{}) error {
+	return l.log("info", fmt.Sprintf(format, a...))
+}
+
+// Messages returns the messages logged so far, as "level: message"
+func (l *Logger) Messages() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return slices.Clone(l.messages)
+}
+
+func (l *Logger) log(level, msg string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.messages = append(l.messages, level+": "+msg)
+	return nil
+}
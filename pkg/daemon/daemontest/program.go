@@ -0,0 +1,47 @@
+package daemontest
+
+import (
+	"sync"
+
+	"github.com/lucasdecamargo/kardianos"
+)
+
+// Program is a fake kardianos.Interface counting its calls. StartFunc and StopFunc,
+// when set, run on each call and provide its result.
+type Program struct {
+	StartFunc func(s kardianos.Service) error
+	StopFunc  func(s kardianos.Service) error
+
+	mu     sync.Mutex
+	starts int
+	stops  int
+}
+
+// Start counts the call and runs StartFunc
+func (p *Program) Start(s kardianos.Service) error {
+	p.mu.Lock()
+	p.starts++
+	p.mu.Unlock()
+	if p.StartFunc != nil {
+		return p.StartFunc(s)
+	}
+	return nil
+}
+
+// Stop counts the call and runs StopFunc
+func (p *Program) Stop(s kardianos.Service) error {
+	p.mu.Lock()
+	p.stops++
+	p.mu.Unlock()
+	if p.StopFunc != nil {
+		return p.StopFunc(s)
+	}
+	return nil
+}
+
+// Calls returns the number of Start and Stop calls so far
+func (p *Program) Calls() (starts, stops int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.starts, p.stops
+}
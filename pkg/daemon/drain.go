@@ -0,0 +1,53 @@
+package daemon
+
+import (
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/lucasdecamargo/go-appservice-example/pkg/ports"
+)
+
+// drainCheckInterval spaces the connection counts of a child past its exit timeout
+const drainCheckInterval = time.Second
+
+// awaitDrain is called once the exit timeout of the child pid, asked to exit at begin,
+// has elapsed. While the child still holds established connections, it waits for exited
+// to close, up to DrainTimeout after begin, and reports whether it did. It returns false
+// right away when draining is disabled.
+func (d *Daemon) awaitDrain(pid int, begin time.Time, exited <-chan struct{}) bool {
+	if d.DrainTimeout <= d.ExitTimeout {
+		return false
+	}
+
+	deadline, last := begin.Add(d.DrainTimeout), -1
+	for {
+		n, err := ports.Established(processTree(pid)...)
+		switch {
+		case errors.Is(err, ports.ErrUnsupported):
+			return false
+		case err != nil:
+			slog.Warn("Failed to count the child connections, not extending its exit timeout", "pid", pid, "error", err)
+			return false
+		case n == 0:
+			return false
+		}
+
+		wait := min(drainCheckInterval, time.Until(deadline))
+		if wait <= 0 {
+			slog.Warn("Child still holds connections at the drain timeout", "pid", pid, "connections", n)
+			return false
+		}
+		if n != last {
+			slog.Info("Child still draining connections, extending its exit timeout", "pid", pid,
+				"connections", n, "timeLeft", time.Until(deadline).Round(time.Second))
+			last = n
+		}
+
+		select {
+		case <-exited:
+			return true
+		case <-time.After(wait):
+		}
+	}
+}
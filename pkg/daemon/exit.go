@@ -0,0 +1,50 @@
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+)
+
+// ExitInfo describes how a child exited
+type ExitInfo struct {
+	Code     int    `json:"code"`               // Exit status, -1 when killed by a signal
+	Signal   string `json:"signal,omitempty"`   // Signal that killed the child, on Unix
+	Status   string `json:"status,omitempty"`   // NTSTATUS name, such as STATUS_ACCESS_VIOLATION, on Windows
+	Reason   string `json:"reason"`             // Human-readable summary
+	Abnormal bool   `json:"abnormal,omitempty"` // Killed by a signal or an unhandled exception rather than exiting
+}
+
+// DescribeExit classifies the error returned by waiting for a child. It returns false
+// for errors other than a process exit, such as a failed start.
+func DescribeExit(err error) (ExitInfo, bool) {
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return ExitInfo{}, false
+	}
+	return describeExit(exitErr), true
+}
+
+// exitError wraps a child exit error with its human-readable reason
+type exitError struct {
+	info ExitInfo
+	err  error
+}
+
+func (e *exitError) Error() string { return e.info.Reason }
+func (e *exitError) Unwrap() error { return e.err }
+
+// explainExit returns err with its message replaced by the exit reason, when err is a
+// process exit
+func explainExit(err error) error {
+	info, ok := DescribeExit(err)
+	if !ok {
+		return err
+	}
+	return &exitError{info: info, err: err}
+}
+
+// exitStatusReason formats the reason of a plain exit
+func exitStatusReason(code int) string {
+	return fmt.Sprintf("exit status %d", code)
+}
@@ -0,0 +1,27 @@
+//go:build !windows
+
+package daemon
+
+import (
+	"fmt"
+	"os/exec"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// describeExit decodes the wait status of a child, naming the signal that killed it
+func describeExit(err *exec.ExitError) ExitInfo {
+	ws, ok := err.Sys().(syscall.WaitStatus)
+	if !ok || !ws.Signaled() {
+		return ExitInfo{Code: err.ExitCode(), Reason: exitStatusReason(err.ExitCode())}
+	}
+
+	sig := ws.Signal()
+	name := unix.SignalName(sig)
+	reason := fmt.Sprintf("killed by %s (%s)", name, sig)
+	if ws.CoreDump() {
+		reason += ", core dumped"
+	}
+	return ExitInfo{Code: -1, Signal: name, Reason: reason, Abnormal: true}
+}
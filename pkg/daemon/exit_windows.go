@@ -0,0 +1,22 @@
+package daemon
+
+import (
+	"fmt"
+	"os/exec"
+)
+
+// describeExit decodes the exit code of a child, naming the NTSTATUS of the unhandled
+// exception that terminated it, if any
+func describeExit(err *exec.ExitError) ExitInfo {
+	code := uint32(err.ExitCode())
+	name, desc, ok := ntStatus(code)
+	if !ok {
+		return ExitInfo{Code: int(code), Reason: exitStatusReason(int(code))}
+	}
+	return ExitInfo{
+		Code:     int(code),
+		Status:   name,
+		Reason:   fmt.Sprintf("exit code 0x%08X: %s (%s)", code, name, desc),
+		Abnormal: true,
+	}
+}
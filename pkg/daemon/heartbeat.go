@@ -0,0 +1,211 @@
+package daemon
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lucasdecamargo/go-appservice-example/pkg/history"
+)
+
+// EnvHeartbeatFD names the environment variable holding the file descriptor, or the
+// handle on Windows, the child writes its heartbeat answers to. See AnswerHeartbeats.
+const EnvHeartbeatFD = "SVCAPP_HEARTBEAT_FD"
+
+// LivenessPolicy determines what happens when the child misses a heartbeat
+type LivenessPolicy int
+
+const (
+	// LivenessRestart restarts the child
+	LivenessRestart LivenessPolicy = iota
+	// LivenessStop stops the service, leaving restarts to the service manager
+	LivenessStop
+)
+
+// Heartbeat checks the liveness of children that can't open sockets. The supervisor
+// writes "ping <n>" lines to the child stdin every Interval, and the child must write
+// "pong <n>" to the EnvHeartbeatFD descriptor within Timeout.
+type Heartbeat struct {
+	Interval  time.Duration // Zero disables heartbeats
+	Timeout   time.Duration // Interval when zero
+	OnFailure LivenessPolicy
+}
+
+// heartbeatConn holds the supervisor ends of the heartbeat pipes of a child
+type heartbeatConn struct {
+	pings *os.File // Child stdin
+	pongs *os.File // Answers of the child
+	child []*os.File
+}
+
+// attachHeartbeat connects the heartbeat pipes to cmd. It returns nil when heartbeats
+// are disabled. The child ends must be closed once the child is started.
+func (d *Daemon) attachHeartbeat(cmd *exec.Cmd) (*heartbeatConn, error) {
+	if d.Heartbeat.Interval <= 0 {
+		return nil, nil
+	}
+
+	stdin, pings, err := os.Pipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create heartbeat pipe: %w", err)
+	}
+	pongs, answers, err := os.Pipe()
+	if err != nil {
+		stdin.Close()
+		pings.Close()
+		return nil, fmt.Errorf("failed to create heartbeat pipe: %w", err)
+	}
+
+	cmd.Stdin = stdin
+	fd, err := passFile(cmd, answers)
+	if err != nil {
+		closeFiles([]*os.File{stdin, pings, pongs, answers})
+		return nil, err
+	}
+	if cmd.Env == nil {
+		cmd.Env = os.Environ()
+	}
+	cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%d", EnvHeartbeatFD, fd))
+	return &heartbeatConn{pings: pings, pongs: pongs, child: []*os.File{stdin, answers}}, nil
+}
+
+// close releases the supervisor ends of the pipes
+func (hb *heartbeatConn) close() {
+	hb.pings.Close()
+	hb.pongs.Close()
+}
+
+// monitorHeartbeat pings the child identified by exited until it exits, applying the
+// liveness policy when an answer is late
+func (d *Daemon) monitorHeartbeat(hb *heartbeatConn, exited chan struct{}, pid int) {
+	defer hb.close()
+
+	timeout := d.Heartbeat.Timeout
+	if timeout <= 0 {
+		timeout = d.Heartbeat.Interval
+	}
+
+	pongs := make(chan uint64, 1)
+	go readPongs(hb.pongs, pongs)
+
+	ticker := time.NewTicker(d.Heartbeat.Interval)
+	defer ticker.Stop()
+
+	for seq := uint64(1); ; seq++ {
+		select {
+		case <-exited:
+			return
+		case <-ticker.C:
+		}
+
+		hb.pings.SetWriteDeadline(time.Now().Add(timeout))
+		err := d.awaitPong(hb.pings, pongs, seq, timeout, exited)
+		if errors.Is(err, ErrNotRunning) {
+			return
+		}
+		if err != nil {
+			d.livenessFailed(pid, exited, err)
+			return
+		}
+	}
+}
+
+// awaitPong sends ping seq and waits for its answer. It returns ErrNotRunning when
+// the child exits meanwhile.
+func (d *Daemon) awaitPong(pings *os.File, pongs <-chan uint64, seq uint64, timeout time.Duration, exited chan struct{}) error {
+	if _, err := fmt.Fprintf(pings, "ping %d\n", seq); err != nil {
+		return fmt.Errorf("failed to send heartbeat %d: %w", seq, err)
+	}
+
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	for {
+		select {
+		case n := <-pongs:
+			if n == seq {
+				return nil
+			}
+		case <-deadline.C:
+			return fmt.Errorf("heartbeat %d not answered within %v", seq, timeout)
+		case <-exited:
+			return ErrNotRunning
+		}
+	}
+}
+
+// livenessFailed records a missed heartbeat and applies the liveness policy
+func (d *Daemon) livenessFailed(pid int, exited chan struct{}, err error) {
+	d.mu.Lock()
+	current := d.exited == exited
+	s := d.service
+	d.mu.Unlock()
+	if !current {
+		return
+	}
+
+	slog.Warn("Child missed a heartbeat", "pid", pid, "error", err)
+	d.appendEvent(history.Event{Time: time.Now(), Kind: history.KindLiveness, Error: err.Error(), Detail: fmt.Sprintf("pid %d", pid)})
+
+	switch d.Heartbeat.OnFailure {
+	case LivenessStop:
+		stopService(s)
+	default:
+		if err := d.RestartChildFor(RestartHealth); err != nil && !errors.Is(err, ErrNotRunning) {
+			slog.Warn("Failed to restart unresponsive child", "error", err)
+		}
+	}
+}
+
+// readPongs forwards the sequence numbers answered by the child until its end of
+// the pipe is closed
+func readPongs(f *os.File, pongs chan<- uint64) {
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		n, ok := strings.CutPrefix(strings.TrimSpace(sc.Text()), "pong ")
+		if !ok {
+			continue
+		}
+		if seq, err := strconv.ParseUint(n, 10, 64); err == nil {
+			select {
+			case pongs <- seq:
+			default: // Only the latest answer matters
+			}
+		}
+	}
+}
+
+// AnswerHeartbeats answers the heartbeats of the supervising daemon in the background,
+// reading pings from stdin. It is a no-op when heartbeats are disabled.
+func AnswerHeartbeats() error {
+	v := os.Getenv(EnvHeartbeatFD)
+	if v == "" {
+		return nil
+	}
+	fd, err := strconv.ParseUint(v, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid %s %q: %w", EnvHeartbeatFD, v, err)
+	}
+	out := os.NewFile(uintptr(fd), "heartbeat")
+	if out == nil {
+		return fmt.Errorf("invalid %s %q", EnvHeartbeatFD, v)
+	}
+
+	go func() {
+		defer out.Close()
+		sc := bufio.NewScanner(os.Stdin)
+		for sc.Scan() {
+			if seq, ok := strings.CutPrefix(sc.Text(), "ping "); ok {
+				if _, err := fmt.Fprintf(out, "pong %s\n", seq); err != nil {
+					return
+				}
+			}
+		}
+	}()
+	return nil
+}
@@ -0,0 +1,50 @@
+package daemon
+
+import (
+	"github.com/lucasdecamargo/go-appservice-example/pkg/history"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/jobs"
+)
+
+// startJobs runs the scheduled jobs until the daemon stops, if any are configured
+func (d *Daemon) startJobs() {
+	if d.jobs == nil {
+		return
+	}
+	d.jobsDone = make(chan struct{})
+	go func() {
+		defer close(d.jobsDone)
+		d.jobs.Run(d.stopCtx)
+	}()
+}
+
+// waitJobs waits for the jobs killed by the daemon stop to exit
+func (d *Daemon) waitJobs() {
+	if d.jobsDone != nil {
+		<-d.jobsDone
+	}
+}
+
+// recordJob appends the outcome of a job run to the history
+func (d *Daemon) recordJob(r jobs.Result) {
+	e := history.Event{Time: r.Started.Add(r.Duration), Kind: history.KindJob, Duration: r.Duration, Detail: r.Name}
+	if r.Err != nil {
+		e.Error = r.Err.Error()
+	}
+	d.appendEvent(e)
+}
+
+// JobStatus returns the status of the scheduled jobs
+func (d *Daemon) JobStatus() []jobs.Status {
+	if d.jobs == nil {
+		return []jobs.Status{}
+	}
+	return d.jobs.Status()
+}
+
+// RunJob runs the named job now, outside of its schedule
+func (d *Daemon) RunJob(name string) error {
+	if d.jobs == nil {
+		return jobs.ErrUnknownJob
+	}
+	return d.jobs.Trigger(name)
+}
@@ -0,0 +1,38 @@
+package daemon
+
+import "errors"
+
+// ErrKVDisabled is returned by the key/value methods when the daemon has no store
+var ErrKVDisabled = errors.New("key/value store disabled")
+
+// KVList returns every key and value of the child store
+func (d *Daemon) KVList() (map[string]string, error) {
+	if d.KV == nil {
+		return nil, ErrKVDisabled
+	}
+	return d.KV.All(), nil
+}
+
+// KVGet returns the value of key in the child store, or kv.ErrNotFound
+func (d *Daemon) KVGet(key string) (string, error) {
+	if d.KV == nil {
+		return "", ErrKVDisabled
+	}
+	return d.KV.Get(key)
+}
+
+// KVSet stores value under key in the child store
+func (d *Daemon) KVSet(key, value string) error {
+	if d.KV == nil {
+		return ErrKVDisabled
+	}
+	return d.KV.Set(key, value)
+}
+
+// KVDelete removes key from the child store, or returns kv.ErrNotFound
+func (d *Daemon) KVDelete(key string) error {
+	if d.KV == nil {
+		return ErrKVDisabled
+	}
+	return d.KV.Delete(key)
+}
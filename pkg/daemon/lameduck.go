@@ -0,0 +1,109 @@
+package daemon
+
+import (
+	"bufio"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EnvLameDuckFD names the environment variable holding the file descriptor, or the
+// handle on Windows, the child reads lame duck notices from. See NotifyLameDuck.
+const EnvLameDuckFD = "SVCAPP_LAMEDUCK_FD"
+
+// LameDuck warns the child a Period before the supervisor asks it to stop or restart,
+// so it can stop accepting new work early. The supervisor writes "lameduck <ms>" to
+// the EnvLameDuckFD descriptor, with the milliseconds left before the stop request.
+type LameDuck struct {
+	Period time.Duration // Zero disables the notice
+	Signal os.Signal     // Also sent to the child when set, see ParseSignal
+}
+
+// attachLameDuck connects the lame duck pipe to cmd. It returns the supervisor end,
+// or nil when the notice is disabled, and the child end to close once it is started.
+func (d *Daemon) attachLameDuck(cmd *exec.Cmd) (notice, child *os.File, err error) {
+	if d.LameDuck.Period <= 0 {
+		return nil, nil, nil
+	}
+
+	child, notice, err = os.Pipe()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create lame duck pipe: %w", err)
+	}
+	fd, err := passFile(cmd, child)
+	if err != nil {
+		closeFiles([]*os.File{child, notice})
+		return nil, nil, err
+	}
+	if cmd.Env == nil {
+		cmd.Env = os.Environ()
+	}
+	cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%d", EnvLameDuckFD, fd))
+	return notice, child, nil
+}
+
+// enterLameDuck warns the child of cmd that it is about to be stopped, then waits for
+// the lame duck period or until it exits. It must not be called with d.mu held.
+func (d *Daemon) enterLameDuck(cmd *exec.Cmd, notice *os.File, exited chan struct{}) {
+	if d.LameDuck.Period <= 0 || notice == nil {
+		return
+	}
+
+	pid := cmd.Process.Pid
+	slog.Info("Child entering lame duck mode", "pid", pid, "period", d.LameDuck.Period)
+	notice.SetWriteDeadline(time.Now().Add(time.Second))
+	if _, err := fmt.Fprintf(notice, "lameduck %d\n", d.LameDuck.Period.Milliseconds()); err != nil {
+		slog.Warn("Failed to send the lame duck notice", "pid", pid, "error", err)
+	}
+	if d.LameDuck.Signal != nil {
+		if err := cmd.Process.Signal(d.LameDuck.Signal); err != nil {
+			slog.Warn("Failed to signal lame duck mode", "pid", pid, "signal", d.LameDuck.Signal, "error", err)
+		}
+	}
+
+	timer := time.NewTimer(d.LameDuck.Period)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-exited:
+	}
+}
+
+// NotifyLameDuck returns a channel receiving the time the supervising daemon will ask
+// the process to stop, once it announces it. The channel is nil when the daemon
+// sends no lame duck notices.
+func NotifyLameDuck() (<-chan time.Time, error) {
+	v := os.Getenv(EnvLameDuckFD)
+	if v == "" {
+		return nil, nil
+	}
+	fd, err := strconv.ParseUint(v, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s %q: %w", EnvLameDuckFD, v, err)
+	}
+	in := os.NewFile(uintptr(fd), "lameduck")
+	if in == nil {
+		return nil, fmt.Errorf("invalid %s %q", EnvLameDuckFD, v)
+	}
+
+	notices := make(chan time.Time, 1)
+	go func() {
+		defer in.Close()
+		sc := bufio.NewScanner(in)
+		for sc.Scan() {
+			ms, ok := strings.CutPrefix(strings.TrimSpace(sc.Text()), "lameduck ")
+			if !ok {
+				continue
+			}
+			if n, err := strconv.ParseInt(ms, 10, 64); err == nil {
+				notices <- time.Now().Add(time.Duration(n) * time.Millisecond)
+				return
+			}
+		}
+	}()
+	return notices, nil
+}
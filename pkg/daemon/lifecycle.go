@@ -0,0 +1,83 @@
+package daemon
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/lucasdecamargo/go-appservice-example/pkg/crash"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/history"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/lsm"
+)
+
+// Lifecycle transitions of the child reported to OnLifecycle
+const (
+	EventStarted   = "started"   // A child was spawned
+	EventReady     = "ready"     // The child reported readiness, or was spawned when readiness isn't tracked
+	EventCrashed   = "crashed"   // The child exited with an error without being asked to
+	EventRestarted = "restarted" // The child was restarted on request
+	EventStopped   = "stopped"   // The supervisor stopped the child
+	EventUpdate    = "update"    // A new version is available, installed or rolled back, described by Detail
+)
+
+// LifecycleEvent is a transition of the supervised child
+type LifecycleEvent struct {
+	Type   string    `json:"type"`
+	Time   time.Time `json:"time"`
+	PID    int       `json:"pid,omitempty"` // Child process ID
+	Error  string    `json:"error,omitempty"`
+	Detail string    `json:"detail,omitempty"`
+}
+
+// emit reports a lifecycle transition to OnLifecycle. It must not be called with d.mu held.
+func (d *Daemon) emit(kind string, pid int, err error) {
+	if d.OnLifecycle == nil {
+		return
+	}
+	e := LifecycleEvent{Type: kind, Time: time.Now(), PID: pid}
+	if err != nil {
+		e.Error = err.Error()
+	}
+	d.OnLifecycle(e)
+}
+
+// reportExit reports a child that exited with an error on its own as crashed, records
+// why in the history, and writes a crash report when it was killed by a signal or an
+// unhandled exception, or denied by SELinux or AppArmor
+func (d *Daemon) reportExit(pid int, err error) {
+	d.mu.Lock()
+	expected := d.stopping || d.restarting
+	d.mu.Unlock()
+
+	if err == nil || expected {
+		return
+	}
+	d.mu.Lock()
+	d.state.Crashed = d.crashReason(err)
+	d.saveState()
+	d.mu.Unlock()
+
+	d.emit(EventCrashed, pid, err)
+	d.appendEvent(history.Event{Time: time.Now(), Kind: history.KindCrash, Error: err.Error(), Detail: fmt.Sprintf("pid %d", pid)})
+
+	var denials []string
+	if pid != 0 {
+		denials = lsm.Denials(pid)
+	}
+	if len(denials) > 0 {
+		slog.Error("Child was denied by the security policy", "pid", pid, "denials", len(denials), "last", denials[len(denials)-1])
+	}
+
+	info, ok := DescribeExit(err)
+	if !ok || (!info.Abnormal && len(denials) == 0) || d.CrashDir == "" {
+		return
+	}
+	r := crash.NewExitReport(pid, append([]string{d.Executable}, d.Args...), info.Reason)
+	r.Denials = denials
+	path, err := crash.Write(d.CrashDir, r, d.CrashCompression)
+	if err != nil {
+		slog.Warn("Failed to write crash report", "error", err)
+		return
+	}
+	slog.Error("Child crashed", "pid", pid, "reason", info.Reason, "report", path)
+}
@@ -0,0 +1,12 @@
+package daemon
+
+import "github.com/lucasdecamargo/go-appservice-example/pkg/logexport"
+
+// RecentLogs returns the latest supervisor and child log lines held in memory within
+// rg, nil when Logs is disabled
+func (d *Daemon) RecentLogs(rg logexport.Range) []logexport.Entry {
+	if d.Logs == nil {
+		return nil
+	}
+	return d.Logs.Entries(rg)
+}
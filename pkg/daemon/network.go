@@ -0,0 +1,37 @@
+package daemon
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// waitForNetwork holds the child start until WaitForNetwork passes, NetworkTimeout
+// elapses or the daemon stops. The child starts anyway after the timeout.
+func (d *Daemon) waitForNetwork() {
+	if !d.WaitForNetwork.Enabled() {
+		return
+	}
+
+	ctx := d.stopCtx
+	if d.NetworkTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d.NetworkTimeout)
+		defer cancel()
+	}
+
+	err := d.WaitForNetwork.Run(ctx)
+	if err == nil {
+		return
+	}
+	slog.Info("Waiting for the network before starting the child", "reason", err)
+
+	begin := time.Now()
+	if err := d.WaitForNetwork.Wait(ctx); err != nil {
+		if d.stopCtx.Err() == nil {
+			slog.Warn("Starting the child without network", "error", err)
+		}
+		return
+	}
+	slog.Info("Network ready", "waited", time.Since(begin))
+}
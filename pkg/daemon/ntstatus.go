@@ -0,0 +1,43 @@
+package daemon
+
+import "fmt"
+
+// ntStatuses names the NTSTATUS codes a process most often terminates with
+var ntStatuses = map[uint32][2]string{
+	0x40000015: {"STATUS_FATAL_APP_EXIT", "fatal application exit"},
+	0x80000003: {"STATUS_BREAKPOINT", "breakpoint"},
+	0xC0000005: {"STATUS_ACCESS_VIOLATION", "access violation"},
+	0xC0000006: {"STATUS_IN_PAGE_ERROR", "in-page error"},
+	0xC0000008: {"STATUS_INVALID_HANDLE", "invalid handle"},
+	0xC0000017: {"STATUS_NO_MEMORY", "out of memory"},
+	0xC000001D: {"STATUS_ILLEGAL_INSTRUCTION", "illegal instruction"},
+	0xC0000025: {"STATUS_NONCONTINUABLE_EXCEPTION", "noncontinuable exception"},
+	0xC000008C: {"STATUS_ARRAY_BOUNDS_EXCEEDED", "array bounds exceeded"},
+	0xC000008E: {"STATUS_FLOAT_DIVIDE_BY_ZERO", "floating-point division by zero"},
+	0xC0000091: {"STATUS_FLOAT_OVERFLOW", "floating-point overflow"},
+	0xC0000094: {"STATUS_INTEGER_DIVIDE_BY_ZERO", "integer division by zero"},
+	0xC0000095: {"STATUS_INTEGER_OVERFLOW", "integer overflow"},
+	0xC0000096: {"STATUS_PRIVILEGED_INSTRUCTION", "privileged instruction"},
+	0xC00000FD: {"STATUS_STACK_OVERFLOW", "stack overflow"},
+	0xC0000135: {"STATUS_DLL_NOT_FOUND", "DLL not found"},
+	0xC0000138: {"STATUS_ORDINAL_NOT_FOUND", "DLL ordinal not found"},
+	0xC0000139: {"STATUS_ENTRYPOINT_NOT_FOUND", "DLL entry point not found"},
+	0xC000013A: {"STATUS_CONTROL_C_EXIT", "terminated by Ctrl+C"},
+	0xC0000142: {"STATUS_DLL_INIT_FAILED", "DLL initialization failed"},
+	0xC0000374: {"STATUS_HEAP_CORRUPTION", "heap corruption"},
+	0xC0000409: {"STATUS_STACK_BUFFER_OVERRUN", "stack buffer overrun or fail-fast exit"},
+	0xC0000417: {"STATUS_INVALID_CRUNTIME_PARAMETER", "invalid C runtime parameter"},
+	0xC0000420: {"STATUS_ASSERTION_FAILURE", "assertion failure"},
+}
+
+// ntStatus returns the name and description of an NTSTATUS exit code. Unknown codes
+// with the error severity are still reported, as exit codes that large are exceptions.
+func ntStatus(code uint32) (name, desc string, ok bool) {
+	if s, ok := ntStatuses[code]; ok {
+		return s[0], s[1], true
+	}
+	if code&0xC0000000 == 0xC0000000 {
+		return fmt.Sprintf("NTSTATUS 0x%08X", code), "unhandled exception", true
+	}
+	return "", "", false
+}
@@ -0,0 +1,111 @@
+package daemon
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"os/exec"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/lucasdecamargo/go-appservice-example/pkg/ports"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/procinfo"
+)
+
+// PortDetection finds the TCP ports the child listens on, for children that pick
+// ephemeral ports. They are reported in the state.
+type PortDetection struct {
+	Match *ports.Matcher // Finds ports in the child output, nil to disable
+	Poll  time.Duration  // Interval of the scans of the child sockets, zero to disable
+}
+
+// String describes the enabled detection methods, such as "output, poll 5s"
+func (p PortDetection) String() string {
+	var methods []string
+	if p.Match != nil {
+		methods = append(methods, "output")
+	}
+	if p.Poll > 0 {
+		methods = append(methods, "poll "+p.Poll.String())
+	}
+	return strings.Join(methods, ", ")
+}
+
+// portWriter returns w, also reporting the ports matched in the output of cmd
+func (d *Daemon) portWriter(cmd *exec.Cmd, w io.Writer) io.Writer {
+	if d.Ports.Match == nil {
+		return w
+	}
+	return io.MultiWriter(w, d.Ports.Match.Writer(func(port int) { d.addPort(cmd, port) }))
+}
+
+// addPort records that the child cmd listens on port
+func (d *Daemon) addPort(cmd *exec.Cmd, port int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.cmd != cmd || slices.Contains(d.state.Ports, port) {
+		return
+	}
+	slog.Info("Child listening", "pid", d.state.ChildPID, "port", port)
+	d.state.Ports = ports.Add(d.state.Ports, port)
+	d.saveState()
+}
+
+// pollPorts scans the sockets of the child pid and its descendants every Poll interval
+// until it exits, replacing the ports in the state with the ones listening
+func (d *Daemon) pollPorts(pid int, exited <-chan struct{}) {
+	if d.Ports.Poll <= 0 {
+		return
+	}
+	ticker := time.NewTicker(d.Ports.Poll)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-exited:
+			return
+		case <-ticker.C:
+		}
+
+		found, err := ports.Listening(processTree(pid)...)
+		if errors.Is(err, ports.ErrUnsupported) {
+			slog.Warn("Port polling disabled", "error", err)
+			return
+		}
+		if err != nil {
+			slog.Debug("Failed to scan the child sockets", "pid", pid, "error", err)
+			continue
+		}
+
+		d.mu.Lock()
+		if d.state.ChildPID == pid && !slices.Equal(d.state.Ports, found) {
+			slog.Info("Child listening", "pid", pid, "ports", found)
+			d.state.Ports = found
+			d.saveState()
+		}
+		d.mu.Unlock()
+	}
+}
+
+// processTree returns pid and the PIDs of its descendants, or only pid when the
+// process table can't be read
+func processTree(pid int) []int {
+	procs, err := procinfo.List()
+	if err != nil {
+		return []int{pid}
+	}
+	root := procinfo.Tree(procs, pid)
+	if root == nil {
+		return []int{pid}
+	}
+	var pids []int
+	var walk func(n *procinfo.Node)
+	walk = func(n *procinfo.Node) {
+		pids = append(pids, n.PID)
+		for _, c := range n.Children {
+			walk(c)
+		}
+	}
+	walk(root)
+	return pids
+}
@@ -0,0 +1,40 @@
+package daemon
+
+import (
+	"errors"
+	"log/slog"
+	"math/rand/v2"
+	"time"
+)
+
+// armRecycle recycles the child identified by exited once it has run for MaxRuntime
+// plus a random share of MaxRuntimeJitter, so a fleet doesn't recycle all at once
+func (d *Daemon) armRecycle(exited chan struct{}) {
+	if d.MaxRuntime <= 0 {
+		return
+	}
+
+	after := d.MaxRuntime
+	if d.MaxRuntimeJitter > 0 {
+		after += rand.N(d.MaxRuntimeJitter)
+	}
+
+	timer := time.AfterFunc(after, func() {
+		d.mu.Lock()
+		current := d.exited == exited
+		d.mu.Unlock()
+		if !current {
+			return
+		}
+
+		slog.Info("Recycling child", "runtime", after)
+		if err := d.RestartChildFor(RestartRecycle); err != nil && !errors.Is(err, ErrNotRunning) {
+			slog.Warn("Failed to recycle child", "error", err)
+		}
+	})
+
+	go func() {
+		<-exited
+		timer.Stop()
+	}()
+}
@@ -0,0 +1,75 @@
+package daemon
+
+import (
+	"runtime"
+	"slices"
+	"sync"
+)
+
+// Supervisor subsystems with a footprint of their own
+const (
+	SubsystemMetrics = "metrics"
+	SubsystemHistory = "history"
+)
+
+// Footprint is the estimated cost of a supervisor subsystem
+type Footprint struct {
+	Subsystem  string `json:"subsystem"`
+	Enabled    bool   `json:"enabled"`
+	Bytes      int64  `json:"bytes"`                // Memory held by its buffers
+	Goroutines int    `json:"goroutines,omitempty"` // Background goroutines it runs
+	Detail     string `json:"detail,omitempty"`
+}
+
+// Resources reports the memory used by the supervisor and the share of its subsystems
+type Resources struct {
+	Lean       bool        `json:"lean"`
+	HeapBytes  uint64      `json:"heapBytes"`  // Live heap allocations
+	SysBytes   uint64      `json:"sysBytes"`   // Memory obtained from the OS by the Go runtime
+	Goroutines int         `json:"goroutines"` // All goroutines of the supervisor
+	Subsystems []Footprint `json:"subsystems"`
+}
+
+// footprints holds the functions reporting the subsystems registered by the caller
+type footprints struct {
+	mu    sync.Mutex
+	funcs []func() Footprint
+}
+
+// RegisterFootprint adds a subsystem to the Resources report
+func (d *Daemon) RegisterFootprint(f func() Footprint) {
+	d.footprints.mu.Lock()
+	defer d.footprints.mu.Unlock()
+	d.footprints.funcs = append(d.footprints.funcs, f)
+}
+
+// Resources returns the supervisor memory usage, with the estimated footprint of
+// the built-in and registered subsystems
+func (d *Daemon) Resources() Resources {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+
+	r := Resources{
+		Lean:       d.Lean,
+		HeapBytes:  ms.HeapAlloc,
+		SysBytes:   ms.Sys,
+		Goroutines: runtime.NumGoroutine(),
+	}
+
+	m := Footprint{Subsystem: SubsystemMetrics, Enabled: !d.Lean, Detail: "lifecycle latency histograms"}
+	if m.Enabled {
+		for _, h := range d.latency {
+			m.Bytes += h.Size()
+		}
+	}
+	r.Subsystems = append(r.Subsystems, m,
+		Footprint{Subsystem: SubsystemHistory, Enabled: d.Store != nil && !d.Lean, Detail: "appended to storage, not held in memory"})
+
+	d.footprints.mu.Lock()
+	funcs := slices.Clone(d.footprints.funcs)
+	d.footprints.mu.Unlock()
+	for _, f := range funcs {
+		r.Subsystems = append(r.Subsystems, f())
+	}
+	return r
+}
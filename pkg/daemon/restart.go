@@ -0,0 +1,53 @@
+package daemon
+
+import (
+	"maps"
+	"time"
+
+	"github.com/lucasdecamargo/go-appservice-example/pkg/history"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/state"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/tuning"
+)
+
+// Restart reasons, counted in the state and recorded as restart history events
+const (
+	RestartCrash   = "crash"          // The child exited with an error on its own and the service was restarted
+	RestartOOM     = "oom"            // Same as RestartCrash, after the kernel killed the child for running out of memory
+	RestartHealth  = "health-failure" // The child missed a heartbeat
+	RestartManual  = "manual"         // A control API request, a reload or a deferred restart
+	RestartUpgrade = "upgrade"        // A new version was installed or rolled back
+	RestartRecycle = "recycle"        // The child ran for MaxRuntime
+)
+
+// countRestart counts a child restart for reason and records it in the history. It
+// must be called with d.mu held.
+func (d *Daemon) countRestart(reason string) {
+	if d.state.RestartReasons == nil {
+		d.state.RestartReasons = map[string]int{}
+	}
+	d.state.RestartReasons[reason]++
+	d.appendEvent(history.Event{Time: time.Now(), Kind: history.KindRestart, Detail: reason})
+}
+
+// restoreRestarts carries the restart counters over from a previous supervisor run,
+// counting the restart of the service after a crash stopped it. It must be called
+// with d.mu held.
+func (d *Daemon) restoreRestarts(prev *state.State) {
+	if prev == nil {
+		return
+	}
+	d.state.RestartReasons = maps.Clone(prev.RestartReasons)
+	if prev.Crashed != "" {
+		d.countRestart(prev.Crashed)
+	}
+}
+
+// crashReason returns the restart reason of a child that exited with err on its own,
+// RestartOOM when the kernel killed a process of the cgroup since the child started
+func (d *Daemon) crashReason(err error) string {
+	info, ok := DescribeExit(err)
+	if ok && info.Signal == "SIGKILL" && tuning.OOMKills() > d.oomKills {
+		return RestartOOM
+	}
+	return RestartCrash
+}
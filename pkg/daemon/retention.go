@@ -0,0 +1,61 @@
+package daemon
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/lucasdecamargo/go-appservice-example/pkg/crash"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/retention"
+)
+
+// pruneInterval spaces the retention passes of the daemon
+const pruneInterval = time.Hour
+
+// Retention limits the history events and crash reports the daemon keeps
+type Retention struct {
+	History retention.Policy
+	Crashes retention.Policy
+}
+
+// IsZero reports whether everything is kept
+func (r Retention) IsZero() bool {
+	return r.History.IsZero() && r.Crashes.IsZero()
+}
+
+// startPruner applies the retention policies on start, then every pruneInterval until
+// the daemon stops
+func (d *Daemon) startPruner() {
+	if d.Retention.IsZero() {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(pruneInterval)
+		defer ticker.Stop()
+		for {
+			d.prune()
+			select {
+			case <-d.stopCtx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// prune deletes the history events and crash reports that don't fit the retention policies
+func (d *Daemon) prune() {
+	if d.Store != nil && !d.Retention.History.IsZero() {
+		if n, err := d.Store.PruneEvents(d.Retention.History); err != nil {
+			slog.Warn("Failed to prune history", "error", err)
+		} else if n > 0 {
+			slog.Info("Pruned history", "events", n)
+		}
+	}
+	if d.CrashDir != "" && !d.Retention.Crashes.IsZero() {
+		if n, err := crash.Prune(d.CrashDir, d.Retention.Crashes); err != nil {
+			slog.Warn("Failed to prune crash reports", "error", err)
+		} else if n > 0 {
+			slog.Info("Pruned crash reports", "reports", n)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package daemon
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/lucasdecamargo/go-appservice-example/pkg/state"
+)
+
+// Deferred actions accepted by Schedule
+const (
+	ActionStop    = "stop"
+	ActionRestart = "restart"
+)
+
+// Schedule arranges for action to run at the given time, replacing any pending
+// schedule. A stop stops the whole service, while a restart recycles the child.
+func (d *Daemon) Schedule(action string, at time.Time) error {
+	if action != ActionStop && action != ActionRestart {
+		return fmt.Errorf("cannot schedule action %q", action)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if !d.started || d.stopping {
+		return ErrNotRunning
+	}
+
+	d.cancelScheduleLocked()
+	d.state.Scheduled = &state.Scheduled{Action: action, At: at}
+	d.schedule = time.AfterFunc(time.Until(at), func() { d.runScheduled(action) })
+	d.saveState()
+
+	slog.Info("Scheduled action", "action", action, "at", at)
+	return nil
+}
+
+// CancelSchedule cancels the pending scheduled action, reporting whether there was one
+func (d *Daemon) CancelSchedule() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	pending := d.state.Scheduled != nil
+	d.cancelScheduleLocked()
+	d.saveState()
+	return pending
+}
+
+// cancelScheduleLocked stops the schedule timer. It must be called with d.mu held.
+func (d *Daemon) cancelScheduleLocked() {
+	if d.schedule != nil {
+		d.schedule.Stop()
+		d.schedule = nil
+	}
+	d.state.Scheduled = nil
+}
+
+// restoreSchedule re-arms a schedule persisted by a previous supervisor run
+func (d *Daemon) restoreSchedule(prev *state.State) {
+	if prev == nil || prev.Scheduled == nil || prev.Scheduled.At.Before(time.Now()) {
+		return
+	}
+
+	if err := d.Schedule(prev.Scheduled.Action, prev.Scheduled.At); err != nil {
+		slog.Warn("Failed to restore scheduled action", "error", err)
+	}
+}
+
+// runScheduled executes a scheduled action once its timer fires
+func (d *Daemon) runScheduled(action string) {
+	d.mu.Lock()
+	d.schedule = nil
+	d.state.Scheduled = nil
+	d.saveState()
+	s := d.service
+	d.mu.Unlock()
+
+	slog.Info("Running scheduled action", "action", action)
+
+	switch action {
+	case ActionStop:
+		stopService(s)
+	case ActionRestart:
+		if err := d.RestartChild(); err != nil {
+			slog.Warn("Scheduled restart failed", "error", err)
+		}
+	}
+}
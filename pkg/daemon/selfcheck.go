@@ -0,0 +1,23 @@
+package daemon
+
+// ReportControl records whether the control socket at addr accepts connections, nil
+// once it does again, for the status
+func (d *Daemon) ReportControl(addr string, err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err == nil {
+		delete(d.state.ControlDown, addr)
+		if len(d.state.ControlDown) == 0 {
+			d.state.ControlDown = nil
+		}
+	} else {
+		if d.state.ControlDown == nil {
+			d.state.ControlDown = make(map[string]string)
+		}
+		d.state.ControlDown[addr] = err.Error()
+	}
+	if d.started {
+		d.saveState() // For the status while the socket is down
+	}
+}
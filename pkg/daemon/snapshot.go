@@ -0,0 +1,107 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/lucasdecamargo/go-appservice-example/pkg/history"
+)
+
+// Operations snapshots are taken before, besides "update to <version>"
+const (
+	SnapshotReload = "reload" // The child is restarted with a new configuration
+	SnapshotManual = "manual" // A control API request
+)
+
+// Snapshots takes a crash-consistent snapshot of the service data before risky
+// operations, and restores it when they are rolled back
+type Snapshots struct {
+	// Take snapshots the data before the operation reason and returns the snapshot
+	// name, nil to disable
+	Take func(ctx context.Context, reason string) (string, error)
+	// Restore restores the snapshot name while no child runs, nil when restores are manual
+	Restore func(ctx context.Context, name string) error
+	// Required cancels an operation whose snapshot failed instead of going on without one
+	Required bool
+}
+
+// TakeSnapshot snapshots the service data before the operation reason and records it
+// in the history
+func (d *Daemon) TakeSnapshot(reason string) (string, error) {
+	if d.Snapshots.Take == nil {
+		return "", ErrSnapshotUnsupported
+	}
+	begin := time.Now()
+	name, err := d.Snapshots.Take(context.Background(), reason)
+	e := history.Event{Time: begin, Kind: history.KindSnapshot, Duration: time.Since(begin), Detail: "took " + name + " before " + reason}
+	if err != nil {
+		e.Detail = "before " + reason
+		e.Error = err.Error()
+		slog.Error("Failed to take a snapshot", "reason", reason, "error", err)
+	} else {
+		slog.Info("Took a snapshot", "snapshot", name, "reason", reason, "duration", e.Duration)
+	}
+	d.appendEvent(e)
+	return name, err
+}
+
+// SnapshotBefore is TakeSnapshot for an operation that goes on without a snapshot,
+// returning an error only when Snapshots are Required. Without a Take hook it does
+// nothing.
+func (d *Daemon) SnapshotBefore(reason string) (string, error) {
+	if d.Snapshots.Take == nil {
+		return "", nil
+	}
+	name, err := d.TakeSnapshot(reason)
+	if err != nil && !d.Snapshots.Required {
+		return "", nil
+	}
+	return name, err
+}
+
+// RestoreSnapshot queues the restore of the snapshot name, run once the current child
+// has exited and before the next one starts. The caller restarts the child.
+func (d *Daemon) RestoreSnapshot(name string) error {
+	if d.Snapshots.Restore == nil {
+		return ErrSnapshotUnsupported
+	}
+	d.mu.Lock()
+	d.restore = name
+	d.mu.Unlock()
+	return nil
+}
+
+// restorePending restores the snapshot queued by RestoreSnapshot, if any, and records
+// it in the history. A failure is logged and doesn't hold the child start.
+func (d *Daemon) restorePending() {
+	d.mu.Lock()
+	name := d.restore
+	d.restore = ""
+	d.mu.Unlock()
+	if name == "" {
+		return
+	}
+
+	slog.Info("Restoring a snapshot", "snapshot", name)
+	begin := time.Now()
+	err := d.Snapshots.Restore(context.Background(), name)
+	e := history.Event{Time: begin, Kind: history.KindSnapshot, Duration: time.Since(begin), Detail: "restored " + name}
+	if err != nil {
+		e.Error = err.Error()
+		slog.Error("Failed to restore the snapshot", "snapshot", name, "error", err)
+	}
+	d.appendEvent(e)
+}
+
+// RollbackToSnapshot restarts the child after restoring the snapshot name in between
+func (d *Daemon) RollbackToSnapshot(name string) error {
+	if err := d.RestoreSnapshot(name); err != nil {
+		return err
+	}
+	if err := d.RestartChild(); err != nil && !errors.Is(err, ErrNotRunning) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,23 @@
+package daemon
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// tether has the kernel kill the child when the supervisor dies, even by SIGKILL, so a
+// crashed supervisor leaves no unmanaged child behind. The signal is tied to the thread
+// starting the child, which the Go runtime only ends along with the supervisor.
+func tether(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Pdeathsig = syscall.SIGKILL
+}
+
+// tetherStarted does nothing, a Linux child is tethered as it starts and an adopted
+// process can't be
+func tetherStarted(p *os.Process) error {
+	return nil
+}
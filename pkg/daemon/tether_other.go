@@ -0,0 +1,16 @@
+//go:build !linux && !windows
+
+package daemon
+
+import (
+	"os"
+	"os/exec"
+)
+
+// tether does nothing, the platform can't kill the child when the supervisor dies
+func tether(cmd *exec.Cmd) {}
+
+// tetherStarted does nothing, the platform can't kill the child when the supervisor dies
+func tetherStarted(p *os.Process) error {
+	return nil
+}
@@ -0,0 +1,47 @@
+package daemon
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"sync"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// supervisorJob is the job object holding the children, killed when the supervisor exits
+var supervisorJob = sync.OnceValues(func() (windows.Handle, error) {
+	job, err := windows.CreateJobObject(nil, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create job object: %w", err)
+	}
+	info := windows.JOBOBJECT_EXTENDED_LIMIT_INFORMATION{
+		BasicLimitInformation: windows.JOBOBJECT_BASIC_LIMIT_INFORMATION{LimitFlags: windows.JOB_OBJECT_LIMIT_KILL_ON_JOB_CLOSE},
+	}
+	if _, err := windows.SetInformationJobObject(job, windows.JobObjectExtendedLimitInformation,
+		uintptr(unsafe.Pointer(&info)), uint32(unsafe.Sizeof(info))); err != nil {
+		windows.CloseHandle(job)
+		return 0, fmt.Errorf("failed to configure job object: %w", err)
+	}
+	return job, nil
+})
+
+// tether does nothing, a Windows child is tethered once started
+func tether(cmd *exec.Cmd) {}
+
+// tetherStarted adds p to the job object of the supervisor. Windows closes the job when
+// the supervisor exits, even when it crashes, and kills p with its descendants, so no
+// unmanaged child is left behind.
+func tetherStarted(p *os.Process) error {
+	job, err := supervisorJob()
+	if err != nil {
+		return err
+	}
+	h, err := windows.OpenProcess(windows.PROCESS_SET_QUOTA|windows.PROCESS_TERMINATE, false, uint32(p.Pid))
+	if err != nil {
+		return err
+	}
+	defer windows.CloseHandle(h)
+	return windows.AssignProcessToJobObject(job, h)
+}
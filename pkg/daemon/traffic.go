@@ -0,0 +1,52 @@
+package daemon
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// Traffic shifts the traffic of an external load balancer away from the child before
+// it is stopped or restarted on purpose, and back to the child once it is ready
+type Traffic struct {
+	// Deregister takes the child pid out of the load balancer, nil to disable
+	Deregister func(ctx context.Context, pid int) error
+	// Register puts the ready child pid back into the load balancer, nil to disable
+	Register func(ctx context.Context, pid int) error
+	// Delay is waited after Deregister, for the requests on their way to reach the child
+	Delay time.Duration
+}
+
+// deregister takes the child pid out of the load balancer before it is stopped. A
+// failure is logged and doesn't hold the stop. It must not be called with d.mu held.
+func (d *Daemon) deregister(pid int) {
+	if d.Traffic.Deregister == nil {
+		return
+	}
+	slog.Info("Taking the child out of the load balancer", "pid", pid)
+	if err := d.Traffic.Deregister(context.Background(), pid); err != nil {
+		slog.Warn("Failed to take the child out of the load balancer", "pid", pid, "error", err)
+	}
+	if d.Traffic.Delay > 0 {
+		time.Sleep(d.Traffic.Delay)
+	}
+}
+
+// register puts the ready child pid back into the load balancer. It must not be
+// called with d.mu held.
+func (d *Daemon) register(pid int) {
+	if d.Traffic.Register == nil || pid == 0 {
+		return
+	}
+	d.mu.Lock()
+	stopping := d.stopping
+	d.mu.Unlock()
+	if stopping {
+		return
+	}
+	if err := d.Traffic.Register(context.Background(), pid); err != nil {
+		slog.Error("Failed to put the child into the load balancer", "pid", pid, "error", err)
+		return
+	}
+	slog.Info("Put the child into the load balancer", "pid", pid)
+}
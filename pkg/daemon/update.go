@@ -0,0 +1,35 @@
+package daemon
+
+import (
+	"time"
+
+	"github.com/lucasdecamargo/go-appservice-example/pkg/history"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/state"
+)
+
+// SetUpdate records the outcome of an update check in the state shown by status
+func (d *Daemon) SetUpdate(u state.Update) {
+	d.mu.Lock()
+	d.state.Update = &u
+	d.saveState()
+	d.mu.Unlock()
+}
+
+// RestartForUpdate restarts the child on a version just installed or rolled back
+func (d *Daemon) RestartForUpdate() error {
+	return d.RestartChildFor(RestartUpgrade)
+}
+
+// NotifyUpdate records a new version becoming available, installed or rolled back, as
+// described by detail, and reports it to OnLifecycle
+func (d *Daemon) NotifyUpdate(detail string, err error) {
+	e := history.Event{Time: time.Now(), Kind: history.KindUpdate, Detail: detail}
+	if err != nil {
+		e.Error = err.Error()
+	}
+	d.appendEvent(e)
+
+	if d.OnLifecycle != nil {
+		d.OnLifecycle(LifecycleEvent{Type: EventUpdate, Time: e.Time, Detail: detail, Error: e.Error})
+	}
+}
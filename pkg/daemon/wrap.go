@@ -0,0 +1,99 @@
+package daemon
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/lucasdecamargo/kardianos"
+)
+
+// Service actions seen by wrappers
+const (
+	WrapStart    = "start"
+	WrapStop     = "stop"
+	WrapShutdown = "shutdown" // The machine is shutting down, see kardianos.Shutdowner
+)
+
+// Wrapper decorates a kardianos.Interface, such as the Daemon, with cross-cutting
+// behavior such as logging, metrics or tracing
+type Wrapper func(next kardianos.Interface) kardianos.Interface
+
+// Wrap decorates i with the wrappers. The first wrapper is the outermost one.
+func Wrap(i kardianos.Interface, ws ...Wrapper) kardianos.Interface {
+	for j := len(ws) - 1; j >= 0; j-- {
+		i = ws[j](i)
+	}
+	return i
+}
+
+// Around returns a wrapper calling f for each Start, Stop and Shutdown, with the
+// action name and the call to the wrapped interface
+func Around(f func(action string, call func() error) error) Wrapper {
+	return func(next kardianos.Interface) kardianos.Interface {
+		return &wrapped{next: next, around: f}
+	}
+}
+
+// wrapped routes the calls to a kardianos.Interface through around
+type wrapped struct {
+	next   kardianos.Interface
+	around func(action string, call func() error) error
+}
+
+func (w *wrapped) Start(s kardianos.Service) error {
+	return w.around(WrapStart, func() error { return w.next.Start(s) })
+}
+
+func (w *wrapped) Stop(s kardianos.Service) error {
+	return w.around(WrapStop, func() error { return w.next.Stop(s) })
+}
+
+// Shutdown forwards to the wrapped interface when it handles shutdowns, and stops
+// it otherwise, as kardianos would
+func (w *wrapped) Shutdown(s kardianos.Service) error {
+	sd, ok := w.next.(kardianos.Shutdowner)
+	if !ok {
+		return w.Stop(s)
+	}
+	return w.around(WrapShutdown, func() error { return sd.Shutdown(s) })
+}
+
+// WithLogging logs each service action with its duration and error. A nil logger
+// uses the default one at the time of the call.
+func WithLogging(logger *slog.Logger) Wrapper {
+	return Around(func(action string, call func() error) error {
+		l := logger
+		if l == nil {
+			l = slog.Default()
+		}
+		start := time.Now()
+		err := call()
+		if err != nil {
+			l.Error("Service action failed", "action", action, "duration", time.Since(start), "error", err)
+		} else {
+			l.Info("Service action completed", "action", action, "duration", time.Since(start))
+		}
+		return err
+	})
+}
+
+// WithMetrics reports the duration and result of each service action to observe
+func WithMetrics(observe func(action string, duration time.Duration, err error)) Wrapper {
+	return Around(func(action string, call func() error) error {
+		start := time.Now()
+		err := call()
+		observe(action, time.Since(start), err)
+		return err
+	})
+}
+
+// WithTracing opens a span around each service action. begin starts the span and
+// returns the function ending it with the result of the action.
+func WithTracing(begin func(action string) (end func(err error))) Wrapper {
+	return Around(func(action string, call func() error) error {
+		end := begin(action)
+		err := call()
+		end(err)
+		return err
+	})
+}
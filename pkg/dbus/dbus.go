@@ -0,0 +1,60 @@
+package dbus
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/lucasdecamargo/go-appservice-example/pkg/paths"
+)
+
+// Names under which the supervisor is exported on the system bus, next to its BusName
+const (
+	ObjectPath = "/org/svcapp/Manager1"
+	Interface  = "org.svcapp.Manager1"
+)
+
+// ErrUnsupported is returned on platforms without a system D-Bus
+var ErrUnsupported = errors.New("D-Bus is not supported on this platform")
+
+// invalidElement matches the characters not allowed in a bus name element
+var invalidElement = regexp.MustCompile(`[^A-Za-z0-9_-]`)
+
+// BusName returns the name the supervisor of service owns on the system bus, as in
+// org.svcapp.Manager1 for svcapp and org.svcapp.Manager1.a for its instance svcapp@a
+func BusName(service string) string {
+	app, instance, _ := strings.Cut(service, paths.InstanceSeparator)
+	name := "org." + busElement(app) + ".Manager1"
+	if instance != "" {
+		name += "." + busElement(instance)
+	}
+	return name
+}
+
+// busElement replaces the characters of s not allowed in a bus name element with _,
+// prefixing it with _ when it starts with a digit
+func busElement(s string) string {
+	s = invalidElement.ReplaceAllString(s, "_")
+	if s == "" || (s[0] >= '0' && s[0] <= '9') {
+		s = "_" + s
+	}
+	return s
+}
+
+// Policy allows root to own busName and call every method, and everyone else to read
+// the status. It is installed in the system bus configuration directory.
+func Policy(busName string) string {
+	return `<!DOCTYPE busconfig PUBLIC "-//freedesktop//DTD D-BUS Bus Configuration 1.0//EN"
+ "http://www.freedesktop.org/standards/dbus/1.0/busconfig.dtd">
+<busconfig>
+  <policy user="root">
+    <allow own="` + busName + `"/>
+    <allow send_destination="` + busName + `"/>
+  </policy>
+  <policy context="default">
+    <allow send_destination="` + busName + `" send_interface="` + Interface + `" send_member="Status"/>
+    <allow send_destination="` + busName + `" send_interface="org.freedesktop.DBus.Introspectable"/>
+  </policy>
+</busconfig>
+`
+}
@@ -0,0 +1,146 @@
+package dbus
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	godbus "github.com/godbus/dbus/v5"
+	"github.com/godbus/dbus/v5/introspect"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/audit"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/control"
+)
+
+// policyDir is where the system bus reads service policies from
+const policyDir = "/etc/dbus-1/system.d"
+
+// manager exposes a control.Controller as the org.svcapp.Manager1 interface
+type manager struct {
+	c     control.Controller
+	conn  *godbus.Conn
+	audit *audit.Logger
+}
+
+// Status returns the daemon state as a JSON document
+func (m *manager) Status() (string, *godbus.Error) {
+	data, err := json.Marshal(m.c.Status())
+	if err != nil {
+		return "", godbus.MakeFailedError(err)
+	}
+	return string(data), nil
+}
+
+// Schedule defers a stop or restart until the given Unix time
+func (m *manager) Schedule(sender godbus.Sender, action string, at int64) *godbus.Error {
+	err := m.c.Schedule(action, time.Unix(at, 0))
+	m.record(sender, "schedule", action, err)
+	if err != nil {
+		return godbus.MakeFailedError(err)
+	}
+	return nil
+}
+
+// CancelSchedule cancels the pending deferred action, reporting whether there was one
+func (m *manager) CancelSchedule(sender godbus.Sender) (bool, *godbus.Error) {
+	canceled := m.c.CancelSchedule()
+	m.record(sender, "cancel-schedule", "", nil)
+	return canceled, nil
+}
+
+// Reload rebuilds the child from the configuration file and restarts it
+func (m *manager) Reload(sender godbus.Sender) *godbus.Error {
+	err := m.c.Reload()
+	m.record(sender, "reload", "", err)
+	if err != nil {
+		return godbus.MakeFailedError(err)
+	}
+	return nil
+}
+
+// record audits action invoked by sender, identified by the user the bus knows it as
+func (m *manager) record(sender godbus.Sender, action, target string, err error) {
+	if m.audit == nil {
+		return
+	}
+	actor := audit.Actor{Identity: string(sender)}
+	var uid, pid uint32
+	if m.conn.BusObject().Call("org.freedesktop.DBus.GetConnectionUnixUser", 0, string(sender)).Store(&uid) == nil {
+		actor.UID = strconv.FormatUint(uint64(uid), 10)
+		if u, err := user.LookupId(actor.UID); err == nil {
+			actor.User = u.Username
+		}
+	}
+	if m.conn.BusObject().Call("org.freedesktop.DBus.GetConnectionUnixProcessID", 0, string(sender)).Store(&pid) == nil {
+		actor.PID = int(pid)
+	}
+	if aerr := m.audit.Record(audit.Record{Source: audit.SourceDBus, Action: action, Target: target, Actor: actor}, err); aerr != nil {
+		slog.Warn("Failed to audit a D-Bus action", "action", action, "error", aerr)
+	}
+}
+
+// Serve exports c on the system bus under the BusName of service until ctx is done.
+// The actions changing the daemon are recorded to a, unless it is nil.
+func Serve(ctx context.Context, service string, c control.Controller, a *audit.Logger) error {
+	conn, err := godbus.ConnectSystemBus()
+	if err != nil {
+		return fmt.Errorf("failed to connect to the system bus: %w", err)
+	}
+	defer conn.Close()
+
+	m := &manager{c: c, conn: conn, audit: a}
+	if err := conn.Export(m, ObjectPath, Interface); err != nil {
+		return err
+	}
+
+	node := &introspect.Node{
+		Name: ObjectPath,
+		Interfaces: []introspect.Interface{
+			introspect.IntrospectData,
+			{Name: Interface, Methods: introspect.Methods(m)},
+		},
+	}
+	if err := conn.Export(introspect.NewIntrospectable(node), ObjectPath, "org.freedesktop.DBus.Introspectable"); err != nil {
+		return err
+	}
+
+	name := BusName(service)
+	reply, err := conn.RequestName(name, godbus.NameFlagDoNotQueue)
+	if err != nil {
+		return fmt.Errorf("failed to request %s: %w", name, err)
+	}
+	if reply != godbus.RequestNameReplyPrimaryOwner {
+		return fmt.Errorf("%s is already owned", name)
+	}
+
+	<-ctx.Done()
+	return nil
+}
+
+// InstallPolicy writes the Policy of service to the system bus configuration directory
+func InstallPolicy(service string) error {
+	if err := os.MkdirAll(policyDir, 0o755); err != nil {
+		return err
+	}
+	name := BusName(service)
+	return os.WriteFile(policyPath(name), []byte(Policy(name)), 0o644)
+}
+
+// RemovePolicy removes the policy of service written by InstallPolicy
+func RemovePolicy(service string) error {
+	if err := os.Remove(policyPath(BusName(service))); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// policyPath returns the policy file of busName
+func policyPath(busName string) string {
+	return filepath.Join(policyDir, busName+".conf")
+}
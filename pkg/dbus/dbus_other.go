@@ -0,0 +1,25 @@
+//go:build !linux
+
+package dbus
+
+import (
+	"context"
+
+	"github.com/lucasdecamargo/go-appservice-example/pkg/audit"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/control"
+)
+
+// Serve returns ErrUnsupported outside Linux
+func Serve(ctx context.Context, service string, c control.Controller, a *audit.Logger) error {
+	return ErrUnsupported
+}
+
+// InstallPolicy is a no-op outside Linux
+func InstallPolicy(service string) error {
+	return nil
+}
+
+// RemovePolicy is a no-op outside Linux
+func RemovePolicy(service string) error {
+	return nil
+}
@@ -0,0 +1,61 @@
+package detach
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+)
+
+// settleTime is how long the detached process must survive to be reported as started
+const settleTime = time.Second
+
+// Options describe the process started in the background
+type Options struct {
+	Args    []string // Arguments passed to the current executable
+	LogFile string   // File receiving stdout and stderr, appended to
+	Stdin   *os.File // Handed to the process when set, otherwise it reads from the null device
+}
+
+// Start re-executes the current program with opts.Args, detached from the terminal and
+// session, and returns its PID once it has survived its first second
+func Start(opts Options) (int, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return 0, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(opts.LogFile), 0o755); err != nil {
+		return 0, fmt.Errorf("failed to create log directory: %w", err)
+	}
+	logFile, err := os.OpenFile(opts.LogFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o640)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open log file: %w", err)
+	}
+	defer logFile.Close()
+
+	cmd := exec.Command(exe, opts.Args...)
+	cmd.Stdin = opts.Stdin
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	configureCommand(cmd)
+
+	if err := cmd.Start(); err != nil {
+		return 0, fmt.Errorf("failed to start detached process: %w", err)
+	}
+
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+
+	select {
+	case err := <-exited:
+		if err == nil {
+			err = errors.New("exited")
+		}
+		return 0, fmt.Errorf("detached process stopped early, see %s: %w", opts.LogFile, err)
+	case <-time.After(settleTime):
+		return cmd.Process.Pid, nil
+	}
+}
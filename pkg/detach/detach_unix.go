@@ -0,0 +1,13 @@
+//go:build !windows
+
+package detach
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// configureCommand starts the process in a new session, without a controlling terminal
+func configureCommand(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+}
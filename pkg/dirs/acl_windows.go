@@ -0,0 +1,65 @@
+package dirs
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/windows"
+)
+
+// Restrict replaces the inherited ACL of path with one granting full control to
+// SYSTEM, Administrators and owner, and read access to readers. Accounts are user
+// or group names; empty ones are skipped.
+func Restrict(path, owner string, readers ...string) error {
+	entries := make([]windows.EXPLICIT_ACCESS, 0, 3+len(readers))
+	for _, wk := range []windows.WELL_KNOWN_SID_TYPE{windows.WinLocalSystemSid, windows.WinBuiltinAdministratorsSid} {
+		sid, err := windows.CreateWellKnownSid(wk)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, grant(sid, windows.GENERIC_ALL))
+	}
+
+	if owner != "" {
+		sid, _, _, err := windows.LookupSID("", owner)
+		if err != nil {
+			return fmt.Errorf("cannot resolve account %s: %w", owner, err)
+		}
+		entries = append(entries, grant(sid, windows.GENERIC_ALL))
+	}
+	for _, reader := range readers {
+		if reader == "" {
+			continue
+		}
+		sid, _, _, err := windows.LookupSID("", reader)
+		if err != nil {
+			return fmt.Errorf("cannot resolve account %s: %w", reader, err)
+		}
+		entries = append(entries, grant(sid, windows.GENERIC_READ|windows.GENERIC_EXECUTE))
+	}
+
+	acl, err := windows.ACLFromEntries(entries, nil)
+	if err != nil {
+		return err
+	}
+
+	// A protected DACL stops the permissive parent ACL from being inherited
+	info := windows.SECURITY_INFORMATION(windows.DACL_SECURITY_INFORMATION | windows.PROTECTED_DACL_SECURITY_INFORMATION)
+	if err := windows.SetNamedSecurityInfo(path, windows.SE_FILE_OBJECT, info, nil, nil, acl, nil); err != nil {
+		return fmt.Errorf("cannot restrict access to %s: %w", path, err)
+	}
+	return nil
+}
+
+// grant builds an inheritable access entry for sid
+func grant(sid *windows.SID, mask windows.ACCESS_MASK) windows.EXPLICIT_ACCESS {
+	return windows.EXPLICIT_ACCESS{
+		AccessPermissions: mask,
+		AccessMode:        windows.GRANT_ACCESS,
+		Inheritance:       windows.SUB_CONTAINERS_AND_OBJECTS_INHERIT,
+		Trustee: windows.TRUSTEE{
+			TrusteeForm:  windows.TRUSTEE_IS_SID,
+			TrusteeType:  windows.TRUSTEE_IS_UNKNOWN,
+			TrusteeValue: windows.TrusteeValueFromSID(sid),
+		},
+	}
+}
@@ -0,0 +1,26 @@
+//go:build !windows
+
+package dirs
+
+import (
+	"os"
+	"os/user"
+	"strconv"
+)
+
+// chown hands path to the named user and their primary group
+func chown(path, username string) error {
+	u, err := user.Lookup(username)
+	if err != nil {
+		return err
+	}
+	uid, err := strconv.Atoi(u.Uid)
+	if err != nil {
+		return err
+	}
+	gid, err := strconv.Atoi(u.Gid)
+	if err != nil {
+		return err
+	}
+	return os.Chown(path, uid, gid)
+}
@@ -0,0 +1,6 @@
+package dirs
+
+// chown is a no-op on Windows, where access is governed by ACLs. See Restrict.
+func chown(path, username string) error {
+	return nil
+}
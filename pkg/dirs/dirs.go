@@ -0,0 +1,84 @@
+package dirs
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+)
+
+const dirMode = 0o755
+
+// Ensure creates each directory if missing, hands ownership to the named user when
+// set, and verifies it is writable. It fails on the first unusable path.
+func Ensure(username string, paths ...string) error {
+	for _, path := range paths {
+		if path == "" {
+			continue
+		}
+		if err := ensure(username, path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ensure prepares a single directory
+func ensure(username, path string) error {
+	fi, err := os.Stat(path)
+	switch {
+	case os.IsNotExist(err):
+		if err := os.MkdirAll(path, dirMode); err != nil {
+			return fmt.Errorf("cannot create directory %s: %w", path, err)
+		}
+		if username != "" {
+			if err := chown(path, username); err != nil {
+				return fmt.Errorf("cannot hand %s to user %s: %w", path, username, err)
+			}
+		}
+	case err != nil:
+		return fmt.Errorf("cannot access %s: %w", path, err)
+	case !fi.IsDir():
+		return fmt.Errorf("%s is not a directory", path)
+	}
+
+	return checkWritable(path)
+}
+
+// checkWritable verifies that the current process can create files in path
+func checkWritable(path string) error {
+	f, err := os.CreateTemp(path, ".svcapp-write-check-*")
+	if err != nil {
+		return fmt.Errorf("directory %s is not writable: %w", path, err)
+	}
+	name := f.Name()
+	f.Close()
+	return os.Remove(name)
+}
+
+// ExpandHome resolves a leading "~" to the home directory of the named user, or of
+// the current user when username is empty
+func ExpandHome(path, username string) (string, error) {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path, nil
+	}
+
+	var u *user.User
+	var err error
+	if username != "" {
+		u, err = user.Lookup(username)
+	} else {
+		u, err = user.Current()
+	}
+	if err != nil {
+		return "", fmt.Errorf("cannot resolve home directory: %w", err)
+	}
+
+	return filepath.Join(u.HomeDir, strings.TrimPrefix(path, "~")), nil
+}
+
+// Chown hands path to the named user and their primary group. It does nothing on Windows.
+func Chown(path, username string) error {
+	return chown(path, username)
+}
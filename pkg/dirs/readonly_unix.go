@@ -0,0 +1,13 @@
+//go:build !windows
+
+package dirs
+
+import (
+	"errors"
+	"syscall"
+)
+
+// IsReadOnly reports whether err comes from writing to a read-only file system
+func IsReadOnly(err error) bool {
+	return errors.Is(err, syscall.EROFS)
+}
@@ -0,0 +1,12 @@
+package dirs
+
+import (
+	"errors"
+
+	"golang.org/x/sys/windows"
+)
+
+// IsReadOnly reports whether err comes from writing to a write-protected volume
+func IsReadOnly(err error) bool {
+	return errors.Is(err, windows.ERROR_WRITE_PROTECT)
+}
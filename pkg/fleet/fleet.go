@@ -0,0 +1,321 @@
+package fleet
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/lucasdecamargo/go-appservice-example/pkg/atomicfile"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/audit"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/control"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/daemon"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/state"
+)
+
+const (
+	defaultInterval = 30 * time.Second
+	requestTimeout  = 10 * time.Second
+	maxResponseSize = 1 << 20
+
+	// maxCommandLifetime bounds the expiry of a command, and so the time its ID has to
+	// be remembered to reject replays
+	maxCommandLifetime = 10 * time.Minute
+)
+
+// Commands accepted from the management server
+const (
+	CommandStop    = daemon.ActionStop
+	CommandRestart = daemon.ActionRestart
+	CommandCancel  = "cancel"
+	CommandReload  = "reload"
+)
+
+// Options configures an Agent
+type Options struct {
+	URL       string            // Management endpoint, https only
+	ID        string            // Agent ID, the hostname by default
+	Token     string            // Bearer token sent with every request
+	PublicKey ed25519.PublicKey // Key verifying commands, nil to ignore commands
+	Interval  time.Duration     // Heartbeat interval
+	Audit     *audit.Logger     // Records the commands run, nil to disable
+	SeenFile  string            // Keeps the IDs of executed commands across restarts, empty to keep them in memory
+}
+
+// Command is a control action signed by the management server
+type Command struct {
+	ID        string    `json:"id"`
+	Action    string    `json:"action"`      // stop, restart, cancel or reload
+	At        time.Time `json:"at,omitzero"` // When to stop or restart, immediately when zero
+	Expires   time.Time `json:"expires"`     // The command is rejected after this time, at most 10 minutes ahead
+	Signature string    `json:"signature"`   // Base64 ed25519 signature of Payload
+}
+
+// Payload returns the bytes signed for agent: the agent ID, command ID, action, time
+// and expiry, one per line, with times in RFC 3339 UTC
+func (c *Command) Payload(agent string) []byte {
+	at := ""
+	if !c.At.IsZero() {
+		at = c.At.UTC().Format(time.RFC3339Nano)
+	}
+	return fmt.Appendf(nil, "%s\n%s\n%s\n%s\n%s", agent, c.ID, c.Action, at, c.Expires.UTC().Format(time.RFC3339Nano))
+}
+
+// Result reports the outcome of a command
+type Result struct {
+	ID    string `json:"id"`
+	Error string `json:"error,omitempty"`
+}
+
+// Heartbeat is sent on registration and then periodically
+type Heartbeat struct {
+	ID       string      `json:"id"`
+	Hostname string      `json:"hostname"`
+	Platform string      `json:"platform"`
+	Time     time.Time   `json:"time"`
+	Status   state.State `json:"status"`
+	Results  []Result    `json:"results,omitempty"` // Outcomes of the commands received since the last heartbeat
+}
+
+// HeartbeatResponse is returned by the management server
+type HeartbeatResponse struct {
+	Commands []Command `json:"commands,omitempty"`
+}
+
+// Agent registers the daemon with a management server, reports heartbeats and runs
+// the signed commands it receives
+type Agent struct {
+	Options
+	c       control.Controller
+	client  *http.Client
+	seen    map[string]time.Time // IDs of executed commands, until they expire
+	results []Result
+}
+
+// New creates an agent reporting c
+func New(opts Options, c control.Controller) (*Agent, error) {
+	u, err := url.Parse(opts.URL)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return nil, fmt.Errorf("fleet URL must be an https:// URL: %q", opts.URL)
+	}
+	if opts.ID == "" {
+		if opts.ID, err = os.Hostname(); err != nil {
+			return nil, fmt.Errorf("fleet agent ID not set: %w", err)
+		}
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = defaultInterval
+	}
+
+	a := &Agent{
+		Options: opts,
+		c:       c,
+		client:  &http.Client{Timeout: requestTimeout},
+		seen:    map[string]time.Time{},
+	}
+	a.loadSeen()
+	return a, nil
+}
+
+// loadSeen reads the IDs of the commands executed before a restart from SeenFile
+func (a *Agent) loadSeen() {
+	if a.SeenFile == "" {
+		return
+	}
+	data, err := os.ReadFile(a.SeenFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return
+	}
+	if err == nil {
+		err = json.Unmarshal(data, &a.seen)
+	}
+	if err != nil {
+		slog.Warn("Failed to read the executed fleet commands", "path", a.SeenFile, "error", err)
+	}
+}
+
+// saveSeen writes the IDs of the executed commands to SeenFile
+func (a *Agent) saveSeen() {
+	if a.SeenFile == "" {
+		return
+	}
+	data, err := json.Marshal(a.seen)
+	if err == nil {
+		err = atomicfile.WriteFile(a.SeenFile, data, 0o600, true)
+	}
+	if err != nil {
+		slog.Warn("Failed to save the executed fleet commands", "path", a.SeenFile, "error", err)
+	}
+}
+
+// ParsePublicKey decodes a base64 ed25519 public key
+func ParsePublicKey(s string) (ed25519.PublicKey, error) {
+	key, err := base64.StdEncoding.DecodeString(s)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return nil, errors.New("fleet public key must be a base64 ed25519 key")
+	}
+	return ed25519.PublicKey(key), nil
+}
+
+// Run registers the agent and sends heartbeats until ctx is done. Failed requests
+// are retried on the next interval.
+func (a *Agent) Run(ctx context.Context) {
+	ticker := time.NewTicker(a.Interval)
+	defer ticker.Stop()
+
+	registered := false
+	for {
+		if !registered {
+			if err := a.post(ctx, "/v1/agents/register", a.heartbeat(), nil); err != nil {
+				slog.Warn("Fleet registration failed", "url", a.URL, "error", err)
+			} else {
+				slog.Info("Registered with fleet server", "url", a.URL, "id", a.ID)
+				registered = true
+			}
+		} else {
+			var resp HeartbeatResponse
+			if err := a.post(ctx, "/v1/agents/"+url.PathEscape(a.ID)+"/heartbeat", a.heartbeat(), &resp); err != nil {
+				slog.Warn("Fleet heartbeat failed", "url", a.URL, "error", err)
+			} else {
+				a.results = nil
+				for _, cmd := range resp.Commands {
+					a.results = append(a.results, a.execute(cmd))
+				}
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// heartbeat builds the next heartbeat
+func (a *Agent) heartbeat() Heartbeat {
+	hostname, _ := os.Hostname()
+	return Heartbeat{
+		ID:       a.ID,
+		Hostname: hostname,
+		Platform: runtime.GOOS + "/" + runtime.GOARCH,
+		Time:     time.Now(),
+		Status:   a.c.Status(),
+		Results:  a.results,
+	}
+}
+
+// execute verifies and runs a command
+func (a *Agent) execute(cmd Command) Result {
+	if err := a.verify(cmd); err != nil {
+		slog.Warn("Rejected fleet command", "id", cmd.ID, "action", cmd.Action, "error", err)
+		a.record(cmd, err)
+		return Result{ID: cmd.ID, Error: err.Error()}
+	}
+	a.seen[cmd.ID] = cmd.Expires
+	a.saveSeen()
+
+	slog.Info("Running fleet command", "id", cmd.ID, "action", cmd.Action)
+	var err error
+	switch cmd.Action {
+	case CommandStop, CommandRestart:
+		at := cmd.At
+		if at.IsZero() {
+			at = time.Now()
+		}
+		err = a.c.Schedule(cmd.Action, at)
+	case CommandCancel:
+		a.c.CancelSchedule()
+	case CommandReload:
+		err = a.c.Reload()
+	default:
+		err = fmt.Errorf("unknown command %q", cmd.Action)
+	}
+
+	a.record(cmd, err)
+	if err != nil {
+		return Result{ID: cmd.ID, Error: err.Error()}
+	}
+	return Result{ID: cmd.ID}
+}
+
+// record audits cmd, run or rejected with err
+func (a *Agent) record(cmd Command, err error) {
+	r := audit.Record{
+		Source: audit.SourceFleet,
+		Action: cmd.Action,
+		Actor:  audit.Actor{Identity: fmt.Sprintf("command %s from %s", cmd.ID, a.URL)},
+	}
+	if aerr := a.Audit.Record(r, err); aerr != nil {
+		slog.Warn("Failed to audit a fleet command", "id", cmd.ID, "error", aerr)
+	}
+}
+
+// verify checks the signature, expiry and uniqueness of a command
+func (a *Agent) verify(cmd Command) error {
+	if a.PublicKey == nil {
+		return errors.New("no public key configured to verify commands")
+	}
+
+	now := time.Now()
+	for id, expires := range a.seen {
+		if now.After(expires) {
+			delete(a.seen, id)
+		}
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(cmd.Signature)
+	switch {
+	case err != nil || !ed25519.Verify(a.PublicKey, cmd.Payload(a.ID), sig):
+		return errors.New("invalid signature")
+	case now.After(cmd.Expires):
+		return errors.New("command expired")
+	case cmd.Expires.After(now.Add(maxCommandLifetime)):
+		return fmt.Errorf("command expires more than %s ahead", maxCommandLifetime)
+	case !a.seen[cmd.ID].IsZero():
+		return errors.New("command already executed")
+	}
+	return nil
+}
+
+// post sends body as JSON to the management server and decodes the JSON response into out
+func (a *Agent) post(ctx context.Context, route string, body, out any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(a.URL, "/")+route, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if a.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+a.Token)
+	}
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("fleet server returned %s", resp.Status)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize)).Decode(out)
+}
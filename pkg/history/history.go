@@ -0,0 +1,127 @@
+package history
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/lucasdecamargo/go-appservice-example/pkg/atomicfile"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/retention"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/state"
+)
+
+// Event kinds recorded by the daemon
+const (
+	KindStart    = "start"    // From a start or restart request to the child being ready
+	KindStop     = "stop"     // From a stop request to the child having exited
+	KindDrift    = "drift"    // The child arguments or environment changed since the previous run
+	KindJob      = "job"      // A scheduled job ran, named by Detail
+	KindCrash    = "crash"    // The child exited with an error without being asked to
+	KindLiveness = "liveness" // The child missed a heartbeat
+	KindUpdate   = "update"   // A new version is available, installed or rolled back, described by Detail
+	KindRestart  = "restart"  // The child was restarted, for the reason in Detail
+	KindSnapshot = "snapshot" // A snapshot was taken or restored, described by Detail
+)
+
+// Event is a single entry of the daemon history
+type Event struct {
+	Time     time.Time     `json:"time"`
+	Kind     string        `json:"kind"`
+	Duration time.Duration `json:"duration"`
+	Error    string        `json:"error,omitempty"`
+	Detail   string        `json:"detail,omitempty"`
+}
+
+// DefaultPath returns the history file path, next to the state file
+func DefaultPath() string {
+	return filepath.Join(filepath.Dir(state.DefaultPath()), "history.jsonl")
+}
+
+// Append adds an event to the history file at path, one JSON document per line.
+// With sync, the event is on storage when Append returns.
+func Append(path string, e Event, sync bool) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if err := atomicfile.Append(path, append(data, '\n'), 0o644, sync); err != nil {
+		return fmt.Errorf("failed to write history: %w", err)
+	}
+	return nil
+}
+
+// Load reads the history file at path. A missing file yields no events.
+func Load(path string) ([]Event, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+	defer f.Close()
+
+	var events []Event
+	sc := bufio.NewScanner(f)
+	for line := 1; sc.Scan(); line++ {
+		var e Event
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("failed to parse history %s line %d: %w", path, line, err)
+		}
+		events = append(events, e)
+	}
+	return events, sc.Err()
+}
+
+// Prune deletes the oldest events of the history file at path that don't fit p, and
+// returns how many were deleted. The file is rewritten in place while locked, so
+// events appended meanwhile are kept.
+func Prune(path string, p retention.Policy, sync bool) (int, error) {
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to open history: %w", err)
+	}
+	defer f.Close()
+	if err := atomicfile.Lock(f); err != nil {
+		return 0, err
+	}
+	defer atomicfile.Unlock(f)
+
+	var lines [][]byte
+	var records []retention.Record
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		var e Event
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			return 0, fmt.Errorf("failed to parse history %s line %d: %w", path, len(lines)+1, err)
+		}
+		lines = append(lines, append(bytes.Clone(sc.Bytes()), '\n'))
+		records = append(records, retention.Record{Time: e.Time, Size: int64(len(sc.Bytes()) + 1)})
+	}
+	if err := sc.Err(); err != nil {
+		return 0, fmt.Errorf("failed to read history: %w", err)
+	}
+
+	n := p.Expired(records, time.Now())
+	if n == 0 {
+		return 0, nil
+	}
+	if err := f.Truncate(0); err != nil {
+		return 0, fmt.Errorf("failed to prune history: %w", err)
+	}
+	if _, err := f.WriteAt(bytes.Join(lines[n:], nil), 0); err != nil {
+		return 0, fmt.Errorf("failed to prune history: %w", err)
+	}
+	if sync {
+		return n, f.Sync()
+	}
+	return n, nil
+}
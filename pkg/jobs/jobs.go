@@ -0,0 +1,230 @@
+// Package jobs runs short-lived commands, such as backups or cache warms, on a
+// schedule next to the supervised child.
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"slices"
+	"sync"
+	"time"
+)
+
+// jobWaitDelay bounds the wait for the output of a job killed on timeout or stop
+const jobWaitDelay = 5 * time.Second
+
+// upcomingRuns is the number of run times previewed in the job status
+const upcomingRuns = 5
+
+var (
+	// ErrUnknownJob is returned when no job has the requested name
+	ErrUnknownJob = errors.New("unknown job")
+	// ErrJobRunning is returned when a job is triggered while it runs
+	ErrJobRunning = errors.New("job already running")
+)
+
+// Job is a command run on a schedule
+type Job struct {
+	Name     string
+	Command  []string      // Executable and arguments
+	Schedule string        // See ParseSchedule
+	TimeZone string        // IANA time zone of a cron schedule, the local one when empty
+	DST      DSTPolicy     // Handling of daylight saving transitions, DSTRunOnce when empty
+	Timeout  time.Duration // Kills the job after this long, zero for no limit
+	Env      []string      // KEY=VALUE pairs added to the daemon environment
+	Output   io.Writer     // Receives the job stdout and stderr, discarded when nil
+}
+
+// Result is the outcome of one run of a job
+type Result struct {
+	Name     string
+	Started  time.Time
+	Duration time.Duration
+	Err      error
+}
+
+// Status describes a job and its last run
+type Status struct {
+	Name         string        `json:"name"`
+	Schedule     string        `json:"schedule"`
+	TimeZone     string        `json:"timeZone,omitempty"`
+	Next         time.Time     `json:"next,omitzero"`
+	Upcoming     []time.Time   `json:"upcoming,omitempty"` // Next run times, in the job time zone
+	Running      bool          `json:"running"`
+	LastRun      time.Time     `json:"lastRun,omitzero"`
+	LastDuration time.Duration `json:"lastDuration,omitempty"`
+	LastError    string        `json:"lastError,omitempty"`
+}
+
+// Scheduler runs jobs on their schedules. A job never overlaps with itself: a run
+// due while the previous one is still going is skipped.
+type Scheduler struct {
+	onDone func(Result)
+
+	mu   sync.Mutex
+	jobs []*entry
+}
+
+// entry is a job with its parsed schedule and its status, guarded by Scheduler.mu
+type entry struct {
+	Job
+	schedule Schedule
+	trigger  chan struct{}
+	status   Status
+}
+
+// New validates jobs and creates their scheduler. onDone, when set, is called after
+// each run.
+func New(jobs []Job, onDone func(Result)) (*Scheduler, error) {
+	s := &Scheduler{onDone: onDone}
+	for _, j := range jobs {
+		if j.Name == "" {
+			return nil, errors.New("job name is required")
+		}
+		if slices.ContainsFunc(s.jobs, func(e *entry) bool { return e.Name == j.Name }) {
+			return nil, fmt.Errorf("job %q is defined twice", j.Name)
+		}
+		if len(j.Command) == 0 {
+			return nil, fmt.Errorf("job %q has no command", j.Name)
+		}
+		schedule, err := NewSchedule(j.Schedule, j.TimeZone, j.DST)
+		if err != nil {
+			return nil, fmt.Errorf("job %q: %w", j.Name, err)
+		}
+		if j.Output == nil {
+			j.Output = io.Discard
+		}
+		s.jobs = append(s.jobs, &entry{
+			Job:      j,
+			schedule: schedule,
+			trigger:  make(chan struct{}, 1),
+			status:   Status{Name: j.Name, Schedule: j.Schedule, TimeZone: j.TimeZone},
+		})
+	}
+	return s, nil
+}
+
+// Run runs the jobs until ctx is done, then kills the running ones and waits for them
+func (s *Scheduler) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, e := range s.jobs {
+		wg.Go(func() { s.loop(ctx, e) })
+	}
+	wg.Wait()
+}
+
+// Trigger runs the named job now, outside of its schedule
+func (s *Scheduler) Trigger(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := slices.IndexFunc(s.jobs, func(e *entry) bool { return e.Name == name })
+	if i < 0 {
+		return fmt.Errorf("%w %q", ErrUnknownJob, name)
+	}
+	e := s.jobs[i]
+	if e.status.Running {
+		return fmt.Errorf("%w: %s", ErrJobRunning, name)
+	}
+	select {
+	case e.trigger <- struct{}{}:
+	default: // Already triggered
+	}
+	return nil
+}
+
+// Status returns the status of every job, in configuration order
+func (s *Scheduler) Status() []Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	statuses := make([]Status, len(s.jobs))
+	for i, e := range s.jobs {
+		statuses[i] = e.status
+	}
+	return statuses
+}
+
+// loop runs a job each time it is due or triggered, until ctx is done
+func (s *Scheduler) loop(ctx context.Context, e *entry) {
+	for {
+		upcoming := Upcoming(e.schedule, time.Now(), upcomingRuns)
+		var next time.Time
+		if len(upcoming) > 0 {
+			next = upcoming[0]
+		}
+		s.mu.Lock()
+		e.status.Next, e.status.Upcoming = next, upcoming
+		s.mu.Unlock()
+
+		timer := time.NewTimer(time.Until(next))
+		due := timer.C
+		if next.IsZero() {
+			due = nil // Only runs when triggered
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-due:
+		case <-e.trigger:
+		}
+		timer.Stop()
+		if ctx.Err() != nil {
+			return
+		}
+		s.run(ctx, e)
+	}
+}
+
+// run executes a job once and records its outcome
+func (s *Scheduler) run(ctx context.Context, e *entry) {
+	if e.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, e.Timeout)
+		defer cancel()
+	}
+
+	cmd := exec.CommandContext(ctx, e.Command[0], e.Command[1:]...)
+	if len(e.Env) > 0 {
+		cmd.Env = append(os.Environ(), e.Env...)
+	}
+	cmd.Stdout, cmd.Stderr = e.Output, e.Output
+	cmd.WaitDelay = jobWaitDelay
+
+	started := time.Now()
+	s.mu.Lock()
+	e.status.Running = true
+	s.mu.Unlock()
+
+	slog.Info("Job started", "job", e.Name)
+	fmt.Fprintf(e.Output, "--- %s started at %s\n", e.Name, started.Format(time.RFC3339))
+	err := cmd.Run()
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		err = fmt.Errorf("timed out after %v: %w", e.Timeout, err)
+	}
+	r := Result{Name: e.Name, Started: started, Duration: time.Since(started), Err: err}
+
+	s.mu.Lock()
+	e.status.Running = false
+	e.status.LastRun = started
+	e.status.LastDuration = r.Duration
+	e.status.LastError = ""
+	if err != nil {
+		e.status.LastError = err.Error()
+	}
+	s.mu.Unlock()
+
+	if err != nil {
+		slog.Warn("Job failed", "job", e.Name, "duration", r.Duration, "error", err)
+	} else {
+		slog.Info("Job completed", "job", e.Name, "duration", r.Duration)
+	}
+	if s.onDone != nil {
+		s.onDone(r)
+	}
+}
@@ -0,0 +1,301 @@
+package jobs
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	_ "time/tzdata" // Windows has no zoneinfo database
+)
+
+// DSTPolicy selects how a cron schedule handles the local times a daylight saving
+// transition skips or repeats
+type DSTPolicy string
+
+// DST policies
+const (
+	DSTSkip     DSTPolicy = "skip"      // Skipped times don't run, repeated times run once
+	DSTRunOnce  DSTPolicy = "run-once"  // Skipped times run at the transition, repeated times run once
+	DSTRunTwice DSTPolicy = "run-twice" // Skipped times run at the transition, repeated times run twice
+)
+
+// DSTPolicies lists the valid DST policies
+var DSTPolicies = []DSTPolicy{DSTSkip, DSTRunOnce, DSTRunTwice}
+
+// transitionWindow bounds the search for the instant of a daylight saving transition
+const transitionWindow = 12 * time.Hour
+
+// Schedule computes the run times of a job
+type Schedule interface {
+	// Next returns the first run time after t, or the zero time if there is none
+	Next(t time.Time) time.Time
+}
+
+// descriptors are the shorthands accepted in place of a cron expression
+var descriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// ParseSchedule parses "@every <duration>", a descriptor such as "@daily", or a
+// five-field cron expression "minute hour day-of-month month day-of-week". Fields
+// accept *, numbers, ranges a-b, lists and /step; Sunday is 0 or 7.
+func ParseSchedule(spec string) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if d, ok := strings.CutPrefix(spec, "@every "); ok {
+		interval, err := time.ParseDuration(strings.TrimSpace(d))
+		if err != nil || interval <= 0 {
+			return nil, fmt.Errorf("invalid schedule %q: expected a positive duration", spec)
+		}
+		return every(interval), nil
+	}
+	if expr, ok := descriptors[spec]; ok {
+		spec = expr
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("invalid schedule %q: expected 5 cron fields", spec)
+	}
+
+	var c cron
+	var err error
+	bounds := []struct {
+		set      *uint64
+		min, max int
+	}{{&c.minute, 0, 59}, {&c.hour, 0, 23}, {&c.dom, 1, 31}, {&c.month, 1, 12}, {&c.dow, 0, 7}}
+	for i, b := range bounds {
+		if *b.set, err = parseField(fields[i], b.min, b.max); err != nil {
+			return nil, fmt.Errorf("invalid schedule %q: %w", spec, err)
+		}
+	}
+	if c.dow&(1<<7) != 0 {
+		c.dow |= 1 // Sunday
+	}
+	c.domAny, c.dowAny = fields[2] == "*", fields[4] == "*"
+	return c, nil
+}
+
+// NewSchedule parses spec like ParseSchedule. Cron expressions are evaluated in the
+// IANA time zone tz, the local one when empty, and handle daylight saving transitions
+// with dst, DSTRunOnce when empty. Intervals ignore both.
+func NewSchedule(spec, tz string, dst DSTPolicy) (Schedule, error) {
+	schedule, err := ParseSchedule(spec)
+	if err != nil {
+		return nil, err
+	}
+	loc := time.Local
+	if tz != "" {
+		if loc, err = time.LoadLocation(tz); err != nil {
+			return nil, fmt.Errorf("invalid time zone %q: %w", tz, err)
+		}
+	}
+	if dst == "" {
+		dst = DSTRunOnce
+	}
+	if !slices.Contains(DSTPolicies, dst) {
+		return nil, fmt.Errorf("invalid DST policy %q, use skip, run-once or run-twice", dst)
+	}
+
+	c, ok := schedule.(cron)
+	if !ok {
+		return schedule, nil
+	}
+	return zoned{cron: c, loc: loc, dst: dst}, nil
+}
+
+// Upcoming returns the next n run times of s after t
+func Upcoming(s Schedule, t time.Time, n int) []time.Time {
+	var runs []time.Time
+	for range n {
+		if t = s.Next(t); t.IsZero() {
+			break
+		}
+		runs = append(runs, t)
+	}
+	return runs
+}
+
+// every runs a job at a fixed interval
+type every time.Duration
+
+func (e every) Next(t time.Time) time.Time {
+	return t.Add(time.Duration(e))
+}
+
+// cron runs a job at the times matching a cron expression, one bit per allowed value
+type cron struct {
+	minute, hour, dom, month, dow uint64
+	domAny, dowAny                bool
+}
+
+// cronHorizon bounds the search for the next run of expressions that rarely match
+const cronHorizon = 5 * 366 * 24 * time.Hour
+
+func (c cron) Next(t time.Time) time.Time {
+	loc := t.Location()
+	t = t.Truncate(time.Minute).Add(time.Minute)
+
+	for limit := t.Add(cronHorizon); t.Before(limit); {
+		y, m, d := t.Date()
+		switch {
+		case c.month&(1<<uint(m)) == 0:
+			t = time.Date(y, m+1, 1, 0, 0, 0, 0, loc)
+		case !c.dayMatches(t):
+			t = time.Date(y, m, d+1, 0, 0, 0, 0, loc)
+		case c.hour&(1<<uint(t.Hour())) == 0:
+			t = time.Date(y, m, d, t.Hour()+1, 0, 0, 0, loc)
+		case c.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// zoned evaluates a cron expression on the wall clock of a time zone
+type zoned struct {
+	cron
+	loc *time.Location
+	dst DSTPolicy
+}
+
+func (z zoned) Next(t time.Time) time.Time {
+	// Start early enough for a repeated time before the wall clock of t to still run
+	// at its second occurrence
+	c := wallClock(t.In(z.loc)).Truncate(time.Minute).Add(-time.Minute - z.setBack(t, t.Add(transitionWindow)))
+	var next time.Time
+	for limit := c.Add(cronHorizon); ; {
+		if c = z.cron.Next(c); c.IsZero() || c.After(limit) {
+			return next
+		}
+		// Within a repeated hour, a later wall clock time may run earlier: keep the
+		// earliest run until the wall clock times are past those that could
+		if !next.IsZero() && !c.Before(wallClock(next).Add(z.setBack(next.Add(-transitionWindow), next))) {
+			return next
+		}
+		for _, run := range z.runs(c) {
+			if run.After(t) && (next.IsZero() || run.Before(next)) {
+				next = run
+			}
+		}
+	}
+}
+
+// setBack returns by how much the wall clock of the zone is set back between the
+// instants from and to, zero when it isn't
+func (z zoned) setBack(from, to time.Time) time.Duration {
+	_, before := from.In(z.loc).Zone()
+	_, after := to.In(z.loc).Zone()
+	return time.Duration(max(before-after, 0)) * time.Second
+}
+
+// runs returns the instants a job due at the wall clock time c runs at
+func (z zoned) runs(c time.Time) []time.Time {
+	instants := z.instants(c)
+	switch {
+	case len(instants) == 0 && z.dst == DSTSkip:
+		return nil
+	case len(instants) == 0:
+		return []time.Time{z.transition(c)}
+	case z.dst != DSTRunTwice:
+		return instants[:1]
+	}
+	return instants
+}
+
+// instants returns the instants showing the wall clock time c in the zone: none when
+// a transition skips it, two when one repeats it
+func (z zoned) instants(c time.Time) []time.Time {
+	guess := time.Date(c.Year(), c.Month(), c.Day(), c.Hour(), c.Minute(), 0, 0, z.loc)
+	var instants []time.Time
+	for _, d := range []time.Duration{-transitionWindow, 0, transitionWindow} {
+		_, offset := guess.Add(d).Zone()
+		u := time.Unix(c.Unix()-int64(offset), 0).In(z.loc)
+		if wallClock(u).Equal(c) && !slices.ContainsFunc(instants, u.Equal) {
+			instants = append(instants, u)
+		}
+	}
+	slices.SortFunc(instants, time.Time.Compare)
+	return instants
+}
+
+// transition returns the first instant showing a wall clock time after c, which a
+// transition skips
+func (z zoned) transition(c time.Time) time.Time {
+	guess := time.Date(c.Year(), c.Month(), c.Day(), c.Hour(), c.Minute(), 0, 0, z.loc)
+	lo, hi := guess.Add(-transitionWindow).Unix(), guess.Add(transitionWindow).Unix()
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		if wallClock(time.Unix(mid, 0).In(z.loc)).After(c) {
+			hi = mid
+		} else {
+			lo = mid + 1
+		}
+	}
+	return time.Unix(lo, 0).In(z.loc)
+}
+
+// wallClock returns the wall clock time of t as the same time in UTC
+func wallClock(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+}
+
+// dayMatches applies the cron rule that a day matches either restricted day field
+// when both are restricted
+func (c cron) dayMatches(t time.Time) bool {
+	dom := c.dom&(1<<uint(t.Day())) != 0
+	dow := c.dow&(1<<uint(t.Weekday())) != 0
+	if c.domAny || c.dowAny {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// parseField returns the set of values in [min, max] selected by a cron field
+func parseField(field string, min, max int) (uint64, error) {
+	var set uint64
+	for _, part := range strings.Split(field, ",") {
+		expr, stepStr, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepStr); err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step in %q", part)
+			}
+		}
+
+		lo, hi := min, max
+		if expr != "*" {
+			loStr, hiStr, isRange := strings.Cut(expr, "-")
+			var err error
+			if lo, err = strconv.Atoi(loStr); err != nil {
+				return 0, fmt.Errorf("invalid value in %q", part)
+			}
+			hi = lo
+			if isRange {
+				if hi, err = strconv.Atoi(hiStr); err != nil {
+					return 0, fmt.Errorf("invalid range in %q", part)
+				}
+			} else if hasStep {
+				hi = max
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return 0, fmt.Errorf("%q out of range %d-%d", part, min, max)
+		}
+
+		for v := lo; v <= hi; v += step {
+			set |= 1 << uint(v)
+		}
+	}
+	return set, nil
+}
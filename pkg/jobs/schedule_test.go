@@ -0,0 +1,61 @@
+package jobs
+
+import (
+	"testing"
+	"time"
+)
+
+func TestZonedNextDST(t *testing.T) {
+	tests := []struct {
+		name  string
+		spec  string
+		dst   DSTPolicy
+		from  string
+		count int
+		want  []string
+	}{
+		{"spring forward skip", "30 2 * * *", DSTSkip, "2026-03-07T12:00:00-05:00", 2,
+			[]string{"2026-03-09T02:30:00-04:00", "2026-03-10T02:30:00-04:00"}},
+		{"spring forward run-once", "30 2 * * *", DSTRunOnce, "2026-03-07T12:00:00-05:00", 2,
+			[]string{"2026-03-08T03:00:00-04:00", "2026-03-09T02:30:00-04:00"}},
+		{"spring forward run-twice", "30 2 * * *", DSTRunTwice, "2026-03-07T12:00:00-05:00", 2,
+			[]string{"2026-03-08T03:00:00-04:00", "2026-03-09T02:30:00-04:00"}},
+		{"fall back skip", "*/30 * * * *", DSTSkip, "2026-11-01T00:45:00-04:00", 5,
+			[]string{"2026-11-01T01:00:00-04:00", "2026-11-01T01:30:00-04:00", "2026-11-01T02:00:00-05:00", "2026-11-01T02:30:00-05:00", "2026-11-01T03:00:00-05:00"}},
+		{"fall back run-once", "*/30 * * * *", DSTRunOnce, "2026-11-01T00:45:00-04:00", 5,
+			[]string{"2026-11-01T01:00:00-04:00", "2026-11-01T01:30:00-04:00", "2026-11-01T02:00:00-05:00", "2026-11-01T02:30:00-05:00", "2026-11-01T03:00:00-05:00"}},
+		{"fall back run-twice", "*/30 * * * *", DSTRunTwice, "2026-11-01T00:45:00-04:00", 5,
+			[]string{"2026-11-01T01:00:00-04:00", "2026-11-01T01:30:00-04:00", "2026-11-01T01:00:00-05:00", "2026-11-01T01:30:00-05:00", "2026-11-01T02:00:00-05:00"}},
+		{"fall back run-twice within the repeated hour", "*/30 * * * *", DSTRunTwice, "2026-11-01T01:00:00-04:00", 3,
+			[]string{"2026-11-01T01:30:00-04:00", "2026-11-01T01:00:00-05:00", "2026-11-01T01:30:00-05:00"}},
+		{"fall back run-twice daily", "30 1 * * *", DSTRunTwice, "2026-10-31T12:00:00-04:00", 3,
+			[]string{"2026-11-01T01:30:00-04:00", "2026-11-01T01:30:00-05:00", "2026-11-02T01:30:00-05:00"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := NewSchedule(tt.spec, "America/New_York", tt.dst)
+			if err != nil {
+				t.Fatal(err)
+			}
+			from, err := time.Parse(time.RFC3339, tt.from)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := Upcoming(s, from, tt.count)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d runs %v, want %d", len(got), got, len(tt.want))
+			}
+			for i, w := range tt.want {
+				want, err := time.Parse(time.RFC3339, w)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !got[i].Equal(want) {
+					t.Errorf("run %d = %s, want %s", i, got[i].Format(time.RFC3339), w)
+				}
+			}
+		})
+	}
+}
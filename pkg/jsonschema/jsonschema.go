@@ -0,0 +1,259 @@
+// Package jsonschema validates JSON documents against the subset of JSON Schema that
+// configdoc generates: type, properties, additionalProperties, required, items,
+// pattern and enum. A format only names the pattern in errors.
+package jsonschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// maxErrors bounds the errors reported for one document
+const maxErrors = 10
+
+// Schema is a compiled JSON Schema
+type Schema struct {
+	Types                []string           `json:"-"`
+	Properties           map[string]*Schema `json:"properties"`
+	AdditionalProperties json.RawMessage    `json:"additionalProperties"`
+	Required             []string           `json:"required"`
+	Items                *Schema            `json:"items"`
+	Pattern              string             `json:"pattern"`
+	Format               string             `json:"format"`
+	Enum                 []any              `json:"enum"`
+
+	additional *Schema // Schema of the additional properties, nil when they are denied
+	open       bool    // Additional properties are allowed
+	pattern    *regexp.Regexp
+}
+
+// Error is a violation of the schema at a path of the document
+type Error struct {
+	Path    string // Dotted path, such as jobs[0].schedule, empty for the document
+	Message string
+}
+
+func (e *Error) Error() string {
+	if e.Path == "" {
+		return e.Message
+	}
+	return e.Path + ": " + e.Message
+}
+
+// Compile parses a JSON Schema document
+func Compile(data []byte) (*Schema, error) {
+	var s Schema
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+	if err := s.compile(); err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+	return &s, nil
+}
+
+// UnmarshalJSON decodes a schema, with a type given as a name or a list of names
+func (s *Schema) UnmarshalJSON(data []byte) error {
+	type plain Schema
+	var aux struct {
+		*plain
+		Type json.RawMessage `json:"type"`
+	}
+	aux.plain = (*plain)(s)
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	if len(aux.Type) == 0 {
+		return nil
+	}
+	var name string
+	if err := json.Unmarshal(aux.Type, &name); err == nil {
+		s.Types = []string{name}
+		return nil
+	}
+	return json.Unmarshal(aux.Type, &s.Types)
+}
+
+// compile prepares the patterns and additional properties of s and its subschemas
+func (s *Schema) compile() error {
+	var err error
+	if s.Pattern != "" {
+		if s.pattern, err = regexp.Compile(s.Pattern); err != nil {
+			return err
+		}
+	}
+	switch ap := string(bytes.TrimSpace(s.AdditionalProperties)); ap {
+	case "", "true":
+		s.open = true
+	case "false":
+	default:
+		s.open = true
+		if err := json.Unmarshal(s.AdditionalProperties, &s.additional); err != nil {
+			return err
+		}
+	}
+	for _, sub := range s.Properties {
+		if err := sub.compile(); err != nil {
+			return err
+		}
+	}
+	for _, sub := range []*Schema{s.additional, s.Items} {
+		if sub != nil {
+			if err := sub.compile(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Validate checks the JSON document data against s. It returns the violations, at
+// most maxErrors, joined.
+func (s *Schema) Validate(data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return err
+	}
+	var errs []error
+	s.validate(v, "", &errs)
+	return errors.Join(errs...)
+}
+
+// validate appends the violations of v, found at path, to errs
+func (s *Schema) validate(v any, path string, errs *[]error) {
+	if len(*errs) >= maxErrors {
+		return
+	}
+	fail := func(format string, a ...any) {
+		if len(*errs) < maxErrors {
+			*errs = append(*errs, &Error{Path: path, Message: fmt.Sprintf(format, a...)})
+		}
+	}
+
+	if len(s.Types) > 0 && !slices.ContainsFunc(s.Types, func(t string) bool { return hasType(v, t) }) {
+		fail("expected %s, got %s", strings.Join(s.Types, " or "), typeOf(v))
+		return
+	}
+	if len(s.Enum) > 0 && !slices.ContainsFunc(s.Enum, func(e any) bool { return equal(e, v) }) {
+		fail("must be one of %s", formatEnum(s.Enum))
+	}
+
+	switch v := v.(type) {
+	case string:
+		switch {
+		case s.pattern == nil || s.pattern.MatchString(v):
+		case s.Format != "":
+			fail("%q is not a valid %s", v, s.Format)
+		default:
+			fail("%q doesn't match %s", v, s.Pattern)
+		}
+	case []any:
+		if s.Items != nil {
+			for i, item := range v {
+				s.Items.validate(item, path+"["+strconv.Itoa(i)+"]", errs)
+			}
+		}
+	case map[string]any:
+		for _, key := range s.Required {
+			if _, ok := v[key]; !ok {
+				fail("missing required key %q", key)
+			}
+		}
+		for _, key := range slices.Sorted(maps.Keys(v)) {
+			sub := s.Properties[key]
+			if sub == nil {
+				sub = s.additional
+			}
+			switch {
+			case sub != nil:
+				sub.validate(v[key], join(path, key), errs)
+			case !s.open:
+				*errs = append(*errs, &Error{Path: join(path, key), Message: "unknown key" + suggest(key, s.Properties)})
+				if len(*errs) >= maxErrors {
+					return
+				}
+			}
+		}
+	}
+}
+
+// join appends key to the dotted path
+func join(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// hasType reports whether v is a JSON value of type t
+func hasType(v any, t string) bool {
+	switch v := v.(type) {
+	case nil:
+		return t == "null"
+	case bool:
+		return t == "boolean"
+	case string:
+		return t == "string"
+	case json.Number:
+		if t == "number" {
+			return true
+		}
+		_, err := v.Int64()
+		return t == "integer" && err == nil
+	case []any:
+		return t == "array"
+	case map[string]any:
+		return t == "object"
+	}
+	return false
+}
+
+// typeOf names the JSON type of v
+func typeOf(v any) string {
+	for _, t := range []string{"null", "boolean", "string", "integer", "number", "array", "object"} {
+		if hasType(v, t) {
+			return t
+		}
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// equal compares a schema enum value with a document value
+func equal(e, v any) bool {
+	if n, ok := v.(json.Number); ok {
+		f, err := n.Float64()
+		ef, isNum := e.(float64)
+		return err == nil && isNum && f == ef
+	}
+	return e == v
+}
+
+// formatEnum lists enum values as JSON
+func formatEnum(enum []any) string {
+	parts := make([]string, len(enum))
+	for i, e := range enum {
+		data, _ := json.Marshal(e)
+		parts[i] = string(data)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// suggest returns a hint naming the property key was probably meant to be, differing
+// only in case
+func suggest(key string, props map[string]*Schema) string {
+	for name := range props {
+		if strings.EqualFold(name, key) {
+			return fmt.Sprintf(", did you mean %q?", name)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,166 @@
+// Package kv is a small persistent key/value store the supervisor keeps for its child,
+// so a wrapped application can remember tiny bits of state, such as its last run or a
+// cursor, without setting up storage of its own
+package kv
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/lucasdecamargo/go-appservice-example/pkg/atomicfile"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/state"
+)
+
+// EnvFile names the environment variable holding the store file path, which the child
+// can read directly. Writes go through the control socket.
+const EnvFile = "SVCAPP_KV_FILE"
+
+// Store limits, keeping the store small enough to be rewritten on every change
+const (
+	MaxKeys      = 1000
+	MaxKeySize   = 256
+	MaxValueSize = 64 << 10
+)
+
+var (
+	ErrNotFound   = errors.New("key not found")
+	ErrInvalidKey = errors.New("keys are 1 to 256 letters, digits and . _ - characters, in segments separated by /")
+	ErrTooLarge   = errors.New("value larger than 64 KiB")
+	ErrFull       = errors.New("store full, delete keys first")
+)
+
+// DefaultPath returns the store path, next to the state file, so each instance of the
+// service has its own store
+func DefaultPath() string {
+	return filepath.Join(filepath.Dir(state.DefaultPath()), "kv.json")
+}
+
+// Store is a JSON file of string values, rewritten atomically on every change
+type Store struct {
+	path string
+
+	mu   sync.Mutex
+	data map[string]string
+}
+
+// Open loads the store at path. A missing file yields an empty store.
+func Open(path string) (*Store, error) {
+	s := &Store{path: path, data: map[string]string{}}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the key/value store: %w", err)
+	}
+	if err := json.Unmarshal(data, &s.data); err != nil {
+		return nil, fmt.Errorf("failed to parse the key/value store %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// Path returns the store file path
+func (s *Store) Path() string {
+	return s.path
+}
+
+// Get returns the value of key, or ErrNotFound
+func (s *Store) Get(key string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, ok := s.data[key]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return v, nil
+}
+
+// All returns a copy of every key and value
+func (s *Store) All() map[string]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return maps.Clone(s.data)
+}
+
+// Set stores value under key and writes the store
+func (s *Store) Set(key, value string) error {
+	if err := ValidateKey(key); err != nil {
+		return err
+	}
+	if len(value) > MaxValueSize {
+		return ErrTooLarge
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	prev, existed := s.data[key]
+	if !existed && len(s.data) >= MaxKeys {
+		return ErrFull
+	}
+	s.data[key] = value
+	if err := s.save(); err != nil {
+		if existed {
+			s.data[key] = prev
+		} else {
+			delete(s.data, key)
+		}
+		return err
+	}
+	return nil
+}
+
+// Delete removes key and writes the store, or returns ErrNotFound
+func (s *Store) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	prev, ok := s.data[key]
+	if !ok {
+		return ErrNotFound
+	}
+	delete(s.data, key)
+	if err := s.save(); err != nil {
+		s.data[key] = prev
+		return err
+	}
+	return nil
+}
+
+// save writes the store file. It must be called with s.mu held.
+func (s *Store) save() error {
+	data, err := json.MarshalIndent(s.data, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := atomicfile.WriteFile(s.path, data, 0o600, false); err != nil {
+		return fmt.Errorf("failed to write the key/value store: %w", err)
+	}
+	return nil
+}
+
+// ValidateKey checks that key is a valid store key
+func ValidateKey(key string) error {
+	if len(key) > MaxKeySize {
+		return ErrInvalidKey
+	}
+	// Empty, . and .. segments would be cleaned from control request paths
+	for _, seg := range strings.Split(key, "/") {
+		if seg == "" || seg == "." || seg == ".." {
+			return ErrInvalidKey
+		}
+	}
+	for _, r := range key {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '.', r == '_', r == '-', r == '/':
+		default:
+			return ErrInvalidKey
+		}
+	}
+	return nil
+}
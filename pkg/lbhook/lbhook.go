@@ -0,0 +1,137 @@
+// Package lbhook runs the hooks that take the service out of an external load balancer
+// before a planned restart, and put it back once the new child is ready. A hook is
+// either an HTTP request or a command.
+package lbhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"strconv"
+	"time"
+)
+
+// Environment variables set for hook commands
+const (
+	// EnvPhase holds the phase a hook command runs for, deregister or register
+	EnvPhase = "SVCAPP_LB_PHASE"
+	// EnvPID holds the PID of the child a hook command runs for
+	EnvPID = "SVCAPP_LB_PID"
+)
+
+// Phases of a restart the hooks run in
+const (
+	PhaseDeregister = "deregister" // Before the child is stopped
+	PhaseRegister   = "register"   // Once the new child is ready
+)
+
+// DefaultTimeout bounds a hook without its own timeout
+const DefaultTimeout = 10 * time.Second
+
+// Hook is an HTTP request or a command
+type Hook struct {
+	URL     string        // Called with Method, http:// or https://
+	Method  string        // HTTP method, POST by default
+	Command []string      // Executable and arguments, run instead of calling URL
+	Timeout time.Duration // DefaultTimeout when zero
+}
+
+// Payload is the JSON body of a hook request
+type Payload struct {
+	Phase    string `json:"phase"`
+	Service  string `json:"service"`
+	Hostname string `json:"hostname"`
+	PID      int    `json:"pid,omitempty"` // Child process ID
+}
+
+// Validate checks that h is either a command or an http(s) URL
+func (h Hook) Validate() error {
+	switch {
+	case h.URL != "" && len(h.Command) > 0:
+		return errors.New("set either url or command, not both")
+	case len(h.Command) > 0:
+		return nil
+	case h.URL == "":
+		return errors.New("url or command is required")
+	}
+	u, err := url.Parse(h.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("url must be an http:// or https:// URL: %q", h.URL)
+	}
+	return nil
+}
+
+// String describes h in logs
+func (h Hook) String() string {
+	if len(h.Command) > 0 {
+		return h.Command[0]
+	}
+	return h.URL
+}
+
+// Run runs the hooks of p one after another, each within its timeout, and returns their
+// joined errors. A failing hook doesn't stop the next ones.
+func Run(ctx context.Context, hooks []Hook, p Payload) error {
+	var errs []error
+	for _, h := range hooks {
+		if err := h.run(ctx, p); err != nil {
+			errs = append(errs, fmt.Errorf("%s hook %s: %w", p.Phase, h, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// run runs h for p
+func (h Hook) run(ctx context.Context, p Payload) error {
+	timeout := h.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	if len(h.Command) > 0 {
+		cmd := exec.CommandContext(ctx, h.Command[0], h.Command[1:]...)
+		cmd.Env = append(os.Environ(), EnvPhase+"="+p.Phase, EnvPID+"="+strconv.Itoa(p.PID))
+		if out, err := cmd.CombinedOutput(); err != nil {
+			if out = bytes.TrimSpace(out); len(out) > 0 {
+				return fmt.Errorf("%w: %s", err, out)
+			}
+			return err
+		}
+		return nil
+	}
+
+	body, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	method := h.Method
+	if method == "" {
+		method = http.MethodPost
+	}
+	req, err := http.NewRequestWithContext(ctx, method, h.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("returned %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,199 @@
+package listener
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Supported address schemes
+const (
+	SchemeTCP  = "tcp"
+	SchemeTCP4 = "tcp4"
+	SchemeTCP6 = "tcp6"
+	SchemeUnix = "unix"
+	SchemePipe = "npipe" // Windows named pipe
+)
+
+const defaultSocketMode os.FileMode = 0o660
+
+// Address is a parsed listen address
+type Address struct {
+	Network string      // Network passed to net.Listen
+	Address string      // Host:port or socket path
+	Mode    os.FileMode // Permissions applied to unix sockets
+	Allow   []string    // Accounts allowed to connect to a named pipe, besides SYSTEM and Administrators
+}
+
+// String formats the address back into URL form
+func (a Address) String() string {
+	switch a.Network {
+	case SchemeUnix:
+		return fmt.Sprintf("%s://%s?mode=%#o", a.Network, a.Address, a.Mode)
+	case SchemePipe:
+		s := SchemePipe + "://" + strings.ReplaceAll(strings.TrimPrefix(a.Address, `\\`), `\`, "/")
+		if len(a.Allow) > 0 {
+			s += "?allow=" + url.QueryEscape(strings.Join(a.Allow, ","))
+		}
+		return s
+	}
+	return fmt.Sprintf("%s://%s", a.Network, a.Address)
+}
+
+// Parse parses addresses such as "tcp://:8080", "tcp6://[::1]:8080",
+// "unix:///run/svcapp.sock?mode=0600" or "npipe://./pipe/svcapp?allow=Users".
+// A bare "host:port" is treated as tcp.
+func Parse(addr string) (Address, error) {
+	if !strings.Contains(addr, "://") {
+		return Address{Network: SchemeTCP, Address: addr}, nil
+	}
+
+	u, err := url.Parse(addr)
+	if err != nil {
+		return Address{}, fmt.Errorf("invalid listen address %q: %w", addr, err)
+	}
+
+	switch u.Scheme {
+	case SchemeTCP, SchemeTCP4, SchemeTCP6:
+		if u.Host == "" {
+			return Address{}, fmt.Errorf("listen address %q has no host:port", addr)
+		}
+		return Address{Network: u.Scheme, Address: u.Host}, nil
+
+	case SchemeUnix:
+		path := u.Host + u.Path
+		if path == "" {
+			return Address{}, fmt.Errorf("listen address %q has no socket path", addr)
+		}
+		mode := defaultSocketMode
+		if m := u.Query().Get("mode"); m != "" {
+			v, err := strconv.ParseUint(m, 8, 32)
+			if err != nil {
+				return Address{}, fmt.Errorf("invalid socket mode %q: %w", m, err)
+			}
+			mode = os.FileMode(v)
+		}
+		return Address{Network: SchemeUnix, Address: path, Mode: mode}, nil
+
+	case SchemePipe:
+		if u.Host == "" || !strings.HasPrefix(u.Path, "/pipe/") {
+			return Address{}, fmt.Errorf("listen address %q is not of the form npipe://<host>/pipe/<name>", addr)
+		}
+		a := Address{Network: SchemePipe, Address: `\\` + u.Host + strings.ReplaceAll(u.Path, "/", `\`)}
+		if allow := u.Query().Get("allow"); allow != "" {
+			a.Allow = strings.Split(allow, ",")
+		}
+		return a, nil
+
+	default:
+		return Address{}, fmt.Errorf("unsupported listen scheme %q", u.Scheme)
+	}
+}
+
+// Listen parses addr and opens a listener on it. Stale unix sockets are removed
+// before binding. A unix socket is bound in a directory only the owner can enter and
+// moved into place once its permissions are applied, so nobody connects before.
+func Listen(addr string) (net.Listener, error) {
+	a, err := Parse(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	if a.Network == SchemePipe {
+		return listenPipe(a)
+	}
+	if a.Network != SchemeUnix {
+		return net.Listen(a.Network, a.Address)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(a.Address), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create socket directory: %w", err)
+	}
+	if err := removeStaleSocket(a.Address); err != nil {
+		return nil, err
+	}
+
+	private, err := os.MkdirTemp(filepath.Dir(a.Address), ".l")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create socket directory: %w", err)
+	}
+	defer os.RemoveAll(private)
+
+	tmp := filepath.Join(private, "s")
+	l, err := net.Listen(a.Network, tmp)
+	if err != nil {
+		return nil, err
+	}
+	l.(*net.UnixListener).SetUnlinkOnClose(false)
+	if err := os.Chmod(tmp, a.Mode); err != nil {
+		l.Close()
+		return nil, fmt.Errorf("failed to set socket permissions: %w", err)
+	}
+	if err := os.Rename(tmp, a.Address); err != nil {
+		l.Close()
+		return nil, fmt.Errorf("failed to move socket into place: %w", err)
+	}
+
+	return &unixListener{Listener: l, path: a.Address}, nil
+}
+
+// unixListener removes its socket file, moved after binding, on the first Close
+type unixListener struct {
+	net.Listener
+	path   string
+	unlink sync.Once
+}
+
+func (l *unixListener) Close() error {
+	err := l.Listener.Close()
+	l.unlink.Do(func() { os.Remove(l.path) })
+	return err
+}
+
+// removeStaleSocket removes a socket left behind at path, refusing to touch other files
+// and sockets another process still listens on
+func removeStaleSocket(path string) error {
+	fi, err := os.Lstat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if fi.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("%s exists and is not a socket", path)
+	}
+	conn, err := net.DialTimeout(SchemeUnix, path, time.Second)
+	if err == nil {
+		conn.Close()
+		return fmt.Errorf("%s: address in use", path)
+	}
+	if !isRefused(err) {
+		return fmt.Errorf("failed to check the socket: %w", err)
+	}
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("failed to remove stale socket: %w", err)
+	}
+	return nil
+}
+
+// Dial connects to a listen address, such as one served by Listen
+func Dial(ctx context.Context, addr string) (net.Conn, error) {
+	a, err := Parse(addr)
+	if err != nil {
+		return nil, err
+	}
+	if a.Network == SchemePipe {
+		return dialPipe(ctx, a)
+	}
+	var d net.Dialer
+	return d.DialContext(ctx, a.Network, a.Address)
+}
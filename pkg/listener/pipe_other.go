@@ -0,0 +1,21 @@
+//go:build !windows
+
+package listener
+
+import (
+	"context"
+	"errors"
+	"net"
+)
+
+var errPipeUnsupported = errors.New("named pipes are only supported on Windows")
+
+// listenPipe fails outside Windows
+func listenPipe(a Address) (net.Listener, error) {
+	return nil, errPipeUnsupported
+}
+
+// dialPipe fails outside Windows
+func dialPipe(ctx context.Context, a Address) (net.Conn, error) {
+	return nil, errPipeUnsupported
+}
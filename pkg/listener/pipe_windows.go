@@ -0,0 +1,45 @@
+package listener
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/Microsoft/go-winio"
+	"golang.org/x/sys/windows"
+)
+
+// listenPipe opens a named pipe that only SYSTEM, Administrators and the accounts
+// in a.Allow may connect to
+func listenPipe(a Address) (net.Listener, error) {
+	sd, err := pipeSecurity(a.Allow)
+	if err != nil {
+		return nil, err
+	}
+	return winio.ListenPipe(a.Address, &winio.PipeConfig{SecurityDescriptor: sd})
+}
+
+// dialPipe connects to a named pipe
+func dialPipe(ctx context.Context, a Address) (net.Conn, error) {
+	return winio.DialPipeContext(ctx, a.Address)
+}
+
+// pipeSecurity builds an SDDL security descriptor with a protected DACL granting full
+// control to SYSTEM and Administrators, and read/write access to the allowed accounts.
+// Accounts are SIDs or user and group names.
+func pipeSecurity(allow []string) (string, error) {
+	var b strings.Builder
+	b.WriteString("D:P(A;;GA;;;SY)(A;;GA;;;BA)")
+
+	for _, account := range allow {
+		sid, err := windows.StringToSid(account)
+		if err != nil {
+			if sid, _, _, err = windows.LookupSID("", account); err != nil {
+				return "", fmt.Errorf("cannot resolve account %s: %w", account, err)
+			}
+		}
+		fmt.Fprintf(&b, "(A;;GRGW;;;%s)", sid)
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,13 @@
+//go:build !windows
+
+package listener
+
+import (
+	"errors"
+	"syscall"
+)
+
+// isRefused reports whether err is a connection refused by a socket nobody listens on
+func isRefused(err error) bool {
+	return errors.Is(err, syscall.ECONNREFUSED)
+}
@@ -0,0 +1,12 @@
+package listener
+
+import (
+	"errors"
+
+	"golang.org/x/sys/windows"
+)
+
+// isRefused reports whether err is a connection refused by a socket nobody listens on
+func isRefused(err error) bool {
+	return errors.Is(err, windows.WSAECONNREFUSED)
+}
@@ -0,0 +1,103 @@
+package logexport
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lucasdecamargo/go-appservice-example/pkg/archive"
+)
+
+// maxLine bounds the lines read from log files
+const maxLine = 1 << 20
+
+// ReadFile returns the lines of the log file at path and of its rotated backups within
+// rg, as entries of source. Lines without a slog timestamp take the time of the line
+// before them, or the modification time of the oldest file for the first ones. A
+// missing file has no lines.
+func ReadFile(path, source string, rg Range) ([]Entry, error) {
+	files, err := rotated(path)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(path); err == nil {
+		files = append(files, path)
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	var (
+		entries []Entry
+		last    time.Time
+	)
+	for _, file := range files {
+		if last.IsZero() {
+			if fi, err := os.Stat(file); err == nil {
+				last = fi.ModTime()
+			}
+		}
+		if last, err = readFile(file, source, rg, last, &entries); err != nil {
+			return nil, err
+		}
+	}
+	return entries, nil
+}
+
+// readFile appends the lines of file within rg to entries, and returns the time of
+// the last line
+func readFile(file, source string, rg Range, last time.Time, entries *[]Entry) (time.Time, error) {
+	f, err := archive.Open(file)
+	if err != nil {
+		return last, err
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	sc.Buffer(nil, maxLine)
+	for sc.Scan() {
+		line := strings.TrimRight(sc.Text(), "\r")
+		if line == "" {
+			continue
+		}
+		e, _ := parseLine(line, source, last)
+		last = e.Time
+		if rg.Contains(e.Time) {
+			*entries = append(*entries, e)
+		}
+	}
+	return last, sc.Err()
+}
+
+// rotated returns the backups of the log file at path, oldest first, compressed or not
+func rotated(path string) ([]string, error) {
+	matches, err := filepath.Glob(path + ".*")
+	if err != nil {
+		return nil, err
+	}
+	type backup struct {
+		path string
+		n    int
+	}
+	var backups []backup
+	for _, m := range matches {
+		suffix := strings.TrimPrefix(m, path+".")
+		for _, ext := range archive.Exts {
+			suffix = strings.TrimSuffix(suffix, ext)
+		}
+		if n, err := strconv.Atoi(suffix); err == nil && n > 0 {
+			backups = append(backups, backup{m, n})
+		}
+	}
+	slices.SortFunc(backups, func(a, b backup) int { return b.n - a.n })
+
+	paths := make([]string, len(backups))
+	for i, b := range backups {
+		paths[i] = b.path
+	}
+	return paths, nil
+}
@@ -0,0 +1,139 @@
+// Package logexport merges the supervisor and child logs of a time range, read from the
+// log files, the daemon memory and the system log, into one ordered file
+package logexport
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Sources of the entries
+const (
+	SourceSupervisor = "supervisor" // The daemon's own log
+	SourceStdout     = "stdout"     // The child standard output
+	SourceStderr     = "stderr"     // The child standard error
+	SourceSystem     = "system"     // journald or the Windows event log, holding either
+)
+
+// Export formats
+const (
+	FormatJSONL = "jsonl" // One JSON entry per line
+	FormatText  = "text"  // One "<time> <source> <line>" per line
+)
+
+// dedupWindow is how far apart the same line read from two places is still one entry
+const dedupWindow = 2 * time.Second
+
+// ErrUnsupported is returned by System where there is no system log to read
+var ErrUnsupported = errors.New("no system log on this platform")
+
+// Entry is a single log line
+type Entry struct {
+	Time    time.Time `json:"time"`
+	Source  string    `json:"source"`
+	Level   string    `json:"level,omitempty"` // Of slog records and system log entries
+	Message string    `json:"msg"`             // The line as written
+}
+
+// Range bounds the entries to export. A zero bound leaves that side open.
+type Range struct {
+	Since time.Time
+	Until time.Time
+}
+
+// Contains reports whether t falls within the range
+func (r Range) Contains(t time.Time) bool {
+	return (r.Since.IsZero() || !t.Before(r.Since)) && (r.Until.IsZero() || !t.After(r.Until))
+}
+
+// record holds the fields of a slog JSON line
+type record struct {
+	Time  time.Time `json:"time"`
+	Level string    `json:"level"`
+}
+
+// parseLine returns the entry of line. The time and level of slog JSON records are
+// used, other lines are given fallback and report false.
+func parseLine(line, source string, fallback time.Time) (Entry, bool) {
+	e := Entry{Time: fallback, Source: source, Message: line}
+	if !strings.HasPrefix(line, "{") {
+		return e, false
+	}
+	var r record
+	if json.Unmarshal([]byte(line), &r) != nil || r.Time.IsZero() {
+		return e, false
+	}
+	e.Time, e.Level = r.Time, r.Level
+	return e, true
+}
+
+// Merge orders the entries of all sets by time. A line found in several sets, such
+// as in a log file and in the daemon memory, is kept once.
+func Merge(sets ...[]Entry) []Entry {
+	type origin struct {
+		Entry
+		set int
+	}
+	var all []origin
+	for i, set := range sets {
+		for _, e := range set {
+			all = append(all, origin{e, i})
+		}
+	}
+	slices.SortStableFunc(all, func(a, b origin) int { return a.Time.Compare(b.Time) })
+
+	merged := make([]Entry, 0, len(all))
+	for i, e := range all {
+		duplicate := false
+		for j := i - 1; j >= 0 && e.Time.Sub(all[j].Time) <= dedupWindow; j-- {
+			if all[j].set != e.set && all[j].Message == e.Message {
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			merged = append(merged, e.Entry)
+		}
+	}
+	return merged
+}
+
+// Write writes the entries to w in format
+func Write(w io.Writer, entries []Entry, format string) error {
+	switch format {
+	case FormatJSONL, "":
+		enc := json.NewEncoder(w)
+		for _, e := range entries {
+			if err := enc.Encode(e); err != nil {
+				return err
+			}
+		}
+	case FormatText:
+		for _, e := range entries {
+			source := e.Source
+			if e.Level != "" && !strings.HasPrefix(e.Message, "{") {
+				source += " " + e.Level
+			}
+			if _, err := fmt.Fprintf(w, "%s %s %s\n", e.Time.Format(time.RFC3339Nano), source, e.Message); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("unknown format %q, use %s or %s", format, FormatJSONL, FormatText)
+	}
+	return nil
+}
+
+// Count returns the number of entries of each source
+func Count(entries []Entry) map[string]int {
+	counts := make(map[string]int)
+	for _, e := range entries {
+		counts[e.Source]++
+	}
+	return counts
+}
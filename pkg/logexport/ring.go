@@ -0,0 +1,123 @@
+package logexport
+
+import (
+	"bytes"
+	"io"
+	"sync"
+	"time"
+)
+
+// Ring sizes, in lines
+const (
+	DefaultRingSize = 2000
+	LeanRingSize    = 200 // Suggested for memory-constrained devices
+)
+
+// maxPartialLine bounds the unterminated line a ring writer holds
+const maxPartialLine = 64 << 10
+
+// Ring keeps the latest log lines of the supervisor and the child in memory, so they
+// can be exported even when they only went to the console
+type Ring struct {
+	mu      sync.Mutex
+	entries []Entry // Oldest first once full, from next on
+	next    int
+	size    int
+	bytes   int64
+}
+
+// NewRing creates a ring holding up to size lines
+func NewRing(size int) *Ring {
+	return &Ring{size: size}
+}
+
+// Resize changes the number of lines held, dropping the oldest ones that don't fit
+func (r *Ring) Resize(size int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	entries := r.ordered()
+	if len(entries) > size {
+		entries = entries[len(entries)-size:]
+	}
+	r.entries, r.next, r.size, r.bytes = nil, 0, size, 0
+	for _, e := range entries {
+		r.add(e)
+	}
+}
+
+// Writer returns a writer adding each line written to it as an entry of source. Lines
+// are timed when written, unless they are slog JSON records.
+func (r *Ring) Writer(source string) io.Writer {
+	return &ringWriter{ring: r, source: source}
+}
+
+// Entries returns the lines held within rg, oldest first
+func (r *Ring) Entries(rg Range) []Entry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var entries []Entry
+	for _, e := range r.ordered() {
+		if rg.Contains(e.Time) {
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
+
+// Footprint returns the approximate memory held by the lines, in bytes
+func (r *Ring) Footprint() int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.bytes
+}
+
+// add appends e, replacing the oldest line once full. It must be called with r.mu held.
+func (r *Ring) add(e Entry) {
+	if r.size <= 0 {
+		return
+	}
+	r.bytes += int64(len(e.Message))
+	if len(r.entries) < r.size {
+		r.entries = append(r.entries, e)
+		return
+	}
+	r.bytes -= int64(len(r.entries[r.next].Message))
+	r.entries[r.next] = e
+	r.next = (r.next + 1) % r.size
+}
+
+// ordered returns the lines oldest first. It must be called with r.mu held.
+func (r *Ring) ordered() []Entry {
+	return append(r.entries[r.next:len(r.entries):len(r.entries)], r.entries[:r.next]...)
+}
+
+// ringWriter splits the writes of one source into lines
+type ringWriter struct {
+	ring    *Ring
+	source  string
+	partial []byte
+}
+
+func (w *ringWriter) Write(p []byte) (int, error) {
+	w.ring.mu.Lock()
+	defer w.ring.mu.Unlock()
+
+	now := time.Now()
+	data := append(w.partial, p...)
+	for {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			break
+		}
+		if line := string(bytes.TrimRight(data[:i], "\r")); line != "" {
+			e, _ := parseLine(line, w.source, now)
+			w.ring.add(e)
+		}
+		data = data[i+1:]
+	}
+	if len(data) > maxPartialLine {
+		data = data[len(data)-maxPartialLine:]
+	}
+	w.partial = append(w.partial[:0], data...)
+	return len(p), nil
+}
@@ -0,0 +1,104 @@
+package logexport
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"time"
+)
+
+// journalEntry holds the fields of a journalctl JSON line
+type journalEntry struct {
+	Realtime string          `json:"__REALTIME_TIMESTAMP"` // Microseconds since the epoch
+	Priority string          `json:"PRIORITY"`
+	Message  json.RawMessage `json:"MESSAGE"` // A string, or a byte array when not UTF-8
+}
+
+// System returns the journald entries of the unit named after the service within rg.
+// It returns ErrUnsupported when journalctl isn't installed.
+func System(ctx context.Context, name string, rg Range) ([]Entry, error) {
+	if _, err := exec.LookPath("journalctl"); err != nil {
+		return nil, ErrUnsupported
+	}
+	args := []string{"--unit", name + ".service", "--output", "json", "--no-pager", "--quiet"}
+	if !rg.Since.IsZero() {
+		args = append(args, "--since", fmt.Sprintf("@%d", rg.Since.Unix()))
+	}
+	if !rg.Until.IsZero() {
+		args = append(args, "--until", fmt.Sprintf("@%d", rg.Until.Unix()+1))
+	}
+
+	cmd := exec.CommandContext(ctx, "journalctl", args...)
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to read the journal: %w", err)
+	}
+
+	var entries []Entry
+	sc := bufio.NewScanner(out)
+	sc.Buffer(nil, maxLine)
+	for sc.Scan() {
+		var j journalEntry
+		if json.Unmarshal(sc.Bytes(), &j) != nil {
+			continue
+		}
+		us, err := strconv.ParseInt(j.Realtime, 10, 64)
+		if err != nil {
+			continue
+		}
+		e, ok := parseLine(journalMessage(j.Message), SourceSystem, time.UnixMicro(us))
+		e.Time = time.UnixMicro(us)
+		if !ok {
+			priority, _ := strconv.Atoi(j.Priority)
+			e.Level = syslogLevel(priority)
+		}
+		if rg.Contains(e.Time) {
+			entries = append(entries, e)
+		}
+	}
+	scanErr := sc.Err()
+	if err := cmd.Wait(); err != nil {
+		var exit *exec.ExitError
+		if errors.As(err, &exit) && len(entries) == 0 {
+			return nil, fmt.Errorf("failed to read the journal: %w", err)
+		}
+	}
+	return entries, scanErr
+}
+
+// journalMessage decodes a MESSAGE field, held as a byte array when not valid UTF-8
+func journalMessage(raw json.RawMessage) string {
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return s
+	}
+	var b []byte
+	var ints []int
+	if json.Unmarshal(raw, &ints) == nil {
+		for _, i := range ints {
+			b = append(b, byte(i))
+		}
+	}
+	return string(b)
+}
+
+// syslogLevel maps a syslog priority to the name of a slog level
+func syslogLevel(priority int) string {
+	switch {
+	case priority <= 3:
+		return "ERROR"
+	case priority == 4:
+		return "WARN"
+	case priority == 7:
+		return "DEBUG"
+	default:
+		return "INFO"
+	}
+}
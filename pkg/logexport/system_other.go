@@ -0,0 +1,10 @@
+//go:build !linux && !windows
+
+package logexport
+
+import "context"
+
+// System returns ErrUnsupported, the system log is only read on Linux and Windows
+func System(ctx context.Context, name string, rg Range) ([]Entry, error) {
+	return nil, ErrUnsupported
+}
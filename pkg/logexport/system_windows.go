@@ -0,0 +1,77 @@
+package logexport
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// eventLogEntry holds the fields of an event rendered as XML by wevtutil
+type eventLogEntry struct {
+	System struct {
+		Level       int `xml:"Level"`
+		TimeCreated struct {
+			SystemTime time.Time `xml:"SystemTime,attr"`
+		} `xml:"TimeCreated"`
+	} `xml:"System"`
+	Data []string `xml:"EventData>Data"`
+}
+
+// System returns the Application event log entries of the service source within rg
+func System(ctx context.Context, name string, rg Range) ([]Entry, error) {
+	query := fmt.Sprintf("*[System[Provider[@Name='%s']", name)
+	var times []string
+	if !rg.Since.IsZero() {
+		times = append(times, fmt.Sprintf("@SystemTime>='%s'", rg.Since.UTC().Format(time.RFC3339Nano)))
+	}
+	if !rg.Until.IsZero() {
+		times = append(times, fmt.Sprintf("@SystemTime<='%s'", rg.Until.UTC().Format(time.RFC3339Nano)))
+	}
+	if len(times) > 0 {
+		query += " and TimeCreated[" + strings.Join(times, " and ") + "]"
+	}
+	query += "]]"
+
+	out, err := exec.CommandContext(ctx, "wevtutil", "qe", "Application", "/q:"+query, "/f:xml", "/e:Events").Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the event log: %w", err)
+	}
+
+	var events struct {
+		Events []eventLogEntry `xml:"Event"`
+	}
+	if err := xml.NewDecoder(bytes.NewReader(out)).Decode(&events); err != nil {
+		return nil, fmt.Errorf("failed to parse the event log: %w", err)
+	}
+
+	entries := make([]Entry, 0, len(events.Events))
+	for _, ev := range events.Events {
+		e, ok := parseLine(strings.Join(ev.Data, " "), SourceSystem, ev.System.TimeCreated.SystemTime)
+		e.Time = ev.System.TimeCreated.SystemTime
+		if !ok {
+			e.Level = eventLevel(ev.System.Level)
+		}
+		if rg.Contains(e.Time) {
+			entries = append(entries, e)
+		}
+	}
+	return entries, nil
+}
+
+// eventLevel maps an event log level to the name of a slog level
+func eventLevel(level int) string {
+	switch level {
+	case 1, 2:
+		return "ERROR"
+	case 3:
+		return "WARN"
+	case 5:
+		return "DEBUG"
+	default:
+		return "INFO"
+	}
+}
@@ -0,0 +1,150 @@
+package logfile
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/lucasdecamargo/go-appservice-example/pkg/archive"
+)
+
+// Default rotation settings
+const (
+	DefaultMaxSize    = 10 << 20 // 10 MiB
+	DefaultMaxBackups = 5
+)
+
+// Writer is an io.WriteCloser appending to a file that is rotated once it grows past
+// MaxSize. Rotated files are renamed to path.1, path.2, ... keeping MaxBackups of them,
+// and compressed in the background when a compression algorithm is set.
+type Writer struct {
+	path       string
+	maxSize    int64
+	maxBackups int
+	compress   archive.Options
+
+	mu          sync.Mutex
+	file        *os.File
+	size        int64
+	unappended  bool           // Whether O_APPEND was cleared on file for splicing
+	compressing sync.WaitGroup // Compression of the last rotated file
+}
+
+// New opens path for appending, creating its directory if needed. Zero values select
+// the default size and backup count, and leave rotated files uncompressed.
+func New(path string, maxSize int64, maxBackups int, compress archive.Options) (*Writer, error) {
+	if maxSize <= 0 {
+		maxSize = DefaultMaxSize
+	}
+	if maxBackups <= 0 {
+		maxBackups = DefaultMaxBackups
+	}
+
+	if err := compress.Validate(); err != nil {
+		return nil, err
+	}
+
+	w := &Writer{path: path, maxSize: maxSize, maxBackups: maxBackups, compress: compress}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create log directory: %w", err)
+	}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Path returns the path of the active log file
+func (w *Writer) Path() string {
+	return w.path
+}
+
+// Write appends p, rotating the file first when p would exceed the size limit
+func (w *Writer) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		return 0, os.ErrClosed
+	}
+
+	if w.size > 0 && w.size+int64(len(p)) > w.maxSize {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// Close closes the active log file, once the last rotated file is compressed
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.compressing.Wait()
+
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Close()
+	w.file = nil
+	return err
+}
+
+// open opens the active log file and records its size
+func (w *Writer) open() error {
+	f, err := os.OpenFile(w.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o640)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	w.file, w.size, w.unappended = f, fi.Size(), false
+	return nil
+}
+
+// rotate shifts the backups, moves the active file to path.1 and opens a new one.
+// Backups are shifted whatever their compression, which may have changed since they
+// were written.
+func (w *Writer) rotate() error {
+	if err := w.file.Close(); err != nil {
+		return err
+	}
+	w.compressing.Wait()
+
+	exts := append([]string{""}, archive.Exts...)
+	for _, ext := range exts {
+		os.Remove(backupName(w.path, w.maxBackups) + ext)
+	}
+	for i := w.maxBackups - 1; i >= 1; i-- {
+		for _, ext := range exts {
+			os.Rename(backupName(w.path, i)+ext, backupName(w.path, i+1)+ext)
+		}
+	}
+	rotated := backupName(w.path, 1)
+	if err := os.Rename(w.path, rotated); err != nil {
+		return fmt.Errorf("failed to rotate log file: %w", err)
+	}
+
+	if w.compress.Algorithm != archive.None {
+		w.compressing.Go(func() {
+			if _, err := archive.CompressFile(rotated, w.compress); err != nil {
+				slog.Warn("Rotated log file left uncompressed", "path", rotated, "error", err)
+			}
+		})
+	}
+
+	return w.open()
+}
+
+// backupName returns the name of the n-th rotated file, without compression extension
+func backupName(path string, n int) string {
+	return fmt.Sprintf("%s.%d", path, n)
+}
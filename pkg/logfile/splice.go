@@ -0,0 +1,44 @@
+package logfile
+
+import (
+	"io"
+	"os"
+	"sync/atomic"
+	"syscall"
+)
+
+// Spliced is a Writer that moves the data of pipes into the log file without copying
+// it through user space, when io.Copy reads from them, as exec.Cmd does for the child
+// output. Other readers, and pipes where SpliceSupported is false, are copied as usual.
+type Spliced struct {
+	*Writer
+	spliced atomic.Uint64
+}
+
+// Spliced returns w as a Spliced writer
+func (w *Writer) Spliced() *Spliced {
+	return &Spliced{Writer: w}
+}
+
+// ReadFrom writes the data of r until its end, splicing it when r is a pipe
+func (s *Spliced) ReadFrom(r io.Reader) (int64, error) {
+	if p, ok := r.(pipe); ok && SpliceSupported {
+		if fi, err := p.Stat(); err == nil && fi.Mode()&os.ModeNamedPipe != 0 {
+			if n, handled, err := s.splice(p, &s.spliced); handled {
+				return n, err
+			}
+		}
+	}
+	return io.Copy(struct{ io.Writer }{s.Writer}, r)
+}
+
+// SplicedBytes returns the number of bytes moved into the file within the kernel
+func (s *Spliced) SplicedBytes() uint64 {
+	return s.spliced.Load()
+}
+
+// pipe is the part of *os.File the splicing relies on
+type pipe interface {
+	Stat() (os.FileInfo, error)
+	SyscallConn() (syscall.RawConn, error)
+}
@@ -0,0 +1,94 @@
+package logfile
+
+import (
+	"errors"
+	"io"
+	"os"
+	"sync/atomic"
+
+	"golang.org/x/sys/unix"
+)
+
+// SpliceSupported reports whether Spliced moves pipe data within the kernel
+const SpliceSupported = true
+
+// maxSplice bounds the bytes moved by one splice call
+const maxSplice = 1 << 20
+
+// splice moves the data of p into the log file with splice(2) until p is closed,
+// rotating the file as it grows, and counts the bytes moved in spliced. handled is
+// false when nothing was moved and splice isn't usable, to copy instead.
+func (w *Writer) splice(p pipe, spliced *atomic.Uint64) (n int64, handled bool, err error) {
+	rc, err := p.SyscallConn()
+	if err != nil {
+		return 0, false, nil
+	}
+
+	for {
+		var moved int64
+		var serr error
+		rerr := rc.Read(func(fd uintptr) bool {
+			w.mu.Lock()
+			defer w.mu.Unlock()
+			if w.file == nil {
+				serr = os.ErrClosed
+				return true
+			}
+			if w.size > 0 && w.size >= w.maxSize {
+				if serr = w.rotate(); serr != nil {
+					return true
+				}
+			}
+			if serr = w.unappend(); serr != nil {
+				return true
+			}
+			limit := min(maxSplice, max(w.maxSize-w.size, 1))
+			moved, serr = unix.Splice(int(fd), nil, int(w.file.Fd()), nil, int(limit), unix.SPLICE_F_MOVE|unix.SPLICE_F_NONBLOCK)
+			if errors.Is(serr, unix.EAGAIN) {
+				return false // Wait for the child to write more
+			}
+			if moved > 0 {
+				w.size += int64(moved)
+			}
+			return true
+		})
+
+		switch {
+		case errors.Is(serr, unix.EINVAL) && n == 0:
+			return 0, false, nil // The file system doesn't support splice
+		case serr != nil:
+			return n, true, serr
+		case rerr != nil:
+			return n, true, rerr
+		case moved == 0:
+			return n, true, nil // The child closed its end
+		}
+		n += moved
+		spliced.Add(uint64(moved))
+	}
+}
+
+// unappend clears O_APPEND on the active file, which splice rejects, and moves to its
+// end. It must be called with w.mu held.
+func (w *Writer) unappend() error {
+	if w.unappended {
+		return nil
+	}
+	fd := int(w.file.Fd())
+	flags, err := unix.FcntlInt(uintptr(fd), unix.F_GETFL, 0)
+	if err != nil {
+		return err
+	}
+	if flags&unix.O_APPEND == 0 {
+		w.unappended = true
+		return nil
+	}
+	if _, err := unix.FcntlInt(uintptr(fd), unix.F_SETFL, flags&^unix.O_APPEND); err != nil {
+		return err
+	}
+	if _, err := w.file.Seek(0, io.SeekEnd); err != nil {
+		return err
+	}
+	w.unappended = true
+	return nil
+}
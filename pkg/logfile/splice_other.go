@@ -0,0 +1,13 @@
+//go:build !linux
+
+package logfile
+
+import "sync/atomic"
+
+// SpliceSupported reports whether Spliced moves pipe data within the kernel
+const SpliceSupported = false
+
+// splice is only supported on Linux
+func (w *Writer) splice(p pipe, spliced *atomic.Uint64) (n int64, handled bool, err error) {
+	return 0, false, nil
+}
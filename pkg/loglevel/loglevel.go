@@ -0,0 +1,39 @@
+// Package loglevel holds the level of the default logger so it can be changed at runtime
+package loglevel
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+)
+
+// step is the distance between the standard slog levels
+const step = slog.LevelInfo - slog.LevelDebug
+
+// Level is the level of the default logger. Pass it as slog.HandlerOptions.Level.
+var Level = new(slog.LevelVar)
+
+// Set changes the level and logs the change
+func Set(l slog.Level) slog.Level {
+	prev := Level.Level()
+	Level.Set(l)
+	slog.Log(context.Background(), max(l, prev), "Log level changed", "from", prev.String(), "to", l.String())
+	return l
+}
+
+// MoreVerbose lowers the level by one step, down to debug
+func MoreVerbose() slog.Level {
+	return Set(max(Level.Level()-step, slog.LevelDebug))
+}
+
+// LessVerbose raises the level by one step, up to error
+func LessVerbose() slog.Level {
+	return Set(min(Level.Level()+step, slog.LevelError))
+}
+
+// Parse parses a level name such as "debug" or "WARN"
+func Parse(s string) (slog.Level, error) {
+	var l slog.Level
+	err := l.UnmarshalText([]byte(strings.ToUpper(s)))
+	return l, err
+}
@@ -0,0 +1,8 @@
+//go:build !unix
+
+package loglevel
+
+// HandleSignals does nothing where there are no SIGUSR1 and SIGUSR2, use the control API instead
+func HandleSignals() (stop func()) {
+	return func() {}
+}
@@ -0,0 +1,37 @@
+//go:build unix
+
+package loglevel
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// HandleSignals makes SIGUSR1 lower the level and SIGUSR2 raise it, until the returned
+// function is called
+func HandleSignals() (stop func()) {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGUSR1, syscall.SIGUSR2)
+	done := make(chan struct{})
+
+	go func() {
+		for {
+			select {
+			case sig := <-sigs:
+				if sig == syscall.SIGUSR1 {
+					MoreVerbose()
+				} else {
+					LessVerbose()
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		signal.Stop(sigs)
+		close(done)
+	}
+}
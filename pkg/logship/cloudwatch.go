@@ -0,0 +1,167 @@
+package logship
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+const cloudWatchService = "logs"
+
+// AWSCredentials are the keys requests to CloudWatch Logs are signed with
+type AWSCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+}
+
+// AWSCredentialsFromEnv reads AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN
+func AWSCredentialsFromEnv() (AWSCredentials, error) {
+	c := AWSCredentials{
+		AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+	}
+	if c.AccessKeyID == "" || c.SecretAccessKey == "" {
+		return c, errors.New("AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY must be set")
+	}
+	return c, nil
+}
+
+// CloudWatch puts entries into a CloudWatch Logs stream, creating the stream on the
+// first batch. The log group must exist.
+type CloudWatch struct {
+	Region      string
+	LogGroup    string
+	LogStream   string
+	Credentials AWSCredentials
+	Endpoint    string // Overrides https://logs.<region>.amazonaws.com
+	Client      *http.Client
+
+	streamReady bool
+}
+
+// cloudWatchEvent is an InputLogEvent of the PutLogEvents API
+type cloudWatchEvent struct {
+	Timestamp int64  `json:"timestamp"` // Milliseconds since the epoch
+	Message   string `json:"message"`
+}
+
+// Send implements Sink
+func (c *CloudWatch) Send(ctx context.Context, batch []Entry) error {
+	if !c.streamReady {
+		err := c.call(ctx, "CreateLogStream", map[string]string{"logGroupName": c.LogGroup, "logStreamName": c.LogStream})
+		if err != nil && !strings.Contains(err.Error(), "ResourceAlreadyExistsException") {
+			return fmt.Errorf("failed to create log stream: %w", err)
+		}
+		c.streamReady = true
+	}
+
+	// Events must be in chronological order
+	events := make([]cloudWatchEvent, len(batch))
+	for i, e := range batch {
+		events[i] = cloudWatchEvent{Timestamp: e.Time.UnixMilli(), Message: "[" + e.Stream + "] " + e.Line}
+	}
+	slices.SortStableFunc(events, func(a, b cloudWatchEvent) int { return cmp.Compare(a.Timestamp, b.Timestamp) })
+
+	return c.call(ctx, "PutLogEvents", map[string]any{
+		"logGroupName":  c.LogGroup,
+		"logStreamName": c.LogStream,
+		"logEvents":     events,
+	})
+}
+
+// call invokes a CloudWatch Logs API action with a SigV4 signed JSON request
+func (c *CloudWatch) call(ctx context.Context, action string, in any) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+
+	endpoint := c.Endpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://logs.%s.amazonaws.com/", c.Region)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "Logs_20140328."+action)
+	signV4(req, body, c.Credentials, c.Region, cloudWatchService, time.Now())
+
+	return do(c.Client, req)
+}
+
+// signV4 adds an AWS Signature Version 4 Authorization header to req, signing its
+// host, content type and x-amz-* headers
+func signV4(req *http.Request, body []byte, creds AWSCredentials, region, service string, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	if creds.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", creds.SessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		name = strings.ToLower(name)
+		if name == "content-type" || strings.HasPrefix(name, "x-amz-") {
+			headers[name] = strings.TrimSpace(strings.Join(values, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	canonicalRequest := strings.Join([]string{
+		req.Method, path, req.URL.RawQuery, canonicalHeaders.String(), signedHeaders, hashHex(body),
+	}, "\n")
+
+	scope := date + "/" + region + "/" + service + "/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hashHex([]byte(canonicalRequest))
+
+	key := []byte("AWS4" + creds.SecretAccessKey)
+	for _, part := range []string{date, region, service, "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		creds.AccessKeyID, scope, signedHeaders, signature))
+}
+
+func hashHex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
@@ -0,0 +1,116 @@
+package logship
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const (
+	gcpEndpoint    = "https://logging.googleapis.com/v2/entries:write"
+	gcpTokenURL    = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token"
+	gcpTokenLeeway = time.Minute
+)
+
+// CloudLogging writes entries to GCP Cloud Logging. Without a static token, an access
+// token of the instance service account is fetched from the metadata server.
+type CloudLogging struct {
+	Project  string            // GCP project ID
+	LogName  string            // Log ID within the project
+	Labels   map[string]string // Labels added to every entry
+	Token    string            // Static OAuth access token, optional
+	Endpoint string            // Overrides the entries:write URL
+	Client   *http.Client
+
+	token   string
+	expires time.Time
+}
+
+// gcpEntry is a LogEntry of the Cloud Logging API
+type gcpEntry struct {
+	Timestamp   string            `json:"timestamp"`
+	TextPayload string            `json:"textPayload"`
+	Labels      map[string]string `json:"labels"`
+}
+
+// Send implements Sink
+func (g *CloudLogging) Send(ctx context.Context, batch []Entry) error {
+	token, err := g.accessToken(ctx)
+	if err != nil {
+		return err
+	}
+
+	entries := make([]gcpEntry, len(batch))
+	for i, e := range batch {
+		entries[i] = gcpEntry{
+			Timestamp:   e.Time.UTC().Format(time.RFC3339Nano),
+			TextPayload: e.Line,
+			Labels:      map[string]string{"stream": e.Stream},
+		}
+	}
+	body, err := json.Marshal(map[string]any{
+		"logName":  fmt.Sprintf("projects/%s/logs/%s", g.Project, url.PathEscape(g.LogName)),
+		"resource": map[string]string{"type": "global"},
+		"labels":   g.Labels,
+		"entries":  entries,
+	})
+	if err != nil {
+		return err
+	}
+
+	endpoint := g.Endpoint
+	if endpoint == "" {
+		endpoint = gcpEndpoint
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	return do(g.Client, req)
+}
+
+// accessToken returns the static token or a cached metadata server token
+func (g *CloudLogging) accessToken(ctx context.Context) (string, error) {
+	if g.Token != "" {
+		return g.Token, nil
+	}
+	if g.token != "" && time.Now().Before(g.expires) {
+		return g.token, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, gcpTokenURL, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+
+	client := g.Client
+	if client == nil {
+		client = defaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to get access token from the metadata server: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("metadata server returned %s", resp.Status)
+	}
+
+	var t struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&t); err != nil {
+		return "", fmt.Errorf("invalid metadata server token: %w", err)
+	}
+	g.token = t.AccessToken
+	g.expires = time.Now().Add(time.Duration(t.ExpiresIn)*time.Second - gcpTokenLeeway)
+	return g.token, nil
+}
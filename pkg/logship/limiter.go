@@ -0,0 +1,40 @@
+package logship
+
+import (
+	"context"
+	"time"
+)
+
+// limiter spaces out uploads to stay under a bandwidth limit. It is only used by
+// the forwarder goroutine.
+type limiter struct {
+	bytesPerSec float64
+	next        time.Time // When the bandwidth reserved by previous uploads is used up
+}
+
+// newLimiter returns a limiter, or nil when the bandwidth is unlimited
+func newLimiter(bytesPerSec int) *limiter {
+	if bytesPerSec <= 0 {
+		return nil
+	}
+	return &limiter{bytesPerSec: float64(bytesPerSec)}
+}
+
+// wait blocks until the previous uploads have drained, then reserves n bytes
+func (l *limiter) wait(ctx context.Context, n int) error {
+	if l == nil {
+		return nil
+	}
+
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	delay := l.next.Sub(now)
+	l.next = l.next.Add(time.Duration(float64(n) / l.bytesPerSec * float64(time.Second)))
+
+	if delay <= 0 {
+		return nil
+	}
+	return sleep(ctx, delay)
+}
@@ -0,0 +1,294 @@
+package logship
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+	"time"
+	"unsafe"
+)
+
+// Default forwarding settings
+const (
+	DefaultBatchSize     = 500
+	DefaultFlushInterval = 5 * time.Second
+	DefaultRetries       = 3
+	DefaultBufferMax     = 64 << 20 // 64 MiB
+	DefaultQueueSize     = 4096
+	LeanQueueSize        = 64 // Queue size suited to memory-constrained devices
+
+	maxLineSize   = 64 << 10
+	entryOverhead = 32 // Approximate encoding cost of an entry, for the bandwidth limit
+	retryBackoff  = time.Second
+	closeTimeout  = 5 * time.Second
+)
+
+// Entry is a single line of child output
+type Entry struct {
+	Time   time.Time `json:"time"`
+	Stream string    `json:"stream"` // stdout or stderr
+	Line   string    `json:"line"`
+}
+
+// Sink delivers batches of entries to a logging service. Send is only called from
+// a single goroutine.
+type Sink interface {
+	Send(ctx context.Context, batch []Entry) error
+}
+
+// PermanentError reports a batch the sink rejected, which retrying won't deliver
+type PermanentError struct {
+	Err error
+}
+
+func (e *PermanentError) Error() string { return e.Err.Error() }
+func (e *PermanentError) Unwrap() error { return e.Err }
+
+// Options configures a Forwarder. Zero values select the defaults.
+type Options struct {
+	BatchSize      int           // Entries sent in one request
+	FlushInterval  time.Duration // Longest time an entry waits for its batch to fill
+	Retries        int           // Retries of a failed batch before it is buffered on disk
+	MaxBytesPerSec int           // Upload bandwidth limit, unlimited when zero
+	BufferDir      string        // Directory buffering batches while the sink is unreachable, empty to drop them
+	BufferMax      int64         // Size of the disk buffer past which entries are dropped
+	QueueSize      int           // Entries held in memory before spilling to the disk buffer
+}
+
+// Forwarder batches lines written to its writers and ships them to a sink in the
+// background. Batches that can't be delivered are buffered on disk and replayed,
+// oldest first, once the sink is reachable again.
+type Forwarder struct {
+	sink    Sink
+	opts    Options
+	queue   chan Entry
+	spool   *spool
+	limiter *limiter
+
+	cancel    context.CancelFunc
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// New starts forwarding to sink
+func New(sink Sink, opts Options) (*Forwarder, error) {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultBatchSize
+	}
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = DefaultFlushInterval
+	}
+	if opts.Retries <= 0 {
+		opts.Retries = DefaultRetries
+	}
+	if opts.BufferMax <= 0 {
+		opts.BufferMax = DefaultBufferMax
+	}
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = DefaultQueueSize
+	}
+
+	f := &Forwarder{
+		sink:    sink,
+		opts:    opts,
+		queue:   make(chan Entry, opts.QueueSize),
+		limiter: newLimiter(opts.MaxBytesPerSec),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	if opts.BufferDir != "" {
+		s, err := openSpool(opts.BufferDir, opts.BufferMax)
+		if err != nil {
+			return nil, err
+		}
+		f.spool = s
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	f.cancel = cancel
+	go f.run(ctx)
+	return f, nil
+}
+
+// Writer returns a writer forwarding each line written to it as an entry of stream
+func (f *Forwarder) Writer(stream string) io.Writer {
+	return &lineWriter{f: f, stream: stream}
+}
+
+// Footprint returns the approximate memory held by the queue and the batch being
+// filled, in bytes, not counting the lines themselves
+func (f *Forwarder) Footprint() int64 {
+	return int64(cap(f.queue)+f.opts.BatchSize) * int64(unsafe.Sizeof(Entry{}))
+}
+
+// Close sends the queued entries, buffering them on disk when the sink is unreachable
+func (f *Forwarder) Close() error {
+	f.closeOnce.Do(func() {
+		close(f.stop)
+		f.cancel()
+		<-f.done
+	})
+	return nil
+}
+
+// enqueue queues e for the next batch, spilling it to disk when the queue is full
+func (f *Forwarder) enqueue(e Entry) {
+	select {
+	case f.queue <- e:
+	default:
+		f.buffer([]Entry{e})
+	}
+}
+
+// run collects entries into batches and flushes them until the forwarder is closed
+func (f *Forwarder) run(ctx context.Context) {
+	defer close(f.done)
+
+	ticker := time.NewTicker(f.opts.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]Entry, 0, f.opts.BatchSize)
+	for {
+		select {
+		case e := <-f.queue:
+			batch = append(batch, e)
+			if len(batch) >= f.opts.BatchSize {
+				f.flush(ctx, batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			f.flush(ctx, batch)
+			batch = batch[:0]
+		case <-f.stop:
+			for len(f.queue) > 0 {
+				batch = append(batch, <-f.queue)
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), closeTimeout)
+			defer cancel()
+			f.flush(ctx, batch)
+			return
+		}
+	}
+}
+
+// flush replays the disk buffer, then sends batch. The batch is buffered when either fails.
+func (f *Forwarder) flush(ctx context.Context, batch []Entry) {
+	if f.spool != nil && f.spool.pending() {
+		err := f.spool.replay(f.opts.BatchSize, func(b []Entry) error { return f.send(ctx, b) })
+		if err != nil {
+			f.buffer(batch)
+			return
+		}
+	}
+	if len(batch) == 0 {
+		return
+	}
+	if err := f.send(ctx, batch); err != nil {
+		f.buffer(batch)
+	}
+}
+
+// send delivers batch within the bandwidth limit, retrying with a doubling backoff.
+// Batches rejected by the sink are dropped.
+func (f *Forwarder) send(ctx context.Context, batch []Entry) error {
+	size := 0
+	for _, e := range batch {
+		size += len(e.Line) + entryOverhead
+	}
+
+	var err error
+	backoff := retryBackoff
+	for attempt := 0; attempt <= f.opts.Retries; attempt++ {
+		if attempt > 0 {
+			if err := sleep(ctx, backoff); err != nil {
+				return err
+			}
+			backoff *= 2
+		}
+		if err := f.limiter.wait(ctx, size); err != nil {
+			return err
+		}
+
+		if err = f.sink.Send(ctx, batch); err == nil {
+			return nil
+		}
+		var perr *PermanentError
+		if errors.As(err, &perr) {
+			slog.Warn("Log sink rejected batch, dropping it", "entries", len(batch), "error", err)
+			return nil
+		}
+		if ctx.Err() != nil {
+			return err
+		}
+	}
+
+	slog.Warn("Failed to forward logs", "entries", len(batch), "attempts", f.opts.Retries+1, "error", err)
+	return err
+}
+
+// buffer stores entries on disk for a later replay, or drops them without a buffer
+func (f *Forwarder) buffer(entries []Entry) {
+	if len(entries) == 0 {
+		return
+	}
+	if f.spool == nil {
+		slog.Warn("Dropped log entries", "entries", len(entries))
+		return
+	}
+	if err := f.spool.write(entries); err != nil {
+		slog.Warn("Failed to buffer log entries", "entries", len(entries), "error", err)
+	}
+}
+
+// lineWriter splits written data into entries, one per line
+type lineWriter struct {
+	f      *Forwarder
+	stream string
+
+	mu  sync.Mutex
+	buf []byte
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		w.emit(w.buf[:i])
+		w.buf = w.buf[i+1:]
+	}
+
+	// Ship overlong lines in pieces rather than buffering them without bound
+	if len(w.buf) >= maxLineSize {
+		w.emit(w.buf)
+		w.buf = nil
+	}
+	w.buf = append([]byte(nil), w.buf...)
+	return len(p), nil
+}
+
+// emit queues line as an entry, without its trailing carriage return
+func (w *lineWriter) emit(line []byte) {
+	w.f.enqueue(Entry{Time: time.Now(), Stream: w.stream, Line: string(bytes.TrimSuffix(line, []byte("\r")))})
+}
+
+// sleep waits for d or until ctx is done
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
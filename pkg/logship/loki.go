@@ -0,0 +1,59 @@
+package logship
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"maps"
+	"net/http"
+	"strconv"
+)
+
+// Loki pushes entries to a Grafana Loki push endpoint
+type Loki struct {
+	URL    string            // Push URL, such as https://loki:3100/loki/api/v1/push
+	Labels map[string]string // Stream labels, to which the output stream is added as "stream"
+	Token  string            // Bearer token, optional
+	Client *http.Client
+}
+
+// lokiStream is a stream of the Loki push API
+type lokiStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"` // Unix nanosecond timestamp and line
+}
+
+// Send implements Sink
+func (l *Loki) Send(ctx context.Context, batch []Entry) error {
+	streams := map[string]*lokiStream{}
+	var order []*lokiStream
+	for _, e := range batch {
+		s, ok := streams[e.Stream]
+		if !ok {
+			labels := maps.Clone(l.Labels)
+			if labels == nil {
+				labels = map[string]string{}
+			}
+			labels["stream"] = e.Stream
+			s = &lokiStream{Stream: labels}
+			streams[e.Stream] = s
+			order = append(order, s)
+		}
+		s.Values = append(s.Values, [2]string{strconv.FormatInt(e.Time.UnixNano(), 10), e.Line})
+	}
+
+	body, err := json.Marshal(map[string]any{"streams": order})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, l.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if l.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+l.Token)
+	}
+	return do(l.Client, req)
+}
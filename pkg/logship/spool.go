@@ -0,0 +1,160 @@
+package logship
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+const (
+	spoolFile  = "buffer.jsonl"
+	replayFile = "buffer.replay.jsonl"
+)
+
+// spool buffers entries on disk as JSON lines. New entries are appended to the
+// buffer file, which is moved aside to be replayed, so writes never wait on the sink.
+type spool struct {
+	path, replayPath string
+	max              int64
+
+	mu      sync.Mutex
+	size    int64
+	dropped int
+}
+
+// openSpool opens the buffer in dir, keeping entries left over from a previous run
+func openSpool(dir string, max int64) (*spool, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create log buffer directory: %w", err)
+	}
+
+	s := &spool{path: filepath.Join(dir, spoolFile), replayPath: filepath.Join(dir, replayFile), max: max}
+	if fi, err := os.Stat(s.path); err == nil {
+		s.size = fi.Size()
+	}
+	return s, nil
+}
+
+// write appends entries, dropping those that would grow the buffer past its limit
+func (s *spool) write(entries []Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o640)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	for _, e := range entries {
+		data, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		if s.size+int64(len(data))+1 > s.max {
+			s.dropped++
+			continue
+		}
+		w.Write(data)
+		w.WriteByte('\n')
+		s.size += int64(len(data)) + 1
+	}
+	return w.Flush()
+}
+
+// pending reports whether entries wait to be replayed
+func (s *spool) pending() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.size > 0 {
+		return true
+	}
+	_, err := os.Stat(s.replayPath)
+	return err == nil
+}
+
+// replay sends the buffered entries in batches of batchSize. The entries that couldn't
+// be sent are kept for the next replay.
+func (s *spool) replay(batchSize int, send func([]Entry) error) error {
+	if err := s.takeBuffer(); err != nil {
+		return err
+	}
+
+	entries, err := readEntries(s.replayPath)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < len(entries); i += batchSize {
+		if err := send(entries[i:min(i+batchSize, len(entries))]); err != nil {
+			return errors.Join(err, writeEntries(s.replayPath, entries[i:]))
+		}
+	}
+	return os.Remove(s.replayPath)
+}
+
+// takeBuffer moves the buffer file aside for replay, unless a previous replay is unfinished
+func (s *spool) takeBuffer() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := os.Stat(s.replayPath); err == nil || s.size == 0 {
+		return nil
+	}
+	if err := os.Rename(s.path, s.replayPath); err != nil {
+		return err
+	}
+	s.size = 0
+	if s.dropped > 0 {
+		slog.Warn("Log buffer full, dropped entries", "entries", s.dropped)
+		s.dropped = 0
+	}
+	return nil
+}
+
+// readEntries reads a buffer file, skipping lines that don't decode
+func readEntries(path string) ([]Entry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []Entry
+	sc := bufio.NewScanner(f)
+	sc.Buffer(nil, 4*maxLineSize)
+	for sc.Scan() {
+		var e Entry
+		if json.Unmarshal(sc.Bytes(), &e) == nil {
+			entries = append(entries, e)
+		}
+	}
+	return entries, sc.Err()
+}
+
+// writeEntries replaces the buffer file at path with entries
+func writeEntries(path string, entries []Entry) error {
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o640)
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for _, e := range entries {
+		if err := enc.Encode(e); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := errors.Join(w.Flush(), f.Close()); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,60 @@
+// Package lsm confines a child process with an SELinux context or an AppArmor profile,
+// and finds the denials these Linux security modules logged for a process
+package lsm
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrUnsupported is returned by Exec on platforms without Linux security modules
+var ErrUnsupported = errors.New("SELinux and AppArmor are not supported on this platform")
+
+// maxDenials bounds the denials Denials returns
+const maxDenials = 20
+
+// Label is the security context a child is executed in. At most one is set.
+type Label struct {
+	SELinux  string `json:"selinuxContext,omitempty"`  // Such as system_u:system_r:svcapp_t:s0
+	AppArmor string `json:"apparmorProfile,omitempty"` // Name of a loaded profile
+}
+
+// IsZero reports whether the label leaves the child in the context of the daemon
+func (l Label) IsZero() bool {
+	return l.SELinux == "" && l.AppArmor == ""
+}
+
+// Validate checks that at most one module is configured
+func (l Label) Validate() error {
+	if l.SELinux != "" && l.AppArmor != "" {
+		return errors.New("set either selinuxContext or apparmorProfile, not both")
+	}
+	if strings.ContainsAny(l.SELinux+l.AppArmor, "\n\x00") {
+		return errors.New("invalid security label")
+	}
+	return nil
+}
+
+// String names the label, such as "apparmor:svcapp-child", empty when zero
+func (l Label) String() string {
+	switch {
+	case l.SELinux != "":
+		return "selinux:" + l.SELinux
+	case l.AppArmor != "":
+		return "apparmor:" + l.AppArmor
+	}
+	return ""
+}
+
+// isDenial reports whether an audit or kernel log line is a denial of process pid
+func isDenial(line string, pid string) bool {
+	if !strings.Contains(line, "avc:  denied") && !strings.Contains(line, `apparmor="DENIED"`) {
+		return false
+	}
+	for _, field := range strings.Fields(line) {
+		if field == "pid="+pid {
+			return true
+		}
+	}
+	return false
+}
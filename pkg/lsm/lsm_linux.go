@@ -0,0 +1,99 @@
+package lsm
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strconv"
+)
+
+// denialLogs are searched by Denials, the audit log first and the kernel log when
+// auditd doesn't run
+var denialLogs = []string{"/var/log/audit/audit.log", "/var/log/kern.log", "/var/log/messages"}
+
+// logTail is how much of the end of each log Denials reads
+const logTail = 4 << 20
+
+// Exec calls start, which must fork and execute a process, with the label set for the
+// next execution of the calling thread. The thread is locked for the duration so the
+// forked process inherits the label, which is then reset.
+func Exec(l Label, start func() error) error {
+	if l.IsZero() {
+		return start()
+	}
+
+	runtime.LockOSThread()
+
+	attr, value := "/proc/thread-self/attr/exec", l.SELinux
+	if l.AppArmor != "" {
+		value = "exec " + l.AppArmor // Like aa_change_onexec
+		if _, err := os.Stat("/proc/thread-self/attr/apparmor/exec"); err == nil {
+			attr = "/proc/thread-self/attr/apparmor/exec" // Stacked security modules
+		}
+	}
+	if err := writeAttr(attr, value); err != nil {
+		runtime.UnlockOSThread()
+		return fmt.Errorf("failed to set the %s label: %w", l, err)
+	}
+	err := start()
+	if writeAttr(attr, "") == nil {
+		// Otherwise the thread stays locked, so no other goroutine executes with the label
+		runtime.UnlockOSThread()
+	}
+	return err
+}
+
+// writeAttr writes value to the attr file of the calling thread, empty to reset it
+func writeAttr(path, value string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return errors.New("the security module is not enabled")
+		}
+		return err
+	}
+	defer f.Close()
+	_, err = f.WriteString(value)
+	return err
+}
+
+// Denials returns the last SELinux and AppArmor denials logged for process pid, at
+// most 20, oldest first
+func Denials(pid int) []string {
+	id := strconv.Itoa(pid)
+	for _, path := range denialLogs {
+		if denials := findDenials(path, id); len(denials) > 0 {
+			return denials
+		}
+	}
+	return nil
+}
+
+// findDenials scans the end of the log at path for denials of pid
+func findDenials(path, pid string) []string {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	if info, err := f.Stat(); err == nil && info.Size() > logTail {
+		f.Seek(-logTail, io.SeekEnd)
+	}
+
+	var denials []string
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	for sc.Scan() {
+		if isDenial(sc.Text(), pid) {
+			denials = append(denials, sc.Text())
+		}
+	}
+	if len(denials) > maxDenials {
+		denials = denials[len(denials)-maxDenials:]
+	}
+	return denials
+}
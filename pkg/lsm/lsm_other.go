@@ -0,0 +1,17 @@
+//go:build !linux
+
+package lsm
+
+// Exec calls start, which must fork and execute a process. A label is only
+// supported on Linux.
+func Exec(l Label, start func() error) error {
+	if !l.IsZero() {
+		return ErrUnsupported
+	}
+	return start()
+}
+
+// Denials returns nothing outside Linux
+func Denials(pid int) []string {
+	return nil
+}
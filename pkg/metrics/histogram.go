@@ -0,0 +1,102 @@
+package metrics
+
+import (
+	"math"
+	"slices"
+	"sync"
+	"time"
+	"unsafe"
+)
+
+// DefaultBuckets are the upper bounds used for lifecycle latencies
+var DefaultBuckets = []time.Duration{
+	10 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	1 * time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+	10 * time.Second,
+	30 * time.Second,
+}
+
+// Histogram counts durations into cumulative buckets. It is safe for concurrent use.
+type Histogram struct {
+	mu      sync.Mutex
+	buckets []time.Duration
+	counts  []uint64
+	count   uint64
+	sum     time.Duration
+}
+
+// Bucket is the number of observations lower than or equal to Le
+type Bucket struct {
+	Le    time.Duration `json:"le"`
+	Count uint64        `json:"count"`
+}
+
+// Snapshot is a point-in-time copy of a histogram
+type Snapshot struct {
+	Buckets []Bucket      `json:"buckets"`
+	Count   uint64        `json:"count"`
+	Sum     time.Duration `json:"sum"`
+}
+
+// NewHistogram creates a histogram with the given bucket upper bounds,
+// or DefaultBuckets when none are given
+func NewHistogram(buckets ...time.Duration) *Histogram {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	buckets = slices.Clone(buckets)
+	slices.Sort(buckets)
+	return &Histogram{buckets: buckets, counts: make([]uint64, len(buckets))}
+}
+
+// Observe records one duration
+func (h *Histogram) Observe(d time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for i, le := range h.buckets {
+		if d <= le {
+			h.counts[i]++
+		}
+	}
+	h.count++
+	h.sum += d
+}
+
+// Snapshot returns a copy of the histogram
+func (h *Histogram) Snapshot() Snapshot {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s := Snapshot{Buckets: make([]Bucket, len(h.buckets)), Count: h.count, Sum: h.sum}
+	for i, le := range h.buckets {
+		s.Buckets[i] = Bucket{Le: le, Count: h.counts[i]}
+	}
+	return s
+}
+
+// Percentile returns the p-th percentile (0-100) of samples using the nearest-rank
+// method. It sorts samples in place and returns zero when there are none.
+func Percentile(samples []time.Duration, p float64) time.Duration {
+	if len(samples) == 0 {
+		return 0
+	}
+	slices.Sort(samples)
+
+	rank := int(math.Ceil(p / 100 * float64(len(samples))))
+	rank = min(max(rank, 1), len(samples))
+	return samples[rank-1]
+}
+
+// Size returns the approximate memory held by the histogram, in bytes
+func (h *Histogram) Size() int64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return int64(unsafe.Sizeof(*h)) + int64(cap(h.buckets))*int64(unsafe.Sizeof(time.Duration(0))) + int64(cap(h.counts))*8
+}
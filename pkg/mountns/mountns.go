@@ -0,0 +1,81 @@
+// Package mountns gives a child process its own mount namespace, with a private /tmp,
+// read-only paths and bind mounts, like the systemd PrivateTmp, ProtectSystem,
+// ReadOnlyPaths and BindPaths directives do for services
+package mountns
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// EnvSpec passes the Spec to the helper process that sets the mounts up, see Init
+const EnvSpec = "SVCAPP_MOUNTNS"
+
+// ErrUnsupported is returned by Wrap on platforms without mount namespaces
+var ErrUnsupported = errors.New("mount namespaces are only supported on Linux")
+
+// systemPaths are made read-only by ProtectSystem, like ProtectSystem=yes
+var systemPaths = []string{"/usr", "/boot", "/efi"}
+
+// Spec describes the mounts of the child namespace. Mounts never propagate to the host.
+type Spec struct {
+	PrivateTmp    bool     `json:"privateTmp,omitempty"`    // Empty tmpfs on /tmp and /var/tmp
+	ProtectSystem bool     `json:"protectSystem,omitempty"` // Read-only /usr, /boot and /efi
+	ReadOnly      []string `json:"readOnly,omitempty"`      // Paths made read-only
+	Bind          []Bind   `json:"bind,omitempty"`          // Paths mounted elsewhere
+}
+
+// Bind mounts Source on Target, which must exist
+type Bind struct {
+	Source   string `json:"source"`             // Host path
+	Target   string `json:"target"`             // Path seen by the child
+	ReadOnly bool   `json:"readOnly,omitempty"` // Mount it read-only
+}
+
+// IsZero reports whether the spec leaves the child in the mount namespace of the daemon
+func (s Spec) IsZero() bool {
+	return !s.PrivateTmp && !s.ProtectSystem && len(s.ReadOnly) == 0 && len(s.Bind) == 0
+}
+
+// Validate checks that every path is absolute
+func (s Spec) Validate() error {
+	for _, p := range s.ReadOnly {
+		if !filepath.IsAbs(p) {
+			return fmt.Errorf("read-only path %q is not absolute", p)
+		}
+	}
+	for _, b := range s.Bind {
+		if !filepath.IsAbs(b.Source) || !filepath.IsAbs(b.Target) {
+			return fmt.Errorf("bind mount %q on %q: paths must be absolute", b.Source, b.Target)
+		}
+	}
+	return nil
+}
+
+// String summarizes the spec, such as "privateTmp, 2 read-only, 1 bind", empty when zero
+func (s Spec) String() string {
+	var parts []string
+	if s.PrivateTmp {
+		parts = append(parts, "privateTmp")
+	}
+	if s.ProtectSystem {
+		parts = append(parts, "protectSystem")
+	}
+	if len(s.ReadOnly) > 0 {
+		parts = append(parts, fmt.Sprintf("%d read-only", len(s.ReadOnly)))
+	}
+	if len(s.Bind) > 0 {
+		parts = append(parts, fmt.Sprintf("%d bind", len(s.Bind)))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// readOnlyPaths returns the paths made read-only, including the system paths
+func (s Spec) readOnlyPaths() []string {
+	if !s.ProtectSystem {
+		return s.ReadOnly
+	}
+	return append(append([]string(nil), systemPaths...), s.ReadOnly...)
+}
@@ -0,0 +1,120 @@
+package mountns
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// Wrap makes cmd start in a new mount namespace. The current executable runs first as
+// a helper, which sets the mounts of s up and then executes the command in place, so
+// the PID and the inherited files are those of the command.
+func Wrap(cmd *exec.Cmd, s Spec) error {
+	if s.IsZero() {
+		return nil
+	}
+	self, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("executable path not found: %w", err)
+	}
+	spec, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	cmd.Args = append([]string{self, cmd.Path}, cmd.Args[1:]...)
+	cmd.Path = self
+	if cmd.Env == nil {
+		cmd.Env = os.Environ()
+	}
+	cmd.Env = append(cmd.Env, EnvSpec+"="+string(spec))
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Cloneflags |= syscall.CLONE_NEWNS
+	return nil
+}
+
+// TempDir returns a directory for temporary files shared with a child started with s,
+// or "" for the default one. The private /tmp hides the default one from the child.
+func TempDir(s Spec) string {
+	if s.PrivateTmp {
+		return "/dev/shm"
+	}
+	return ""
+}
+
+// Init runs the helper started by Wrap, and must be called first thing in main. It
+// returns at once in any other process. The helper never returns: it executes the
+// command once the mounts are set up, or exits with status 127 when they fail.
+func Init() {
+	spec, ok := os.LookupEnv(EnvSpec)
+	if !ok {
+		return
+	}
+	os.Unsetenv(EnvSpec)
+
+	err := setup(spec)
+	if err == nil {
+		if len(os.Args) < 2 {
+			err = errors.New("no command to execute")
+		} else {
+			err = syscall.Exec(os.Args[1], os.Args[1:], os.Environ())
+		}
+	}
+	fmt.Fprintln(os.Stderr, "mount namespace setup failed:", err)
+	os.Exit(127)
+}
+
+// setup applies the JSON encoded Spec to the mount namespace of the process
+func setup(spec string) error {
+	var s Spec
+	if err := json.Unmarshal([]byte(spec), &s); err != nil {
+		return err
+	}
+
+	// Keep every mount below private to the namespace
+	if err := unix.Mount("", "/", "", unix.MS_REC|unix.MS_PRIVATE, ""); err != nil {
+		return fmt.Errorf("make / private: %w", err)
+	}
+	if s.PrivateTmp {
+		for _, dir := range []string{"/tmp", "/var/tmp"} {
+			if err := unix.Mount("tmpfs", dir, "tmpfs", unix.MS_NOSUID|unix.MS_NODEV, "mode=1777"); err != nil {
+				return fmt.Errorf("private %s: %w", dir, err)
+			}
+		}
+	}
+	for _, b := range s.Bind {
+		if err := bind(b.Source, b.Target, b.ReadOnly); err != nil {
+			return err
+		}
+	}
+	for _, path := range s.readOnlyPaths() {
+		if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+			continue // Like systemd, which ignores missing system paths such as /efi
+		}
+		if err := bind(path, path, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// bind mounts source on target with its submounts, read-only when asked to
+func bind(source, target string, readOnly bool) error {
+	if err := unix.Mount(source, target, "", unix.MS_BIND|unix.MS_REC, ""); err != nil {
+		return fmt.Errorf("bind %s on %s: %w", source, target, err)
+	}
+	if !readOnly {
+		return nil
+	}
+	if err := unix.Mount("", target, "", unix.MS_BIND|unix.MS_REMOUNT|unix.MS_RDONLY, ""); err != nil {
+		return fmt.Errorf("make %s read-only: %w", target, err)
+	}
+	return nil
+}
@@ -0,0 +1,21 @@
+//go:build !linux
+
+package mountns
+
+import "os/exec"
+
+// Wrap leaves cmd unchanged, mount namespaces are only supported on Linux
+func Wrap(cmd *exec.Cmd, s Spec) error {
+	if s.IsZero() {
+		return nil
+	}
+	return ErrUnsupported
+}
+
+// TempDir returns "", the default directory, since there is no private /tmp
+func TempDir(s Spec) string {
+	return ""
+}
+
+// Init is a no-op outside Linux
+func Init() {}
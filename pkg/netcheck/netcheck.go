@@ -0,0 +1,88 @@
+// Package netcheck waits for the network to be usable, as an alternative to
+// network-online.target, which distributions implement inconsistently
+package netcheck
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
+const (
+	pollInterval = time.Second
+	dialTimeout  = 3 * time.Second
+)
+
+// routeProbes are documentation addresses dialed over UDP to look up a route without
+// sending a packet, see RFC 5737 and RFC 3849
+var routeProbes = []string{"192.0.2.1:9", "[2001:db8::1]:9"}
+
+// Check lists the conditions under which the network is considered usable
+type Check struct {
+	Route   bool     // A route to the outside, normally the default route, must exist
+	Resolve []string // Host names that must resolve
+	Reach   []string // host:port addresses that must accept TCP connections
+}
+
+// Enabled reports whether c has any condition
+func (c Check) Enabled() bool {
+	return c.Route || len(c.Resolve) > 0 || len(c.Reach) > 0
+}
+
+// Run evaluates every condition once, returning the first that fails
+func (c Check) Run(ctx context.Context) error {
+	if c.Route {
+		if err := checkRoute(); err != nil {
+			return err
+		}
+	}
+	for _, host := range c.Resolve {
+		if _, err := net.DefaultResolver.LookupHost(ctx, host); err != nil {
+			return fmt.Errorf("cannot resolve %s: %w", host, err)
+		}
+	}
+	for _, addr := range c.Reach {
+		d := net.Dialer{Timeout: dialTimeout}
+		conn, err := d.DialContext(ctx, "tcp", addr)
+		if err != nil {
+			return fmt.Errorf("cannot reach %s: %w", addr, err)
+		}
+		conn.Close()
+	}
+	return nil
+}
+
+// Wait runs c until it passes or ctx is done, in which case the last failure is returned
+func (c Check) Wait(ctx context.Context) error {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		err := c.Run(ctx)
+		if err == nil {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("network not ready: %w", err)
+		case <-ticker.C:
+		}
+	}
+}
+
+// checkRoute reports whether an IPv4 or IPv6 route to the outside exists
+func checkRoute() error {
+	var errs []error
+	for _, addr := range routeProbes {
+		conn, err := net.Dial("udp", addr)
+		if err == nil {
+			conn.Close()
+			return nil
+		}
+		errs = append(errs, err)
+	}
+	return fmt.Errorf("no route: %w", errors.Join(errs...))
+}
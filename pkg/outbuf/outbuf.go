@@ -0,0 +1,189 @@
+// Package outbuf decouples the child output from slow destinations with a bounded buffer
+package outbuf
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Defaults applied to zero Options fields
+const (
+	DefaultSize     = 1 << 20
+	DefaultMaxBlock = time.Second
+)
+
+// LeanSize is the buffer size suggested for memory-constrained devices
+const LeanSize = 64 << 10
+
+const (
+	dropReportInterval = 10 * time.Second // Limits how often dropped output is logged
+	closeTimeout       = 5 * time.Second  // Bounds the flush of the queued output on Close
+)
+
+// Options bounds the buffer of a Writer
+type Options struct {
+	Size     int           // Bytes held while the destination is slow, DefaultSize when zero
+	MaxBlock time.Duration // How long a write may wait for space before it is dropped, DefaultMaxBlock when zero
+	Drop     bool          // Drop writes as soon as the buffer is full, without waiting
+}
+
+// Writer forwards writes to its destination from a background goroutine. When the
+// destination falls behind and the buffer is full, a write waits up to MaxBlock for
+// space, then is dropped and counted. Writes never fail, so the child is never handed
+// a broken pipe.
+type Writer struct {
+	dst  io.Writer
+	name string
+	opts Options
+
+	mu     sync.Mutex
+	queue  [][]byte
+	size   int           // Bytes queued or being written
+	space  chan struct{} // Closed when space is freed
+	ready  chan struct{} // Signals queued data to the flusher
+	closed bool
+	done   chan struct{}
+
+	droppedWrites atomic.Uint64
+	droppedBytes  atomic.Uint64
+}
+
+// New starts a writer forwarding to dst. name identifies the stream in the logs.
+func New(dst io.Writer, name string, opts Options) *Writer {
+	if opts.Size <= 0 {
+		opts.Size = DefaultSize
+	}
+	if opts.MaxBlock <= 0 {
+		opts.MaxBlock = DefaultMaxBlock
+	}
+	w := &Writer{
+		dst:   dst,
+		name:  name,
+		opts:  opts,
+		space: make(chan struct{}),
+		ready: make(chan struct{}, 1),
+		done:  make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+// Write queues a copy of p. It always reports success unless the writer is closed.
+func (w *Writer) Write(p []byte) (int, error) {
+	var deadline <-chan time.Time
+	for {
+		w.mu.Lock()
+		if w.closed {
+			w.mu.Unlock()
+			return 0, os.ErrClosed
+		}
+		if w.size+len(p) <= w.opts.Size {
+			w.queue = append(w.queue, append([]byte(nil), p...))
+			w.size += len(p)
+			w.mu.Unlock()
+			select {
+			case w.ready <- struct{}{}:
+			default:
+			}
+			return len(p), nil
+		}
+		space := w.space
+		w.mu.Unlock()
+
+		if w.opts.Drop || len(p) > w.opts.Size {
+			break
+		}
+		if deadline == nil {
+			timer := time.NewTimer(w.opts.MaxBlock)
+			defer timer.Stop()
+			deadline = timer.C
+		}
+		select {
+		case <-space:
+			continue
+		case <-deadline:
+		}
+		break
+	}
+
+	w.droppedWrites.Add(1)
+	w.droppedBytes.Add(uint64(len(p)))
+	return len(p), nil
+}
+
+// Dropped returns the number of writes and bytes dropped so far
+func (w *Writer) Dropped() (writes, bytes uint64) {
+	return w.droppedWrites.Load(), w.droppedBytes.Load()
+}
+
+// Buffered returns the bytes waiting for the destination
+func (w *Writer) Buffered() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.size
+}
+
+// Close stops accepting writes and waits a few seconds for the queued ones to reach
+// the destination
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	w.closed = true
+	w.mu.Unlock()
+	select {
+	case w.ready <- struct{}{}:
+	default:
+	}
+
+	select {
+	case <-w.done:
+	case <-time.After(closeTimeout):
+		slog.Warn("Child output not flushed", "stream", w.name, "bytes", w.Buffered())
+	}
+	return nil
+}
+
+// run writes the queued data to the destination until the writer is closed and drained
+func (w *Writer) run() {
+	defer close(w.done)
+
+	var reported uint64
+	var lastReport time.Time
+	for {
+		w.mu.Lock()
+		batch := w.queue
+		w.queue = nil
+		closed := w.closed
+		w.mu.Unlock()
+
+		if len(batch) == 0 {
+			if closed {
+				return
+			}
+			<-w.ready
+			continue
+		}
+
+		n := 0
+		for _, b := range batch {
+			w.dst.Write(b)
+			n += len(b)
+		}
+
+		w.mu.Lock()
+		w.size -= n
+		close(w.space)
+		w.space = make(chan struct{})
+		w.mu.Unlock()
+
+		if dropped := w.droppedBytes.Load(); dropped > reported && time.Since(lastReport) >= dropReportInterval {
+			writes, _ := w.Dropped()
+			slog.Warn("Child output dropped, the destination is too slow", "stream", w.name,
+				"bytes", dropped-reported, "totalBytes", dropped, "totalWrites", writes)
+			reported, lastReport = dropped, time.Now()
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package outbuf
+
+import (
+	"bytes"
+	"io"
+	"sync/atomic"
+)
+
+// Sampler forwards one line in every rate to its destination and discards the others,
+// counting their bytes. With a rate of zero, every write is discarded without being
+// split into lines.
+type Sampler struct {
+	dst  io.Writer
+	rate uint64
+	line uint64 // Index of the line being written
+
+	discarded atomic.Uint64
+}
+
+// NewSampler returns a sampler forwarding one line in every rate to dst
+func NewSampler(dst io.Writer, rate int) *Sampler {
+	return &Sampler{dst: dst, rate: uint64(max(rate, 0))}
+}
+
+// NewDiscard returns a sampler discarding everything
+func NewDiscard() *Sampler {
+	return NewSampler(io.Discard, 0)
+}
+
+func (s *Sampler) Write(p []byte) (int, error) {
+	if s.rate == 0 {
+		s.discarded.Add(uint64(len(p)))
+		return len(p), nil
+	}
+	if s.rate == 1 {
+		return s.dst.Write(p)
+	}
+
+	data := p
+	for len(data) > 0 {
+		end := len(data)
+		if i := bytes.IndexByte(data, '\n'); i >= 0 {
+			end = i + 1
+		}
+		if s.line%s.rate == 0 {
+			if _, err := s.dst.Write(data[:end]); err != nil {
+				return len(p) - len(data), err
+			}
+		} else {
+			s.discarded.Add(uint64(end))
+		}
+		if data[end-1] == '\n' {
+			s.line++
+		}
+		data = data[end:]
+	}
+	return len(p), nil
+}
+
+// Discarded returns the bytes discarded so far
+func (s *Sampler) Discarded() uint64 {
+	return s.discarded.Load()
+}
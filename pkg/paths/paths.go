@@ -0,0 +1,145 @@
+// Package paths holds the default locations of the svcapp files on each platform.
+// System-wide locations, such as /etc/svcapp and /var/lib/svcapp, are used by root
+// and wherever a system-wide installation exists. Other users get per-user locations,
+// such as the XDG base directories, so commands work without root. Every location is
+// named after the app, set with SetApp, so applications built from svcapp don't share
+// their files.
+package paths
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+// EnvScope forces the system or user locations, see Scope
+const EnvScope = "SVCAPP_SCOPE"
+
+// EnvInstance names the instance of the service the process belongs to, set in the
+// environment of installed instances
+const EnvInstance = "SVCAPP_INSTANCE"
+
+// InstanceSeparator joins a service name and an instance name, as in svcapp@a
+const InstanceSeparator = "@"
+
+// Location scopes
+const (
+	ScopeSystem = "system"
+	ScopeUser   = "user"
+)
+
+// app names the directory of the application within each base directory
+var app = "svcapp"
+
+// validApp matches the app names usable as file, pipe and bus names
+var validApp = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_-]*$`)
+
+// SetApp names the directories, PID file, sockets and store files of the application
+// after name, svcapp by default. It must be called before any location is used.
+func SetApp(name string) error {
+	if !validApp.MatchString(name) {
+		return fmt.Errorf("invalid app name %q: expected a letter followed by letters, digits, _ or -", name)
+	}
+	app = name
+	return nil
+}
+
+// App returns the application name set with SetApp
+func App() string {
+	return app
+}
+
+// Instance returns the instance of the service the process belongs to, empty for the
+// service itself
+func Instance() string {
+	return os.Getenv(EnvInstance)
+}
+
+// Service returns the name of the service the process belongs to, the app name
+// followed by the instance as in svcapp@a
+func Service() string {
+	if instance := Instance(); instance != "" {
+		return app + InstanceSeparator + instance
+	}
+	return app
+}
+
+// InstanceDir returns dir, or the directory of the instance within dir when the
+// process belongs to an instance
+func InstanceDir(dir string) string {
+	if instance := Instance(); instance != "" {
+		return filepath.Join(dir, instance)
+	}
+	return dir
+}
+
+// Scope returns the scope of the default locations. EnvScope wins when set. Otherwise
+// root, Windows and users of a system-wide installation, whose state directory exists,
+// get the system scope, and other users the user scope.
+func Scope() string {
+	switch s := os.Getenv(EnvScope); s {
+	case ScopeSystem, ScopeUser:
+		return s
+	}
+	if systemScope() {
+		return ScopeSystem
+	}
+	if _, err := os.Stat(systemDirs().State); err == nil {
+		return ScopeSystem
+	}
+	return ScopeUser
+}
+
+// Dirs are the default directories of one scope
+type Dirs struct {
+	Config  string // Configuration file
+	State   string // State, history, crash reports and secrets
+	Logs    string // Child and job log files
+	Runtime string // PID file and control socket
+}
+
+// Default returns the default directories of the current scope
+func Default() Dirs {
+	if Scope() == ScopeUser {
+		return userDirs()
+	}
+	return systemDirs()
+}
+
+// ConfigDir returns the default directory of the configuration file
+func ConfigDir() string {
+	return Default().Config
+}
+
+// StateDir returns the default directory of the state file, history and crash reports
+func StateDir() string {
+	return Default().State
+}
+
+// LogDir returns the default directory of the child log files
+func LogDir() string {
+	return Default().Logs
+}
+
+// RuntimeDir returns the default directory of the PID file and control socket
+func RuntimeDir() string {
+	return Default().Runtime
+}
+
+// PIDFile returns the default PID file path, named after the service
+func PIDFile() string {
+	if Scope() == ScopeSystem {
+		return systemPIDFile()
+	}
+	return filepath.Join(RuntimeDir(), Service()+".pid")
+}
+
+// userHome returns the home directory of the current user, or the temporary directory
+// when it is unknown
+func userHome() string {
+	if home, err := os.UserHomeDir(); err == nil {
+		return home
+	}
+	return os.TempDir()
+}
@@ -0,0 +1,64 @@
+//go:build !windows
+
+package paths
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// systemPIDFile returns the PID file of the system scope, kept where earlier releases
+// put it
+func systemPIDFile() string {
+	return filepath.Join("/var/run", Service()+".pid")
+}
+
+// systemScope reports whether the process runs as root
+func systemScope() bool {
+	return os.Geteuid() == 0
+}
+
+// systemDirs returns the FHS directories
+func systemDirs() Dirs {
+	return Dirs{
+		Config:  filepath.Join("/etc", app),
+		State:   filepath.Join("/var/lib", app),
+		Logs:    filepath.Join("/var/log", app),
+		Runtime: filepath.Join("/run", app),
+	}
+}
+
+// userDirs returns the XDG base directories, or the Library ones on macOS
+func userDirs() Dirs {
+	home := userHome()
+	if runtime.GOOS == "darwin" {
+		support := filepath.Join(home, "Library", "Application Support", app)
+		return Dirs{
+			Config:  support,
+			State:   support,
+			Logs:    filepath.Join(home, "Library", "Logs", app),
+			Runtime: support,
+		}
+	}
+
+	state := filepath.Join(xdg("XDG_STATE_HOME", filepath.Join(home, ".local", "state")), app)
+	runtimeDir := state
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		runtimeDir = filepath.Join(dir, app)
+	}
+	return Dirs{
+		Config:  filepath.Join(xdg("XDG_CONFIG_HOME", filepath.Join(home, ".config")), app),
+		State:   state,
+		Logs:    filepath.Join(state, "logs"),
+		Runtime: runtimeDir,
+	}
+}
+
+// xdg returns the XDG base directory set by env, or def when it is unset or relative
+func xdg(env, def string) string {
+	if dir := os.Getenv(env); filepath.IsAbs(dir) {
+		return dir
+	}
+	return def
+}
@@ -0,0 +1,33 @@
+package paths
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// systemPIDFile returns no path, the service control manager tracks the process instead
+func systemPIDFile() string {
+	return ""
+}
+
+// systemScope is always true, services run under system accounts and the files are
+// shared through ACLs
+func systemScope() bool {
+	return true
+}
+
+// systemDirs returns the directories under ProgramData
+func systemDirs() Dirs {
+	root := filepath.Join(os.Getenv("ProgramData"), app)
+	return Dirs{Config: root, State: root, Logs: filepath.Join(root, "logs"), Runtime: root}
+}
+
+// userDirs returns the directories under LocalAppData
+func userDirs() Dirs {
+	base := os.Getenv("LocalAppData")
+	if base == "" {
+		base = filepath.Join(userHome(), "AppData", "Local")
+	}
+	root := filepath.Join(base, app)
+	return Dirs{Config: root, State: root, Logs: filepath.Join(root, "logs"), Runtime: root}
+}
@@ -0,0 +1,145 @@
+package pidfile
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lucasdecamargo/go-appservice-example/pkg/atomicfile"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/procinfo"
+)
+
+const (
+	commLen    = 15              // Process names are truncated to this length on Linux
+	clockSlack = 2 * time.Second // Tolerance between process start times and file times
+)
+
+// ErrRunning is returned by Acquire when the PID file points to a live instance
+var ErrRunning = errors.New("already running")
+
+// Acquire writes the current PID to path. A PID file left behind by a dead process,
+// or pointing to a process that is not this program, is logged and replaced. It
+// returns ErrRunning when the file points to a live instance of this program. The
+// file is locked meanwhile, so of concurrent starts only the first one succeeds.
+func Acquire(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create PID file directory: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open PID file: %w", err)
+	}
+	defer f.Close()
+	if err := atomicfile.Lock(f); err != nil {
+		return fmt.Errorf("failed to lock PID file: %w", err)
+	}
+	defer atomicfile.Unlock(f)
+
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return fmt.Errorf("failed to read PID file: %w", err)
+	}
+	if pid, err := parse(data); err == nil {
+		if reason := stale(path, pid); reason != "" {
+			slog.Warn("Replacing stale PID file", "path", path, "pid", pid, "reason", reason)
+		} else {
+			return fmt.Errorf("%w as PID %d (%s)", ErrRunning, pid, path)
+		}
+	}
+
+	if err := f.Truncate(0); err != nil {
+		return fmt.Errorf("failed to write PID file: %w", err)
+	}
+	if _, err := f.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0); err != nil {
+		return fmt.Errorf("failed to write PID file: %w", err)
+	}
+	return nil
+}
+
+// Release removes the PID file at path if it still holds the current PID
+func Release(path string) error {
+	if pid, ok := read(path); !ok || pid != os.Getpid() {
+		return nil
+	}
+	return os.Remove(path)
+}
+
+// Read returns the PID stored at path
+func Read(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	pid, err := parse(data)
+	if err != nil {
+		return 0, fmt.Errorf("%w in %s", err, path)
+	}
+	return pid, nil
+}
+
+// parse returns the PID in the content of a PID file
+func parse(data []byte) (int, error) {
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || pid <= 0 {
+		return 0, errors.New("no PID")
+	}
+	return pid, nil
+}
+
+// read returns the PID stored at path, reporting false when there is none
+func read(path string) (int, bool) {
+	pid, err := Read(path)
+	return pid, err == nil
+}
+
+// stale returns why the PID file at path does not belong to a live instance of
+// this program, or "" when it does
+func stale(path string, pid int) string {
+	var written time.Time
+	if fi, err := os.Stat(path); err == nil {
+		written = fi.ModTime()
+	}
+	return Stale(pid, written)
+}
+
+// Stale returns why pid is not a live instance of this program that was already
+// running at since, or "" when it is. A zero since skips the start time check.
+func Stale(pid int, since time.Time) string {
+	if pid == os.Getpid() {
+		return "current process"
+	}
+
+	p, err := procinfo.Get(pid)
+	if errors.Is(err, procinfo.ErrUnsupported) {
+		// Without the process details, only a process that is gone is proven stale
+		if !alive(pid) {
+			return "process not running"
+		}
+		return ""
+	}
+	if err != nil {
+		return "process not running"
+	}
+
+	exe, err := os.Executable()
+	if err == nil && !sameProgram(p.Name, filepath.Base(exe)) {
+		return fmt.Sprintf("PID reused by %s", p.Name)
+	}
+
+	// A process started after since reused the PID
+	if !since.IsZero() && !p.StartTime.IsZero() && p.StartTime.After(since.Add(clockSlack)) {
+		return "PID reused after it was recorded"
+	}
+	return ""
+}
+
+// sameProgram reports whether a process name refers to the executable exe
+func sameProgram(name, exe string) bool {
+	return name == exe || (len(name) == commLen && strings.HasPrefix(exe, name))
+}
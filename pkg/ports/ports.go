@@ -0,0 +1,101 @@
+// Package ports finds the TCP ports a child listens on and the connections it holds,
+// from its output or the system socket tables
+package ports
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strconv"
+)
+
+// ErrUnsupported is returned on platforms without socket tables to read
+var ErrUnsupported = errors.New("socket inspection not supported on this platform")
+
+// maxLine bounds the partial line a Writer holds, longer lines are skipped
+const maxLine = 4096
+
+// Matcher finds a port in lines of output
+type Matcher struct {
+	re *regexp.Regexp
+}
+
+// Compile returns a matcher for pattern. Its first capturing group, or the group
+// named port, must match the port number.
+func Compile(pattern string) (*Matcher, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid port pattern: %w", err)
+	}
+	if re.NumSubexp() == 0 {
+		return nil, fmt.Errorf("port pattern %q has no group capturing the port", pattern)
+	}
+	return &Matcher{re: re}, nil
+}
+
+// Match returns the port found in line
+func (m *Matcher) Match(line []byte) (int, bool) {
+	sub := m.re.FindSubmatch(line)
+	if sub == nil {
+		return 0, false
+	}
+	group := 1
+	if i := m.re.SubexpIndex("port"); i > 0 {
+		group = i
+	}
+	port, err := strconv.Atoi(string(sub[group]))
+	if err != nil || port <= 0 || port > 65535 {
+		return 0, false
+	}
+	return port, true
+}
+
+// Writer returns a writer calling found with each port matched in the lines written
+// to it. Writes never fail.
+func (m *Matcher) Writer(found func(port int)) io.Writer {
+	return &lineWriter{m: m, found: found}
+}
+
+// lineWriter splits its input into lines for a matcher
+type lineWriter struct {
+	m       *Matcher
+	found   func(int)
+	partial []byte
+	skip    bool // Discarding the rest of a line longer than maxLine
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	data := p
+	for len(data) > 0 {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			if !w.skip && len(w.partial)+len(data) <= maxLine {
+				w.partial = append(w.partial, data...)
+			} else {
+				w.partial, w.skip = w.partial[:0], true
+			}
+			break
+		}
+		if !w.skip && len(w.partial)+i <= maxLine {
+			line := append(w.partial, data[:i]...)
+			if port, ok := w.m.Match(line); ok {
+				w.found(port)
+			}
+		}
+		w.partial, w.skip = w.partial[:0], false
+		data = data[i+1:]
+	}
+	return len(p), nil
+}
+
+// Add returns ports with port added, sorted
+func Add(ports []int, port int) []int {
+	i, found := slices.BinarySearch(ports, port)
+	if found {
+		return ports
+	}
+	return slices.Insert(slices.Clone(ports), i, port)
+}
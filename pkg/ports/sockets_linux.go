@@ -0,0 +1,99 @@
+package ports
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// TCP socket states in /proc/net/tcp
+const (
+	tcpEstablished = "01"
+	tcpListen      = "0A"
+)
+
+// Listening returns the TCP ports the processes pids listen on, sorted, from the
+// socket tables of /proc
+func Listening(pids ...int) ([]int, error) {
+	local, err := sockets(pids, tcpListen)
+	if err != nil {
+		return nil, err
+	}
+	var ports []int
+	for _, port := range local {
+		ports = Add(ports, port)
+	}
+	return ports, nil
+}
+
+// Established returns the number of established TCP connections of the processes
+// pids, from the socket tables of /proc, like ss does
+func Established(pids ...int) (int, error) {
+	local, err := sockets(pids, tcpEstablished)
+	return len(local), err
+}
+
+// sockets returns the local ports of the TCP sockets of the processes pids in state
+func sockets(pids []int, state string) ([]int, error) {
+	inodes := make(map[string]bool)
+	for _, pid := range pids {
+		fds, err := os.ReadDir(fmt.Sprintf("/proc/%d/fd", pid))
+		if err != nil {
+			continue // Exited, or not ours to inspect
+		}
+		for _, fd := range fds {
+			link, err := os.Readlink(fmt.Sprintf("/proc/%d/fd/%s", pid, fd.Name()))
+			if err == nil && strings.HasPrefix(link, "socket:[") {
+				inodes[strings.TrimSuffix(strings.TrimPrefix(link, "socket:["), "]")] = true
+			}
+		}
+	}
+	if len(inodes) == 0 {
+		return nil, nil
+	}
+
+	var ports []int
+	for _, table := range []string{"tcp", "tcp6"} {
+		// The tables of the network namespace of the process
+		found, err := socketsIn(fmt.Sprintf("/proc/%d/net/%s", pids[0], table), inodes, state)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue // No IPv6
+			}
+			return nil, err
+		}
+		ports = append(ports, found...)
+	}
+	return ports, nil
+}
+
+// socketsIn returns the local ports of the sockets of path in state whose inode is in
+// inodes
+func socketsIn(path string, inodes map[string]bool, state string) ([]int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var ports []int
+	sc := bufio.NewScanner(f)
+	sc.Scan() // Header
+	for sc.Scan() {
+		// sl local_address rem_address st tx_queue:rx_queue tr:tm->when retrnsmt uid timeout inode
+		fields := strings.Fields(sc.Text())
+		if len(fields) < 10 || fields[3] != state || !inodes[fields[9]] {
+			continue
+		}
+		_, hex, ok := strings.Cut(fields[1], ":")
+		if !ok {
+			continue
+		}
+		if port, err := strconv.ParseUint(hex, 16, 16); err == nil {
+			ports = append(ports, int(port))
+		}
+	}
+	return ports, sc.Err()
+}
@@ -0,0 +1,14 @@
+//go:build !linux && !windows
+
+package ports
+
+// Listening returns ErrUnsupported, the sockets are only inspected on Linux and Windows
+func Listening(pids ...int) ([]int, error) {
+	return nil, ErrUnsupported
+}
+
+// Established returns ErrUnsupported, the sockets are only inspected on Linux and
+// Windows
+func Established(pids ...int) (int, error) {
+	return 0, ErrUnsupported
+}
@@ -0,0 +1,57 @@
+package ports
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os/exec"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Listening returns the TCP ports the processes pids listen on, sorted, as listed by
+// netstat
+func Listening(pids ...int) ([]int, error) {
+	local, err := sockets(pids, "LISTENING")
+	if err != nil {
+		return nil, err
+	}
+	var ports []int
+	for _, port := range local {
+		ports = Add(ports, port)
+	}
+	return ports, nil
+}
+
+// Established returns the number of established TCP connections of the processes
+// pids, as listed by netstat
+func Established(pids ...int) (int, error) {
+	local, err := sockets(pids, "ESTABLISHED")
+	return len(local), err
+}
+
+// sockets returns the local ports of the TCP sockets of the processes pids in state
+func sockets(pids []int, state string) ([]int, error) {
+	out, err := exec.Command("netstat", "-ano", "-p", "TCP").Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to run netstat: %w", err)
+	}
+	var ports []int
+	sc := bufio.NewScanner(bytes.NewReader(out))
+	for sc.Scan() {
+		// Proto  Local Address  Foreign Address  State  PID
+		fields := strings.Fields(sc.Text())
+		if len(fields) != 5 || fields[3] != state {
+			continue
+		}
+		if pid, err := strconv.Atoi(fields[4]); err != nil || !slices.Contains(pids, pid) {
+			continue
+		}
+		i := strings.LastIndexByte(fields[1], ':')
+		if port, err := strconv.Atoi(fields[1][i+1:]); err == nil {
+			ports = append(ports, port)
+		}
+	}
+	return ports, nil
+}
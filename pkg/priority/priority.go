@@ -0,0 +1,57 @@
+// Package priority raises the CPU and I/O scheduling priority of a running process
+package priority
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// I/O scheduling classes, from the highest to the lowest
+const (
+	IORealtime   = "realtime"
+	IOBestEffort = "best-effort"
+	IOIdle       = "idle"
+)
+
+// ErrUnsupported is returned by Apply on platforms without scheduling priorities
+var ErrUnsupported = errors.New("scheduling priorities are not supported on this platform")
+
+// Boost is the scheduling priority given to a process. Zero values leave it unchanged.
+type Boost struct {
+	Nice    int    `json:"nice,omitempty"`    // From -20, the highest, to 19. Mapped to a priority class on Windows.
+	IOClass string `json:"ioClass,omitempty"` // realtime, best-effort or idle, Linux only
+	IOLevel int    `json:"ioLevel,omitempty"` // From 0, the highest, to 7 within the I/O class
+}
+
+// IsZero reports whether the boost leaves the priority unchanged
+func (b Boost) IsZero() bool {
+	return b.Nice == 0 && b.IOClass == ""
+}
+
+// Validate checks the nice value and the I/O class and level
+func (b Boost) Validate() error {
+	if b.Nice < -20 || b.Nice > 19 {
+		return fmt.Errorf("nice %d is not between -20 and 19", b.Nice)
+	}
+	if b.IOClass != "" && !slices.Contains([]string{IORealtime, IOBestEffort, IOIdle}, b.IOClass) {
+		return fmt.Errorf("unknown I/O class %q, use %s, %s or %s", b.IOClass, IORealtime, IOBestEffort, IOIdle)
+	}
+	if b.IOLevel < 0 || b.IOLevel > 7 {
+		return fmt.Errorf("I/O level %d is not between 0 and 7", b.IOLevel)
+	}
+	return nil
+}
+
+// String summarizes the boost, such as "nice -10, io realtime/4", empty when zero
+func (b Boost) String() string {
+	var parts []string
+	if b.Nice != 0 {
+		parts = append(parts, fmt.Sprintf("nice %d", b.Nice))
+	}
+	if b.IOClass != "" {
+		parts = append(parts, fmt.Sprintf("io %s/%d", b.IOClass, b.IOLevel))
+	}
+	return strings.Join(parts, ", ")
+}
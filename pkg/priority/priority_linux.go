@@ -0,0 +1,75 @@
+package priority
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+
+	"golang.org/x/sys/unix"
+)
+
+// ioprio_set arguments, see ioprio_set(2)
+const (
+	ioprioWhoProcess = 1
+	ioprioClassShift = 13
+)
+
+// ioClasses maps the I/O class names to the kernel classes
+var ioClasses = map[string]int{IORealtime: 1, IOBestEffort: 2, IOIdle: 3}
+
+// Apply sets the boost on every thread of the running process pid. Raising the
+// priority requires CAP_SYS_NICE, and CAP_SYS_ADMIN for the realtime I/O class.
+func Apply(pid int, b Boost) error {
+	if b.IsZero() {
+		return nil
+	}
+	tids, err := threads(pid)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, tid := range tids {
+		if err := applyThread(tid, b); err != nil && !errors.Is(err, unix.ESRCH) {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		// Every thread fails the same way, one error is enough
+		return errs[0]
+	}
+	return nil
+}
+
+// applyThread sets the boost on the thread tid, since Linux schedules threads and not
+// processes
+func applyThread(tid int, b Boost) error {
+	if b.Nice != 0 {
+		if err := unix.Setpriority(unix.PRIO_PROCESS, tid, b.Nice); err != nil {
+			return fmt.Errorf("failed to set nice %d: %w", b.Nice, err)
+		}
+	}
+	if b.IOClass != "" {
+		prio := ioClasses[b.IOClass]<<ioprioClassShift | b.IOLevel
+		if _, _, errno := unix.Syscall(unix.SYS_IOPRIO_SET, ioprioWhoProcess, uintptr(tid), uintptr(prio)); errno != 0 {
+			return fmt.Errorf("failed to set I/O class %s: %w", b.IOClass, errno)
+		}
+	}
+	return nil
+}
+
+// threads lists the thread IDs of the process pid
+func threads(pid int) ([]int, error) {
+	entries, err := os.ReadDir("/proc/" + strconv.Itoa(pid) + "/task")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the threads of %d: %w", pid, err)
+	}
+	tids := make([]int, 0, len(entries))
+	for _, e := range entries {
+		if tid, err := strconv.Atoi(e.Name()); err == nil {
+			tids = append(tids, tid)
+		}
+	}
+	return tids, nil
+}
@@ -0,0 +1,12 @@
+//go:build !linux && !windows
+
+package priority
+
+// Apply sets the boost on the running process pid, which is only supported on Linux
+// and Windows
+func Apply(pid int, b Boost) error {
+	if b.IsZero() {
+		return nil
+	}
+	return ErrUnsupported
+}
@@ -0,0 +1,52 @@
+package priority
+
+import (
+	"errors"
+	"fmt"
+
+	"golang.org/x/sys/windows"
+)
+
+// Apply sets the priority class matching the nice value on the running process pid.
+// I/O classes are not supported on Windows.
+func Apply(pid int, b Boost) error {
+	if b.IsZero() {
+		return nil
+	}
+	var err error
+	if b.Nice != 0 {
+		err = setPriorityClass(pid, priorityClass(b.Nice))
+	}
+	if b.IOClass != "" {
+		err = errors.Join(err, errors.New("I/O classes are only supported on Linux"))
+	}
+	return err
+}
+
+// setPriorityClass sets the priority class of the process pid
+func setPriorityClass(pid int, class uint32) error {
+	h, err := windows.OpenProcess(windows.PROCESS_SET_INFORMATION, false, uint32(pid))
+	if err != nil {
+		return fmt.Errorf("failed to open process %d: %w", pid, err)
+	}
+	defer windows.CloseHandle(h)
+	if err := windows.SetPriorityClass(h, class); err != nil {
+		return fmt.Errorf("failed to set the priority class: %w", err)
+	}
+	return nil
+}
+
+// priorityClass maps a nice value to the nearest priority class. The realtime class
+// is never used, it can starve the system.
+func priorityClass(nice int) uint32 {
+	switch {
+	case nice <= -10:
+		return windows.HIGH_PRIORITY_CLASS
+	case nice < 0:
+		return windows.ABOVE_NORMAL_PRIORITY_CLASS
+	case nice >= 10:
+		return windows.IDLE_PRIORITY_CLASS
+	default:
+		return windows.BELOW_NORMAL_PRIORITY_CLASS
+	}
+}
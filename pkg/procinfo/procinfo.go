@@ -0,0 +1,67 @@
+package procinfo
+
+import (
+	"errors"
+	"slices"
+	"time"
+)
+
+// ErrUnsupported is returned by List on platforms without a process table implementation
+var ErrUnsupported = errors.New("process listing not supported on this platform")
+
+// Process describes a running process
+type Process struct {
+	PID       int
+	PPID      int
+	Name      string
+	CPUTime   time.Duration // User plus system time consumed
+	RSS       uint64        // Resident memory in bytes
+	StartTime time.Time
+}
+
+// CPUPercent returns the average CPU usage since the process started
+func (p Process) CPUPercent() float64 {
+	elapsed := time.Since(p.StartTime)
+	if elapsed <= 0 {
+		return 0
+	}
+	return 100 * p.CPUTime.Seconds() / elapsed.Seconds()
+}
+
+// Node is a process along with its descendants
+type Node struct {
+	Process
+	Children []*Node
+}
+
+// Tree builds the process tree rooted at pid from a process list. It returns nil
+// when pid is not in the list.
+func Tree(procs []Process, pid int) *Node {
+	children := make(map[int][]Process)
+	var root *Node
+	for _, p := range procs {
+		if p.PID == pid {
+			root = &Node{Process: p}
+		}
+		children[p.PPID] = append(children[p.PPID], p)
+	}
+	if root == nil {
+		return nil
+	}
+
+	var walk func(n *Node)
+	walk = func(n *Node) {
+		for _, c := range children[n.PID] {
+			if c.PID == n.PID {
+				continue // PID 0 is its own parent on Windows
+			}
+			child := &Node{Process: c}
+			walk(child)
+			n.Children = append(n.Children, child)
+		}
+		slices.SortFunc(n.Children, func(a, b *Node) int { return a.PID - b.PID })
+	}
+	walk(root)
+
+	return root
+}
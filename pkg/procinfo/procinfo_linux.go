@@ -0,0 +1,111 @@
+package procinfo
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// clockTicks is USER_HZ, which is 100 on all mainstream Linux architectures
+const clockTicks = 100
+
+// List returns all processes found in procfs
+func List() ([]Process, error) {
+	boot, err := bootTime()
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return nil, err
+	}
+
+	var procs []Process
+	for _, e := range entries {
+		pid, err := strconv.Atoi(e.Name())
+		if err != nil {
+			continue
+		}
+		p, err := readStat(pid, boot)
+		if err != nil {
+			continue // The process exited while listing
+		}
+		procs = append(procs, p)
+	}
+	return procs, nil
+}
+
+// Get returns a single process from procfs
+func Get(pid int) (Process, error) {
+	boot, err := bootTime()
+	if err != nil {
+		return Process{}, err
+	}
+	return readStat(pid, boot)
+}
+
+// readStat parses /proc/<pid>/stat, see proc(5)
+func readStat(pid int, boot time.Time) (Process, error) {
+	data, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return Process{}, err
+	}
+
+	// The command name is parenthesized and may contain spaces
+	s := string(data)
+	open, end := strings.IndexByte(s, '('), strings.LastIndexByte(s, ')')
+	if open < 0 || end < open {
+		return Process{}, fmt.Errorf("malformed stat for pid %d", pid)
+	}
+	fields := strings.Fields(s[end+1:])
+	if len(fields) < 22 {
+		return Process{}, fmt.Errorf("malformed stat for pid %d", pid)
+	}
+
+	// Field indexes are offset by 3 from proc(5): pid, comm and state are consumed
+	ppid, _ := strconv.Atoi(fields[1])
+	utime, _ := strconv.ParseUint(fields[11], 10, 64)
+	stime, _ := strconv.ParseUint(fields[12], 10, 64)
+	start, _ := strconv.ParseUint(fields[19], 10, 64)
+	rss, _ := strconv.ParseUint(fields[21], 10, 64)
+
+	return Process{
+		PID:       pid,
+		PPID:      ppid,
+		Name:      s[open+1 : end],
+		CPUTime:   ticks(utime + stime),
+		RSS:       rss * uint64(os.Getpagesize()),
+		StartTime: boot.Add(ticks(start)),
+	}, nil
+}
+
+// ticks converts clock ticks to a duration
+func ticks(n uint64) time.Duration {
+	return time.Duration(n) * time.Second / clockTicks
+}
+
+// bootTime reads the system boot time from /proc/stat
+func bootTime() (time.Time, error) {
+	f, err := os.Open("/proc/stat")
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if v, ok := strings.CutPrefix(scanner.Text(), "btime "); ok {
+			sec, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+			if err != nil {
+				return time.Time{}, err
+			}
+			return time.Unix(sec, 0), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("boot time not found in /proc/stat")
+}
@@ -0,0 +1,13 @@
+//go:build !linux && !windows
+
+package procinfo
+
+// List is not implemented on this platform
+func List() ([]Process, error) {
+	return nil, ErrUnsupported
+}
+
+// Get is not implemented on this platform
+func Get(pid int) (Process, error) {
+	return Process{}, ErrUnsupported
+}
@@ -0,0 +1,95 @@
+package procinfo
+
+import (
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var procGetProcessMemoryInfo = windows.NewLazySystemDLL("psapi.dll").NewProc("GetProcessMemoryInfo")
+
+// processMemoryCounters mirrors PROCESS_MEMORY_COUNTERS from psapi.h
+type processMemoryCounters struct {
+	CB                         uint32
+	PageFaultCount             uint32
+	PeakWorkingSetSize         uintptr
+	WorkingSetSize             uintptr
+	QuotaPeakPagedPoolUsage    uintptr
+	QuotaPagedPoolUsage        uintptr
+	QuotaPeakNonPagedPoolUsage uintptr
+	QuotaNonPagedPoolUsage     uintptr
+	PagefileUsage              uintptr
+	PeakPagefileUsage          uintptr
+}
+
+// List returns all processes from a Toolhelp32 snapshot
+func List() ([]Process, error) {
+	snapshot, err := windows.CreateToolhelp32Snapshot(windows.TH32CS_SNAPPROCESS, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer windows.CloseHandle(snapshot)
+
+	var entry windows.ProcessEntry32
+	entry.Size = uint32(unsafe.Sizeof(entry))
+	if err := windows.Process32First(snapshot, &entry); err != nil {
+		return nil, err
+	}
+
+	var procs []Process
+	for {
+		p := Process{
+			PID:  int(entry.ProcessID),
+			PPID: int(entry.ParentProcessID),
+			Name: windows.UTF16ToString(entry.ExeFile[:]),
+		}
+		queryProcess(&p)
+		procs = append(procs, p)
+
+		if err := windows.Process32Next(snapshot, &entry); err != nil {
+			break
+		}
+	}
+	return procs, nil
+}
+
+// Get returns a single process
+func Get(pid int) (Process, error) {
+	procs, err := List()
+	if err != nil {
+		return Process{}, err
+	}
+	for _, p := range procs {
+		if p.PID == pid {
+			return p, nil
+		}
+	}
+	return Process{}, windows.ERROR_NOT_FOUND
+}
+
+// queryProcess fills in times and memory usage, leaving them empty when access is denied
+func queryProcess(p *Process) {
+	h, err := windows.OpenProcess(windows.PROCESS_QUERY_LIMITED_INFORMATION, false, uint32(p.PID))
+	if err != nil {
+		return
+	}
+	defer windows.CloseHandle(h)
+
+	var creation, exit, kernel, user windows.Filetime
+	if err := windows.GetProcessTimes(h, &creation, &exit, &kernel, &user); err == nil {
+		p.StartTime = time.Unix(0, creation.Nanoseconds())
+		p.CPUTime = filetimeDuration(kernel) + filetimeDuration(user)
+	}
+
+	var mem processMemoryCounters
+	mem.CB = uint32(unsafe.Sizeof(mem))
+	if r, _, _ := procGetProcessMemoryInfo.Call(uintptr(h), uintptr(unsafe.Pointer(&mem)), uintptr(mem.CB)); r != 0 {
+		p.RSS = uint64(mem.WorkingSetSize)
+	}
+}
+
+// filetimeDuration converts a FILETIME interval in 100ns units to a duration
+func filetimeDuration(ft windows.Filetime) time.Duration {
+	return time.Duration(uint64(ft.HighDateTime)<<32|uint64(ft.LowDateTime)) * 100
+}
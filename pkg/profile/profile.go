@@ -0,0 +1,110 @@
+// Package profile fetches CPU, heap and goroutine profiles from the net/http/pprof
+// handlers of a child
+package profile
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Profile kinds
+const (
+	KindCPU       = "cpu"
+	KindHeap      = "heap"
+	KindGoroutine = "goroutine"
+)
+
+// Kinds lists the profile kinds Fetch supports
+var Kinds = []string{KindCPU, KindHeap, KindGoroutine}
+
+// DefaultPath is where net/http/pprof serves its handlers
+const DefaultPath = "/debug/pprof"
+
+// probeTimeout bounds the request made to each port by Detect
+const probeTimeout = 2 * time.Second
+
+// ErrNotFound is returned by Detect when no port serves the pprof handlers
+var ErrNotFound = errors.New("no pprof handlers found")
+
+// ParseURL checks the base URL of the pprof handlers, such as
+// http://localhost:6060/debug/pprof. A host:port stands for its DefaultPath over http.
+func ParseURL(s string) (string, error) {
+	if !strings.Contains(s, "://") {
+		s = "http://" + s + DefaultPath
+	}
+	u, err := url.Parse(s)
+	if err != nil {
+		return "", err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", fmt.Errorf("%q is not an http or https URL", s)
+	}
+	return strings.TrimSuffix(u.String(), "/"), nil
+}
+
+// Fetch downloads a profile of kind from the pprof handlers at base. CPU profiles
+// are sampled for the duration d.
+func Fetch(ctx context.Context, base, kind string, d time.Duration) ([]byte, error) {
+	endpoint := base + "/" + kind
+	switch kind {
+	case KindCPU:
+		endpoint = base + "/profile?seconds=" + strconv.Itoa(max(int(d.Seconds()), 1))
+	case KindHeap, KindGoroutine:
+	default:
+		return nil, fmt.Errorf("unknown profile %q, use %s", kind, strings.Join(Kinds, ", "))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s: %s", endpoint, resp.Status, strings.TrimSpace(string(data)))
+	}
+	return data, nil
+}
+
+// Detect returns the base URL of the pprof handlers served on one of ports of the
+// local host, trying each at its DefaultPath
+func Detect(ctx context.Context, ports []int) (string, error) {
+	for _, port := range ports {
+		base := fmt.Sprintf("http://localhost:%d%s", port, DefaultPath)
+		if probe(ctx, base) {
+			return base, nil
+		}
+	}
+	return "", ErrNotFound
+}
+
+// probe reports whether base serves the pprof index
+func probe(ctx context.Context, base string) bool {
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, base+"/", nil)
+	if err != nil {
+		return false
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return false
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	return resp.StatusCode == http.StatusOK && strings.Contains(string(body), "goroutine")
+}
@@ -0,0 +1,48 @@
+// Package retention decides which of the oldest records, such as history events or
+// crash reports, to delete so the rest fit a retention policy
+package retention
+
+import "time"
+
+// Policy limits the records kept. Zero values don't limit.
+type Policy struct {
+	MaxAge   time.Duration // Records older than this are deleted
+	MaxCount int           // Only the newest records up to this count are kept
+	MaxSize  int64         // Only the newest records up to this total size in bytes are kept
+}
+
+// IsZero reports whether the policy keeps everything
+func (p Policy) IsZero() bool {
+	return p.MaxAge <= 0 && p.MaxCount <= 0 && p.MaxSize <= 0
+}
+
+// Record is the age and size of a record subject to a policy
+type Record struct {
+	Time time.Time
+	Size int64
+}
+
+// Expired returns how many of records, oldest first, must be deleted at now for the
+// rest to fit the policy
+func (p Policy) Expired(records []Record, now time.Time) int {
+	n := 0
+	if p.MaxAge > 0 {
+		cutoff := now.Add(-p.MaxAge)
+		for n < len(records) && records[n].Time.Before(cutoff) {
+			n++
+		}
+	}
+	if p.MaxCount > 0 && len(records)-n > p.MaxCount {
+		n = len(records) - p.MaxCount
+	}
+	if p.MaxSize > 0 {
+		var size int64
+		for _, r := range records[n:] {
+			size += r.Size
+		}
+		for ; n < len(records) && size > p.MaxSize; n++ {
+			size -= records[n].Size
+		}
+	}
+	return n
+}
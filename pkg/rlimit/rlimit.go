@@ -0,0 +1,97 @@
+// Package rlimit describes POSIX resource limits, rendered as systemd directives or
+// applied to a process directly where no service manager does it
+package rlimit
+
+import (
+	"errors"
+	"math"
+	"strconv"
+)
+
+// Infinity removes a limit, like RLIM_INFINITY
+const Infinity = math.MaxUint64
+
+// ErrUnsupported is returned by Apply on platforms without resource limits
+var ErrUnsupported = errors.New("resource limits are not supported on this platform")
+
+// Value is the soft and hard value of a limit
+type Value struct {
+	Soft uint64
+	Hard uint64
+}
+
+// Same returns a limit whose soft and hard values are both v
+func Same(v uint64) *Value {
+	return &Value{Soft: v, Hard: v}
+}
+
+// String renders the value as systemd does: one value when soft and hard are equal,
+// soft:hard otherwise
+func (v Value) String() string {
+	if v.Soft == v.Hard {
+		return format(v.Soft)
+	}
+	return format(v.Soft) + ":" + format(v.Hard)
+}
+
+func format(n uint64) string {
+	if n == Infinity {
+		return "infinity"
+	}
+	return strconv.FormatUint(n, 10)
+}
+
+// Limits are the resource limits of a process, nil to inherit them. Sizes are in
+// bytes. LimitNOFILE is a kardianos option and isn't repeated here.
+type Limits struct {
+	AS         *Value // Address space
+	Core       *Value // Core dump size
+	Data       *Value // Data segment
+	FSize      *Value // Size of created files
+	MemLock    *Value // Locked memory
+	MsgQueue   *Value // POSIX message queues, Linux only
+	Nice       *Value // Ceiling of the nice value as 20 - nice, Linux only
+	NProc      *Value // Processes of the user
+	RTPrio     *Value // Real-time priority, Linux only
+	SigPending *Value // Queued signals, Linux only
+	Stack      *Value // Stack size
+}
+
+// limit is a set limit with its systemd name
+type limit struct {
+	name  string
+	value Value
+}
+
+// set returns the limits that are set, in a stable order
+func (l Limits) set() []limit {
+	all := []struct {
+		name  string
+		value *Value
+	}{
+		{"AS", l.AS}, {"CORE", l.Core}, {"DATA", l.Data}, {"FSIZE", l.FSize},
+		{"MEMLOCK", l.MemLock}, {"MSGQUEUE", l.MsgQueue}, {"NICE", l.Nice}, {"NPROC", l.NProc},
+		{"RTPRIO", l.RTPrio}, {"SIGPENDING", l.SigPending}, {"STACK", l.Stack},
+	}
+	var out []limit
+	for _, a := range all {
+		if a.value != nil {
+			out = append(out, limit{a.name, *a.value})
+		}
+	}
+	return out
+}
+
+// IsZero reports whether no limit is set
+func (l Limits) IsZero() bool {
+	return len(l.set()) == 0
+}
+
+// Directives renders the limits as systemd [Service] lines, such as LimitCORE=infinity
+func (l Limits) Directives() []string {
+	var lines []string
+	for _, s := range l.set() {
+		lines = append(lines, "Limit"+s.name+"="+s.value.String())
+	}
+	return lines
+}
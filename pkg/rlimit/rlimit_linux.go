@@ -0,0 +1,39 @@
+package rlimit
+
+import (
+	"errors"
+	"fmt"
+
+	"golang.org/x/sys/unix"
+)
+
+// Supported reports whether Apply can set limits on this platform
+const Supported = true
+
+// resources maps the systemd limit names to the Linux resources
+var resources = map[string]int{
+	"AS":         unix.RLIMIT_AS,
+	"CORE":       unix.RLIMIT_CORE,
+	"DATA":       unix.RLIMIT_DATA,
+	"FSIZE":      unix.RLIMIT_FSIZE,
+	"MEMLOCK":    unix.RLIMIT_MEMLOCK,
+	"MSGQUEUE":   unix.RLIMIT_MSGQUEUE,
+	"NICE":       unix.RLIMIT_NICE,
+	"NPROC":      unix.RLIMIT_NPROC,
+	"RTPRIO":     unix.RLIMIT_RTPRIO,
+	"SIGPENDING": unix.RLIMIT_SIGPENDING,
+	"STACK":      unix.RLIMIT_STACK,
+}
+
+// Apply sets the limits on the running process pid. Raising a hard limit requires
+// CAP_SYS_RESOURCE. Every limit is attempted, and the failures are joined.
+func Apply(pid int, l Limits) error {
+	var errs []error
+	for _, s := range l.set() {
+		rlim := unix.Rlimit{Cur: s.value.Soft, Max: s.value.Hard}
+		if err := unix.Prlimit(pid, resources[s.name], &rlim, nil); err != nil {
+			errs = append(errs, fmt.Errorf("failed to set Limit%s=%s: %w", s.name, s.value, err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,14 @@
+//go:build !linux
+
+package rlimit
+
+// Supported reports whether Apply can set limits on this platform
+const Supported = false
+
+// Apply sets the limits on the running process pid, which is only supported on Linux
+func Apply(pid int, l Limits) error {
+	if l.IsZero() {
+		return nil
+	}
+	return ErrUnsupported
+}
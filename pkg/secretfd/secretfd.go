@@ -0,0 +1,47 @@
+// Package secretfd passes secrets to a child process through inherited file descriptors,
+// so they never appear in its arguments, its environment or on disk. The child finds
+// each secret at the /proc/self/fd/N path held by the <NAME>_FILE environment variable.
+package secretfd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// FileSuffix is appended to the secret name to form the variable holding its path
+const FileSuffix = "_FILE"
+
+// ErrUnsupported is returned by Open where file descriptors can't be passed by path
+var ErrUnsupported = errors.New("fileless secrets are only supported on Linux")
+
+// Secret is a named value passed to the child
+type Secret struct {
+	Name  string
+	Value []byte
+}
+
+// Parse splits KEY=VALUE lines, as read by config.ParseEnv, into secrets
+func Parse(vars []string) []Secret {
+	secrets := make([]Secret, 0, len(vars))
+	for _, v := range vars {
+		name, value, _ := strings.Cut(v, "=")
+		secrets = append(secrets, Secret{Name: strings.TrimSpace(name), Value: []byte(value)})
+	}
+	return secrets
+}
+
+// Env returns the variable telling the child the path of the secret passed as fd
+func Env(name string, fd int) string {
+	return fmt.Sprintf("%s%s=/proc/self/fd/%d", name, FileSuffix, fd)
+}
+
+// Lookup reads the secret passed to the current process under name
+func Lookup(name string) ([]byte, error) {
+	path := os.Getenv(name + FileSuffix)
+	if path == "" {
+		return nil, fmt.Errorf("secret %s not passed", name)
+	}
+	return os.ReadFile(path)
+}
@@ -0,0 +1,70 @@
+package secretfd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// Supported reports whether secrets can be passed on this platform
+const Supported = true
+
+// pipeWriteTimeout bounds the write of a secret into a pipe the child hasn't read yet
+const pipeWriteTimeout = time.Second
+
+// Open returns a read-only file holding the secret, to be inherited by the child. It is
+// a sealed memfd, which the child can read any number of times, or a pipe read once
+// on kernels without memfd_create.
+func Open(s Secret) (*os.File, error) {
+	f, err := openMemfd(s)
+	if errors.Is(err, unix.ENOSYS) {
+		f, err = openPipe(s)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to pass secret %s: %w", s.Name, err)
+	}
+	return f, nil
+}
+
+// openMemfd writes the secret to an anonymous memory file sealed against changes
+func openMemfd(s Secret) (*os.File, error) {
+	fd, err := unix.MemfdCreate("secret-"+s.Name, unix.MFD_CLOEXEC|unix.MFD_ALLOW_SEALING)
+	if err != nil {
+		return nil, err
+	}
+	f := os.NewFile(uintptr(fd), "memfd:secret-"+s.Name)
+
+	if _, err := f.Write(s.Value); err != nil {
+		f.Close()
+		return nil, err
+	}
+	seals := unix.F_SEAL_SHRINK | unix.F_SEAL_GROW | unix.F_SEAL_WRITE | unix.F_SEAL_SEAL
+	if _, err := unix.FcntlInt(uintptr(fd), unix.F_ADD_SEALS, seals); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if _, err := f.Seek(0, 0); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+// openPipe writes the secret into a pipe, failing when it exceeds the pipe buffer
+func openPipe(s Secret) (*os.File, error) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	defer w.Close()
+
+	w.SetWriteDeadline(time.Now().Add(pipeWriteTimeout))
+	if _, err := w.Write(s.Value); err != nil {
+		r.Close()
+		return nil, fmt.Errorf("secret too large for a pipe: %w", err)
+	}
+	return r, nil
+}
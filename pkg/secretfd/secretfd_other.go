@@ -0,0 +1,13 @@
+//go:build !linux
+
+package secretfd
+
+import "os"
+
+// Supported reports whether secrets can be passed on this platform
+const Supported = false
+
+// Open is not supported outside Linux
+func Open(s Secret) (*os.File, error) {
+	return nil, ErrUnsupported
+}
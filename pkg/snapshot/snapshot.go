@@ -0,0 +1,93 @@
+// Package snapshot runs the commands taking a crash-consistent snapshot of the service
+// data before a risky operation, such as a database dump or an LVM or ZFS snapshot,
+// and restoring it when the operation has to be rolled back.
+package snapshot
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Environment variables set for the snapshot commands
+const (
+	// EnvReason holds the operation a snapshot is taken before, such as reload
+	EnvReason = "SVCAPP_SNAPSHOT_REASON"
+	// EnvName holds the name of the snapshot to restore
+	EnvName = "SVCAPP_SNAPSHOT"
+)
+
+// DefaultTimeout bounds a snapshot command without its own timeout
+const DefaultTimeout = 5 * time.Minute
+
+// Hook takes and restores snapshots with commands
+type Hook struct {
+	Command        []string      // Executable and arguments, printing the snapshot name as its last output line
+	RestoreCommand []string      // Executable and arguments restoring the snapshot named by EnvName, nil when manual
+	Timeout        time.Duration // DefaultTimeout when zero
+}
+
+// Validate checks that h has a command taking snapshots
+func (h Hook) Validate() error {
+	if len(h.Command) == 0 {
+		return errors.New("command is required")
+	}
+	return nil
+}
+
+// Take runs the command before the operation reason and returns the name of the
+// snapshot it printed. A command printing nothing names the snapshot after the time.
+func (h Hook) Take(ctx context.Context, reason string) (string, error) {
+	out, err := h.run(ctx, h.Command, EnvReason+"="+reason)
+	if err != nil {
+		return "", err
+	}
+	var name string
+	sc := bufio.NewScanner(bytes.NewReader(out))
+	for sc.Scan() {
+		if line := strings.TrimSpace(sc.Text()); line != "" {
+			name = line
+		}
+	}
+	if name == "" {
+		name = time.Now().UTC().Format("20060102T150405Z")
+	}
+	return name, nil
+}
+
+// Restore runs the restore command for the snapshot name
+func (h Hook) Restore(ctx context.Context, name string) error {
+	if len(h.RestoreCommand) == 0 {
+		return errors.New("no restore command configured")
+	}
+	_, err := h.run(ctx, h.RestoreCommand, EnvName+"="+name)
+	return err
+}
+
+// run runs command within the timeout with env added, returning its standard output
+func (h Hook) run(ctx context.Context, command []string, env string) ([]byte, error) {
+	timeout := h.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, command[0], command[1:]...)
+	cmd.Env = append(os.Environ(), env)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := bytes.TrimSpace(stderr.Bytes()); len(msg) > 0 {
+			return nil, fmt.Errorf("%s: %w: %s", command[0], err, msg)
+		}
+		return nil, fmt.Errorf("%s: %w", command[0], err)
+	}
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,121 @@
+package state
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+)
+
+// Spec fingerprints the arguments and environment a child was started with. Only
+// hashes are kept, so secrets passed to the child never reach the state file.
+type Spec struct {
+	Args    []string          `json:"args"`
+	Env     map[string]string `json:"env,omitempty"`
+	Sources Sources           `json:"sources,omitzero"`
+}
+
+// Sources fingerprints the files a child is built from, so a restart can be skipped
+// when none of them changed since the child started
+type Sources struct {
+	Executable string `json:"executable,omitempty"`
+	Config     string `json:"config,omitempty"`
+	Env        string `json:"env,omitempty"` // Files the managed environment variables are read from
+}
+
+// Changed names the sources differing from s to next, skipping those s doesn't know
+func (s Sources) Changed(next Sources) []string {
+	var changed []string
+	for _, f := range []struct{ name, old, cur string }{
+		{"executable", s.Executable, next.Executable},
+		{"config", s.Config, next.Config},
+		{"env sources", s.Env, next.Env},
+	} {
+		if f.old != "" && f.old != f.cur {
+			changed = append(changed, f.name)
+		}
+	}
+	return changed
+}
+
+// FingerprintFiles returns a short hash identifying the paths and contents of the files
+// at paths, missing ones included, or an empty string without paths
+func FingerprintFiles(paths ...string) string {
+	if len(paths) == 0 {
+		return ""
+	}
+	h := sha256.New()
+	for _, path := range paths {
+		fmt.Fprintf(h, "%s\x00", path)
+		if f, err := os.Open(path); err == nil {
+			io.Copy(h, f)
+			f.Close()
+		}
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil)[:8])
+}
+
+// NewSpec fingerprints args and KEY=VALUE env entries. Later entries override earlier ones.
+func NewSpec(args, env []string) *Spec {
+	s := &Spec{Args: make([]string, len(args)), Env: make(map[string]string, len(env))}
+	for i, arg := range args {
+		s.Args[i] = fingerprint(arg)
+	}
+	for _, kv := range env {
+		key, value, _ := strings.Cut(kv, "=")
+		s.Env[key] = fingerprint(value)
+	}
+	return s
+}
+
+// Diff describes what changed from s to next by argument position and variable
+// name, never by value. It returns nil when s is nil or nothing changed.
+func (s *Spec) Diff(next *Spec) []string {
+	if s == nil || next == nil {
+		return nil
+	}
+
+	var changes []string
+	if len(s.Args) != len(next.Args) {
+		changes = append(changes, fmt.Sprintf("args: %d -> %d", len(s.Args), len(next.Args)))
+	}
+	for i := range min(len(s.Args), len(next.Args)) {
+		if s.Args[i] != next.Args[i] {
+			changes = append(changes, fmt.Sprintf("arg[%d] changed", i))
+		}
+	}
+
+	keys := slices.Sorted(maps.Keys(s.Env))
+	for _, key := range slices.Sorted(maps.Keys(next.Env)) {
+		if _, ok := s.Env[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	for _, source := range s.Sources.Changed(next.Sources) {
+		changes = append(changes, source+" changed")
+	}
+	for _, key := range keys {
+		old, hadOld := s.Env[key]
+		cur, hasCur := next.Env[key]
+		switch {
+		case !hadOld:
+			changes = append(changes, "env "+key+" added")
+		case !hasCur:
+			changes = append(changes, "env "+key+" removed")
+		case old != cur:
+			changes = append(changes, "env "+key+" changed")
+		}
+	}
+	return changes
+}
+
+// fingerprint returns a short hash identifying value
+func fingerprint(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:8])
+}
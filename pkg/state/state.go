@@ -0,0 +1,110 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/lucasdecamargo/go-appservice-example/pkg/atomicfile"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/paths"
+)
+
+// EnvState overrides the state file path
+const EnvState = "SVCAPP_STATE"
+
+// State is the persisted state of a running daemon
+type State struct {
+	Service   string     `json:"service,omitempty"`   // Service name, with the instance as in svcapp@a
+	PID       int        `json:"pid"`                 // Supervisor process ID
+	ChildPID  int        `json:"childPid,omitempty"`  // Current child process ID
+	Ready     bool       `json:"ready,omitempty"`     // Whether the current child reported readiness
+	StartedAt time.Time  `json:"startedAt"`           // When the supervisor started
+	Restarts  int        `json:"restarts"`            // Child restarts since the supervisor started
+	Scheduled *Scheduled `json:"scheduled,omitempty"` // Pending deferred action
+	Child     *Spec      `json:"child,omitempty"`     // What the last child was started with
+	Update    *Update    `json:"update,omitempty"`    // Outcome of the last update check
+	Ports     []int      `json:"ports,omitempty"`     // TCP ports the current child listens on, when detected
+
+	RestartReasons map[string]int    `json:"restartReasons,omitempty"` // Child restarts by reason, kept across supervisor runs
+	Crashed        string            `json:"crashed,omitempty"`        // Restart reason of a crash that stopped the supervisor
+	Degraded       string            `json:"degraded,omitempty"`       // Why the state and history are only kept in memory
+	ControlDown    map[string]string `json:"controlDown,omitempty"`    // Control sockets not accepting connections, with why
+}
+
+// Clone returns a deep copy of s, which stays unchanged while s is modified
+func (s *State) Clone() *State {
+	c := *s
+	c.Ports = slices.Clone(s.Ports)
+	c.RestartReasons = maps.Clone(s.RestartReasons)
+	c.ControlDown = maps.Clone(s.ControlDown)
+	if s.Scheduled != nil {
+		scheduled := *s.Scheduled
+		c.Scheduled = &scheduled
+	}
+	if s.Update != nil {
+		update := *s.Update
+		c.Update = &update
+	}
+	if s.Child != nil {
+		c.Child = &Spec{Args: slices.Clone(s.Child.Args), Env: maps.Clone(s.Child.Env), Sources: s.Child.Sources}
+	}
+	return &c
+}
+
+// Update is the outcome of the last check of the release feed
+type Update struct {
+	Current   string    `json:"current"`             // Version of the running executable
+	Available string    `json:"available,omitempty"` // Newer version announced by the feed
+	CheckedAt time.Time `json:"checkedAt"`
+	Error     string    `json:"error,omitempty"` // Why the last check or installation failed
+}
+
+// Scheduled is a deferred stop or restart
+type Scheduled struct {
+	Action string    `json:"action"`
+	At     time.Time `json:"at"`
+}
+
+// DefaultPath returns the state file path, honoring EnvState, in a directory of its
+// own for an instance
+func DefaultPath() string {
+	if path := os.Getenv(EnvState); path != "" {
+		return path
+	}
+	return filepath.Join(paths.InstanceDir(paths.StateDir()), "state.json")
+}
+
+// Load reads the state file at path. A missing file yields an empty state.
+func Load(path string) (*State, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &State{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state: %w", err)
+	}
+
+	var s State
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("failed to parse state %s: %w", path, err)
+	}
+	return &s, nil
+}
+
+// Save writes s to path, replacing the previous file atomically. With sync, the new
+// state is on storage when Save returns.
+func Save(path string, s *State, sync bool) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := atomicfile.WriteFile(path, data, 0o644, sync); err != nil {
+		return fmt.Errorf("failed to write state: %w", err)
+	}
+	return nil
+}
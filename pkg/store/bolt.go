@@ -0,0 +1,168 @@
+package store
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/lucasdecamargo/go-appservice-example/pkg/history"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/retention"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/state"
+	bolt "go.etcd.io/bbolt"
+)
+
+const boltTimeout = 5 * time.Second
+
+var (
+	bucketState   = []byte("state")
+	bucketHistory = []byte("history")
+	keyState      = []byte("current")
+)
+
+// BoltStore keeps the state and history in a bbolt database. bbolt locks the file
+// exclusively, so the database is only held open for the duration of each call,
+// letting the CLI read it while the daemon runs.
+type BoltStore struct {
+	path string
+}
+
+// OpenBolt creates a store backed by the bbolt database at path
+func OpenBolt(path string) (*BoltStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create database directory: %w", err)
+	}
+	return &BoltStore{path: path}, nil
+}
+
+func (b *BoltStore) LoadState() (*state.State, error) {
+	var s state.State
+	err := b.view(func(tx *bolt.Tx) error {
+		bk := tx.Bucket(bucketState)
+		if bk == nil {
+			return nil
+		}
+		if data := bk.Get(keyState); data != nil {
+			return json.Unmarshal(data, &s)
+		}
+		return nil
+	})
+	return &s, err
+}
+
+func (b *BoltStore) SaveState(s *state.State) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	return b.update(func(tx *bolt.Tx) error {
+		bk, err := tx.CreateBucketIfNotExists(bucketState)
+		if err != nil {
+			return err
+		}
+		return bk.Put(keyState, data)
+	})
+}
+
+func (b *BoltStore) AppendEvent(e history.Event) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	return b.update(func(tx *bolt.Tx) error {
+		bk, err := tx.CreateBucketIfNotExists(bucketHistory)
+		if err != nil {
+			return err
+		}
+		seq, err := bk.NextSequence()
+		if err != nil {
+			return err
+		}
+		return bk.Put(binary.BigEndian.AppendUint64(nil, seq), data)
+	})
+}
+
+func (b *BoltStore) Events() ([]history.Event, error) {
+	var events []history.Event
+	err := b.view(func(tx *bolt.Tx) error {
+		bk := tx.Bucket(bucketHistory)
+		if bk == nil {
+			return nil
+		}
+		return bk.ForEach(func(_, data []byte) error {
+			var e history.Event
+			if err := json.Unmarshal(data, &e); err != nil {
+				return err
+			}
+			events = append(events, e)
+			return nil
+		})
+	})
+	return events, err
+}
+
+func (b *BoltStore) PruneEvents(p retention.Policy) (int, error) {
+	if _, err := os.Stat(b.path); errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	n := 0
+	err := b.update(func(tx *bolt.Tx) error {
+		bk := tx.Bucket(bucketHistory)
+		if bk == nil {
+			return nil
+		}
+		var keys [][]byte
+		var records []retention.Record
+		err := bk.ForEach(func(k, data []byte) error {
+			var e history.Event
+			if err := json.Unmarshal(data, &e); err != nil {
+				return err
+			}
+			keys = append(keys, k)
+			records = append(records, retention.Record{Time: e.Time, Size: int64(len(data))})
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		n = p.Expired(records, time.Now())
+		for _, k := range keys[:n] {
+			if err := bk.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return n, err
+}
+
+func (b *BoltStore) Close() error {
+	return nil
+}
+
+// update runs fn in a read-write transaction
+func (b *BoltStore) update(fn func(tx *bolt.Tx) error) error {
+	db, err := bolt.Open(b.path, 0o600, &bolt.Options{Timeout: boltTimeout})
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", b.path, err)
+	}
+	defer db.Close()
+	return db.Update(fn)
+}
+
+// view runs fn in a read-only transaction. A missing database reads as empty.
+func (b *BoltStore) view(fn func(tx *bolt.Tx) error) error {
+	if _, err := os.Stat(b.path); errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	db, err := bolt.Open(b.path, 0o600, &bolt.Options{Timeout: boltTimeout, ReadOnly: true})
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", b.path, err)
+	}
+	defer db.Close()
+	return db.View(fn)
+}
@@ -0,0 +1,186 @@
+package store
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/lucasdecamargo/go-appservice-example/pkg/dirs"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/history"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/retention"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/state"
+)
+
+// Degraded store settings
+const (
+	probeInterval   = time.Minute // Time between write attempts on the read-only backend
+	maxMemoryEvents = 1000        // History events kept in memory while degraded
+)
+
+// degrading keeps the state and history in memory once the backend is found on a
+// read-only file system, and writes them back once it is writable again
+type degrading struct {
+	Store // Nil when the backend couldn't be opened at all
+
+	mu       sync.Mutex
+	degraded error // Why the backend is bypassed, nil while it is used
+	probed   time.Time
+	state    *state.State
+	events   []history.Event // Appended while degraded, oldest first
+}
+
+// Degrade wraps s to fall back to memory, with loud warnings, when its file system
+// turns read-only, instead of failing every write
+func Degrade(s Store) Store {
+	return &degrading{Store: s}
+}
+
+// Memory returns a store keeping everything in memory, for when the backend can't be
+// opened because of cause
+func Memory(cause error) Store {
+	slog.Error("State storage is unavailable, keeping the state and history in memory only", "error", cause)
+	return &degrading{degraded: cause}
+}
+
+// DegradedBy returns why s keeps the state and history in memory, or nil when it
+// writes them to storage
+func DegradedBy(s Store) error {
+	if t, ok := s.(*throttled); ok {
+		s = t.Store
+	}
+	if d, ok := s.(*degrading); ok {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		return d.degraded
+	}
+	return nil
+}
+
+func (d *degrading) LoadState() (*state.State, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.state != nil {
+		return d.state.Clone(), nil
+	}
+	if d.Store == nil {
+		return &state.State{}, nil
+	}
+	return d.Store.LoadState()
+}
+
+func (d *degrading) SaveState(s *state.State) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	snapshot := s.Clone() // Kept in memory while s keeps changing
+	if d.degraded != nil && !d.probe() {
+		d.state = snapshot
+		return nil
+	}
+	err := d.Store.SaveState(snapshot)
+	if d.fallBack(err) {
+		d.state = snapshot
+		return nil
+	}
+	return err
+}
+
+func (d *degrading) AppendEvent(e history.Event) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.degraded != nil && !d.probe() {
+		d.appendMemory(e)
+		return nil
+	}
+	err := d.Store.AppendEvent(e)
+	if d.fallBack(err) {
+		d.appendMemory(e)
+		return nil
+	}
+	return err
+}
+
+func (d *degrading) Events() ([]history.Event, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var events []history.Event
+	if d.Store != nil {
+		var err error
+		if events, err = d.Store.Events(); err != nil {
+			return nil, err
+		}
+	}
+	return append(events, d.events...), nil
+}
+
+func (d *degrading) PruneEvents(p retention.Policy) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.degraded != nil {
+		return 0, nil // The memory history is bounded on its own
+	}
+	n, err := d.Store.PruneEvents(p)
+	if d.fallBack(err) {
+		return 0, nil
+	}
+	return n, err
+}
+
+func (d *degrading) Close() error {
+	if d.Store == nil {
+		return nil
+	}
+	return d.Store.Close()
+}
+
+// fallBack switches to memory when err shows the backend is read-only, and reports
+// whether it did. It must be called with d.mu held.
+func (d *degrading) fallBack(err error) bool {
+	if err == nil || !dirs.IsReadOnly(err) {
+		return false
+	}
+	if d.degraded == nil {
+		slog.Error("State storage is read-only, keeping the state and history in memory until it is writable again", "error", err)
+	}
+	d.degraded, d.probed = err, time.Now()
+	return true
+}
+
+// probe writes the state and events kept in memory to the backend, at most once per
+// probeInterval, and reports whether the backend is writable again. It must be called
+// with d.mu held.
+func (d *degrading) probe() bool {
+	if d.Store == nil || time.Since(d.probed) < probeInterval {
+		return false
+	}
+	d.probed = time.Now()
+
+	if d.state != nil {
+		if err := d.Store.SaveState(d.state); err != nil {
+			return false
+		}
+	}
+	for len(d.events) > 0 {
+		if err := d.Store.AppendEvent(d.events[0]); err != nil {
+			return false
+		}
+		d.events = d.events[1:]
+	}
+
+	slog.Info("State storage is writable again, saving the state and history to it")
+	d.degraded, d.state, d.events = nil, nil, nil
+	return true
+}
+
+// appendMemory adds e to the history kept in memory, dropping the oldest events past
+// maxMemoryEvents. It must be called with d.mu held.
+func (d *degrading) appendMemory(e history.Event) {
+	d.events = append(d.events, e)
+	if len(d.events) > maxMemoryEvents {
+		d.events = d.events[len(d.events)-maxMemoryEvents:]
+	}
+}
@@ -0,0 +1,45 @@
+package store
+
+import (
+	"github.com/lucasdecamargo/go-appservice-example/pkg/history"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/retention"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/state"
+)
+
+// FileStore keeps the state in a JSON file, atomically replaced on each save, and the
+// history in an append-only JSON lines file
+type FileStore struct {
+	statePath   string
+	historyPath string
+	sync        bool
+}
+
+// NewFileStore creates a store using the given state and history files. With sync,
+// every write is flushed to storage before returning.
+func NewFileStore(statePath, historyPath string, sync bool) *FileStore {
+	return &FileStore{statePath: statePath, historyPath: historyPath, sync: sync}
+}
+
+func (f *FileStore) LoadState() (*state.State, error) {
+	return state.Load(f.statePath)
+}
+
+func (f *FileStore) SaveState(s *state.State) error {
+	return state.Save(f.statePath, s, f.sync)
+}
+
+func (f *FileStore) AppendEvent(e history.Event) error {
+	return history.Append(f.historyPath, e, f.sync)
+}
+
+func (f *FileStore) Events() ([]history.Event, error) {
+	return history.Load(f.historyPath)
+}
+
+func (f *FileStore) PruneEvents(p retention.Policy) (int, error) {
+	return history.Prune(f.historyPath, p, f.sync)
+}
+
+func (f *FileStore) Close() error {
+	return nil
+}
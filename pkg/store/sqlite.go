@@ -0,0 +1,152 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/lucasdecamargo/go-appservice-example/pkg/history"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/retention"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/state"
+	_ "modernc.org/sqlite" // Registers the "sqlite" driver, without cgo
+)
+
+// SQLiteDriver is the database/sql driver used by the sqlite backend. modernc.org/sqlite
+// is linked by default. Another driver, such as github.com/mattn/go-sqlite3
+// (registered as "sqlite3"), can be registered with a blank import and selected here.
+var SQLiteDriver = "sqlite"
+
+const sqliteSchema = `
+PRAGMA busy_timeout = 5000;
+CREATE TABLE IF NOT EXISTS state (
+	id   INTEGER PRIMARY KEY CHECK (id = 1),
+	data TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS history (
+	id       INTEGER PRIMARY KEY AUTOINCREMENT,
+	time     TEXT NOT NULL,
+	kind     TEXT NOT NULL,
+	duration INTEGER NOT NULL,
+	error    TEXT NOT NULL DEFAULT '',
+	detail   TEXT NOT NULL DEFAULT ''
+);`
+
+// SQLiteStore keeps the state and history in a SQLite database
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+// OpenSQLite opens or creates the SQLite database at path
+func OpenSQLite(path string) (*SQLiteStore, error) {
+	if !slices.Contains(sql.Drivers(), SQLiteDriver) {
+		return nil, fmt.Errorf("sqlite storage requires a %q database/sql driver to be linked", SQLiteDriver)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create database directory: %w", err)
+	}
+
+	db, err := sql.Open(SQLiteDriver, path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize %s: %w", path, err)
+	}
+	return &SQLiteStore{db: db}, nil
+}
+
+func (s *SQLiteStore) LoadState() (*state.State, error) {
+	var st state.State
+	var data string
+	switch err := s.db.QueryRow(`SELECT data FROM state WHERE id = 1`).Scan(&data); err {
+	case nil:
+		return &st, json.Unmarshal([]byte(data), &st)
+	case sql.ErrNoRows:
+		return &st, nil
+	default:
+		return nil, err
+	}
+}
+
+func (s *SQLiteStore) SaveState(st *state.State) error {
+	data, err := json.Marshal(st)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`INSERT INTO state (id, data) VALUES (1, ?)
+		ON CONFLICT (id) DO UPDATE SET data = excluded.data`, string(data))
+	return err
+}
+
+func (s *SQLiteStore) AppendEvent(e history.Event) error {
+	_, err := s.db.Exec(`INSERT INTO history (time, kind, duration, error, detail) VALUES (?, ?, ?, ?, ?)`,
+		e.Time.UTC().Format(time.RFC3339Nano), e.Kind, int64(e.Duration), e.Error, e.Detail)
+	return err
+}
+
+func (s *SQLiteStore) Events() ([]history.Event, error) {
+	rows, err := s.db.Query(`SELECT time, kind, duration, error, detail FROM history ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []history.Event
+	for rows.Next() {
+		var e history.Event
+		var at string
+		var duration int64
+		if err := rows.Scan(&at, &e.Kind, &duration, &e.Error, &e.Detail); err != nil {
+			return nil, err
+		}
+		if e.Time, err = time.Parse(time.RFC3339Nano, at); err != nil {
+			return nil, err
+		}
+		e.Duration = time.Duration(duration)
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+func (s *SQLiteStore) PruneEvents(p retention.Policy) (int, error) {
+	rows, err := s.db.Query(`SELECT id, time, length(kind) + length(error) + length(detail) FROM history ORDER BY id`)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	var records []retention.Record
+	for rows.Next() {
+		var id, size int64
+		var at string
+		if err := rows.Scan(&id, &at, &size); err != nil {
+			return 0, err
+		}
+		t, err := time.Parse(time.RFC3339Nano, at)
+		if err != nil {
+			return 0, err
+		}
+		ids = append(ids, id)
+		records = append(records, retention.Record{Time: t, Size: size + int64(len(at)) + 8})
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	n := p.Expired(records, time.Now())
+	if n == 0 {
+		return 0, nil
+	}
+	_, err = s.db.Exec(`DELETE FROM history WHERE id <= ?`, ids[n-1])
+	return n, err
+}
+
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
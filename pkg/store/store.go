@@ -0,0 +1,97 @@
+package store
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/lucasdecamargo/go-appservice-example/pkg/dirs"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/history"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/paths"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/retention"
+	"github.com/lucasdecamargo/go-appservice-example/pkg/state"
+)
+
+// Storage backends
+const (
+	BackendJSON   = "json"   // state.json and history.jsonl files, the default
+	BackendBolt   = "bolt"   // A single bbolt database
+	BackendSQLite = "sqlite" // A single SQLite database
+)
+
+// Store persists the daemon state and history
+type Store interface {
+	LoadState() (*state.State, error) // Returns an empty state when none was saved
+	SaveState(s *state.State) error
+	AppendEvent(e history.Event) error
+	Events() ([]history.Event, error)
+	PruneEvents(p retention.Policy) (int, error) // Deletes the oldest events that don't fit p
+	Close() error
+}
+
+// Config selects the storage backend
+type Config struct {
+	Backend string `json:"backend,omitempty"` // json, bolt or sqlite, json by default
+	Path    string `json:"path,omitempty"`    // Directory for json, database file otherwise, unique to each instance
+
+	// Sync flushes every json write to storage, so it survives a power loss. The bolt
+	// and sqlite backends always do.
+	Sync bool `json:"sync,omitempty"`
+	// WriteInterval is the minimum time between state writes, such as "30s", to spare
+	// flash storage. Intermediate states are coalesced. Empty writes every change.
+	WriteInterval string `json:"writeInterval,omitempty"`
+}
+
+// Open opens the configured store. Paths default to the state directory, of the
+// instance the process belongs to if any. A configured path is used as written, so
+// instances sharing a config file must not set one. On a read-only file system, the
+// state and history are kept in memory, see Degrade.
+func Open(cfg Config) (Store, error) {
+	var interval time.Duration
+	if cfg.WriteInterval != "" {
+		var err error
+		if interval, err = time.ParseDuration(cfg.WriteInterval); err != nil {
+			return nil, fmt.Errorf("invalid storage writeInterval: %w", err)
+		}
+	}
+
+	s, err := open(cfg)
+	if dirs.IsReadOnly(err) {
+		return Memory(err), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	s = Degrade(s)
+	if interval > 0 {
+		s = Throttle(s, interval)
+	}
+	return s, nil
+}
+
+// open opens the backend selected by cfg
+func open(cfg Config) (Store, error) {
+	dir := filepath.Dir(state.DefaultPath())
+
+	switch cfg.Backend {
+	case "", BackendJSON:
+		if cfg.Path == "" {
+			return NewFileStore(state.DefaultPath(), history.DefaultPath(), cfg.Sync), nil
+		}
+		return NewFileStore(filepath.Join(cfg.Path, "state.json"), filepath.Join(cfg.Path, "history.jsonl"), cfg.Sync), nil
+	case BackendBolt:
+		return OpenBolt(defaultPath(cfg.Path, filepath.Join(dir, paths.App()+".db")))
+	case BackendSQLite:
+		return OpenSQLite(defaultPath(cfg.Path, filepath.Join(dir, paths.App()+".sqlite")))
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", cfg.Backend)
+	}
+}
+
+// defaultPath returns path, or def when path is empty
+func defaultPath(path, def string) string {
+	if path == "" {
+		return def
+	}
+	return path
+}
@@ -0,0 +1,90 @@
+package store
+
+import (
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/lucasdecamargo/go-appservice-example/pkg/state"
+)
+
+// throttled writes the state of a store at most once per interval. A state saved
+// sooner is kept in memory and written when the interval has elapsed, or on Close.
+type throttled struct {
+	Store
+	interval time.Duration
+
+	mu      sync.Mutex
+	last    time.Time    // When the state was last written
+	pending *state.State // State waiting for the interval to elapse
+	timer   *time.Timer
+}
+
+// Throttle limits the state writes of s to one per interval. Events are not throttled.
+func Throttle(s Store, interval time.Duration) Store {
+	return &throttled{Store: s, interval: interval}
+}
+
+func (t *throttled) LoadState() (*state.State, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.pending != nil {
+		return t.pending.Clone(), nil
+	}
+	return t.Store.LoadState()
+}
+
+func (t *throttled) SaveState(s *state.State) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	snapshot := s.Clone() // s keeps changing while the snapshot waits to be written
+	wait := t.interval - time.Since(t.last)
+	if wait <= 0 && t.timer == nil {
+		t.last = time.Now()
+		return t.Store.SaveState(snapshot)
+	}
+
+	t.pending = snapshot
+	if t.timer == nil {
+		t.timer = time.AfterFunc(wait, t.flush)
+	}
+	return nil
+}
+
+// flush writes the pending state once the interval has elapsed
+func (t *throttled) flush() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.timer = nil
+	if err := t.writePending(); err != nil {
+		slog.Warn("Failed to save daemon state", "error", err)
+	}
+}
+
+// writePending writes the pending state, if any. It must be called with t.mu held.
+func (t *throttled) writePending() error {
+	if t.pending == nil {
+		return nil
+	}
+	s := t.pending
+	t.pending = nil
+	t.last = time.Now()
+	return t.Store.SaveState(s)
+}
+
+// Close writes the pending state and closes the underlying store
+func (t *throttled) Close() error {
+	t.mu.Lock()
+	if t.timer != nil {
+		t.timer.Stop()
+		t.timer = nil
+	}
+	err := t.writePending()
+	t.mu.Unlock()
+
+	return errors.Join(err, t.Store.Close())
+}
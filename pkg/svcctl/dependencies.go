@@ -0,0 +1,152 @@
+package svcctl
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// Dependencies orders the service relative to other services. Names are systemd units,
+// translated to their equivalents on other init systems.
+type Dependencies struct {
+	After    []string `json:"after,omitempty"`    // Start after these, without requiring them
+	Requires []string `json:"requires,omitempty"` // Start these too, failing when they fail
+	Wants    []string `json:"wants,omitempty"`    // Start these too, ignoring their failures
+	BindsTo  []string `json:"bindsTo,omitempty"`  // Like Requires, also stopping when they stop
+}
+
+// Directives of a dependency, by init system
+var (
+	systemdDirectives = []string{"After", "Requires", "Wants", "BindsTo"}
+	openrcDirectives  = []string{"after", "need", "use", ""} // BindsTo has no equivalent
+)
+
+// openrcServices maps the systemd targets that have an OpenRC equivalent
+var openrcServices = map[string]string{
+	"network.target":        "net",
+	"network-online.target": "net",
+	"local-fs.target":       "localmount",
+	"remote-fs.target":      "netmount",
+	"time-sync.target":      "ntp-client",
+	"syslog.target":         "logger",
+}
+
+// systemdUnitTypes are the unit suffixes, other than service, only systemd knows
+var systemdUnitTypes = []string{".target", ".socket", ".mount", ".automount", ".timer", ".path", ".slice", ".scope", ".device", ".swap"}
+
+// lists returns the dependency lists in the order of the directive tables
+func (d Dependencies) lists() [][]string {
+	return [][]string{d.After, d.Requires, d.Wants, d.BindsTo}
+}
+
+// Validate checks that the init system of platform, as named by kardianos.Platform,
+// can express the dependencies
+func (d Dependencies) Validate(platform string) error {
+	_, err := d.Render(platform)
+	return err
+}
+
+// Render returns the dependencies as the kardianos Config.Dependencies of platform:
+// unit file lines on systemd, depend() lines on OpenRC and service names on Windows.
+// Init systems without dependency support get none.
+func (d Dependencies) Render(platform string) ([]string, error) {
+	for _, names := range d.lists() {
+		for _, name := range names {
+			if name == "" || strings.IndexFunc(name, unicode.IsSpace) >= 0 {
+				return nil, fmt.Errorf("invalid dependency %q", name)
+			}
+		}
+	}
+
+	switch {
+	case strings.HasSuffix(platform, "systemd"):
+		return renderLines(d.lists(), systemdDirectives, "=", func(name string) (string, error) { return name, nil })
+	case strings.HasSuffix(platform, "openrc"):
+		if len(d.BindsTo) > 0 {
+			return nil, fmt.Errorf("BindsTo is systemd-only and not supported by %s", platform)
+		}
+		return renderLines(d.lists(), openrcDirectives, " ", func(name string) (string, error) {
+			return openrcService(name, platform)
+		})
+	case strings.HasPrefix(platform, "windows"):
+		if len(d.After)+len(d.Wants)+len(d.BindsTo) > 0 {
+			return nil, fmt.Errorf("only Requires is supported by %s, which always starts dependencies first", platform)
+		}
+		names := make([]string, len(d.Requires))
+		for i, name := range d.Requires {
+			if slices.Contains(systemdUnitTypes, path.Ext(name)) {
+				return nil, fmt.Errorf("%s is a systemd unit, not supported by %s", name, platform)
+			}
+			names[i] = strings.TrimSuffix(name, ".service")
+		}
+		return slices.Compact(names), nil
+	}
+	return nil, nil
+}
+
+// renderLines renders one line per non-empty list, joining the directive and the
+// names converted by convert with sep
+func renderLines(lists [][]string, directives []string, sep string, convert func(string) (string, error)) ([]string, error) {
+	var lines []string
+	for i, names := range lists {
+		if len(names) == 0 {
+			continue
+		}
+		var converted []string
+		for _, name := range names {
+			c, err := convert(name)
+			if err != nil {
+				return nil, err
+			}
+			if !slices.Contains(converted, c) {
+				converted = append(converted, c)
+			}
+		}
+		lines = append(lines, directives[i]+sep+strings.Join(converted, " "))
+	}
+	return lines, nil
+}
+
+// openrcService returns the OpenRC service standing for the systemd unit name
+func openrcService(name, platform string) (string, error) {
+	if s, ok := openrcServices[name]; ok {
+		return s, nil
+	}
+	if slices.Contains(systemdUnitTypes, path.Ext(name)) {
+		return "", fmt.Errorf("%s is a systemd unit with no equivalent on %s", name, platform)
+	}
+	return strings.TrimSuffix(name, ".service"), nil
+}
+
+// ParseDependencies reads back the dependencies rendered by Render for platform.
+// Names translated for the init system are returned as translated.
+func ParseDependencies(platform string, lines []string) (Dependencies, error) {
+	var d Dependencies
+	lists := []*[]string{&d.After, &d.Requires, &d.Wants, &d.BindsTo}
+
+	var directives []string
+	sep := " "
+	switch {
+	case strings.HasSuffix(platform, "systemd"):
+		directives, sep = systemdDirectives, "="
+	case strings.HasSuffix(platform, "openrc"):
+		directives = openrcDirectives
+	case strings.HasPrefix(platform, "windows"):
+		d.Requires = slices.Clone(lines)
+		return d, nil
+	default:
+		return d, nil
+	}
+
+	for _, line := range lines {
+		directive, names, ok := strings.Cut(strings.TrimSpace(line), sep)
+		i := slices.Index(directives, strings.TrimSpace(directive))
+		if !ok || directive == "" || i < 0 {
+			return Dependencies{}, fmt.Errorf("unknown dependency %q", line)
+		}
+		*lists[i] = append(*lists[i], strings.Fields(names)...)
+	}
+	return d, nil
+}
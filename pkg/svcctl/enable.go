@@ -0,0 +1,28 @@
+package svcctl
+
+import (
+	"errors"
+	"runtime"
+
+	"github.com/lucasdecamargo/kardianos"
+)
+
+// ErrEnableUnsupported is returned by Disable where the service manager can't keep an
+// installed service from starting at boot
+var ErrEnableUnsupported = errors.New("installing a service without starting it at boot is not supported on this platform")
+
+// ApplyEnable sets the options of cfg deciding whether the service starts at boot, where
+// they are part of the definition: StartType on Windows and RunAtLoad on macOS. Linux
+// init systems enable the service on install, see Disable.
+func ApplyEnable(cfg *kardianos.Config, enable bool) {
+	switch runtime.GOOS {
+	case "windows":
+		if enable {
+			cfg.Option[OptionStartType] = string(StartAutomatic)
+		} else {
+			cfg.Option[OptionStartType] = string(StartManual)
+		}
+	case "darwin":
+		cfg.Option["RunAtLoad"] = enable
+	}
+}
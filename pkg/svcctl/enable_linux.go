@@ -0,0 +1,40 @@
+package svcctl
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// Disable keeps the installed service name from starting at boot, for the init system
+// named by platform as in kardianos.Platform. It can still be started by hand.
+func Disable(name, platform string) error {
+	var cmds [][]string
+	switch platform {
+	case "linux-systemd":
+		cmds = [][]string{{"systemctl", "disable", name + ".service"}}
+	case "linux-openrc":
+		cmds = [][]string{{"rc-update", "del", name}}
+	case "unix-systemv":
+		// Debian and SUSE tools first, then Red Hat's
+		cmds = [][]string{{"update-rc.d", name, "disable"}, {"chkconfig", name, "off"}}
+	default:
+		return ErrEnableUnsupported
+	}
+
+	var err error
+	for _, args := range cmds {
+		if _, lerr := exec.LookPath(args[0]); lerr != nil {
+			continue
+		}
+		out, cerr := exec.Command(args[0], args[1:]...).CombinedOutput()
+		if cerr == nil {
+			return nil
+		}
+		err = fmt.Errorf("%s failed: %w: %s", strings.Join(args, " "), cerr, strings.TrimSpace(string(out)))
+	}
+	if err == nil {
+		return fmt.Errorf("%w: %s not found", ErrEnableUnsupported, cmds[0][0])
+	}
+	return err
+}
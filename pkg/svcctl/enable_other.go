@@ -0,0 +1,9 @@
+//go:build !linux
+
+package svcctl
+
+// Disable does nothing outside Linux, where ApplyEnable already set whether the
+// service starts at boot before it was installed
+func Disable(name, platform string) error {
+	return nil
+}
@@ -0,0 +1,14 @@
+package svcctl
+
+import "errors"
+
+// ErrInspectUnsupported is returned by Inspect where the installed definition can't be read
+var ErrInspectUnsupported = errors.New("reading the installed service definition is not supported on this platform")
+
+// Definition is what the service manager holds for an installed service
+type Definition struct {
+	Path       string // File holding the definition, empty on Windows
+	Executable string // Program the service manager starts
+	UserName   string // Account the service runs as, empty for the default
+	Enabled    bool   // Whether the service starts at boot
+}
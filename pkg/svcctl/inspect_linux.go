@@ -0,0 +1,95 @@
+package svcctl
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Inspect reads the installed definition of the service name, for the init system
+// named by platform as in kardianos.Platform
+func Inspect(name, platform string) (Definition, error) {
+	switch platform {
+	case "linux-systemd":
+		unit := name + ".service"
+		d, err := readDefinition(filepath.Join(systemdUnitDir, unit), "ExecStart=", "User=")
+		if err != nil {
+			return d, err
+		}
+		d.Executable = strings.ReplaceAll(d.Executable, `\x20`, " ")
+		out, _ := exec.Command("systemctl", "is-enabled", unit).Output()
+		d.Enabled = strings.TrimSpace(string(out)) == "enabled"
+		return d, nil
+	case "linux-openrc":
+		d, err := readDefinition("/etc/init.d/"+name, "command=", "")
+		if err != nil {
+			return d, err
+		}
+		d.Executable = strings.ReplaceAll(d.Executable, `\x20`, " ")
+		d.Enabled = matches("/etc/runlevels/*/" + name)
+		return d, nil
+	case "unix-systemv":
+		d, err := readDefinition("/etc/init.d/"+name, `cmd="`, "")
+		if err != nil {
+			return d, err
+		}
+		d.Enabled = matches("/etc/rc[0-6].d/S[0-9][0-9]" + name)
+		return d, nil
+	case "linux-upstart":
+		d, err := readDefinition("/etc/init/"+name+".conf", "exec ", "setuid ")
+		d.Enabled = err == nil // Upstart starts every job with a start stanza
+		return d, err
+	}
+	return Definition{}, ErrInspectUnsupported
+}
+
+// DefinitionPaths returns the files the init system named by platform may hold for the
+// service name, installed or left behind by an uninstall
+func DefinitionPaths(name, platform string) []string {
+	switch platform {
+	case "linux-systemd":
+		unit := filepath.Join(systemdUnitDir, name+".service")
+		return []string{unit, unit + ".d"}
+	case "linux-openrc":
+		return []string{"/etc/init.d/" + name}
+	case "unix-systemv":
+		links, _ := filepath.Glob("/etc/rc[0-6].d/[SK][0-9][0-9]" + name)
+		return append([]string{"/etc/init.d/" + name}, links...)
+	case "linux-upstart":
+		return []string{"/etc/init/" + name + ".conf"}
+	}
+	return nil
+}
+
+// readDefinition reads the definition file at path, taking the executable from the
+// first line starting with execKey and the user from the one starting with userKey
+func readDefinition(path, execKey, userKey string) (Definition, error) {
+	d := Definition{Path: path}
+	f, err := os.Open(path)
+	if err != nil {
+		return d, fmt.Errorf("failed to read the service definition: %w", err)
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if v, ok := strings.CutPrefix(line, execKey); ok && d.Executable == "" {
+			if fields := strings.Fields(v); len(fields) > 0 {
+				d.Executable = strings.Trim(fields[0], `"`)
+			}
+		} else if v, ok := strings.CutPrefix(line, userKey); ok && userKey != "" {
+			d.UserName = strings.TrimSpace(v)
+		}
+	}
+	return d, sc.Err()
+}
+
+// matches reports whether any file matches the glob pattern
+func matches(pattern string) bool {
+	m, _ := filepath.Glob(pattern)
+	return len(m) > 0
+}
@@ -0,0 +1,20 @@
+//go:build !linux && !windows
+
+package svcctl
+
+// Inspect returns ErrInspectUnsupported
+func Inspect(name, platform string) (Definition, error) {
+	return Definition{}, ErrInspectUnsupported
+}
+
+// DefinitionPaths returns the files the init system named by platform may hold for the
+// service name, installed or left behind by an uninstall
+func DefinitionPaths(name, platform string) []string {
+	switch platform {
+	case "darwin-launchd":
+		return []string{"/Library/LaunchDaemons/" + name + ".plist"}
+	case "freebsd":
+		return []string{"/usr/local/etc/rc.d/" + name}
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package svcctl
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+// DefinitionPaths returns nil, the service control manager holds the definition in
+// the registry, which uninstall removes
+func DefinitionPaths(name, platform string) []string {
+	return nil
+}
+
+// Inspect reads the installed definition of the service name from the service
+// control manager. The platform is ignored.
+func Inspect(name, platform string) (Definition, error) {
+	m, err := mgr.Connect()
+	if err != nil {
+		return Definition{}, fmt.Errorf("failed to connect to the service control manager: %w", err)
+	}
+	defer m.Disconnect()
+
+	s, err := m.OpenService(name)
+	if err != nil {
+		return Definition{}, fmt.Errorf("failed to open service %s: %w", name, err)
+	}
+	defer s.Close()
+
+	c, err := s.Config()
+	if err != nil {
+		return Definition{}, fmt.Errorf("failed to read service %s: %w", name, err)
+	}
+
+	d := Definition{Enabled: c.StartType == mgr.StartAutomatic}
+	if path, ok := strings.CutPrefix(c.BinaryPathName, `"`); ok {
+		d.Executable, _, _ = strings.Cut(path, `"`)
+	} else if fields := strings.Fields(c.BinaryPathName); len(fields) > 0 {
+		d.Executable = fields[0]
+	}
+	if !strings.EqualFold(c.ServiceStartName, "LocalSystem") {
+		d.UserName = c.ServiceStartName
+	}
+	return d, nil
+}
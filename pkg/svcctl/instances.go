@@ -0,0 +1,30 @@
+package svcctl
+
+import (
+	"errors"
+	"slices"
+	"strings"
+
+	"github.com/lucasdecamargo/go-appservice-example/pkg/paths"
+)
+
+// ErrInstancesUnsupported is returned by Instances where installed services can't be listed
+var ErrInstancesUnsupported = errors.New("listing service instances is not supported on this platform")
+
+// InstanceName returns the service name of the named instance of service
+func InstanceName(service, instance string) string {
+	return service + paths.InstanceSeparator + instance
+}
+
+// instancesOf returns the sorted instance names of service found among names
+func instancesOf(service string, names []string) []string {
+	prefix := service + paths.InstanceSeparator
+	var instances []string
+	for _, name := range names {
+		if instance, ok := strings.CutPrefix(name, prefix); ok && instance != "" {
+			instances = append(instances, instance)
+		}
+	}
+	slices.Sort(instances)
+	return instances
+}
@@ -0,0 +1,25 @@
+package svcctl
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/lucasdecamargo/go-appservice-example/pkg/paths"
+)
+
+// systemdUnitDir is where kardianos installs system units
+const systemdUnitDir = "/etc/systemd/system"
+
+// Instances returns the names of the installed instances of service, from the systemd
+// units named after it
+func Instances(service string) ([]string, error) {
+	units, err := filepath.Glob(filepath.Join(systemdUnitDir, service+paths.InstanceSeparator+"*.service"))
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(units))
+	for i, path := range units {
+		names[i] = strings.TrimSuffix(filepath.Base(path), ".service")
+	}
+	return instancesOf(service, names), nil
+}
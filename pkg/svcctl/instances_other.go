@@ -0,0 +1,8 @@
+//go:build !linux && !windows
+
+package svcctl
+
+// Instances returns ErrInstancesUnsupported, instances must be named explicitly
+func Instances(service string) ([]string, error) {
+	return nil, ErrInstancesUnsupported
+}
@@ -0,0 +1,23 @@
+package svcctl
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+// Instances returns the names of the installed instances of service, from the
+// services registered with the service control manager
+func Instances(service string) ([]string, error) {
+	m, err := mgr.Connect()
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to the service control manager: %w", err)
+	}
+	defer m.Disconnect()
+
+	names, err := m.ListServices()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list services: %w", err)
+	}
+	return instancesOf(service, names), nil
+}
@@ -0,0 +1,32 @@
+package svcctl
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// PrivilegeError reports the privileges a service action lacks, found before running it
+type PrivilegeError struct {
+	Action  string // Service action, such as install
+	Missing string // What the process lacks
+	Hint    string // How to run the command again with it
+}
+
+func (e *PrivilegeError) Error() string {
+	return fmt.Sprintf("%s requires %s", e.Action, e.Missing)
+}
+
+// Unwrap makes the error match os.ErrPermission
+func (e *PrivilegeError) Unwrap() error {
+	return os.ErrPermission
+}
+
+// commandLine returns the command line of the process, quoted to be run again
+func commandLine() string {
+	args := make([]string, len(os.Args))
+	for i, arg := range os.Args {
+		args[i] = quoteArg(arg)
+	}
+	return strings.Join(args, " ")
+}
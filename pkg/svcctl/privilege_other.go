@@ -0,0 +1,29 @@
+//go:build !windows
+
+package svcctl
+
+import (
+	"os"
+	"strings"
+)
+
+// CheckPrivileges checks that the process can run a service action on the service
+// name. Managing a system service takes root, status needs nothing.
+func CheckPrivileges(name, action string) error {
+	if action == "status" || os.Geteuid() == 0 {
+		return nil
+	}
+	return &PrivilegeError{
+		Action:  action,
+		Missing: "root privileges",
+		Hint:    "Run it again as root: sudo " + commandLine(),
+	}
+}
+
+// quoteArg quotes arg for a POSIX shell, when needed
+func quoteArg(arg string) string {
+	if arg != "" && !strings.ContainsAny(arg, " \t\n'\"\\$`*?[]{}()<>|&;#~!") {
+		return arg
+	}
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
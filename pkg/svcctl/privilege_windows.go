@@ -0,0 +1,69 @@
+package svcctl
+
+import (
+	"errors"
+	"fmt"
+	"syscall"
+
+	"golang.org/x/sys/windows"
+)
+
+// serviceRights are the access rights each action needs on an installed service
+var serviceRights = map[string]struct {
+	access uint32
+	names  string
+}{
+	"start":   {windows.SERVICE_START, "SERVICE_START"},
+	"stop":    {windows.SERVICE_STOP, "SERVICE_STOP"},
+	"restart": {windows.SERVICE_START | windows.SERVICE_STOP, "SERVICE_START and SERVICE_STOP"},
+	"edit":    {windows.SERVICE_CHANGE_CONFIG, "SERVICE_CHANGE_CONFIG"},
+}
+
+// CheckPrivileges checks that the process can run a service action on the service
+// name. Install and uninstall also write the event log registration, so they take an
+// elevated administrator. Other actions take the matching rights on the service, which
+// the service security descriptor may grant to other users.
+func CheckPrivileges(name, action string) error {
+	hint := "Run it again from an elevated prompt (Run as administrator): " + commandLine()
+
+	if action == "install" || action == "uninstall" {
+		if windows.GetCurrentProcessToken().IsElevated() {
+			return nil
+		}
+		return &PrivilegeError{Action: action, Missing: "an elevated administrator", Hint: hint}
+	}
+
+	rights, ok := serviceRights[action]
+	if !ok {
+		return nil
+	}
+	scm, err := windows.OpenSCManager(nil, nil, windows.SC_MANAGER_CONNECT)
+	if err != nil {
+		return checkAccess(err, action, "access to the service control manager", hint)
+	}
+	defer windows.CloseServiceHandle(scm)
+
+	p, err := windows.UTF16PtrFromString(name)
+	if err != nil {
+		return err
+	}
+	h, err := windows.OpenService(scm, p, rights.access)
+	if err != nil {
+		return checkAccess(err, action, fmt.Sprintf("the %s rights on service %s", rights.names, name), hint)
+	}
+	return windows.CloseServiceHandle(h)
+}
+
+// checkAccess returns a PrivilegeError when err denied access. Other errors, such as
+// a missing service, are left for the action to report.
+func checkAccess(err error, action, missing, hint string) error {
+	if errors.Is(err, windows.ERROR_ACCESS_DENIED) {
+		return &PrivilegeError{Action: action, Missing: missing, Hint: hint}
+	}
+	return nil
+}
+
+// quoteArg quotes arg for the command line
+func quoteArg(arg string) string {
+	return syscall.EscapeArg(arg)
+}
@@ -0,0 +1,36 @@
+//go:build !windows
+
+package svcctl
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/lucasdecamargo/kardianos"
+)
+
+// Reconfigure replaces the installed definition of the service described by cfg with
+// next, by reinstalling it. The running service keeps its settings until it restarts.
+// The previous definition is restored when the new one can't be installed.
+func Reconfigure(i kardianos.Interface, cfg, next *kardianos.Config) error {
+	prev, err := kardianos.New(i, cfg)
+	if err != nil {
+		return err
+	}
+	s, err := kardianos.New(i, next)
+	if err != nil {
+		return err
+	}
+
+	if err := prev.Uninstall(); err != nil {
+		return fmt.Errorf("failed to remove the service definition: %w", err)
+	}
+	if err := s.Install(); err != nil {
+		err = fmt.Errorf("failed to install the new service definition: %w", err)
+		if rerr := prev.Install(); rerr != nil {
+			return errors.Join(err, fmt.Errorf("failed to restore the previous definition: %w", rerr))
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package svcctl
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/lucasdecamargo/kardianos"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+// Reconfigure updates the service registered with the service control manager in
+// place with next. The running service keeps its settings until it restarts.
+func Reconfigure(i kardianos.Interface, cfg, next *kardianos.Config) error {
+	exe := next.Executable
+	if exe == "" {
+		var err error
+		if exe, err = os.Executable(); err != nil {
+			return err
+		}
+	}
+
+	m, err := mgr.Connect()
+	if err != nil {
+		return fmt.Errorf("failed to connect to the service control manager: %w", err)
+	}
+	defer m.Disconnect()
+
+	s, err := m.OpenService(next.Name)
+	if err != nil {
+		return kardianos.ErrNotInstalled
+	}
+	defer s.Close()
+
+	c, err := s.Config()
+	if err != nil {
+		return err
+	}
+	c.BinaryPathName = syscall.EscapeArg(exe)
+	for _, arg := range next.Arguments {
+		c.BinaryPathName += " " + syscall.EscapeArg(arg)
+	}
+	c.DisplayName = next.DisplayName
+	c.Description = next.Description
+	c.ServiceStartName = next.UserName
+	if c.ServiceStartName == "" {
+		c.ServiceStartName = "LocalSystem"
+	}
+	c.Password, _ = next.Option["Password"].(string)
+	c.Dependencies = next.Dependencies
+	c.DelayedAutoStart, _ = next.Option["DelayedAutoStart"].(bool)
+	switch t, _ := next.Option[OptionStartType].(string); StartType(t) {
+	case StartManual:
+		c.StartType = mgr.StartManual
+	case StartDisabled:
+		c.StartType = mgr.StartDisabled
+	default:
+		c.StartType = mgr.StartAutomatic
+	}
+	if err := s.UpdateConfig(c); err != nil {
+		return fmt.Errorf("failed to update the service: %w", err)
+	}
+
+	return setRecoveryActions(s, next.Option)
+}
+
+// setRecoveryActions applies the OnFailure options the way kardianos does on install
+func setRecoveryActions(s *mgr.Service, opts kardianos.KeyValue) error {
+	onFailure, _ := opts[OptionOnFailure].(string)
+	if onFailure == "" {
+		return nil
+	}
+
+	delay := time.Second
+	if d, ok := opts[OptionOnFailureDelay].(string); ok {
+		if parsed, err := time.ParseDuration(d); err == nil {
+			delay = parsed
+		}
+	}
+	action := mgr.ServiceRestart
+	switch FailureAction(onFailure) {
+	case FailureReboot:
+		action = mgr.ComputerReboot
+	case FailureNoAction:
+		action = mgr.NoAction
+	}
+	reset, ok := opts["OnFailureResetPeriod"].(int)
+	if !ok {
+		reset = 10
+	}
+
+	if err := s.SetRecoveryActions([]mgr.RecoveryAction{{Type: action, Delay: delay}}, uint32(reset)); err != nil {
+		return fmt.Errorf("failed to set the recovery actions: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package svcctl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lucasdecamargo/kardianos"
+)
+
+// Default retry settings
+const (
+	DefaultAttempts = 3
+	DefaultBackoff  = 1 * time.Second
+	DefaultTimeout  = 30 * time.Second
+)
+
+// ErrTimeout is returned when a single control attempt exceeds its timeout
+var ErrTimeout = errors.New("service control timeout")
+
+// RetryConfig controls how service control actions are retried
+type RetryConfig struct {
+	Attempts int           // Total attempts, including the first one
+	Backoff  time.Duration // Delay before the first retry, doubled on each retry
+	Timeout  time.Duration // Timeout of a single attempt
+}
+
+// DefaultRetryConfig returns the default retry settings
+func DefaultRetryConfig() RetryConfig {
+	return RetryConfig{Attempts: DefaultAttempts, Backoff: DefaultBackoff, Timeout: DefaultTimeout}
+}
+
+// Control runs a kardianos control action with retries. Errors that retrying can't fix
+// are returned immediately, unwrapped, so callers can match them with errors.Is.
+// Before each retry the service status is checked, so an action that took effect
+// despite reporting an error is not repeated.
+func Control(ctx context.Context, s kardianos.Service, action string, cfg RetryConfig) error {
+	if cfg.Attempts < 1 {
+		cfg.Attempts = 1
+	}
+
+	var errs []error
+	backoff := cfg.Backoff
+
+	for attempt := 1; attempt <= cfg.Attempts; attempt++ {
+		if attempt > 1 {
+			if done(s, action) {
+				return nil
+			}
+			select {
+			case <-ctx.Done():
+				return consolidate(s, action, attempt-1, append(errs, ctx.Err()))
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+		}
+
+		err := run(s, action, cfg.Timeout)
+		if err == nil {
+			return nil
+		}
+		if permanent(err) {
+			return err
+		}
+		errs = append(errs, fmt.Errorf("attempt %d: %w", attempt, err))
+	}
+
+	return consolidate(s, action, cfg.Attempts, errs)
+}
+
+// run performs a single control action, abandoning it after timeout
+func run(s kardianos.Service, action string, timeout time.Duration) error {
+	result := make(chan error, 1)
+	go func() { result <- dispatch(s, action) }()
+
+	if timeout <= 0 {
+		return <-result
+	}
+
+	select {
+	case err := <-result:
+		return err
+	case <-time.After(timeout):
+		return fmt.Errorf("%w after %v", ErrTimeout, timeout)
+	}
+}
+
+// dispatch maps an action name to the service method, like kardianos.Control
+// but without flattening the returned error
+func dispatch(s kardianos.Service, action string) error {
+	switch action {
+	case kardianos.ControlAction[0]:
+		return s.Start()
+	case kardianos.ControlAction[1]:
+		return s.Stop()
+	case kardianos.ControlAction[2]:
+		return s.Restart()
+	case kardianos.ControlAction[3]:
+		return s.Install()
+	case kardianos.ControlAction[4]:
+		return s.Uninstall()
+	default:
+		return fmt.Errorf("unknown action %s", action)
+	}
+}
+
+// done reports whether the service is already in the state action leads to
+func done(s kardianos.Service, action string) bool {
+	status, err := s.Status()
+	if err != nil {
+		return false
+	}
+	switch action {
+	case kardianos.ControlAction[0]:
+		return status == kardianos.StatusRunning
+	case kardianos.ControlAction[1]:
+		return status == kardianos.StatusStopped
+	}
+	return false
+}
+
+// permanent reports whether retrying can't change the outcome of err
+func permanent(err error) bool {
+	return errors.Is(err, kardianos.ErrNotInstalled) ||
+		errors.Is(err, kardianos.ErrServiceExists) ||
+		errors.Is(err, kardianos.ErrNoServiceSystemDetected)
+}
+
+// consolidate reports every failed attempt in a single error
+func consolidate(s kardianos.Service, action string, attempts int, errs []error) error {
+	return fmt.Errorf("failed to %s %v after %d attempts: %w", action, s, attempts, errors.Join(errs...))
+}
@@ -0,0 +1,8 @@
+//go:build !windows
+
+package svcctl
+
+// SetTriggers is a no-op outside Windows
+func SetTriggers(name string, triggers []Trigger) error {
+	return nil
+}
@@ -0,0 +1,113 @@
+package svcctl
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+// Service trigger constants from winsvc.h, which x/sys/windows doesn't define
+const (
+	triggerTypeDeviceArrival = 1 // SERVICE_TRIGGER_TYPE_DEVICE_INTERFACE_ARRIVAL
+	triggerTypeIPAddress     = 2 // SERVICE_TRIGGER_TYPE_IP_ADDRESS_AVAILABILITY
+	triggerActionStart       = 1 // SERVICE_TRIGGER_ACTION_SERVICE_START
+	triggerDataTypeString    = 2 // SERVICE_TRIGGER_DATA_TYPE_STRING
+)
+
+// firstIPAddressArrival is NETWORK_MANAGER_FIRST_IP_ADDRESS_ARRIVAL_GUID
+var firstIPAddressArrival = windows.GUID{Data1: 0x4f27f2de, Data2: 0x14e2, Data3: 0x430b, Data4: [8]byte{0xa5, 0x49, 0x7c, 0xd4, 0x8c, 0xbc, 0x82, 0x45}}
+
+// serviceTriggerInfo mirrors SERVICE_TRIGGER_INFO
+type serviceTriggerInfo struct {
+	count    uint32
+	triggers *serviceTrigger
+	reserved *byte
+}
+
+// serviceTrigger mirrors SERVICE_TRIGGER
+type serviceTrigger struct {
+	triggerType uint32
+	action      uint32
+	subtype     *windows.GUID
+	dataCount   uint32
+	data        *serviceTriggerData
+}
+
+// serviceTriggerData mirrors SERVICE_TRIGGER_SPECIFIC_DATA_ITEM
+type serviceTriggerData struct {
+	dataType uint32
+	size     uint32
+	data     *byte
+}
+
+// SetTriggers replaces the start triggers of the installed service name
+func SetTriggers(name string, triggers []Trigger) error {
+	m, err := mgr.Connect()
+	if err != nil {
+		return fmt.Errorf("failed to connect to the service control manager: %w", err)
+	}
+	defer m.Disconnect()
+
+	s, err := m.OpenService(name)
+	if err != nil {
+		return fmt.Errorf("failed to open service %s: %w", name, err)
+	}
+	defer s.Close()
+
+	info := serviceTriggerInfo{count: uint32(len(triggers))}
+	native := make([]serviceTrigger, len(triggers))
+	for i, t := range triggers {
+		if native[i], err = nativeTrigger(t); err != nil {
+			return err
+		}
+	}
+	if len(native) > 0 {
+		info.triggers = &native[0]
+	}
+
+	err = windows.ChangeServiceConfig2(s.Handle, windows.SERVICE_CONFIG_TRIGGER_INFO, (*byte)(unsafe.Pointer(&info)))
+	runtime.KeepAlive(native)
+	if err != nil {
+		return fmt.Errorf("failed to set the service triggers: %w", err)
+	}
+	return nil
+}
+
+// nativeTrigger converts t to a SERVICE_TRIGGER starting the service
+func nativeTrigger(t Trigger) (serviceTrigger, error) {
+	if err := t.Validate(); err != nil {
+		return serviceTrigger{}, err
+	}
+	if t.Event == TriggerNetwork {
+		return serviceTrigger{triggerType: triggerTypeIPAddress, action: triggerActionStart, subtype: &firstIPAddressArrival}, nil
+	}
+
+	class := t.InterfaceClass
+	if !strings.HasPrefix(class, "{") {
+		class = "{" + class + "}"
+	}
+	guid, err := windows.GUIDFromString(class)
+	if err != nil {
+		return serviceTrigger{}, fmt.Errorf("device trigger: %w", err)
+	}
+	nt := serviceTrigger{triggerType: triggerTypeDeviceArrival, action: triggerActionStart, subtype: &guid}
+	if t.HardwareID != "" {
+		// A REG_MULTI_SZ string, ending with two NULs
+		id, err := windows.UTF16FromString(t.HardwareID)
+		if err != nil {
+			return serviceTrigger{}, fmt.Errorf("device trigger: %w", err)
+		}
+		id = append(id, 0)
+		nt.dataCount = 1
+		nt.data = &serviceTriggerData{
+			dataType: triggerDataTypeString,
+			size:     uint32(len(id) * 2),
+			data:     (*byte)(unsafe.Pointer(&id[0])),
+		}
+	}
+	return nt, nil
+}
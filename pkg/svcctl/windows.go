@@ -0,0 +1,206 @@
+package svcctl
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/lucasdecamargo/kardianos"
+)
+
+// Events that start the service on Windows, see Trigger
+const (
+	TriggerNetwork = "network" // The first IP address becomes available
+	TriggerDevice  = "device"  // A device of the InterfaceClass, optionally with the HardwareID, arrives
+)
+
+// OptionTriggers is the kardianos option holding the []Trigger set by WindowsOptions.Apply
+const OptionTriggers = "Triggers"
+
+// kardianos options of the Windows start type and failure actions
+const (
+	OptionStartType      = "StartType"
+	OptionOnFailure      = "OnFailure"
+	OptionOnFailureDelay = "OnFailureDelayDuration"
+)
+
+// StartType is how the service control manager starts the service
+type StartType string
+
+// Start types
+const (
+	StartAutomatic StartType = "automatic" // At boot
+	StartManual    StartType = "manual"    // On request
+	StartDisabled  StartType = "disabled"  // Never
+)
+
+// StartTypes lists the valid start types
+var StartTypes = []StartType{StartAutomatic, StartManual, StartDisabled}
+
+// Validate checks that t is a known start type
+func (t StartType) Validate() error {
+	if !slices.Contains(StartTypes, t) {
+		return fmt.Errorf("unknown start type %q, use %s", t, joinValues(StartTypes))
+	}
+	return nil
+}
+
+// FailureAction is what the service control manager does when the service fails
+type FailureAction string
+
+// Failure actions
+const (
+	FailureRestart  FailureAction = "restart"  // Restart the service
+	FailureReboot   FailureAction = "reboot"   // Reboot the computer
+	FailureNoAction FailureAction = "noaction" // Leave the service stopped
+)
+
+// FailureActions lists the valid failure actions
+var FailureActions = []FailureAction{FailureRestart, FailureReboot, FailureNoAction}
+
+// Validate checks that a is a known failure action
+func (a FailureAction) Validate() error {
+	if !slices.Contains(FailureActions, a) {
+		return fmt.Errorf("unknown failure action %q, use %s", a, joinValues(FailureActions))
+	}
+	return nil
+}
+
+// joinValues lists values for an error message
+func joinValues[T ~string](values []T) string {
+	s := make([]string, len(values))
+	for i, v := range values {
+		s[i] = string(v)
+	}
+	return strings.Join(s, ", ")
+}
+
+// WindowsServiceOptions are the start type and failure actions of a Windows service,
+// which kardianos takes as strings and silently replaces with its defaults when they
+// are misspelled
+type WindowsServiceOptions struct {
+	StartType      StartType     // StartAutomatic when empty
+	OnFailure      FailureAction // No recovery action when empty
+	OnFailureDelay time.Duration // Wait before the failure action, a second when zero
+}
+
+// Options validates o and returns it as kardianos options
+func (o WindowsServiceOptions) Options() (kardianos.KeyValue, error) {
+	opts := kardianos.KeyValue{}
+	if o.StartType != "" {
+		opts[OptionStartType] = string(o.StartType)
+	}
+	if o.OnFailure != "" {
+		opts[OptionOnFailure] = string(o.OnFailure)
+	}
+	if o.OnFailureDelay > 0 {
+		opts[OptionOnFailureDelay] = o.OnFailureDelay.String()
+	}
+	return opts, ValidateWindowsServiceOptions(opts)
+}
+
+// ValidateWindowsServiceOptions checks the start type and failure actions set in opts,
+// such as by service edit
+func ValidateWindowsServiceOptions(opts kardianos.KeyValue) error {
+	if v, ok := opts[OptionStartType]; ok {
+		if s, _ := v.(string); StartType(s).Validate() != nil {
+			return fmt.Errorf("invalid %s %v: expected one of %s", OptionStartType, v, joinValues(StartTypes))
+		}
+	}
+	if v, ok := opts[OptionOnFailure]; ok {
+		if s, _ := v.(string); FailureAction(s).Validate() != nil {
+			return fmt.Errorf("invalid %s %v: expected one of %s", OptionOnFailure, v, joinValues(FailureActions))
+		}
+	}
+	if v, ok := opts[OptionOnFailureDelay]; ok {
+		s, _ := v.(string)
+		if d, err := time.ParseDuration(s); err != nil || d < 0 {
+			return fmt.Errorf("invalid %s %v: expected a duration such as 10s", OptionOnFailureDelay, v)
+		}
+	}
+	return nil
+}
+
+var guidPattern = regexp.MustCompile(`^\{?[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}\}?$`)
+
+// WindowsOptions are the service control manager settings the kardianos options can't
+// express. They are ignored on other platforms.
+type WindowsOptions struct {
+	DelayedAutoStart bool      `json:"delayedAutoStart,omitempty"` // Start automatic services a while after boot
+	Triggers         []Trigger `json:"triggers,omitempty"`         // Also start the service on these events
+}
+
+// Trigger is an event that starts the service, whatever its start type
+type Trigger struct {
+	Event          string `json:"event"`                    // TriggerNetwork or TriggerDevice
+	InterfaceClass string `json:"interfaceClass,omitempty"` // Device interface class GUID, for TriggerDevice
+	HardwareID     string `json:"hardwareId,omitempty"`     // Device hardware ID, such as USB\VID_0403&PID_6001
+}
+
+// ParseTrigger parses "network", "device:<interface class GUID>" or
+// "device:<interface class GUID>:<hardware ID>", as taken by --trigger
+func ParseTrigger(s string) (Trigger, error) {
+	event, rest, _ := strings.Cut(s, ":")
+	t := Trigger{Event: event}
+	t.InterfaceClass, t.HardwareID, _ = strings.Cut(rest, ":")
+	return t, t.Validate()
+}
+
+// Validate checks the event and its device parameters
+func (t Trigger) Validate() error {
+	switch t.Event {
+	case TriggerNetwork:
+		if t.InterfaceClass != "" || t.HardwareID != "" {
+			return errors.New("network trigger takes no device")
+		}
+	case TriggerDevice:
+		if !guidPattern.MatchString(t.InterfaceClass) {
+			return fmt.Errorf("device trigger: invalid interface class GUID %q", t.InterfaceClass)
+		}
+	default:
+		return fmt.Errorf("unknown trigger event %q, use %s or %s", t.Event, TriggerNetwork, TriggerDevice)
+	}
+	return nil
+}
+
+// String formats t the way ParseTrigger reads it
+func (t Trigger) String() string {
+	s := t.Event
+	if t.InterfaceClass != "" {
+		s += ":" + t.InterfaceClass
+	}
+	if t.HardwareID != "" {
+		s += ":" + t.HardwareID
+	}
+	return s
+}
+
+// Validate checks every trigger
+func (o WindowsOptions) Validate() error {
+	for _, t := range o.Triggers {
+		if err := t.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Apply sets the options in opts, where they aren't set already. DelayedAutoStart is
+// installed by kardianos, and the triggers by SetTriggers.
+func (o WindowsOptions) Apply(opts kardianos.KeyValue) {
+	if _, ok := opts["DelayedAutoStart"]; !ok && o.DelayedAutoStart {
+		opts["DelayedAutoStart"] = true
+	}
+	if _, ok := opts[OptionTriggers]; !ok && len(o.Triggers) > 0 {
+		opts[OptionTriggers] = o.Triggers
+	}
+}
+
+// Triggers returns the triggers set in opts by Apply
+func Triggers(opts kardianos.KeyValue) []Trigger {
+	triggers, _ := opts[OptionTriggers].([]Trigger)
+	return triggers
+}
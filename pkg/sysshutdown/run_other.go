@@ -0,0 +1,10 @@
+//go:build !windows
+
+package sysshutdown
+
+import "github.com/lucasdecamargo/kardianos"
+
+// Run runs the service s, see the Windows version accepting preshutdown notifications
+func Run(name string, s kardianos.Service, i kardianos.Interface) error {
+	return s.Run()
+}
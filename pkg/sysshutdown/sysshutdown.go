@@ -0,0 +1,10 @@
+// Package sysshutdown delays the system shutdown or reboot until the supervisor has
+// stopped its child, instead of letting the init system kill everything at once. It
+// takes a systemd-logind inhibitor on Linux, and handles the preshutdown
+// notification of the service control manager on Windows.
+package sysshutdown
+
+import "errors"
+
+// ErrUnsupported is returned by Delay on platforms without shutdown inhibitors
+var ErrUnsupported = errors.New("delaying the system shutdown is only supported with systemd-logind and on Windows")
@@ -0,0 +1,97 @@
+package sysshutdown
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	godbus "github.com/godbus/dbus/v5"
+)
+
+// logind names
+const (
+	login1Dest      = "org.freedesktop.login1"
+	login1Path      = "/org/freedesktop/login1"
+	login1Manager   = "org.freedesktop.login1.Manager"
+	prepareShutdown = "PrepareForShutdown"
+)
+
+// Delay holds a logind delay inhibitor for shutdowns and reboots until ctx is done.
+// When one begins, drain is called and the inhibitor is released once it returns,
+// letting the shutdown go on. logind waits at most InhibitDelayMaxSec, 5 seconds by
+// default, for it. who names the application in "systemd-inhibit --list".
+func Delay(ctx context.Context, who string, drain func()) error {
+	conn, err := godbus.ConnectSystemBus()
+	if err != nil {
+		return fmt.Errorf("failed to connect to the system bus: %w", err)
+	}
+	defer conn.Close()
+
+	if err := conn.AddMatchSignal(
+		godbus.WithMatchObjectPath(login1Path),
+		godbus.WithMatchInterface(login1Manager),
+		godbus.WithMatchMember(prepareShutdown),
+	); err != nil {
+		return fmt.Errorf("failed to watch for shutdowns: %w", err)
+	}
+	signals := make(chan *godbus.Signal, 4)
+	conn.Signal(signals)
+
+	lock, err := inhibit(conn, who)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if lock != nil {
+			lock.Close()
+		}
+	}()
+	slog.Info("Delaying system shutdowns until the child has stopped")
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case sig, ok := <-signals:
+			if !ok {
+				return fmt.Errorf("system bus connection closed")
+			}
+			if sig.Name != login1Manager+"."+prepareShutdown || len(sig.Body) == 0 {
+				continue
+			}
+			if starting, _ := sig.Body[0].(bool); !starting {
+				// The shutdown was canceled, delay the next one
+				if lock == nil {
+					if lock, err = inhibit(conn, who); err != nil {
+						return err
+					}
+				}
+				continue
+			}
+			if lock == nil {
+				continue
+			}
+
+			slog.Info("System shutting down, stopping the child first")
+			begin := time.Now()
+			drain()
+			lock.Close()
+			lock = nil
+			slog.Info("Child stopped, system shutdown released", "duration", time.Since(begin))
+		}
+	}
+}
+
+// inhibit takes a logind delay inhibitor lock for shutdowns, which lasts until the
+// returned file is closed
+func inhibit(conn *godbus.Conn, who string) (*os.File, error) {
+	var fd godbus.UnixFD
+	obj := conn.Object(login1Dest, login1Path)
+	err := obj.Call(login1Manager+".Inhibit", 0, "shutdown", who, "Stopping the supervised child gracefully", "delay").Store(&fd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to take a shutdown inhibitor: %w", err)
+	}
+	return os.NewFile(uintptr(fd), "inhibitor"), nil
+}
@@ -0,0 +1,10 @@
+//go:build !linux && !windows
+
+package sysshutdown
+
+import "context"
+
+// Delay returns ErrUnsupported outside Linux and Windows
+func Delay(ctx context.Context, who string, drain func()) error {
+	return ErrUnsupported
+}
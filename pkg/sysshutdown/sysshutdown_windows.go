@@ -0,0 +1,133 @@
+package sysshutdown
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+	"time"
+	"unsafe"
+
+	"github.com/lucasdecamargo/kardianos"
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+// PreshutdownTimeout is how long Windows waits for the service to stop once a
+// shutdown begins, before sending the shutdown notification
+const PreshutdownTimeout = 3 * time.Minute
+
+// preshutdownInfo mirrors SERVICE_PRESHUTDOWN_INFO, which x/sys/windows doesn't define
+type preshutdownInfo struct {
+	timeout uint32 // Milliseconds
+}
+
+// drainHook holds the drain function of Delay while it runs
+var drainHook atomic.Pointer[func()]
+
+// Delay has Windows wait up to PreshutdownTimeout for the service who to stop before
+// shutting down, until ctx is done. When a shutdown begins, the service run by Run
+// receives the preshutdown notification, calls drain and stops once it returns.
+func Delay(ctx context.Context, who string, drain func()) error {
+	if ok, err := svc.IsWindowsService(); err != nil || !ok {
+		return errors.New("delaying the system shutdown needs the daemon to run as a Windows service")
+	}
+	if err := setPreshutdownTimeout(who, PreshutdownTimeout); err != nil {
+		return err
+	}
+
+	drainHook.Store(&drain)
+	defer drainHook.Store(nil)
+	slog.Info("Delaying system shutdowns until the child has stopped", "timeout", PreshutdownTimeout)
+	<-ctx.Done()
+	return nil
+}
+
+// setPreshutdownTimeout sets the preshutdown timeout of the installed service name
+func setPreshutdownTimeout(name string, timeout time.Duration) error {
+	m, err := mgr.Connect()
+	if err != nil {
+		return fmt.Errorf("failed to connect to the service control manager: %w", err)
+	}
+	defer m.Disconnect()
+
+	s, err := m.OpenService(name)
+	if err != nil {
+		return fmt.Errorf("failed to open service %s: %w", name, err)
+	}
+	defer s.Close()
+
+	info := preshutdownInfo{timeout: uint32(timeout.Milliseconds())}
+	if err := windows.ChangeServiceConfig2(s.Handle, windows.SERVICE_CONFIG_PRESHUTDOWN_INFO, (*byte)(unsafe.Pointer(&info))); err != nil {
+		return fmt.Errorf("failed to set the preshutdown timeout: %w", err)
+	}
+	return nil
+}
+
+// Run runs i as the service s named name, as s.Run does, but also accepts the
+// preshutdown notification Windows sends before the shutdown one, see Delay.
+// Outside the service control manager, it is s.Run.
+func Run(name string, s kardianos.Service, i kardianos.Interface) error {
+	if ok, err := svc.IsWindowsService(); err != nil || !ok {
+		return s.Run()
+	}
+	h := &handler{s: s, i: i}
+	if err := svc.Run(name, h); err != nil {
+		return err
+	}
+	return h.err
+}
+
+// handler serves the service control requests, as the kardianos one does with the
+// preshutdown notification added
+type handler struct {
+	s   kardianos.Service
+	i   kardianos.Interface
+	err error // Why the start or stop failed, read once svc.Run returns
+}
+
+func (h *handler) Execute(args []string, r <-chan svc.ChangeRequest, changes chan<- svc.Status) (bool, uint32) {
+	const accepts = svc.AcceptStop | svc.AcceptShutdown | svc.AcceptPreShutdown
+	changes <- svc.Status{State: svc.StartPending}
+
+	if err := h.i.Start(h.s); err != nil {
+		h.err = err
+		return true, 1
+	}
+	changes <- svc.Status{State: svc.Running, Accepts: accepts}
+
+	for c := range r {
+		stop := h.i.Stop
+		switch c.Cmd {
+		case svc.Interrogate:
+			changes <- c.CurrentStatus
+			continue
+		case svc.PreShutdown:
+			if drain := drainHook.Load(); drain != nil {
+				changes <- svc.Status{State: svc.StopPending, WaitHint: uint32(PreshutdownTimeout.Milliseconds())}
+				slog.Info("System shutting down, stopping the child first")
+				begin := time.Now()
+				(*drain)()
+				slog.Info("Child stopped, system shutdown released", "duration", time.Since(begin))
+				return false, 0
+			}
+		case svc.Shutdown:
+			if sd, ok := h.i.(kardianos.Shutdowner); ok {
+				stop = sd.Shutdown
+			}
+		case svc.Stop:
+		default:
+			continue
+		}
+
+		changes <- svc.Status{State: svc.StopPending}
+		if err := stop(h.s); err != nil {
+			h.err = err
+			return true, 2
+		}
+		return false, 0
+	}
+	return false, 0
+}
@@ -0,0 +1,83 @@
+package systemd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/lucasdecamargo/kardianos"
+)
+
+// templateFuncs are the functions the kardianos systemd templates are rendered with
+var templateFuncs = template.FuncMap{
+	"cmd": func(s string) string {
+		return `"` + strings.ReplaceAll(s, `"`, `\"`) + `"`
+	},
+	"cmdEscape": func(s string) string {
+		return strings.ReplaceAll(s, " ", `\x20`)
+	},
+}
+
+// Render returns the unit file kardianos installs for cfg, using the same options and
+// defaults, so it can be previewed before installing
+func Render(cfg *kardianos.Config) (string, error) {
+	path := cfg.Executable
+	var err error
+	if path != "" {
+		path, err = filepath.Abs(path)
+	} else {
+		path, err = os.Executable()
+	}
+	if err != nil {
+		return "", err
+	}
+
+	script := option(cfg.Option, "SystemdScript", Script(ServiceOptions{}))
+	t, err := template.New("").Funcs(templateFuncs).Parse(script)
+	if err != nil {
+		return "", err
+	}
+
+	data := &struct {
+		*kardianos.Config
+		Path                 string
+		Group                string
+		HasOutputFileSupport bool
+		ReloadSignal         string
+		PIDFile              string
+		LimitNOFILE          int
+		Restart              string
+		RestartSec           int
+		SuccessExitStatus    string
+		LogOutput            bool
+		LogDirectory         string
+	}{
+		cfg,
+		path,
+		option(cfg.Option, "Group", ""),
+		true,
+		option(cfg.Option, "ReloadSignal", ""),
+		option(cfg.Option, "PIDFile", ""),
+		option(cfg.Option, "LimitNOFILE", -1),
+		option(cfg.Option, "Restart", "always"),
+		option(cfg.Option, "RestartSec", 120),
+		option(cfg.Option, "SuccessExitStatus", ""),
+		option(cfg.Option, "LogOutput", false),
+		option(cfg.Option, "LogDirectory", "/var/log"),
+	}
+
+	var b strings.Builder
+	if err := t.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// option returns the kardianos option name when set with the type of def, or def
+func option[T any](kv kardianos.KeyValue, name string, def T) T {
+	if v, ok := kv[name].(T); ok {
+		return v
+	}
+	return def
+}
@@ -0,0 +1,69 @@
+package systemd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lucasdecamargo/go-appservice-example/pkg/rlimit"
+)
+
+// ServiceOptions holds [Service] directives the kardianos unit template can't express
+type ServiceOptions struct {
+	WatchdogSec time.Duration // Restart the service when no watchdog ping arrives in time
+	Limits      rlimit.Limits // Resource limits of the service, rendered as LimitCORE= and friends
+}
+
+// directives renders the options as unit file lines
+func (o ServiceOptions) directives() []string {
+	var lines []string
+	if o.WatchdogSec > 0 {
+		lines = append(lines, fmt.Sprintf("WatchdogSec=%d", int(o.WatchdogSec.Seconds())))
+	}
+	return append(lines, o.Limits.Directives()...)
+}
+
+// Script returns a unit template for the kardianos "SystemdScript" option, extending
+// the default template with opts.
+func Script(opts ServiceOptions) string {
+	return strings.Replace(unitTemplate, extraDirectives, strings.Join(opts.directives(), "\n"), 1)
+}
+
+// extraDirectives marks where ServiceOptions are rendered in unitTemplate
+const extraDirectives = "{{/* extra */}}"
+
+// unitTemplate mirrors the kardianos systemd template, using the same fields and functions
+const unitTemplate = `[Unit]
+Description={{.Description}}
+ConditionFileIsExecutable={{.Path|cmdEscape}}
+{{range $i, $dep := .Dependencies}} 
+{{$dep}} {{end}}
+
+[Service]
+StartLimitInterval=5
+StartLimitBurst=10
+ExecStart={{.Path|cmdEscape}}{{range .Arguments}} {{.|cmd}}{{end}}
+{{if .ChRoot}}RootDirectory={{.ChRoot|cmd}}{{end}}
+{{if .WorkingDirectory}}WorkingDirectory={{.WorkingDirectory|cmdEscape}}{{end}}
+{{if .UserName}}User={{.UserName}}{{end}}
+{{if .Group}}Group={{.Group}}{{end}}
+{{if .ReloadSignal}}ExecReload=/bin/kill -{{.ReloadSignal}} "$MAINPID"{{end}}
+{{if .PIDFile}}PIDFile={{.PIDFile|cmd}}{{end}}
+{{if and .LogOutput .HasOutputFileSupport -}}
+StandardOutput=file:{{.LogDirectory}}/{{.Name}}.out
+StandardError=file:{{.LogDirectory}}/{{.Name}}.err
+{{- end}}
+{{if gt .LimitNOFILE -1 }}LimitNOFILE={{.LimitNOFILE}}{{end}}
+{{if .Restart}}Restart={{.Restart}}{{end}}
+{{if .RestartSec}}RestartSec={{.RestartSec}}{{end}}
+{{if .SuccessExitStatus}}SuccessExitStatus={{.SuccessExitStatus}}{{end}}
+` + extraDirectives + `
+EnvironmentFile=-/etc/sysconfig/{{.Name}}
+
+{{range $k, $v := .EnvVars -}}
+Environment={{$k}}={{$v}}
+{{end -}}
+
+[Install]
+WantedBy=multi-user.target
+`
@@ -0,0 +1,48 @@
+package systemd
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// HealthCheck reports whether a part of the supervisor is working
+type HealthCheck func() error
+
+// RunWatchdog pings the systemd watchdog at half the configured timeout until ctx is
+// done. A ping is only sent while every check passes, so systemd restarts a hung
+// supervisor. It returns immediately when the watchdog is not enabled.
+func RunWatchdog(ctx context.Context, checks ...HealthCheck) {
+	timeout, ok := WatchdogInterval()
+	if !ok {
+		return
+	}
+
+	ticker := time.NewTicker(timeout / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := runChecks(checks); err != nil {
+				slog.Warn("Skipping watchdog ping", "error", err)
+				continue
+			}
+			if _, err := Notify(StateWatchdog); err != nil {
+				slog.Warn("Failed to ping watchdog", "error", err)
+			}
+		}
+	}
+}
+
+// runChecks returns the first failing health check error
+func runChecks(checks []HealthCheck) error {
+	for _, check := range checks {
+		if err := check(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
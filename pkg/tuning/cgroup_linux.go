@@ -0,0 +1,131 @@
+package tuning
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const cgroupRoot = "/sys/fs/cgroup"
+
+// Detect reads the CPU and memory limits of the current cgroup (v2 or v1). Hybrid
+// hosts list a unified hierarchy without controllers, so v1 is used unless the
+// unified cgroup actually exposes controllers.
+func Detect() Limits {
+	if path, ok := cgroupPath(""); ok && hasControllers(filepath.Join(cgroupRoot, path)) {
+		return detectV2(filepath.Join(cgroupRoot, path))
+	}
+	return detectV1()
+}
+
+// detectV2 reads cpu.max and memory.max from a unified hierarchy cgroup
+func detectV2(dir string) Limits {
+	var l Limits
+	if fields := readFields(filepath.Join(dir, "cpu.max")); len(fields) == 2 && fields[0] != "max" {
+		quota, err1 := strconv.ParseFloat(fields[0], 64)
+		period, err2 := strconv.ParseFloat(fields[1], 64)
+		if err1 == nil && err2 == nil && period > 0 {
+			l.CPUs = quota / period
+		}
+	}
+	if fields := readFields(filepath.Join(dir, "memory.max")); len(fields) == 1 && fields[0] != "max" {
+		l.MemoryBytes, _ = strconv.ParseInt(fields[0], 10, 64)
+	}
+	return l
+}
+
+// detectV1 reads the CFS quota and memory limit from the legacy hierarchies
+func detectV1() Limits {
+	var l Limits
+	if path, ok := cgroupPath("cpu"); ok {
+		dir := filepath.Join(cgroupRoot, "cpu", path)
+		quota := readInt(filepath.Join(dir, "cpu.cfs_quota_us"))
+		period := readInt(filepath.Join(dir, "cpu.cfs_period_us"))
+		if quota > 0 && period > 0 {
+			l.CPUs = float64(quota) / float64(period)
+		}
+	}
+	if path, ok := cgroupPath("memory"); ok {
+		limit := readInt(filepath.Join(cgroupRoot, "memory", path, "memory.limit_in_bytes"))
+		if limit > 0 && limit < 1<<62 { // Unlimited is reported as a huge page-aligned value
+			l.MemoryBytes = limit
+		}
+	}
+	return l
+}
+
+// OOMKills returns how many processes of the current cgroup the kernel killed for
+// running out of memory, or 0 when the cgroup doesn't report it
+func OOMKills() int64 {
+	var file string
+	if path, ok := cgroupPath(""); ok && hasControllers(filepath.Join(cgroupRoot, path)) {
+		file = filepath.Join(cgroupRoot, path, "memory.events")
+	} else if path, ok := cgroupPath("memory"); ok {
+		file = filepath.Join(cgroupRoot, "memory", path, "memory.oom_control")
+	} else {
+		return 0
+	}
+
+	// Both files hold "key value" lines
+	fields := readFields(file)
+	for i := 0; i+1 < len(fields); i += 2 {
+		if fields[i] == "oom_kill" {
+			n, _ := strconv.ParseInt(fields[i+1], 10, 64)
+			return n
+		}
+	}
+	return 0
+}
+
+// hasControllers reports whether the unified hierarchy cgroup dir exposes controllers
+func hasControllers(dir string) bool {
+	_, err := os.Stat(filepath.Join(dir, "cgroup.controllers"))
+	return err == nil
+}
+
+// cgroupPath returns the path of the current process in the hierarchy with the given
+// controller, or in the unified hierarchy when controller is empty
+func cgroupPath(controller string) (string, bool) {
+	f, err := os.Open("/proc/self/cgroup")
+	if err != nil {
+		return "", false
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// Lines look like "hierarchy-ID:controller-list:cgroup-path"
+		parts := strings.SplitN(scanner.Text(), ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		if controller == "" && parts[0] == "0" && parts[1] == "" {
+			return parts[2], true
+		}
+		if controller != "" && strings.Contains(","+parts[1]+",", ","+controller+",") {
+			return parts[2], true
+		}
+	}
+	return "", false
+}
+
+// readFields returns the whitespace separated fields of a small file
+func readFields(path string) []string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	return strings.Fields(string(data))
+}
+
+// readInt reads a file holding a single integer, returning 0 on failure
+func readInt(path string) int64 {
+	fields := readFields(path)
+	if len(fields) != 1 {
+		return 0
+	}
+	v, _ := strconv.ParseInt(fields[0], 10, 64)
+	return v
+}
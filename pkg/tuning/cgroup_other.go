@@ -0,0 +1,13 @@
+//go:build !linux
+
+package tuning
+
+// Detect returns no limits on platforms without cgroups
+func Detect() Limits {
+	return Limits{}
+}
+
+// OOMKills returns 0 on platforms without cgroups
+func OOMKills() int64 {
+	return 0
+}
@@ -0,0 +1,75 @@
+package tuning
+
+import (
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Go runtime environment variables set for the child
+const (
+	EnvGOMAXPROCS = "GOMAXPROCS"
+	EnvGOGC       = "GOGC"
+	EnvGOMEMLIMIT = "GOMEMLIMIT"
+)
+
+const defaultMemoryLimitRatio = 0.9
+
+// Limits are the resource limits detected for the current cgroup. Zero means unlimited.
+type Limits struct {
+	CPUs        float64 // CPU quota in cores
+	MemoryBytes int64   // Memory limit in bytes
+}
+
+// Config controls the Go runtime settings derived for the child
+type Config struct {
+	Auto             bool    `json:"auto,omitempty"`             // Derive GOMAXPROCS and GOMEMLIMIT from cgroup limits
+	GOGC             int     `json:"gogc,omitempty"`             // GOGC value, unset when zero
+	MemoryLimitRatio float64 `json:"memoryLimitRatio,omitempty"` // Share of the memory limit used as GOMEMLIMIT, 0.9 by default
+}
+
+// Env returns the runtime variables for the child, skipping any already set in
+// the supervisor environment or in existing, so explicit settings always win
+func Env(cfg Config, existing []string) []string {
+	var vars []string
+	set := func(key, value string) {
+		if !isSet(key, existing) {
+			vars = append(vars, key+"="+value)
+		}
+	}
+
+	if cfg.GOGC != 0 {
+		set(EnvGOGC, strconv.Itoa(cfg.GOGC))
+	}
+
+	if !cfg.Auto {
+		return vars
+	}
+
+	limits := Detect()
+	if limits.CPUs > 0 {
+		set(EnvGOMAXPROCS, strconv.Itoa(max(1, int(limits.CPUs))))
+	}
+	if limits.MemoryBytes > 0 {
+		ratio := cfg.MemoryLimitRatio
+		if ratio <= 0 || ratio > 1 {
+			ratio = defaultMemoryLimitRatio
+		}
+		set(EnvGOMEMLIMIT, strconv.FormatInt(int64(float64(limits.MemoryBytes)*ratio), 10))
+	}
+
+	return vars
+}
+
+// isSet reports whether key is defined in the process environment or in vars
+func isSet(key string, vars []string) bool {
+	if _, ok := os.LookupEnv(key); ok {
+		return true
+	}
+	for _, v := range vars {
+		if strings.HasPrefix(v, key+"=") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,84 @@
+package ui
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+)
+
+// ErrNotInteractive reports a prompt on a non-terminal input or output
+var ErrNotInteractive = errors.New("prompts need an interactive terminal")
+
+// Prompter asks questions on a terminal and reads the answers line by line
+type Prompter struct {
+	r *bufio.Reader
+	w io.Writer
+}
+
+// NewPrompter creates a prompter on stdin and stdout, failing when either isn't a terminal
+func NewPrompter() (*Prompter, error) {
+	if !interactive || !isTerminal(os.Stdin) {
+		return nil, ErrNotInteractive
+	}
+	return &Prompter{r: bufio.NewReader(os.Stdin), w: os.Stdout}, nil
+}
+
+// Ask prints question and returns the answer, or def when the answer is empty
+func (p *Prompter) Ask(question, def string) (string, error) {
+	if def != "" {
+		fmt.Fprintf(p.w, "%s %s: ", Colorize(Bold, question), Colorize(Gray, "["+def+"]"))
+	} else {
+		fmt.Fprintf(p.w, "%s: ", Colorize(Bold, question))
+	}
+
+	line, err := p.r.ReadString('\n')
+	if err != nil && (line == "" || !errors.Is(err, io.EOF)) {
+		return "", err
+	}
+	if line = strings.TrimSpace(line); line == "" {
+		return def, nil
+	}
+	return line, nil
+}
+
+// Choose asks question until the answer is one of choices, returning def on an empty answer
+func (p *Prompter) Choose(question string, choices []string, def string) (string, error) {
+	question = fmt.Sprintf("%s (%s)", question, strings.Join(choices, ", "))
+	for {
+		answer, err := p.Ask(question, def)
+		if err != nil {
+			return "", err
+		}
+		if slices.Contains(choices, answer) {
+			return answer, nil
+		}
+		Warn("Expected one of: %s.", strings.Join(choices, ", "))
+	}
+}
+
+// Confirm asks a yes or no question, returning def on an empty answer
+func (p *Prompter) Confirm(question string, def bool) (bool, error) {
+	hint := "y/N"
+	if def {
+		hint = "Y/n"
+	}
+	for {
+		answer, err := p.Ask(question+" ("+hint+")", "")
+		if err != nil {
+			return false, err
+		}
+		switch strings.ToLower(answer) {
+		case "":
+			return def, nil
+		case "y", "yes":
+			return true, nil
+		case "n", "no":
+			return false, nil
+		}
+		Warn("Expected yes or no.")
+	}
+}
@@ -0,0 +1,46 @@
+package ui
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+const spinnerInterval = 100 * time.Millisecond
+
+var spinnerFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+// Spin runs f while showing msg with a spinner, then reports success or failure.
+// When output is not interactive it prints plain progress lines instead.
+func Spin(w io.Writer, msg string, f func() error) error {
+	if !interactive {
+		fmt.Fprintf(w, "%s...\n", msg)
+		err := f()
+		if err != nil {
+			fmt.Fprintf(w, "%s: failed\n", msg)
+		} else {
+			fmt.Fprintf(w, "%s: done\n", msg)
+		}
+		return err
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- f() }()
+
+	ticker := time.NewTicker(spinnerInterval)
+	defer ticker.Stop()
+
+	for i := 0; ; i++ {
+		select {
+		case err := <-done:
+			if err != nil {
+				fmt.Fprintf(w, "\r%s %s\n", Colorize(Red, "✘"), msg)
+			} else {
+				fmt.Fprintf(w, "\r%s %s\n", Colorize(Green, "✔"), msg)
+			}
+			return err
+		case <-ticker.C:
+			fmt.Fprintf(w, "\r%s %s", Colorize(Yellow, spinnerFrames[i%len(spinnerFrames)]), msg)
+		}
+	}
+}
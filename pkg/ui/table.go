@@ -0,0 +1,40 @@
+package ui
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Table renders aligned two-column "key: value" output, in the style of systemctl status
+type Table struct {
+	w    io.Writer
+	rows [][2]string
+}
+
+// NewTable creates a table writing to w
+func NewTable(w io.Writer) *Table {
+	return &Table{w: w}
+}
+
+// Row adds a key/value row. The value may already be colorized.
+func (t *Table) Row(key string, value any) {
+	t.rows = append(t.rows, [2]string{key, fmt.Sprint(value)})
+}
+
+// Flush writes the buffered rows with keys right-aligned on the colon
+func (t *Table) Flush() error {
+	width := 0
+	for _, row := range t.rows {
+		width = max(width, len(row[0]))
+	}
+
+	for _, row := range t.rows {
+		pad := strings.Repeat(" ", width-len(row[0]))
+		if _, err := fmt.Fprintf(t.w, "%s%s: %s\n", pad, Colorize(Bold, row[0]), row[1]); err != nil {
+			return err
+		}
+	}
+	t.rows = nil
+	return nil
+}
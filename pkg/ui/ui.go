@@ -0,0 +1,69 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+)
+
+// Color is an ANSI escape sequence used to style terminal output
+type Color string
+
+const (
+	Reset  Color = "\033[0m"
+	Bold   Color = "\033[1m"
+	Red    Color = "\033[31m"
+	Green  Color = "\033[32m"
+	Yellow Color = "\033[33m"
+	Gray   Color = "\033[90m"
+)
+
+// EnvNoColor disables styling when set to any value, see https://no-color.org
+const EnvNoColor = "NO_COLOR"
+
+var (
+	interactive  = isTerminal(os.Stdout)
+	colorEnabled = interactive && os.Getenv(EnvNoColor) == ""
+)
+
+// isTerminal reports whether f is attached to a terminal
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return fi.Mode()&os.ModeCharDevice != 0
+}
+
+// Interactive reports whether stdout is a terminal
+func Interactive() bool {
+	return interactive
+}
+
+// SetColor enables or disables styled output. Color is never enabled when
+// stdout is not a terminal.
+func SetColor(enabled bool) {
+	colorEnabled = enabled && interactive
+}
+
+// Colorize wraps s in the given color when styling is enabled
+func Colorize(c Color, s string) string {
+	if !colorEnabled {
+		return s
+	}
+	return string(c) + s + string(Reset)
+}
+
+// Success prints a green message to stdout
+func Success(format string, a ...any) {
+	fmt.Println(Colorize(Green, fmt.Sprintf(format, a...)))
+}
+
+// Warn prints a yellow message to stdout
+func Warn(format string, a ...any) {
+	fmt.Println(Colorize(Yellow, fmt.Sprintf(format, a...)))
+}
+
+// Error prints a red message to stdout
+func Error(format string, a ...any) {
+	fmt.Println(Colorize(Red, fmt.Sprintf(format, a...)))
+}
@@ -0,0 +1,26 @@
+//go:build !windows
+
+package update
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// replaceLink atomically points the symlink link to target, relative to its directory
+func replaceLink(target, link string) error {
+	rel, err := filepath.Rel(filepath.Dir(link), target)
+	if err != nil {
+		rel = target
+	}
+	tmp := link + ".new"
+	os.Remove(tmp)
+	if err := os.Symlink(rel, tmp); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, link); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
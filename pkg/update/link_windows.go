@@ -0,0 +1,33 @@
+package update
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// replaceLink points the junction link to target. Junctions need no privilege, but
+// can't be renamed over each other, so the old one is moved aside first.
+func replaceLink(target, link string) error {
+	tmp := link + ".new"
+	os.Remove(tmp)
+	out, err := exec.Command("cmd", "/c", "mklink", "/J", tmp, target).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("mklink: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+
+	old := link + ".old"
+	os.Remove(old)
+	if err := os.Rename(link, old); err != nil && !os.IsNotExist(err) {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, link); err != nil {
+		os.Rename(old, link)
+		os.Remove(tmp)
+		return err
+	}
+	os.Remove(old) // Removes the junction, not its target
+	return nil
+}
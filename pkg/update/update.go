@@ -0,0 +1,283 @@
+package update
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	maxFeedSize        = 1 << 20
+	backupSuffix       = ".old"     // Executable replaced in place
+	pendingSuffix      = ".pending" // Version of an in-place update whose health check hasn't completed
+	downloadTimeout    = 10 * time.Minute
+	feedRequestTimeout = 30 * time.Second
+)
+
+// Release is the latest release announced by the feed, a JSON document such as
+//
+//	{"version": "1.4.0", "assets": {"linux/amd64": {"url": "https://...", "sha256": "...", "signature": "..."}}}
+type Release struct {
+	Version string           `json:"version"`
+	Notes   string           `json:"notes,omitempty"`
+	Assets  map[string]Asset `json:"assets"` // Executables by GOOS/GOARCH
+}
+
+// Asset is the executable of a release for one platform
+type Asset struct {
+	URL       string `json:"url"`                 // https only
+	SHA256    string `json:"sha256"`              // Hex digest of the executable
+	Signature string `json:"signature,omitempty"` // Base64 ed25519 signature of Payload
+}
+
+// Payload returns the bytes signed for asset: the version, platform and digest, one
+// per line
+func (a *Asset) Payload(version, platform string) []byte {
+	return fmt.Appendf(nil, "%s\n%s\n%s", version, platform, strings.ToLower(a.SHA256))
+}
+
+// Platform returns the asset key of the running executable
+func Platform() string {
+	return runtime.GOOS + "/" + runtime.GOARCH
+}
+
+// ParsePublicKey decodes a base64 ed25519 public key
+func ParsePublicKey(s string) (ed25519.PublicKey, error) {
+	key, err := base64.StdEncoding.DecodeString(s)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return nil, errors.New("update public key must be a base64 ed25519 key")
+	}
+	return ed25519.PublicKey(key), nil
+}
+
+// Fetch reads the latest release from the feed at feedURL
+func Fetch(ctx context.Context, client *http.Client, feedURL string) (*Release, error) {
+	if err := checkURL(feedURL); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, feedRequestTimeout)
+	defer cancel()
+
+	body, err := get(ctx, client, feedURL)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	var r Release
+	if err := json.NewDecoder(io.LimitReader(body, maxFeedSize)).Decode(&r); err != nil {
+		return nil, fmt.Errorf("invalid release feed: %w", err)
+	}
+	if _, err := ParseVersion(r.Version); err != nil {
+		return nil, fmt.Errorf("invalid release feed: %w", err)
+	}
+	return &r, nil
+}
+
+// Download saves the asset of r for this platform to a temporary file next to exe,
+// after checking its digest and, when key is set, its signature. The caller removes
+// the file when it isn't installed.
+func Download(ctx context.Context, client *http.Client, r *Release, key ed25519.PublicKey, exe string) (string, error) {
+	a, ok := r.Assets[Platform()]
+	if !ok {
+		return "", fmt.Errorf("release %s has no executable for %s", r.Version, Platform())
+	}
+	if err := checkURL(a.URL); err != nil {
+		return "", err
+	}
+	want, err := hex.DecodeString(a.SHA256)
+	if err != nil || len(want) != sha256.Size {
+		return "", fmt.Errorf("release %s has an invalid sha256", r.Version)
+	}
+	if key != nil {
+		sig, err := base64.StdEncoding.DecodeString(a.Signature)
+		if err != nil || !ed25519.Verify(key, a.Payload(r.Version, Platform()), sig) {
+			return "", fmt.Errorf("release %s has an invalid signature", r.Version)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, downloadTimeout)
+	defer cancel()
+	body, err := get(ctx, client, a.URL)
+	if err != nil {
+		return "", err
+	}
+	defer body.Close()
+
+	// Download next to the executable, so it can be renamed over it
+	f, err := os.CreateTemp(filepath.Dir(exe), "."+filepath.Base(exe)+"-update-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to download update: %w", err)
+	}
+	h := sha256.New()
+	_, err = io.Copy(io.MultiWriter(f, h), body)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil && !bytes.Equal(h.Sum(nil), want) {
+		err = fmt.Errorf("release %s doesn't match its sha256", r.Version)
+	}
+	if err == nil {
+		err = os.Chmod(f.Name(), 0o755)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", fmt.Errorf("failed to download update: %w", err)
+	}
+	return f.Name(), nil
+}
+
+// Replace installs the executable at path as version of exe. In a versions directory,
+// it becomes a new version and is made current. Otherwise exe is replaced in place,
+// keeping the previous executable for Rollback. A running executable can be renamed
+// on every platform.
+func Replace(exe, path, version string) error {
+	if v, ok := DetectVersions(exe); ok {
+		defer os.Remove(path)
+		if err := v.Install(version, path); err != nil {
+			return fmt.Errorf("failed to install update: %w", err)
+		}
+		return v.Switch(version)
+	}
+
+	backup := exe + backupSuffix
+	os.Remove(backup)
+	if err := os.Rename(exe, backup); err != nil {
+		return fmt.Errorf("failed to back up %s: %w", exe, err)
+	}
+	if err := os.Rename(path, exe); err != nil {
+		os.Rename(backup, exe)
+		return fmt.Errorf("failed to install update: %w", err)
+	}
+	return nil
+}
+
+// Rollback restores the executable replaced by Replace: the previous version in a
+// versions directory, the backup next to exe otherwise. It returns the version
+// restored, empty when unknown.
+func Rollback(exe string) (string, error) {
+	if v, ok := DetectVersions(exe); ok {
+		return v.Rollback()
+	}
+
+	backup := exe + backupSuffix
+	if _, err := os.Stat(backup); err != nil {
+		return "", fmt.Errorf("%w: %w", ErrNoPrevious, err)
+	}
+	// Windows can't rename over the running executable, move it aside first
+	failed, err := os.CreateTemp(filepath.Dir(exe), "."+filepath.Base(exe)+"-failed-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to roll back: %w", err)
+	}
+	failed.Close()
+	os.Remove(failed.Name())
+	if err := os.Rename(exe, failed.Name()); err != nil {
+		return "", fmt.Errorf("failed to roll back: %w", err)
+	}
+	if err := os.Rename(backup, exe); err != nil {
+		os.Rename(failed.Name(), exe)
+		return "", fmt.Errorf("failed to roll back: %w", err)
+	}
+	os.Remove(failed.Name()) // Fails on Windows while the failed child is still running
+	return "", nil
+}
+
+// pendingPath returns the file naming the version of exe whose health check hasn't completed
+func pendingPath(exe string) string {
+	if v, ok := DetectVersions(exe); ok {
+		return filepath.Join(v.Root, pendingFile)
+	}
+	return exe + pendingSuffix
+}
+
+// checkURL accepts https URLs only
+func checkURL(s string) error {
+	u, err := url.Parse(s)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return fmt.Errorf("update URL must be an https:// URL: %q", s)
+	}
+	return nil
+}
+
+// get sends a GET request to u and returns the body of a 200 response
+func get(ctx context.Context, client *http.Client, u string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s returned %s", u, resp.Status)
+	}
+	return resp.Body, nil
+}
+
+// Version is a parsed semantic version
+type Version struct {
+	Major, Minor, Patch int
+	Pre                 string // Pre-release, such as "rc.1", which sorts before the release
+}
+
+// ParseVersion parses "1.2.3", "v1.2.3" or "1.2.3-rc.1". Build metadata is ignored.
+func ParseVersion(s string) (Version, error) {
+	s, _, _ = strings.Cut(strings.TrimPrefix(s, "v"), "+")
+	core, pre, _ := strings.Cut(s, "-")
+	parts := strings.Split(core, ".")
+	if len(parts) != 3 {
+		return Version{}, fmt.Errorf("invalid version %q", s)
+	}
+	var n [3]int
+	for i, p := range parts {
+		v, err := strconv.Atoi(p)
+		if err != nil || v < 0 {
+			return Version{}, fmt.Errorf("invalid version %q", s)
+		}
+		n[i] = v
+	}
+	return Version{Major: n[0], Minor: n[1], Patch: n[2], Pre: pre}, nil
+}
+
+// Compare returns -1, 0 or 1 when v is older than, the same as or newer than o
+func (v Version) Compare(o Version) int {
+	for _, d := range []int{v.Major - o.Major, v.Minor - o.Minor, v.Patch - o.Patch} {
+		if d != 0 {
+			return sign(d)
+		}
+	}
+	switch {
+	case v.Pre == o.Pre:
+		return 0
+	case v.Pre == "":
+		return 1
+	case o.Pre == "":
+		return -1
+	}
+	return strings.Compare(v.Pre, o.Pre)
+}
+
+// sign returns the sign of d
+func sign(d int) int {
+	if d < 0 {
+		return -1
+	}
+	return 1
+}
@@ -0,0 +1,260 @@
+package update
+
+import (
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/lucasdecamargo/go-appservice-example/pkg/state"
+)
+
+const (
+	defaultInterval      = 6 * time.Hour
+	defaultHealthTimeout = time.Minute
+	healthPollInterval   = time.Second
+)
+
+// Target is the daemon an Updater reports to and restarts
+type Target interface {
+	Status() state.State
+	RestartForUpdate() error // Restarts the child on the executable just installed or restored
+	SetUpdate(u state.Update)
+	NotifyUpdate(detail string, err error)
+
+	// SnapshotBefore snapshots the service data before an update, returning an error
+	// when the update must not go on without it
+	SnapshotBefore(reason string) (string, error)
+	// RestoreSnapshot restores a snapshot before the next child start
+	RestoreSnapshot(name string) error
+}
+
+// Options configures an Updater
+type Options struct {
+	Feed       string // Release feed URL, https only
+	Current    string // Version of the running executable
+	Executable string // Executable replaced by updates, the running one by default
+	Interval   time.Duration
+
+	// Auto installs new versions while Window is open, then restarts the child and
+	// rolls back when it isn't ready and stable for HealthTimeout
+	Auto          bool
+	Window        Window
+	HealthTimeout time.Duration
+	PublicKey     ed25519.PublicKey // Verifies the release signatures, required by Auto
+}
+
+// Updater checks the release feed periodically and, when enabled, installs new versions
+type Updater struct {
+	Options
+	current  Version
+	t        Target
+	client   *http.Client
+	notified string          // Last version reported as available
+	failed   map[string]bool // Versions rolled back, not installed again
+}
+
+// New creates an updater for t
+func New(opts Options, t Target) (*Updater, error) {
+	if err := checkURL(opts.Feed); err != nil {
+		return nil, err
+	}
+	current, err := ParseVersion(opts.Current)
+	if err != nil {
+		return nil, fmt.Errorf("unknown current version: %w", err)
+	}
+	if opts.Auto && opts.PublicKey == nil {
+		return nil, errors.New("automatic updates require a public key")
+	}
+	if opts.Executable == "" {
+		if opts.Executable, err = os.Executable(); err != nil {
+			return nil, err
+		}
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = defaultInterval
+	}
+	if opts.HealthTimeout <= 0 {
+		opts.HealthTimeout = defaultHealthTimeout
+	}
+	return &Updater{
+		Options: opts,
+		current: current,
+		t:       t,
+		client:  &http.Client{},
+		failed:  map[string]bool{},
+	}, nil
+}
+
+// Recover rolls back an update of exe whose health check never completed, because the
+// daemon stopped or crashed meanwhile. It returns the version rolled back, if any.
+func Recover(exe string) (string, error) {
+	pending := pendingPath(exe)
+	data, err := os.ReadFile(pending)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+	version := strings.TrimSpace(string(data))
+	if _, err := Rollback(exe); err != nil {
+		return version, err
+	}
+	return version, os.Remove(pending)
+}
+
+// Run checks the feed now and then every Interval until ctx is done
+func (u *Updater) Run(ctx context.Context) {
+	ticker := time.NewTicker(u.Interval)
+	defer ticker.Stop()
+	for {
+		u.check(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// check fetches the latest release, records whether it is newer, and installs it when
+// automatic updates are enabled and the maintenance window is open
+func (u *Updater) check(ctx context.Context) {
+	status := state.Update{Current: u.Current, CheckedAt: time.Now()}
+	defer func() { u.t.SetUpdate(status) }()
+
+	r, err := Fetch(ctx, u.client, u.Feed)
+	if err != nil {
+		slog.Warn("Update check failed", "feed", u.Feed, "error", err)
+		status.Error = err.Error()
+		return
+	}
+	latest, _ := ParseVersion(r.Version) // Checked by Fetch
+	if latest.Compare(u.current) <= 0 {
+		return
+	}
+
+	status.Available = r.Version
+	if u.notified != r.Version {
+		u.notified = r.Version
+		slog.Info("Update available", "current", u.Current, "available", r.Version)
+		u.t.NotifyUpdate(fmt.Sprintf("%s available", r.Version), nil)
+	}
+	if !u.Auto || u.failed[r.Version] || !u.Window.Contains(time.Now()) {
+		return
+	}
+
+	if err := u.install(ctx, r); err != nil {
+		status.Error = err.Error()
+		return
+	}
+	status.Current, status.Available = r.Version, ""
+}
+
+// install replaces the executable with release r and restarts the child with it,
+// rolling back when the child isn't healthy afterwards
+func (u *Updater) install(ctx context.Context, r *Release) error {
+	path, err := Download(ctx, u.client, r, u.PublicKey, u.Executable)
+	if err != nil {
+		slog.Warn("Update download failed", "version", r.Version, "error", err)
+		return err
+	}
+	pending := pendingPath(u.Executable)
+	if err := os.WriteFile(pending, []byte(r.Version), 0o644); err != nil {
+		os.Remove(path)
+		return fmt.Errorf("failed to install update: %w", err)
+	}
+	snapshot, err := u.t.SnapshotBefore("update to " + r.Version)
+	if err != nil {
+		os.Remove(path)
+		os.Remove(pending)
+		return fmt.Errorf("failed to install update: %w", err)
+	}
+	if err := Replace(u.Executable, path, r.Version); err != nil {
+		os.Remove(path)
+		os.Remove(pending)
+		return err
+	}
+
+	slog.Info("Installed update, restarting the child", "version", r.Version)
+	prev := u.t.Status().ChildPID
+	err = u.t.RestartForUpdate()
+	if err == nil {
+		err = u.healthy(ctx, prev)
+	}
+	if ctx.Err() != nil {
+		return ctx.Err() // Stopping, Recover rolls back on the next start
+	}
+	if err != nil {
+		return u.rollback(r.Version, snapshot, err)
+	}
+
+	os.Remove(pending)
+	u.current, _ = ParseVersion(r.Version)
+	u.Current = r.Version
+	slog.Info("Update healthy, the supervisor runs it after the next service restart", "version", r.Version)
+	u.t.NotifyUpdate(fmt.Sprintf("installed %s", r.Version), nil)
+	return nil
+}
+
+// rollback restores the previous executable, and the snapshot taken before the update
+// when not empty, after release version failed with cause
+func (u *Updater) rollback(version, snapshot string, cause error) error {
+	u.failed[version] = true
+	err := fmt.Errorf("update to %s failed: %w", version, cause)
+	slog.Error("Rolling back update", "version", version, "error", cause)
+
+	if _, rerr := Rollback(u.Executable); rerr != nil {
+		slog.Error("Rollback failed", "error", rerr)
+		return errors.Join(err, rerr)
+	}
+	os.Remove(pendingPath(u.Executable))
+	if snapshot != "" {
+		if rerr := u.t.RestoreSnapshot(snapshot); rerr != nil {
+			slog.Warn("Not restoring the snapshot taken before the update", "snapshot", snapshot, "error", rerr)
+		}
+	}
+	if rerr := u.t.RestartForUpdate(); rerr != nil {
+		slog.Warn("Failed to restart the child after the rollback", "error", rerr)
+	}
+	u.t.NotifyUpdate(fmt.Sprintf("rolled back %s", version), cause)
+	return err
+}
+
+// healthy waits HealthTimeout for the child replacing the one with PID prev to be ready
+// and to keep running
+func (u *Updater) healthy(ctx context.Context, prev int) error {
+	deadline := time.After(u.HealthTimeout)
+	ticker := time.NewTicker(healthPollInterval)
+	defer ticker.Stop()
+
+	var pid, restarts int
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-deadline:
+			st := u.t.Status()
+			if pid == 0 || st.ChildPID != pid || !st.Ready {
+				return fmt.Errorf("child not ready after %v", u.HealthTimeout)
+			}
+			return nil
+		case <-ticker.C:
+		}
+
+		st := u.t.Status()
+		switch {
+		case pid == 0:
+			if st.ChildPID != 0 && st.ChildPID != prev {
+				pid, restarts = st.ChildPID, st.Restarts
+			}
+		case st.ChildPID != pid || st.Restarts != restarts:
+			return errors.New("child exited")
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package update
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/lucasdecamargo/go-appservice-example/pkg/atomicfile"
+)
+
+// Names in a versions directory
+const (
+	versionsDir  = "versions" // One directory per installed version
+	linkCurrent  = "current"  // Link to the running version
+	linkPrevious = "previous" // Link to the version rolled back to
+	pendingFile  = "pending"  // Version whose health check hasn't completed
+)
+
+var (
+	// ErrNotVersioned is returned when the executable isn't run from a versions directory
+	ErrNotVersioned = errors.New("the executable isn't run from a versions directory")
+	// ErrNoPrevious is returned by Rollback when there is no version to go back to
+	ErrNoPrevious = errors.New("no previous version to roll back to")
+)
+
+// Versions is a directory keeping every installed version of the executable, as
+//
+//	<root>/versions/<version>/<name>
+//	<root>/current -> versions/<version>
+//	<root>/previous -> versions/<version>
+//
+// The service runs <root>/current/<name>, so switching the link switches the version.
+// Links are symlinks, and junctions on Windows.
+type Versions struct {
+	Root string
+	Name string // File name of the executable
+}
+
+// DetectVersions returns the versions directory exe runs from, through the current
+// link or from its version directory
+func DetectVersions(exe string) (Versions, bool) {
+	dir := filepath.Dir(exe)
+	root := filepath.Dir(dir)
+	if filepath.Base(dir) != linkCurrent {
+		if filepath.Base(root) != versionsDir {
+			return Versions{}, false
+		}
+		root = filepath.Dir(root)
+	}
+	if _, err := os.Lstat(filepath.Join(root, linkCurrent)); err != nil {
+		return Versions{}, false
+	}
+	return Versions{Root: root, Name: filepath.Base(exe)}, true
+}
+
+// Executable returns the running executable, through the current link when it runs
+// from a versions directory, so the service and the child follow version switches
+func Executable() (string, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return "", err
+	}
+	if v, ok := DetectVersions(exe); ok {
+		return v.Executable(), nil
+	}
+	return exe, nil
+}
+
+// Executable returns the path of the current executable
+func (v Versions) Executable() string {
+	return filepath.Join(v.Root, linkCurrent, v.Name)
+}
+
+// Path returns the executable of version
+func (v Versions) Path(version string) string {
+	return filepath.Join(v.Root, versionsDir, version, v.Name)
+}
+
+// Current returns the version the current link points to
+func (v Versions) Current() (string, error) {
+	return v.readLink(linkCurrent)
+}
+
+// Previous returns the version Rollback switches to, or ErrNoPrevious
+func (v Versions) Previous() (string, error) {
+	version, err := v.readLink(linkPrevious)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", ErrNoPrevious
+	}
+	return version, err
+}
+
+// List returns the installed versions, oldest first
+func (v Versions) List() ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(v.Root, versionsDir))
+	if err != nil {
+		return nil, err
+	}
+	var list []string
+	for _, e := range entries {
+		if _, err := ParseVersion(e.Name()); e.IsDir() && err == nil {
+			list = append(list, e.Name())
+		}
+	}
+	slices.SortFunc(list, func(a, b string) int {
+		va, _ := ParseVersion(a)
+		vb, _ := ParseVersion(b)
+		return va.Compare(vb)
+	})
+	return list, nil
+}
+
+// Install copies the executable at src into the directory of version, replacing a
+// previous copy of the same version
+func (v Versions) Install(version, src string) error {
+	if _, err := ParseVersion(version); err != nil || strings.ContainsAny(version, `/\`) {
+		return fmt.Errorf("invalid version %q", version)
+	}
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	dst := v.Path(version)
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+	return atomicfile.WriteFile(dst, data, 0o755, true)
+}
+
+// Switch points the current link to version, and the previous link to the version
+// it replaces
+func (v Versions) Switch(version string) error {
+	if _, err := os.Stat(v.Path(version)); err != nil {
+		return fmt.Errorf("version %s not installed: %w", version, err)
+	}
+	current, err := v.Current()
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if current == version {
+		return nil
+	}
+	if err := v.link(version, linkCurrent); err != nil {
+		return fmt.Errorf("failed to switch to %s: %w", version, err)
+	}
+	if current != "" {
+		if err := v.link(current, linkPrevious); err != nil {
+			return fmt.Errorf("failed to keep %s as the previous version: %w", current, err)
+		}
+	}
+	return nil
+}
+
+// Rollback switches back to the previous version and returns it. The previous link
+// is removed rather than pointed at the version rolled back from, so a second
+// rollback fails with ErrNoPrevious instead of returning to it.
+func (v Versions) Rollback() (string, error) {
+	previous, err := v.Previous()
+	if err != nil {
+		return "", err
+	}
+	if err := v.link(previous, linkCurrent); err != nil {
+		return "", fmt.Errorf("failed to switch to %s: %w", previous, err)
+	}
+	if err := os.Remove(filepath.Join(v.Root, linkPrevious)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return previous, fmt.Errorf("failed to remove the previous link: %w", err)
+	}
+	return previous, nil
+}
+
+// link points the link name to the directory of version, replacing it
+func (v Versions) link(version, name string) error {
+	return replaceLink(filepath.Join(v.Root, versionsDir, version), filepath.Join(v.Root, name))
+}
+
+// readLink returns the version the link name points to
+func (v Versions) readLink(name string) (string, error) {
+	target, err := os.Readlink(filepath.Join(v.Root, name))
+	if err != nil {
+		return "", err
+	}
+	return filepath.Base(target), nil
+}
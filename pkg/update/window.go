@@ -0,0 +1,63 @@
+package update
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Window is a daily maintenance window in local time, such as 02:00-04:00. A window
+// ending before it starts spans midnight. The zero Window is always open.
+type Window struct {
+	Start, End time.Duration // Offsets from midnight
+}
+
+// ParseWindow parses "HH:MM-HH:MM". An empty string yields the zero Window.
+func ParseWindow(s string) (Window, error) {
+	if s == "" {
+		return Window{}, nil
+	}
+	start, end, ok := strings.Cut(s, "-")
+	if !ok {
+		return Window{}, fmt.Errorf("invalid maintenance window %q: expected HH:MM-HH:MM", s)
+	}
+	var w Window
+	for _, p := range []struct {
+		s string
+		d *time.Duration
+	}{{start, &w.Start}, {end, &w.End}} {
+		t, err := time.Parse("15:04", strings.TrimSpace(p.s))
+		if err != nil {
+			return Window{}, fmt.Errorf("invalid maintenance window %q: expected HH:MM-HH:MM", s)
+		}
+		*p.d = time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+	}
+	if w.Start == w.End {
+		return Window{}, fmt.Errorf("invalid maintenance window %q: empty", s)
+	}
+	return w, nil
+}
+
+// Contains reports whether the window is open at t
+func (w Window) Contains(t time.Time) bool {
+	if w == (Window{}) {
+		return true
+	}
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	at := t.Sub(midnight)
+	if w.Start < w.End {
+		return at >= w.Start && at < w.End
+	}
+	return at >= w.Start || at < w.End
+}
+
+// String formats the window as ParseWindow reads it
+func (w Window) String() string {
+	if w == (Window{}) {
+		return "any time"
+	}
+	clock := func(d time.Duration) string {
+		return fmt.Sprintf("%02d:%02d", int(d.Hours()), int(d.Minutes())%60)
+	}
+	return clock(w.Start) + "-" + clock(w.End)
+}
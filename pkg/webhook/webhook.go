@@ -0,0 +1,215 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+	"unsafe"
+
+	"github.com/lucasdecamargo/go-appservice-example/pkg/daemon"
+)
+
+// Headers sent with every delivery
+const (
+	HeaderEvent     = "X-Svcapp-Event"
+	HeaderTimestamp = "X-Svcapp-Timestamp"
+	HeaderSignature = "X-Svcapp-Signature" // "sha256=" and the hex HMAC of Signed
+)
+
+const (
+	defaultRetries = 3
+	retryBackoff   = time.Second
+	requestTimeout = 10 * time.Second
+	queueSize      = 64
+)
+
+// eventTypes are the lifecycle events endpoints can subscribe to
+var eventTypes = []string{daemon.EventStarted, daemon.EventReady, daemon.EventCrashed, daemon.EventRestarted, daemon.EventStopped, daemon.EventUpdate}
+
+// Endpoint is a webhook URL and the events delivered to it
+type Endpoint struct {
+	URL    string   // https:// URL receiving the events
+	Secret string   // HMAC-SHA256 key signing the deliveries, empty to leave them unsigned
+	Events []string // Event types delivered, all of them when empty
+}
+
+// Payload is the JSON body of a delivery
+type Payload struct {
+	daemon.LifecycleEvent
+	Service  string `json:"service"`
+	Hostname string `json:"hostname"`
+}
+
+// Signed returns the bytes signed for a delivery: the timestamp header, a dot and the body
+func Signed(timestamp string, body []byte) []byte {
+	return append([]byte(timestamp+"."), body...)
+}
+
+// Sign returns the signature header value of a delivery
+func Sign(secret, timestamp string, body []byte) string {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write(Signed(timestamp, body))
+	return "sha256=" + hex.EncodeToString(h.Sum(nil))
+}
+
+// Sender delivers lifecycle events to webhook endpoints. Each endpoint has its own
+// queue, so a slow or unreachable endpoint doesn't delay the others.
+type Sender struct {
+	service  string
+	hostname string
+	client   *http.Client
+	queues   []*queue
+	wg       sync.WaitGroup
+
+	mu     sync.Mutex
+	closed bool
+}
+
+// queue holds the pending deliveries of one endpoint
+type queue struct {
+	Endpoint
+	events chan daemon.LifecycleEvent
+}
+
+// New validates the endpoints and starts delivering to them
+func New(service string, endpoints []Endpoint) (*Sender, error) {
+	hostname, _ := os.Hostname()
+	s := &Sender{service: service, hostname: hostname, client: &http.Client{Timeout: requestTimeout}}
+
+	for _, e := range endpoints {
+		u, err := url.Parse(e.URL)
+		if err != nil || u.Scheme != "https" || u.Host == "" {
+			return nil, fmt.Errorf("webhook URL must be an https:// URL: %q", e.URL)
+		}
+		for _, event := range e.Events {
+			if !slices.Contains(eventTypes, event) {
+				return nil, fmt.Errorf("unknown webhook event %q: expected one of %v", event, eventTypes)
+			}
+		}
+		s.queues = append(s.queues, &queue{Endpoint: e, events: make(chan daemon.LifecycleEvent, queueSize)})
+	}
+
+	for _, q := range s.queues {
+		s.wg.Go(func() { s.deliver(q) })
+	}
+	return s, nil
+}
+
+// Footprint returns the approximate memory held by the queues, in bytes, and the
+// number of delivery goroutines
+func (s *Sender) Footprint() (int64, int) {
+	return int64(len(s.queues)*queueSize) * int64(unsafe.Sizeof(daemon.LifecycleEvent{})), len(s.queues)
+}
+
+// Notify queues e for the endpoints subscribed to it, dropping it for those whose
+// queue is full. It never blocks.
+func (s *Sender) Notify(e daemon.LifecycleEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+
+	for _, q := range s.queues {
+		if len(q.Events) > 0 && !slices.Contains(q.Events, e.Type) {
+			continue
+		}
+		select {
+		case q.events <- e:
+		default:
+			slog.Warn("Webhook queue full, dropping event", "url", q.URL, "event", e.Type)
+		}
+	}
+}
+
+// Close stops accepting events and waits up to timeout for the queued ones to be delivered
+func (s *Sender) Close(timeout time.Duration) {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return
+	}
+	s.closed = true
+	for _, q := range s.queues {
+		close(q.events)
+	}
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		slog.Warn("Webhook deliveries pending on exit")
+	}
+}
+
+// deliver sends the events of q in order until its queue is closed
+func (s *Sender) deliver(q *queue) {
+	for e := range q.events {
+		body, err := json.Marshal(Payload{LifecycleEvent: e, Service: s.service, Hostname: s.hostname})
+		if err != nil {
+			continue
+		}
+
+		backoff := retryBackoff
+		for attempt := 0; ; attempt++ {
+			err = s.post(q.Endpoint, e.Type, body)
+			if err == nil || attempt == defaultRetries {
+				break
+			}
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+		if err != nil {
+			slog.Warn("Webhook delivery failed", "url", q.URL, "event", e.Type, "attempts", defaultRetries+1, "error", err)
+		}
+	}
+}
+
+// post delivers one signed event to an endpoint
+func (s *Sender) post(e Endpoint, event string, body []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, event)
+	req.Header.Set(HeaderTimestamp, timestamp)
+	if e.Secret != "" {
+		req.Header.Set(HeaderSignature, Sign(e.Secret, timestamp, body))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
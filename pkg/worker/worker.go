@@ -0,0 +1,166 @@
+// Package worker runs a pool of workers consuming a queue, a building block for the
+// RunFunc of a service built on this skeleton. When the run context is done, the
+// workers drain the queued items before returning, within a bounded time.
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrDrainTimeout is returned by Run when queued items are left once the drain timeout
+// expires
+var ErrDrainTimeout = errors.New("worker pool drain timed out")
+
+// Pool runs Workers goroutines calling Handle for each item received from a queue
+type Pool[T any] struct {
+	Workers int                                     // Number of workers, GOMAXPROCS by default
+	Handle  func(ctx context.Context, item T) error // Processes one item
+	OnError func(item T, err error)                 // Called when Handle fails or panics, logs the error by default
+
+	// DrainTimeout bounds the time the workers have, once the run context is done, to
+	// finish their items and the ones still queued. The context passed to Handle is
+	// canceled when it expires. Zero only lets the workers finish their current items.
+	DrainTimeout time.Duration
+
+	processed, failed atomic.Int64
+}
+
+// Stats are the counts of items handled by a pool
+type Stats struct {
+	Processed int64 // Items handled, including failed ones
+	Failed    int64 // Items Handle returned an error or panicked for
+}
+
+// Run starts the workers and consumes queue until it is closed, or until ctx is done
+// and the queued items are drained. It returns ErrDrainTimeout when the drain timeout
+// expired with items still queued or running.
+func (p *Pool[T]) Run(ctx context.Context, queue <-chan T) error {
+	workers := p.Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	// Handlers keep running through the drain, until its deadline
+	handleCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancel()
+	draining := make(chan struct{})
+	var (
+		timedOut atomic.Bool
+		mu       sync.Mutex
+		deadline *time.Timer // Drain deadline, nil until ctx is done
+		finished bool        // The workers exited, no deadline is needed anymore
+	)
+	stop := context.AfterFunc(ctx, func() {
+		close(draining)
+		if p.DrainTimeout <= 0 {
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if !finished {
+			deadline = time.AfterFunc(p.DrainTimeout, func() {
+				timedOut.Store(true)
+				cancel()
+			})
+		}
+	})
+
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Go(func() { p.work(handleCtx, queue, draining) })
+	}
+	wg.Wait()
+
+	stop()
+	mu.Lock()
+	finished = true
+	if deadline != nil {
+		deadline.Stop()
+	}
+	mu.Unlock()
+
+	if ctx.Err() == nil {
+		return nil
+	}
+	left := len(queue)
+	switch {
+	case timedOut.Load():
+		return fmt.Errorf("%w after %v, %d items left queued", ErrDrainTimeout, p.DrainTimeout, left)
+	case left > 0:
+		slog.Info("Worker pool stopped with items queued", "queued", left)
+	}
+	return nil
+}
+
+// Stats returns the counts of items handled so far
+func (p *Pool[T]) Stats() Stats {
+	return Stats{Processed: p.processed.Load(), Failed: p.failed.Load()}
+}
+
+// work handles items until there are none left to take
+func (p *Pool[T]) work(ctx context.Context, queue <-chan T, draining <-chan struct{}) {
+	for {
+		item, ok := p.next(ctx, queue, draining)
+		if !ok {
+			return
+		}
+		p.handle(ctx, item)
+	}
+}
+
+// next receives the next item of queue. Once draining is closed, it only takes the
+// items already queued, and none when there is no drain timeout or it expired.
+func (p *Pool[T]) next(ctx context.Context, queue <-chan T, draining <-chan struct{}) (T, bool) {
+	var zero T
+	select {
+	case <-draining:
+		if p.DrainTimeout <= 0 || ctx.Err() != nil {
+			return zero, false
+		}
+		select {
+		case item, ok := <-queue:
+			return item, ok
+		default:
+			return zero, false // Drained
+		}
+	default:
+	}
+
+	select {
+	case item, ok := <-queue:
+		return item, ok
+	case <-draining:
+		return p.next(ctx, queue, draining)
+	}
+}
+
+// handle calls Handle for item, turning a panic into an error
+func (p *Pool[T]) handle(ctx context.Context, item T) {
+	err := func() (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("worker panic: %v\n%s", r, debug.Stack())
+			}
+		}()
+		return p.Handle(ctx, item)
+	}()
+
+	p.processed.Add(1)
+	if err == nil {
+		return
+	}
+	p.failed.Add(1)
+	if p.OnError != nil {
+		p.OnError(item, err)
+		return
+	}
+	slog.Error("Worker failed to handle an item", "error", err)
+}